@@ -44,7 +44,10 @@ func (a *Application) Run(ctx context.Context) {
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 	// Running workers
-	workers.RunMultiple(ctx, a.config.ComputingPower, a.config.RetryCount, a.logger, client, do.Do)
+	workers.RunMultiple(ctx, a.config.ComputingPower, a.config.RetryCount, a.logger, client, do.Solver{
+		Scale:    a.config.DecimalScale,
+		Rounding: do.Rounding(a.config.DecimalRounding),
+	}.Solve)
 	// Waiting for context to be done
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 	<-ctx.Done()
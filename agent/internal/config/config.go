@@ -1,6 +1,9 @@
 package config
 
 import (
+	"agent/pkg/do"
+	"fmt"
+
 	"github.com/goloop/env"
 )
 
@@ -9,7 +12,9 @@ type Config struct {
 	ComputingPower int    `env:"COMPUTING_POWER" def:"10"`
 	RetryCount     int    `env:"RETRY_COUNT" def:"5"`
 	LogFile        string `env:"LOG_FILE" def:"logs.log"`
-
+	// Digits after the point for decimal results that are not finite
+	DecimalScale    int    `env:"DECIMAL_SCALE" def:"20"`
+	DecimalRounding string `env:"DECIMAL_ROUNDING" def:"half_even"`
 }
 
 func LoadConfig() (*Config, error) {
@@ -18,6 +23,12 @@ func LoadConfig() (*Config, error) {
 	if err := env.Unmarshal("", &cfg); err != nil {
 		return nil, err
 	}
+	if !do.Rounding(cfg.DecimalRounding).IsValid() {
+		return nil, fmt.Errorf("invalid decimal rounding %q", cfg.DecimalRounding)
+	}
+	if cfg.DecimalScale < 0 {
+		return nil, fmt.Errorf("invalid decimal scale %d", cfg.DecimalScale)
+	}
 
 	return &cfg, nil
 }
@@ -1,14 +1,27 @@
 package workers
 
 import (
+	"agent/pkg/wire"
 	"context"
+	"errors"
 	"io"
 
 	pb "github.com/vandi37/Calculator-Models"
 	"go.uber.org/zap"
 )
 
-type DoingFunc func(req *pb.Task) (float64, error)
+// The worker sets the id of the result
+// Errors with a code send it in the extra field
+func errorOf(id string, err error) *pb.Error {
+	res := &pb.Error{Id: id, Error: err.Error()}
+	var coded interface{ Code() string }
+	if errors.As(err, &coded) {
+		wire.SetString(res, wire.ErrorCode, coded.Code())
+	}
+	return res
+}
+
+type DoingFunc func(req *pb.Task) (*pb.Result, error)
 
 func RunMultiple(ctx context.Context, num, retryCount int, logger *zap.Logger, client pb.TaskServiceClient, doing DoingFunc) {
 	logger.Info("starting workers", zap.Int("workers", num))
@@ -63,22 +76,20 @@ func Run(ctx context.Context, id, retryCount int, logger *zap.Logger, client pb.
 			res, err := doing(task)
 			if err != nil {
 				logger.Debug("task failed", worker, zap.String("id", task.Id), zap.Error(err))
-				_, err := client.SendError(ctx, &pb.Error{Id: task.Id, Error: err.Error()})
+				_, err := client.SendError(ctx, errorOf(task.Id, err))
 				if err != nil {
 					logger.Error("sending error failed", worker, zap.String("id", task.Id), zap.Error(err))
 				}
 				continue
 			}
-			_, err = client.SendResult(ctx, &pb.Result{
-				Id:     task.Id,
-				Result: res,
-			})
+			res.Id = task.Id
+			_, err = client.SendResult(ctx, res)
 			if err != nil {
 				logger.Error("sending result failed", worker, zap.String("id", task.Id), zap.Error(err))
 				continue
 			}
 
-			logger.Debug("sended result", worker, zap.String("id", task.Id), zap.Float64("result", res), zap.Float64("arg1", task.Arg1), zap.String("operation", task.Operation.String()), zap.Float64("arg2", task.Arg2))
+			logger.Debug("sended result", worker, zap.String("id", task.Id), zap.Float64("result", res.Result), zap.Float64("arg1", task.Arg1), zap.String("operation", task.Operation.String()), zap.Float64("arg2", task.Arg2))
 		}
 	}
 }
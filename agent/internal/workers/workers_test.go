@@ -2,6 +2,8 @@ package workers_test
 
 import (
 	"agent/internal/workers"
+	"agent/pkg/do"
+	"agent/pkg/wire"
 	"context"
 	"errors"
 	"testing"
@@ -22,8 +24,8 @@ func TestRunMultiple(t *testing.T) {
 		logger := zaptest.NewLogger(t)
 		client := NewTestingClient()
 
-		go workers.RunMultiple(ctx, 3, 0, logger, client, func(req *pb.Task) (float64, error) {
-			return 0, nil
+		go workers.RunMultiple(ctx, 3, 0, logger, client, func(req *pb.Task) (*pb.Result, error) {
+			return &pb.Result{}, nil
 		})
 
 		time.Sleep(100 * time.Millisecond)
@@ -46,8 +48,8 @@ func TestRunMultiple(t *testing.T) {
 
 		done := make(chan struct{})
 		go func() {
-			workers.RunMultiple(ctx, 2, 0, logger, client, func(req *pb.Task) (float64, error) {
-				return 0, nil
+			workers.RunMultiple(ctx, 2, 0, logger, client, func(req *pb.Task) (*pb.Result, error) {
+				return &pb.Result{}, nil
 			})
 			close(done)
 		}()
@@ -71,8 +73,8 @@ func TestRun(t *testing.T) {
 		logger := zaptest.NewLogger(t)
 		client := NewTestingClient()
 
-		go workers.Run(ctx, 1, 0, logger, client, func(req *pb.Task) (float64, error) {
-			return req.Arg1, nil
+		go workers.Run(ctx, 1, 0, logger, client, func(req *pb.Task) (*pb.Result, error) {
+			return &pb.Result{Result: req.Arg1}, nil
 		})
 
 		task := &pb.Task{
@@ -103,8 +105,8 @@ func TestRun(t *testing.T) {
 		logger := zaptest.NewLogger(t)
 		client := NewTestingClient()
 
-		go workers.Run(ctx, 1, 0, logger, client, func(req *pb.Task) (float64, error) {
-			return 0, errors.New("processing error")
+		go workers.Run(ctx, 1, 0, logger, client, func(req *pb.Task) (*pb.Result, error) {
+			return nil, errors.New("processing error")
 		})
 
 		task := &pb.Task{Id: "test2"}
@@ -120,6 +122,37 @@ func TestRun(t *testing.T) {
 
 		require.Equal(t, SendError, errorReq.RequestType)
 		assert.Equal(t, "processing error", errorReq.Error.Error)
+		_, ok := wire.GetString(errorReq.Error, wire.ErrorCode)
+		assert.False(t, ok)
+	})
+
+	t.Run("sends error code", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		logger := zaptest.NewLogger(t)
+		client := NewTestingClient()
+
+		go workers.Run(ctx, 1, 0, logger, client, func(req *pb.Task) (*pb.Result, error) {
+			return nil, do.Overflow
+		})
+
+		client.TASK(&pb.Task{Id: "test3"})
+		<-client.queue // pass request for stream
+
+		var errorReq Request
+		select {
+		case errorReq = <-client.queue:
+		case <-time.After(500 * time.Millisecond):
+			t.Fatal("timeout waiting for error")
+		}
+
+		require.Equal(t, SendError, errorReq.RequestType)
+		assert.Equal(t, do.Overflow.Error(), errorReq.Error.Error)
+		code, ok := wire.GetString(errorReq.Error, wire.ErrorCode)
+		assert.True(t, ok)
+		assert.Equal(t, wire.Overflow, code)
 	})
 
 	t.Run("handles stream error", func(t *testing.T) {
@@ -132,8 +165,8 @@ func TestRun(t *testing.T) {
 
 		client.TO_ERROR()
 
-		workers.Run(ctx, 1, 0, logger, client, func(req *pb.Task) (float64, error) {
-			return 0, nil
+		workers.Run(ctx, 1, 0, logger, client, func(req *pb.Task) (*pb.Result, error) {
+			return &pb.Result{}, nil
 		})
 	})
 
@@ -145,8 +178,8 @@ func TestRun(t *testing.T) {
 		logger := zaptest.NewLogger(t)
 		client := NewTestingClient()
 
-		go workers.Run(ctx, 1, 5, logger, client, func(req *pb.Task) (float64, error) {
-			return 0, nil
+		go workers.Run(ctx, 1, 5, logger, client, func(req *pb.Task) (*pb.Result, error) {
+			return &pb.Result{}, nil
 		})
 
 		close(client.tasks)
@@ -170,8 +203,8 @@ func TestRun(t *testing.T) {
 
 		done := make(chan struct{})
 		go func() {
-			workers.Run(ctx, 1, 0, logger, client, func(req *pb.Task) (float64, error) {
-				return 0, nil
+			workers.Run(ctx, 1, 0, logger, client, func(req *pb.Task) (*pb.Result, error) {
+				return &pb.Result{}, nil
 			})
 			close(done)
 		}()
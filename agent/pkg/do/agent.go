@@ -1,6 +1,7 @@
 package do
 
 import (
+	"math"
 	"time"
 
 	pb "github.com/vandi37/Calculator-Models"
@@ -20,9 +21,118 @@ func Do(req *pb.Task) (float64, error) {
 			return 0, DivisionByZero
 		}
 		f = req.Arg1 / req.Arg2
+	case Power:
+		f = math.Pow(req.Arg1, req.Arg2)
+	case Modulo:
+		if req.Arg2 == 0 {
+			return 0, ModuloByZero
+		}
+		f = mod(req.Arg1, req.Arg2)
+	case IntDivide:
+		if req.Arg2 == 0 {
+			return 0, DivisionByZero
+		}
+		f = math.Floor(req.Arg1 / req.Arg2)
+	case Sqrt:
+		if req.Arg1 < 0 {
+			return 0, NegativeSqrt
+		}
+		f = math.Sqrt(req.Arg1)
+	case Abs:
+		f = math.Abs(req.Arg1)
+	case Round:
+		f = roundPlaces(req.Arg1, req.Arg2)
+	case Ln:
+		if req.Arg1 <= 0 {
+			return 0, NonPositiveLog
+		}
+		f = math.Log(req.Arg1)
+	case Log:
+		if req.Arg1 <= 0 {
+			return 0, NonPositiveLog
+		}
+		if req.Arg2 <= 0 || req.Arg2 == 1 {
+			return 0, InvalidLogBase
+		}
+		f = math.Log(req.Arg1) / math.Log(req.Arg2)
+	case Min:
+		f = math.Min(req.Arg1, req.Arg2)
+	case Max:
+		f = math.Max(req.Arg1, req.Arg2)
+	case Negate:
+		f = -req.Arg1
+	case Less:
+		f = boolean(req.Arg1 < req.Arg2)
+	case LessEqual:
+		f = boolean(req.Arg1 <= req.Arg2)
+	case Greater:
+		f = boolean(req.Arg1 > req.Arg2)
+	case GreaterEqual:
+		f = boolean(req.Arg1 >= req.Arg2)
+	case Equal:
+		f = boolean(req.Arg1 == req.Arg2)
+	case NotEqual:
+		f = boolean(req.Arg1 != req.Arg2)
+	case And:
+		f = boolean(req.Arg1 != 0 && req.Arg2 != 0)
+	case Or:
+		f = boolean(req.Arg1 != 0 || req.Arg2 != 0)
+	case Not:
+		f = boolean(req.Arg1 == 0)
 	default:
 		return 0, UnknownOperation
 	}
+	if err := finite(f); err != nil {
+		return 0, err
+	}
 	time.Sleep(time.Millisecond * time.Duration(req.OperationTime))
 	return f, nil
 }
+
+func finite(f float64) error {
+	if math.IsNaN(f) {
+		return NotANumber
+	}
+	if math.IsInf(f, 0) {
+		return Overflow
+	}
+	return nil
+}
+
+// The result is zero, but it can't be: 1e-200 * 1e-200 or 2^-2000
+func Underflowed(req *pb.Task, f float64) bool {
+	if f != 0 || req.Arg1 == 0 {
+		return false
+	}
+	switch req.Operation {
+	case pb.Operation_MULTIPLY:
+		return req.Arg2 != 0
+	case pb.Operation_DIVIDE, Power:
+		return true
+	default:
+		return false
+	}
+}
+
+// Rounds to the places after the point, they can be negative (1234 with -1 places is 1230)
+func roundPlaces(x, places float64) float64 {
+	p := math.Pow(10, math.Trunc(places))
+	switch {
+	case p == 0:
+		// All digits of the float are under the place
+		return 0
+	case math.IsInf(p, 0) || math.IsInf(x*p, 0):
+		// The float has no digits at the place
+		return x
+	}
+	return math.Round(x*p) / p
+}
+
+// The remainder has the sign of the divisor, so a == b*(a//b) + a%b stays true
+func mod(a, b float64) float64 {
+	r := math.Mod(a, b)
+	if r != 0 && (r < 0) != (b < 0) {
+		r += b
+	}
+	return r
+}
@@ -160,6 +160,191 @@ func TestDo_Division(t *testing.T) {
 	}
 }
 
+func TestDo_Power(t *testing.T) {
+	tests := []struct {
+		name     string
+		arg1     float64
+		arg2     float64
+		expected float64
+	}{
+		{"positive exponent", 2.0, 10.0, 1024.0},
+		{"zero exponent", 7.8, 0, 1},
+		{"negative exponent", 2.0, -2.0, 0.25},
+		{"fractional exponent", 9.0, 0.5, 3.0},
+		{"negative base", -3.0, 3.0, -27.0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &pb.Task{
+				Operation:     do.Power,
+				Arg1:          tt.arg1,
+				Arg2:          tt.arg2,
+				OperationTime: 25,
+			}
+
+			result, err := do.Do(req)
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+
+			if !floatEquals(result, tt.expected) {
+				t.Errorf("Expected %f, got %f (diff: %e)", tt.expected, result, math.Abs(result-tt.expected))
+			}
+		})
+	}
+}
+
+func TestDo_Modulo(t *testing.T) {
+	tests := []struct {
+		name        string
+		arg1        float64
+		arg2        float64
+		expected    float64
+		expectError bool
+	}{
+		{"integer numbers", 10.0, 3.0, 1.0, false},
+		{"fractional numbers", 5.5, 2.0, 1.5, false},
+		{"negative dividend", -7.0, 3.0, 2.0, false},
+		{"negative divisor", 7.0, -3.0, -2.0, false},
+		{"exact division", 9.0, 3.0, 0, false},
+		{"modulo by zero", 10.0, 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &pb.Task{
+				Operation:     do.Modulo,
+				Arg1:          tt.arg1,
+				Arg2:          tt.arg2,
+				OperationTime: 25,
+			}
+
+			result, err := do.Do(req)
+			if tt.expectError {
+				if err != do.ModuloByZero {
+					t.Errorf("Expected ModuloByZero error, got %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+
+			if !floatEquals(result, tt.expected) {
+				t.Errorf("Expected %f, got %f (diff: %e)", tt.expected, result, math.Abs(result-tt.expected))
+			}
+		})
+	}
+}
+
+func TestDo_IntDivide(t *testing.T) {
+	tests := []struct {
+		name        string
+		arg1        float64
+		arg2        float64
+		expected    float64
+		expectError bool
+	}{
+		{"integer numbers", 7.0, 2.0, 3.0, false},
+		{"fractional numbers", 7.5, 2.5, 3.0, false},
+		{"negative result rounds down", -7.0, 2.0, -4.0, false},
+		{"divide by zero", 10.0, 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &pb.Task{
+				Operation:     do.IntDivide,
+				Arg1:          tt.arg1,
+				Arg2:          tt.arg2,
+				OperationTime: 25,
+			}
+
+			result, err := do.Do(req)
+			if tt.expectError {
+				if err != do.DivisionByZero {
+					t.Errorf("Expected DivisionByZero error, got %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+
+			if !floatEquals(result, tt.expected) {
+				t.Errorf("Expected %f, got %f (diff: %e)", tt.expected, result, math.Abs(result-tt.expected))
+			}
+		})
+	}
+}
+
+func TestDo_Functions(t *testing.T) {
+	tests := []struct {
+		name        string
+		operation   pb.Operation
+		arg1        float64
+		arg2        float64
+		expected    float64
+		expectedErr error
+	}{
+		{"square root", do.Sqrt, 16.0, 0, 4.0, nil},
+		{"square root of negative", do.Sqrt, -4.0, 0, 0, do.NegativeSqrt},
+		{"absolute value", do.Abs, -2.5, 0, 2.5, nil},
+		{"round", do.Round, 2.5, 0, 3.0, nil},
+		{"round negative", do.Round, -2.4, 0, -2.0, nil},
+		{"round with places", do.Round, 3.14159, 2, 3.14, nil},
+		{"round with too many places", do.Round, 1.5, 400, 1.5, nil},
+		{"round above all digits", do.Round, 1.5, -400, 0, nil},
+		{"natural logarithm", do.Ln, math.E, 0, 1.0, nil},
+		{"natural logarithm of zero", do.Ln, 0, 0, 0, do.NonPositiveLog},
+		{"logarithm with base", do.Log, 8.0, 2.0, 3.0, nil},
+		{"logarithm of negative", do.Log, -8.0, 2.0, 0, do.NonPositiveLog},
+		{"logarithm with base one", do.Log, 8.0, 1.0, 0, do.InvalidLogBase},
+		{"minimum", do.Min, -1.0, 2.0, -1.0, nil},
+		{"maximum", do.Max, -1.0, 2.0, 2.0, nil},
+		{"negation", do.Negate, 2.5, 0, -2.5, nil},
+		{"less", do.Less, 1, 2, 1, nil},
+		{"less or equal", do.LessEqual, 2, 2, 1, nil},
+		{"greater", do.Greater, 1, 2, 0, nil},
+		{"greater or equal", do.GreaterEqual, 1, 2, 0, nil},
+		{"equal", do.Equal, 0.5, 0.5, 1, nil},
+		{"not equal", do.NotEqual, 0.5, 0.5, 0, nil},
+		{"and", do.And, 2, -3, 1, nil},
+		{"or", do.Or, 0, 0, 0, nil},
+		{"not", do.Not, 5, 0, 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &pb.Task{
+				Operation:     tt.operation,
+				Arg1:          tt.arg1,
+				Arg2:          tt.arg2,
+				OperationTime: 10,
+			}
+
+			result, err := do.Do(req)
+			if tt.expectedErr != nil {
+				if err != tt.expectedErr {
+					t.Errorf("Expected %v error, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+
+			if !floatEquals(result, tt.expected) {
+				t.Errorf("Expected %f, got %f (diff: %e)", tt.expected, result, math.Abs(result-tt.expected))
+			}
+		})
+	}
+}
+
 func TestDo_UnknownOperation(t *testing.T) {
 	req := &pb.Task{
 		Operation:     pb.Operation(-1),
@@ -217,3 +402,58 @@ func TestDo_TimingAccuracy(t *testing.T) {
 		})
 	}
 }
+
+func TestDo_NonFinite(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation pb.Operation
+		arg1      float64
+		arg2      float64
+		expected  error
+	}{
+		{"overflow", pb.Operation_MULTIPLY, 1e308, 10, do.Overflow},
+		{"negative overflow", pb.Operation_SUBTRACT, -1e308, 1e308, do.Overflow},
+		{"power overflow", do.Power, 10, 400, do.Overflow},
+		{"zero to negative power", do.Power, 0, -1, do.Overflow},
+		{"not a number", do.Power, -8, 1.0 / 3, do.NotANumber},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := do.Do(&pb.Task{Operation: tt.operation, Arg1: tt.arg1, Arg2: tt.arg2})
+			if err != tt.expected {
+				t.Errorf("Expected error %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestUnderflowed(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation pb.Operation
+		arg1      float64
+		arg2      float64
+		expected  bool
+	}{
+		{"multiplication", pb.Operation_MULTIPLY, 1e-200, 1e-200, true},
+		{"division", pb.Operation_DIVIDE, 1e-300, 1e300, true},
+		{"power", do.Power, 2, -2000, true},
+		{"real zero", pb.Operation_MULTIPLY, 0, 5, false},
+		{"subtraction", pb.Operation_SUBTRACT, 1, 1, false},
+		{"not zero", pb.Operation_MULTIPLY, 1e-100, 1e-100, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &pb.Task{Operation: tt.operation, Arg1: tt.arg1, Arg2: tt.arg2}
+			f, err := do.Do(req)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := do.Underflowed(req, f); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package do
+
+import (
+	"agent/pkg/wire"
+	"math/cmplx"
+	"time"
+
+	pb "github.com/vandi37/Calculator-Models"
+)
+
+// The task is complex if one of the arguments has the imaginary part, even a zero one
+func IsComplex(req *pb.Task) bool {
+	_, ok1 := wire.GetDouble(req, wire.Arg1Imag)
+	_, ok2 := wire.GetDouble(req, wire.Arg2Imag)
+	return ok1 || ok2
+}
+
+// The complex version of Do, Arg1 and Arg2 are the real parts
+func DoComplex(req *pb.Task) (complex128, error) {
+	im1, _ := wire.GetDouble(req, wire.Arg1Imag)
+	im2, _ := wire.GetDouble(req, wire.Arg2Imag)
+	x, y := complex(req.Arg1, im1), complex(req.Arg2, im2)
+
+	var z complex128
+	switch req.Operation {
+	case pb.Operation_ADD:
+		z = x + y
+	case pb.Operation_SUBTRACT:
+		z = x - y
+	case pb.Operation_MULTIPLY:
+		z = x * y
+	case pb.Operation_DIVIDE:
+		// Go gives Inf and NaN parts instead
+		if y == 0 {
+			return 0, DivisionByZero
+		}
+		z = x / y
+	case Power:
+		if x == 0 && y != 0 && real(y) <= 0 {
+			return 0, DivisionByZero
+		}
+		z = cmplx.Pow(x, y)
+	case Sqrt:
+		z = cmplx.Sqrt(x)
+	case Abs:
+		z = complex(cmplx.Abs(x), 0)
+	case Round:
+		z = complex(roundPlaces(real(x), real(y)), roundPlaces(imag(x), real(y)))
+	case Ln:
+		if x == 0 {
+			return 0, LogOfZero
+		}
+		z = cmplx.Log(x)
+	case Log:
+		if x == 0 {
+			return 0, LogOfZero
+		}
+		if y == 0 || y == 1 {
+			return 0, InvalidLogBase
+		}
+		z = cmplx.Log(x) / cmplx.Log(y)
+	case Negate:
+		z = -x
+	case Equal:
+		z = complex(boolean(x == y), 0)
+	case NotEqual:
+		z = complex(boolean(x != y), 0)
+	case And:
+		z = complex(boolean(x != 0 && y != 0), 0)
+	case Or:
+		z = complex(boolean(x != 0 || y != 0), 0)
+	case Not:
+		z = complex(boolean(x == 0), 0)
+	// Complex numbers have no order
+	case Modulo, IntDivide, Min, Max, Less, LessEqual, Greater, GreaterEqual:
+		return 0, NotForComplex
+	default:
+		return 0, UnknownOperation
+	}
+	if err := finite(real(z)); err != nil {
+		return 0, err
+	}
+	if err := finite(imag(z)); err != nil {
+		return 0, err
+	}
+	time.Sleep(time.Millisecond * time.Duration(req.OperationTime))
+	return z, nil
+}
@@ -0,0 +1,97 @@
+package do_test
+
+import (
+	"agent/pkg/do"
+	"agent/pkg/wire"
+	"math/cmplx"
+	"testing"
+
+	pb "github.com/vandi37/Calculator-Models"
+)
+
+func complexTask(op pb.Operation, x, y complex128) *pb.Task {
+	req := &pb.Task{Operation: op, Arg1: real(x), Arg2: real(y)}
+	wire.SetDouble(req, wire.Arg1Imag, imag(x))
+	wire.SetDouble(req, wire.Arg2Imag, imag(y))
+	return req
+}
+
+func TestDoComplex(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation pb.Operation
+		x         complex128
+		y         complex128
+		expected  complex128
+	}{
+		{"addition", pb.Operation_ADD, 1 + 2i, 3 - 1i, 4 + 1i},
+		{"multiplication", pb.Operation_MULTIPLY, 2i, 2i, -4},
+		{"division", pb.Operation_DIVIDE, 10, 3 + 4i, 1.2 - 1.6i},
+		{"parallel impedance", pb.Operation_DIVIDE, (10 + 5i) * (10 - 5i), 20, 6.25},
+		{"square root of negative", do.Sqrt, -4, 0, 2i},
+		{"abs", do.Abs, 3 + 4i, 0, 5},
+		{"power", do.Power, 1i, 2, -1},
+		{"round", do.Round, 1.234 + 5.678i, 1, 1.2 + 5.7i},
+		{"round with too many places", do.Round, 1.5 - 2.5i, 400, 1.5 - 2.5i},
+		{"round above all digits", do.Round, 1.5 - 2.5i, -400, 0},
+		{"ln", do.Ln, -1, 0, complex(0, 3.141592653589793)},
+		{"negate", do.Negate, 1 - 1i, 0, -1 + 1i},
+		{"equal", do.Equal, 1 + 2i, 1 + 2i, 1},
+		{"not", do.Not, 1i, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := do.DoComplex(complexTask(tt.operation, tt.x, tt.y))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if cmplx.Abs(result-tt.expected) > epsilon {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestDoComplex_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation pb.Operation
+		x         complex128
+		y         complex128
+		expected  error
+	}{
+		{"division by zero", pb.Operation_DIVIDE, 1 + 1i, 0, do.DivisionByZero},
+		{"zero to negative power", do.Power, 0, -1 + 1i, do.DivisionByZero},
+		{"log of zero", do.Ln, 0, 0, do.LogOfZero},
+		{"log base one", do.Log, 1i, 1, do.InvalidLogBase},
+		{"modulo", do.Modulo, 1i, 2, do.NotForComplex},
+		{"max", do.Max, 1i, 2, do.NotForComplex},
+		{"less", do.Less, 1i, 2, do.NotForComplex},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := do.DoComplex(complexTask(tt.operation, tt.x, tt.y))
+			if err != tt.expected {
+				t.Errorf("Expected error %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestSolver_Complex(t *testing.T) {
+	solver := do.Solver{Scale: 20, Rounding: do.HalfEven}
+
+	res, err := solver.Solve(complexTask(pb.Operation_MULTIPLY, 1+1i, 1-1i))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	im, ok := wire.GetDouble(res, wire.ResultImag)
+	if !ok {
+		t.Fatalf("Expected the imaginary part")
+	}
+	if res.Result != 2 || im != 0 {
+		t.Errorf("Expected 2+0i, got %v%+vi", res.Result, im)
+	}
+}
@@ -0,0 +1,391 @@
+package do
+
+import (
+	"agent/pkg/wire"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/vandi37/Calculator-Models"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type Rounding string
+
+const (
+	HalfEven Rounding = "half_even"
+	HalfUp   Rounding = "half_up" // Half away from zero
+	HalfDown Rounding = "half_down"
+	Up       Rounding = "up" // Away from zero
+	Down     Rounding = "down"
+	Ceiling  Rounding = "ceiling"
+	Floor    Rounding = "floor"
+)
+
+func (r Rounding) IsValid() bool {
+	switch r {
+	case HalfEven, HalfUp, HalfDown, Up, Down, Ceiling, Floor:
+		return true
+	}
+	return false
+}
+
+const (
+	// Limits of the mongo Decimal128 that keeps the result
+	maxDigits   = 34
+	minExponent = -6176
+	maxExponent = 6111
+	// Bigger integer powers are calculated with floats
+	maxExactPower = 1024
+	sqrtPrecision = 256
+)
+
+// Solves complex tasks, tasks with units and tasks with exact decimal or rational arguments.
+// Other tasks are solved by Do
+type Solver struct {
+	// Digits after the point for results that are not finite decimals
+	Scale    int
+	Rounding Rounding
+}
+
+func (s Solver) Solve(req *pb.Task) (*pb.Result, error) {
+	if HasUnits(req) {
+		return solveUnits(req)
+	}
+	if IsComplex(req) {
+		z, err := DoComplex(req)
+		if err != nil {
+			return nil, err
+		}
+		res := &pb.Result{Result: real(z)}
+		wire.SetDouble(res, wire.ResultImag, imag(z))
+		return res, nil
+	}
+	if x, y, ok, err := exactArgs(req, wire.Arg1Rational, wire.Arg2Rational, InvalidRational); err != nil {
+		return nil, err
+	} else if ok {
+		return s.solve(req, x, y, wire.ResultRational, false, formatRational)
+	}
+	if x, y, ok, err := exactArgs(req, wire.Arg1Decimal, wire.Arg2Decimal, InvalidDecimal); err != nil {
+		return nil, err
+	} else if ok {
+		return s.solve(req, x, y, wire.ResultDecimal, true, s.format)
+	}
+
+	f, err := Do(req)
+	if err != nil {
+		return nil, err
+	}
+	res := &pb.Result{Result: f}
+	if Underflowed(req, f) {
+		wire.SetString(res, wire.ResultWarning, wire.Underflow)
+	}
+	return res, nil
+}
+
+// Approximate allows results that are rounded before formatting (square roots)
+func (s Solver) solve(req *pb.Task, x, y *big.Rat, field protowire.Number, approximate bool, format func(*big.Rat) (string, error)) (*pb.Result, error) {
+	r, err := s.do(req.Operation, x, y, approximate)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		// No exact way, the float result is converted
+		f, err := Do(req)
+		if err != nil {
+			return nil, err
+		}
+		res := &pb.Result{Result: f}
+		if r, ok := new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, 64)); ok {
+			if str, err := format(r); err == nil {
+				wire.SetString(res, field, str)
+			}
+		}
+		return res, nil
+	}
+
+	str, err := format(r)
+	if err != nil {
+		return nil, err
+	}
+	f, _ := r.Float64()
+	time.Sleep(time.Millisecond * time.Duration(req.OperationTime))
+	res := &pb.Result{Result: f}
+	wire.SetString(res, field, str)
+	return res, nil
+}
+
+// Arguments without the exact value are the numbers the orchestrator had only as floats.
+// False is returned if both arguments are floats
+func exactArgs(req *pb.Task, field1, field2 protowire.Number, invalid error) (*big.Rat, *big.Rat, bool, error) {
+	arg1, ok1 := wire.GetString(req, field1)
+	arg2, ok2 := wire.GetString(req, field2)
+	if !ok1 && !ok2 {
+		return nil, nil, false, nil
+	}
+	x, err := exactArg(arg1, ok1, req.Arg1, invalid)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	y, err := exactArg(arg2, ok2, req.Arg2, invalid)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	return x, y, true, nil
+}
+
+func exactArg(value string, ok bool, f float64, invalid error) (*big.Rat, error) {
+	if !ok {
+		value = strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	r, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return nil, invalid
+	}
+	return r, nil
+}
+
+// Returns nil if the operation can't be done exactly
+func (s Solver) do(op pb.Operation, x, y *big.Rat, approximate bool) (*big.Rat, error) {
+	r := new(big.Rat)
+	switch op {
+	case pb.Operation_ADD:
+		return r.Add(x, y), nil
+	case pb.Operation_SUBTRACT:
+		return r.Sub(x, y), nil
+	case pb.Operation_MULTIPLY:
+		return r.Mul(x, y), nil
+	case pb.Operation_DIVIDE:
+		if y.Sign() == 0 {
+			return nil, DivisionByZero
+		}
+		return r.Quo(x, y), nil
+	case Power:
+		return power(x, y)
+	case Modulo:
+		if y.Sign() == 0 {
+			return nil, ModuloByZero
+		}
+		r.Quo(x, y)
+		r.SetInt(roundQuo(r.Num(), r.Denom(), Floor))
+		return r.Sub(x, r.Mul(r, y)), nil
+	case IntDivide:
+		if y.Sign() == 0 {
+			return nil, DivisionByZero
+		}
+		r.Quo(x, y)
+		return r.SetInt(roundQuo(r.Num(), r.Denom(), Floor)), nil
+	case Sqrt:
+		if x.Sign() < 0 {
+			return nil, NegativeSqrt
+		}
+		if root, ok := exactSqrt(x); ok {
+			return root, nil
+		}
+		if !approximate {
+			return nil, nil
+		}
+		f := new(big.Float).SetPrec(sqrtPrecision).SetRat(x)
+		f.Sqrt(f)
+		f.Rat(r)
+		return r, nil
+	case Abs:
+		return r.Abs(x), nil
+	case Round:
+		return round(x, y), nil
+	case Ln, Log:
+		return nil, nil
+	case Min:
+		if x.Cmp(y) <= 0 {
+			return r.Set(x), nil
+		}
+		return r.Set(y), nil
+	case Max:
+		if x.Cmp(y) >= 0 {
+			return r.Set(x), nil
+		}
+		return r.Set(y), nil
+	case Negate:
+		return r.Neg(x), nil
+	case Less:
+		return r.SetFloat64(boolean(x.Cmp(y) < 0)), nil
+	case LessEqual:
+		return r.SetFloat64(boolean(x.Cmp(y) <= 0)), nil
+	case Greater:
+		return r.SetFloat64(boolean(x.Cmp(y) > 0)), nil
+	case GreaterEqual:
+		return r.SetFloat64(boolean(x.Cmp(y) >= 0)), nil
+	case Equal:
+		return r.SetFloat64(boolean(x.Cmp(y) == 0)), nil
+	case NotEqual:
+		return r.SetFloat64(boolean(x.Cmp(y) != 0)), nil
+	case And:
+		return r.SetFloat64(boolean(x.Sign() != 0 && y.Sign() != 0)), nil
+	case Or:
+		return r.SetFloat64(boolean(x.Sign() != 0 || y.Sign() != 0)), nil
+	case Not:
+		return r.SetFloat64(boolean(x.Sign() == 0)), nil
+	default:
+		return nil, UnknownOperation
+	}
+}
+
+// Only integer powers are exact
+func power(x, y *big.Rat) (*big.Rat, error) {
+	if !y.IsInt() || y.Num().CmpAbs(big.NewInt(maxExactPower)) > 0 {
+		return nil, nil
+	}
+	n := y.Num().Int64()
+	if n < 0 {
+		if x.Sign() == 0 {
+			return nil, DivisionByZero
+		}
+		x, n = new(big.Rat).Inv(x), -n
+	}
+	num := new(big.Int).Exp(x.Num(), big.NewInt(n), nil)
+	denom := new(big.Int).Exp(x.Denom(), big.NewInt(n), nil)
+	return new(big.Rat).SetFrac(num, denom), nil
+}
+
+// Rounds half away from zero like Do
+func round(x, y *big.Rat) *big.Rat {
+	places := new(big.Int).Quo(y.Num(), y.Denom())
+	// Further places don't change the result
+	if places.CmpAbs(big.NewInt(maxExponent-minExponent)) > 0 {
+		if places.Sign() > 0 {
+			return new(big.Rat).Set(x)
+		}
+		return new(big.Rat)
+	}
+	p := new(big.Int).Exp(big.NewInt(10), new(big.Int).Abs(places), nil)
+	r := new(big.Rat).Set(x)
+	if places.Sign() >= 0 {
+		r.Mul(r, new(big.Rat).SetInt(p))
+	} else {
+		r.Quo(r, new(big.Rat).SetInt(p))
+	}
+	r.SetInt(roundQuo(r.Num(), r.Denom(), HalfUp))
+	if places.Sign() >= 0 {
+		return r.Quo(r, new(big.Rat).SetInt(p))
+	}
+	return r.Mul(r, new(big.Rat).SetInt(p))
+}
+
+// Divides and rounds to an integer
+func roundQuo(n, d *big.Int, rounding Rounding) *big.Int {
+	q, m := new(big.Int).QuoRem(n, d, new(big.Int))
+	if m.Sign() == 0 {
+		return q
+	}
+	sign := int64(n.Sign() * d.Sign())
+	half := new(big.Int).Mul(new(big.Int).Abs(m), big.NewInt(2)).CmpAbs(d)
+	var away bool
+	switch rounding {
+	case Up:
+		away = true
+	case Ceiling:
+		away = sign > 0
+	case Floor:
+		away = sign < 0
+	case HalfUp:
+		away = half >= 0
+	case HalfDown:
+		away = half > 0
+	case HalfEven:
+		away = half > 0 || half == 0 && q.Bit(0) == 1
+	}
+	if away {
+		q.Add(q, big.NewInt(sign))
+	}
+	return q
+}
+
+// Finite decimals are written exactly, others are rounded to the scale.
+// The result always fits into 34 digits
+func (s Solver) format(r *big.Rat) (string, error) {
+	var digits *big.Int
+	var exponent int
+	if places, ok := decimalPlaces(r.Denom()); ok {
+		digits = new(big.Int).Mul(r.Num(), pow10(places))
+		digits.Quo(digits, r.Denom())
+		exponent = -places
+	} else {
+		digits = roundQuo(new(big.Int).Mul(r.Num(), pow10(s.Scale)), r.Denom(), s.Rounding)
+		exponent = -s.Scale
+	}
+
+	for extra := len(new(big.Int).Abs(digits).String()) - maxDigits; extra > 0; extra = len(new(big.Int).Abs(digits).String()) - maxDigits {
+		digits = roundQuo(digits, pow10(extra), s.Rounding)
+		exponent += extra
+	}
+
+	ten, m := big.NewInt(10), new(big.Int)
+	for digits.Sign() != 0 {
+		if q, _ := new(big.Int).QuoRem(digits, ten, m); m.Sign() == 0 {
+			digits, exponent = q, exponent+1
+			continue
+		}
+		break
+	}
+	if digits.Sign() == 0 {
+		return "0", nil
+	}
+	if exponent < minExponent || exponent > maxExponent {
+		return "", DecimalOutOfRange
+	}
+	return decimalString(digits, exponent), nil
+}
+
+// A denominator of a finite decimal has only twos and fives
+func decimalPlaces(denom *big.Int) (int, bool) {
+	d := new(big.Int).Set(denom)
+	var twos, fives int
+	m := new(big.Int)
+	for q := new(big.Int); ; twos++ {
+		if q.QuoRem(d, big.NewInt(2), m); m.Sign() != 0 {
+			break
+		}
+		d.Set(q)
+	}
+	for q := new(big.Int); ; fives++ {
+		if q.QuoRem(d, big.NewInt(5), m); m.Sign() != 0 {
+			break
+		}
+		d.Set(q)
+	}
+	return max(twos, fives), d.IsInt64() && d.Int64() == 1
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// Small exponents are written with the point, others in the scientific notation
+func decimalString(digits *big.Int, exponent int) string {
+	s := digits.String()
+	sign := ""
+	if s[0] == '-' {
+		sign, s = "-", s[1:]
+	}
+	adjusted := len(s) - 1 + exponent
+	switch {
+	case exponent == 0:
+		return sign + s
+	case exponent < 0 && adjusted >= -6:
+		if point := len(s) + exponent; point > 0 {
+			return sign + s[:point] + "." + s[point:]
+		}
+		return sign + "0." + strings.Repeat("0", -exponent-len(s)) + s
+	case exponent > 0 && len(s)+exponent <= maxDigits:
+		return sign + s + strings.Repeat("0", exponent)
+	}
+	mantissa := s[:1]
+	if len(s) > 1 {
+		mantissa += "." + s[1:]
+	}
+	if adjusted > 0 {
+		return sign + mantissa + "E+" + strconv.Itoa(adjusted)
+	}
+	return sign + mantissa + "E" + strconv.Itoa(adjusted)
+}
@@ -0,0 +1,164 @@
+package do_test
+
+import (
+	"agent/pkg/do"
+	"agent/pkg/wire"
+	"strings"
+	"testing"
+
+	pb "github.com/vandi37/Calculator-Models"
+)
+
+func decimalTask(op pb.Operation, arg1, arg2 string) *pb.Task {
+	req := &pb.Task{Operation: op}
+	wire.SetString(req, wire.Arg1Decimal, arg1)
+	wire.SetString(req, wire.Arg2Decimal, arg2)
+	return req
+}
+
+func TestSolver_Decimal(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation pb.Operation
+		arg1      string
+		arg2      string
+		expected  string
+	}{
+		{"exact addition", pb.Operation_ADD, "0.1", "0.2", "0.3"},
+		{"exact subtraction", pb.Operation_SUBTRACT, "1", "0.9", "0.1"},
+		{"exact multiplication", pb.Operation_MULTIPLY, "1.1", "1.1", "1.21"},
+		{"finite division", pb.Operation_DIVIDE, "1", "8", "0.125"},
+		{"rounded division", pb.Operation_DIVIDE, "2", "3", "0.66666666666666666667"},
+		{"integer power", do.Power, "1.1", "3", "1.331"},
+		{"negative power", do.Power, "2", "-2", "0.25"},
+		{"modulo sign", do.Modulo, "-7.5", "2", "0.5"},
+		{"integer division", do.IntDivide, "-7.5", "2", "-4"},
+		{"square root", do.Sqrt, "2.25", "0", "1.5"},
+		{"round half away", do.Round, "-2.345", "2", "-2.35"},
+		{"round to tens", do.Round, "1234", "-1", "1230"},
+		{"min", do.Min, "0.30", "0.3", "0.3"},
+		{"negate", do.Negate, "1.5", "0", "-1.5"},
+		{"big numbers", pb.Operation_MULTIPLY, "1e30", "1e30", "1E+60"},
+		{"small numbers", pb.Operation_DIVIDE, "1", "1e10", "1E-10"},
+		{"too many digits", pb.Operation_ADD, "1234567890123456789012345678901234", "0.5", "1234567890123456789012345678901234"},
+	}
+
+	solver := do.Solver{Scale: 20, Rounding: do.HalfEven}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := solver.Solve(decimalTask(tt.operation, tt.arg1, tt.arg2))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			result, ok := wire.GetString(res, wire.ResultDecimal)
+			if !ok {
+				t.Fatalf("Expected decimal result")
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestSolver_Rounding(t *testing.T) {
+	tests := []struct {
+		rounding do.Rounding
+		positive string
+		negative string
+	}{
+		{do.HalfEven, "4", "-4"},
+		{do.HalfUp, "5", "-5"},
+		{do.HalfDown, "4", "-4"},
+		{do.Up, "5", "-5"},
+		{do.Down, "4", "-4"},
+		{do.Ceiling, "5", "-4"},
+		{do.Floor, "4", "-5"},
+	}
+
+	// The sum has 35 digits, so the half is rounded
+	const digits = "123456789012345678901234567890123"
+	for _, tt := range tests {
+		t.Run(string(tt.rounding), func(t *testing.T) {
+			solver := do.Solver{Scale: 20, Rounding: tt.rounding}
+			for _, sign := range []string{"", "-"} {
+				expected := digits + tt.positive
+				if sign == "-" {
+					expected = "-" + digits + strings.TrimPrefix(tt.negative, "-")
+				}
+				res, err := solver.Solve(decimalTask(pb.Operation_ADD, sign+digits+"4", sign+"0.5"))
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if result, _ := wire.GetString(res, wire.ResultDecimal); result != expected {
+					t.Errorf("Expected %s, got %s", expected, result)
+				}
+			}
+		})
+	}
+}
+
+func TestSolver_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation pb.Operation
+		arg1      string
+		arg2      string
+		expected  error
+	}{
+		{"division by zero", pb.Operation_DIVIDE, "1", "0", do.DivisionByZero},
+		{"modulo by zero", do.Modulo, "1", "0.0", do.ModuloByZero},
+		{"negative sqrt", do.Sqrt, "-0.1", "0", do.NegativeSqrt},
+		{"zero to negative power", do.Power, "0", "-1", do.DivisionByZero},
+		{"invalid decimal", pb.Operation_ADD, "1.2.3", "0", do.InvalidDecimal},
+		{"out of range", do.Power, "1e6000", "2", do.DecimalOutOfRange},
+	}
+
+	solver := do.Solver{Scale: 20, Rounding: do.HalfEven}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := solver.Solve(decimalTask(tt.operation, tt.arg1, tt.arg2))
+			if err != tt.expected {
+				t.Errorf("Expected error %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestSolver_Fallback(t *testing.T) {
+	solver := do.Solver{Scale: 20, Rounding: do.HalfEven}
+
+	res, err := solver.Solve(&pb.Task{Operation: pb.Operation_ADD, Arg1: 0.1, Arg2: 0.2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := wire.GetString(res, wire.ResultDecimal); ok {
+		t.Errorf("Expected no decimal result for a float task")
+	}
+	if !floatEquals(res.Result, 0.3) {
+		t.Errorf("Expected 0.3, got %f", res.Result)
+	}
+
+	req := decimalTask(do.Ln, "1", "0")
+	req.Arg1 = 1
+	res, err = solver.Solve(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result, _ := wire.GetString(res, wire.ResultDecimal); result != "0" {
+		t.Errorf("Expected 0, got %s", result)
+	}
+}
+
+func TestSolver_Warning(t *testing.T) {
+	solver := do.Solver{Scale: 20, Rounding: do.HalfEven}
+
+	res, err := solver.Solve(&pb.Task{Operation: pb.Operation_MULTIPLY, Arg1: 1e-200, Arg2: 1e-200})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if warning, _ := wire.GetString(res, wire.ResultWarning); warning != wire.Underflow {
+		t.Errorf("Expected the underflow warning, got %q", warning)
+	}
+}
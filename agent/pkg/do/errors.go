@@ -1,8 +1,41 @@
 package do
 
-import "errors"
+import "agent/pkg/wire"
+
+// Error of the calculation with a machine readable code, the code is sent with the message
+type Error struct {
+	code    string
+	message string
+}
+
+func (e *Error) Error() string {
+	return e.message
+}
+
+func (e *Error) Code() string {
+	return e.code
+}
+
+func newError(code, message string) *Error {
+	return &Error{code: code, message: message}
+}
 
 var (
-	DivisionByZero   = errors.New("division by zero")
-	UnknownOperation = errors.New("unknown operation")
+	DivisionByZero    = newError("division_by_zero", "division by zero")
+	ModuloByZero      = newError("modulo_by_zero", "modulo by zero")
+	NegativeSqrt      = newError("negative_sqrt", "square root of a negative number")
+	NonPositiveLog    = newError("non_positive_log", "logarithm of a non-positive number")
+	InvalidLogBase    = newError("invalid_log_base", "invalid logarithm base")
+	UnknownOperation  = newError("unknown_operation", "unknown operation")
+	InvalidDecimal    = newError("invalid_decimal", "invalid decimal")
+	DecimalOutOfRange = newError("decimal_out_of_range", "decimal out of range")
+	InvalidRational   = newError("invalid_rational", "invalid rational")
+	RationalTooBig    = newError("rational_too_big", "rational number is too big")
+	LogOfZero         = newError("log_of_zero", "logarithm of zero")
+	NotForComplex     = newError("not_for_complex", "operation isn't defined for complex numbers")
+	IncompatibleUnits = newError("incompatible_units", "units don't fit the operation")
+	InvalidUnit       = newError("invalid_unit", "invalid unit")
+	// Non-finite results, they can't be saved as numbers
+	Overflow   = newError(wire.Overflow, "result is too big")
+	NotANumber = newError(wire.NaN, "result is not a number")
 )
@@ -0,0 +1,37 @@
+package do
+
+import pb "github.com/vandi37/Calculator-Models"
+
+// Operations that the models don't declare.
+// The orchestrator sends them as plain pb.Operation values, so the numbers must match its tree.Operation
+const (
+	Power pb.Operation = iota + pb.Operation_DIVIDE + 1
+	Modulo
+	IntDivide
+	// Functions with one argument get zero as the second one
+	Sqrt
+	Abs
+	Round // The second argument is the number of decimal places
+	Ln
+	Log // The second argument is the base
+	Min
+	Max
+	Negate // Unary minus, the second argument is zero
+	// Comparisons and logic give 1 for true and 0 for false, any number except 0 is true
+	Less
+	LessEqual
+	Greater
+	GreaterEqual
+	Equal
+	NotEqual
+	And
+	Or
+	Not // The second argument is zero
+)
+
+func boolean(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,22 @@
+package do
+
+import "math/big"
+
+// Bigger fractions are an error, otherwise a long chain of powers would take all the memory
+const maxRationalBits = 1 << 15
+
+func formatRational(r *big.Rat) (string, error) {
+	if r.Num().BitLen() > maxRationalBits || r.Denom().BitLen() > maxRationalBits {
+		return "", RationalTooBig
+	}
+	return r.RatString(), nil
+}
+
+// The root is exact only if both parts of the fraction are squares
+func exactSqrt(x *big.Rat) (*big.Rat, bool) {
+	num, denom := new(big.Int).Sqrt(x.Num()), new(big.Int).Sqrt(x.Denom())
+	if new(big.Int).Mul(num, num).Cmp(x.Num()) != 0 || new(big.Int).Mul(denom, denom).Cmp(x.Denom()) != 0 {
+		return nil, false
+	}
+	return new(big.Rat).SetFrac(num, denom), true
+}
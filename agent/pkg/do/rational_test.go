@@ -0,0 +1,98 @@
+package do_test
+
+import (
+	"agent/pkg/do"
+	"agent/pkg/wire"
+	"testing"
+
+	pb "github.com/vandi37/Calculator-Models"
+)
+
+func rationalTask(op pb.Operation, arg1, arg2 string) *pb.Task {
+	req := &pb.Task{Operation: op}
+	wire.SetString(req, wire.Arg1Rational, arg1)
+	wire.SetString(req, wire.Arg2Rational, arg2)
+	return req
+}
+
+func TestSolver_Rational(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation pb.Operation
+		arg1      string
+		arg2      string
+		expected  string
+	}{
+		{"multiplication back", pb.Operation_MULTIPLY, "1/3", "3", "1"},
+		{"addition", pb.Operation_ADD, "1/3", "1/6", "1/2"},
+		{"division", pb.Operation_DIVIDE, "2", "3", "2/3"},
+		{"negative power", do.Power, "2/3", "-2", "9/4"},
+		{"square root", do.Sqrt, "4/9", "0", "2/3"},
+		{"modulo", do.Modulo, "-7/2", "2", "1/2"},
+		{"round", do.Round, "2/3", "2", "67/100"},
+		{"max", do.Max, "1/3", "3/10", "1/3"},
+		{"less", do.Less, "1/3", "3/10", "0"},
+		{"equal", do.Equal, "2/4", "1/2", "1"},
+		{"or", do.Or, "0", "-1/2", "1"},
+	}
+
+	solver := do.Solver{Scale: 20, Rounding: do.HalfEven}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := solver.Solve(rationalTask(tt.operation, tt.arg1, tt.arg2))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			result, ok := wire.GetString(res, wire.ResultRational)
+			if !ok {
+				t.Fatalf("Expected rational result")
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, result)
+			}
+			if _, ok := wire.GetString(res, wire.ResultDecimal); ok {
+				t.Errorf("Expected no decimal result")
+			}
+		})
+	}
+}
+
+func TestSolver_RationalFallback(t *testing.T) {
+	solver := do.Solver{Scale: 20, Rounding: do.HalfEven}
+
+	// The square root of 2 isn't a fraction, so the float is used
+	req := rationalTask(do.Sqrt, "2", "0")
+	req.Arg1 = 2
+	res, err := solver.Solve(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result, _ := wire.GetString(res, wire.ResultRational); result != "14142135623730951/10000000000000000" {
+		t.Errorf("Expected the fraction of the float, got %s", result)
+	}
+}
+
+func TestSolver_RationalErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation pb.Operation
+		arg1      string
+		arg2      string
+		expected  error
+	}{
+		{"division by zero", pb.Operation_DIVIDE, "1/3", "0", do.DivisionByZero},
+		{"invalid rational", pb.Operation_ADD, "1/0", "1", do.InvalidRational},
+		{"too big", do.Power, "1/1099511627776", "1024", do.RationalTooBig},
+	}
+
+	solver := do.Solver{Scale: 20, Rounding: do.HalfEven}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := solver.Solve(rationalTask(tt.operation, tt.arg1, tt.arg2))
+			if err != tt.expected {
+				t.Errorf("Expected error %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package do
+
+import (
+	"agent/pkg/wire"
+	"math"
+	"strconv"
+	"strings"
+
+	pb "github.com/vandi37/Calculator-Models"
+)
+
+// Exponents of the SI base units and the money, the orchestrator converts every unit to them
+type Dimension [8]int8
+
+// The same order as in the orchestrator, the text is "m*kg/s^2"
+var baseUnits = [len(Dimension{})]string{"m", "kg", "s", "A", "K", "mol", "cd", "money"}
+
+// The task has units if one of the arguments has the unit field, even an empty one
+func HasUnits(req *pb.Task) bool {
+	_, ok1 := wire.GetString(req, wire.Arg1Unit)
+	_, ok2 := wire.GetString(req, wire.Arg2Unit)
+	return ok1 || ok2
+}
+
+// Parses the normalized text of the orchestrator, a plain number is ""
+func ParseDimension(text string) (Dimension, error) {
+	var d Dimension
+	if text == "" {
+		return d, nil
+	}
+	// Only the first group is in the numerator
+	for i, group := range strings.Split(text, "/") {
+		sign := int8(-1)
+		if i == 0 {
+			sign = 1
+			if group == "1" {
+				continue
+			}
+		}
+		for _, part := range strings.Split(group, "*") {
+			symbol, exponent, hasExponent := strings.Cut(part, "^")
+			e := int64(1)
+			if hasExponent {
+				var err error
+				if e, err = strconv.ParseInt(exponent, 10, 8); err != nil {
+					return Dimension{}, InvalidUnit
+				}
+			}
+			i := indexOf(symbol)
+			if i < 0 {
+				return Dimension{}, InvalidUnit
+			}
+			d[i] += sign * int8(e)
+		}
+	}
+	return d, nil
+}
+
+func indexOf(symbol string) int {
+	for i, s := range baseUnits {
+		if s == symbol {
+			return i
+		}
+	}
+	return -1
+}
+
+func (d Dimension) IsZero() bool {
+	return d == Dimension{}
+}
+
+func (d Dimension) String() string {
+	var num, den []string
+	for i, e := range d {
+		switch {
+		case e > 0:
+			num = append(num, withExponent(baseUnits[i], e))
+		case e < 0:
+			den = append(den, withExponent(baseUnits[i], -e))
+		}
+	}
+	if len(num) == 0 && len(den) == 0 {
+		return ""
+	}
+	text := strings.Join(num, "*")
+	if text == "" {
+		text = "1"
+	}
+	for _, part := range den {
+		text += "/" + part
+	}
+	return text
+}
+
+func withExponent(symbol string, e int8) string {
+	if e == 1 {
+		return symbol
+	}
+	return symbol + "^" + strconv.Itoa(int(e))
+}
+
+// Quantities are floats in the SI base units, so the value is calculated by Do
+func solveUnits(req *pb.Task) (*pb.Result, error) {
+	if IsComplex(req) {
+		return nil, NotForComplex
+	}
+	d, err := ResultDimension(req)
+	if err != nil {
+		return nil, err
+	}
+	f, err := Do(req)
+	if err != nil {
+		return nil, err
+	}
+	res := &pb.Result{Result: f}
+	if Underflowed(req, f) {
+		wire.SetString(res, wire.ResultWarning, wire.Underflow)
+	}
+	wire.SetString(res, wire.ResultUnit, d.String())
+	return res, nil
+}
+
+// Finds the dimension of the result, the units of the arguments must fit the operation
+func ResultDimension(req *pb.Task) (Dimension, error) {
+	arg1, _ := wire.GetString(req, wire.Arg1Unit)
+	arg2, _ := wire.GetString(req, wire.Arg2Unit)
+	x, err := ParseDimension(arg1)
+	if err != nil {
+		return Dimension{}, err
+	}
+	y, err := ParseDimension(arg2)
+	if err != nil {
+		return Dimension{}, err
+	}
+
+	var d Dimension
+	switch req.Operation {
+	case pb.Operation_ADD, pb.Operation_SUBTRACT, Modulo, Min, Max:
+		if x != y {
+			return Dimension{}, IncompatibleUnits
+		}
+		return x, nil
+	case IntDivide, Less, LessEqual, Greater, GreaterEqual, Equal, NotEqual:
+		if x != y {
+			return Dimension{}, IncompatibleUnits
+		}
+		return d, nil
+	case And, Or, Not:
+		return d, nil
+	case pb.Operation_MULTIPLY:
+		for i := range d {
+			e := int(x[i]) + int(y[i])
+			if e > math.MaxInt8 || e < math.MinInt8 {
+				return Dimension{}, IncompatibleUnits
+			}
+			d[i] = int8(e)
+		}
+		return d, nil
+	case pb.Operation_DIVIDE:
+		for i := range d {
+			e := int(x[i]) - int(y[i])
+			if e > math.MaxInt8 || e < math.MinInt8 {
+				return Dimension{}, IncompatibleUnits
+			}
+			d[i] = int8(e)
+		}
+		return d, nil
+	case Power:
+		if !y.IsZero() {
+			return Dimension{}, IncompatibleUnits
+		}
+		if x.IsZero() {
+			return d, nil
+		}
+		// Only integer powers keep the unit whole
+		n := req.Arg2
+		if n != math.Trunc(n) {
+			return Dimension{}, IncompatibleUnits
+		}
+		for i := range d {
+			e := float64(x[i]) * n
+			if e > math.MaxInt8 || e < math.MinInt8 {
+				return Dimension{}, IncompatibleUnits
+			}
+			d[i] = int8(e)
+		}
+		return d, nil
+	case Sqrt:
+		for i := range d {
+			if x[i]%2 != 0 {
+				return Dimension{}, IncompatibleUnits
+			}
+			d[i] = x[i] / 2
+		}
+		return d, nil
+	case Round:
+		if !y.IsZero() {
+			return Dimension{}, IncompatibleUnits
+		}
+		return x, nil
+	case Ln, Log:
+		if !x.IsZero() || !y.IsZero() {
+			return Dimension{}, IncompatibleUnits
+		}
+		return d, nil
+	case Abs, Negate:
+		return x, nil
+	default:
+		return Dimension{}, UnknownOperation
+	}
+}
@@ -0,0 +1,120 @@
+package do_test
+
+import (
+	"agent/pkg/do"
+	"agent/pkg/wire"
+	"testing"
+
+	pb "github.com/vandi37/Calculator-Models"
+)
+
+func unitTask(op pb.Operation, x float64, xUnit string, y float64, yUnit string) *pb.Task {
+	req := &pb.Task{Operation: op, Arg1: x, Arg2: y}
+	wire.SetString(req, wire.Arg1Unit, xUnit)
+	wire.SetString(req, wire.Arg2Unit, yUnit)
+	return req
+}
+
+func TestParseDimension(t *testing.T) {
+	tests := []string{"", "m", "m/s", "m*kg/s^2", "1/s", "m^2*kg/s^3/A^2", "m^-1", "money", "money/s"}
+	for _, text := range tests {
+		t.Run(text, func(t *testing.T) {
+			d, err := do.ParseDimension(text)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			again, err := do.ParseDimension(d.String())
+			if err != nil || again != d {
+				t.Errorf("Expected %v after %q, got %v", d, d.String(), again)
+			}
+		})
+	}
+
+	if _, err := do.ParseDimension("km"); err != do.InvalidUnit {
+		t.Errorf("Expected %v, got %v", do.InvalidUnit, err)
+	}
+}
+
+func TestSolver_Units(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation pb.Operation
+		x         float64
+		xUnit     string
+		y         float64
+		yUnit     string
+		expected  float64
+		unit      string
+	}{
+		{"addition", pb.Operation_ADD, 5000, "m", 300, "m", 5300, "m"},
+		{"speed", pb.Operation_DIVIDE, 100, "m", 20, "s", 5, "m/s"},
+		{"force", pb.Operation_MULTIPLY, 2, "kg", 9.8, "m/s^2", 19.6, "m*kg/s^2"},
+		{"same units", pb.Operation_DIVIDE, 10, "m", 5, "m", 2, ""},
+		{"number times unit", pb.Operation_MULTIPLY, 3, "", 2, "s", 6, "s"},
+		{"frequency", pb.Operation_DIVIDE, 1, "", 4, "s", 0.25, "1/s"},
+		{"area", do.Power, 3, "m", 2, "", 9, "m^2"},
+		{"square root", do.Sqrt, 16, "m^2", 0, "", 4, "m"},
+		{"int division", do.IntDivide, 7, "m", 2, "m", 3, ""},
+		{"negate", do.Negate, 5, "kg", 0, "", -5, "kg"},
+		{"round", do.Round, 1.26, "s", 1, "", 1.3, "s"},
+		{"max", do.Max, 1, "A", 2, "A", 2, "A"},
+		{"money", pb.Operation_ADD, 80, "money", 20, "money", 100, "money"},
+		{"comparison", do.Greater, 5000, "m", 300, "m", 1, ""},
+		{"not", do.Not, 5, "s", 0, "", 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := do.Solver{Scale: 20, Rounding: do.HalfEven}.Solve(unitTask(tt.operation, tt.x, tt.xUnit, tt.y, tt.yUnit))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if diff := res.Result - tt.expected; diff > epsilon || diff < -epsilon {
+				t.Errorf("Expected %v, got %v", tt.expected, res.Result)
+			}
+			unit, ok := wire.GetString(res, wire.ResultUnit)
+			if !ok || unit != tt.unit {
+				t.Errorf("Expected unit %q, got %q", tt.unit, unit)
+			}
+		})
+	}
+}
+
+func TestSolver_IncompatibleUnits(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation pb.Operation
+		x         float64
+		xUnit     string
+		y         float64
+		yUnit     string
+	}{
+		{"addition", pb.Operation_ADD, 5, "m", 2, "s"},
+		{"unit and number", pb.Operation_SUBTRACT, 5, "m", 2, ""},
+		{"exponent with unit", do.Power, 2, "", 2, "s"},
+		{"fractional power", do.Power, 4, "m", 0.5, ""},
+		{"odd square root", do.Sqrt, 4, "m", 0, ""},
+		{"logarithm", do.Ln, 4, "kg", 0, ""},
+		{"min", do.Min, 1, "A", 1, "K"},
+		{"comparison", do.Equal, 1, "m", 1, "s"},
+		{"exponent overflow", pb.Operation_MULTIPLY, 1, "m^100", 1, "m^100"},
+		{"exponent underflow", pb.Operation_DIVIDE, 1, "1/s^100", 1, "s^100"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := do.Solver{Scale: 20, Rounding: do.HalfEven}.Solve(unitTask(tt.operation, tt.x, tt.xUnit, tt.y, tt.yUnit))
+			if err != do.IncompatibleUnits {
+				t.Errorf("Expected %v, got %v", do.IncompatibleUnits, err)
+			}
+		})
+	}
+}
+
+func TestSolver_UnitsWithComplex(t *testing.T) {
+	req := unitTask(pb.Operation_ADD, 1, "m", 1, "m")
+	wire.SetDouble(req, wire.Arg1Imag, 1)
+	if _, err := (do.Solver{}).Solve(req); err != do.NotForComplex {
+		t.Errorf("Expected %v, got %v", do.NotForComplex, err)
+	}
+}
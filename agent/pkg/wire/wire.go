@@ -0,0 +1,132 @@
+// This package adds fields to the messages of the models without changing them.
+//
+// The fields are sent as unknown protobuf fields, so agents that don't know them just ignore them.
+// The orchestrator has the same numbers, they must match
+package wire
+
+import (
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// Fields of pb.Task
+const (
+	// Exact decimal arguments, they are set only in the decimal precision
+	Arg1Decimal protowire.Number = 100
+	Arg2Decimal protowire.Number = 101
+	// Exact fractions p/q, they are set only in the rational precision
+	Arg1Rational protowire.Number = 102
+	Arg2Rational protowire.Number = 103
+	// Imaginary parts, they are set if one of the arguments is complex
+	Arg1Imag protowire.Number = 104
+	Arg2Imag protowire.Number = 105
+	// Dimensions in the SI base units ("m/s"), they are set if one of the arguments has a unit, a number has ""
+	Arg1Unit protowire.Number = 106
+	Arg2Unit protowire.Number = 107
+)
+
+// Fields of pb.Result
+const (
+	// Exact decimal result, the agent sets it if the task had decimal arguments
+	ResultDecimal protowire.Number = 100
+	// Exact fraction result, the agent sets it if the task had rational arguments
+	ResultRational protowire.Number = 101
+	// Imaginary part of the result, Result is the real part
+	ResultImag protowire.Number = 102
+	// Code of a warning, the result is still used
+	ResultWarning protowire.Number = 103
+	// Dimension of the result, the agent sets it if the task had units
+	ResultUnit protowire.Number = 104
+)
+
+// Fields of pb.Error
+const (
+	// Machine readable code of the error, the message stays in Error
+	ErrorCode protowire.Number = 100
+)
+
+// Codes that both sides know, other codes of the agent are just saved
+const (
+	Overflow = "overflow"
+	NaN      = "nan"
+	// Warning, the result became zero
+	Underflow = "underflow"
+	// Errors without the code (from old agents)
+	UnknownError = "calculation_error"
+)
+
+// Adds the string field to the message
+func SetString(m proto.Message, number protowire.Number, value string) {
+	r := m.ProtoReflect()
+	unknown := protowire.AppendTag(r.GetUnknown(), number, protowire.BytesType)
+	unknown = protowire.AppendString(unknown, value)
+	r.SetUnknown(unknown)
+}
+
+// Finds the string field in the message, the last value wins like for usual protobuf fields
+func GetString(m proto.Message, number protowire.Number) (string, bool) {
+	unknown := m.ProtoReflect().GetUnknown()
+	var value string
+	var found bool
+	for len(unknown) > 0 {
+		num, typ, n := protowire.ConsumeTag(unknown)
+		if n < 0 {
+			return "", false
+		}
+		unknown = unknown[n:]
+		if num == number && typ == protowire.BytesType {
+			v, n := protowire.ConsumeString(unknown)
+			if n < 0 {
+				return "", false
+			}
+			value, found = v, true
+			unknown = unknown[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, unknown)
+		if n < 0 {
+			return "", false
+		}
+		unknown = unknown[n:]
+	}
+	return value, found
+}
+
+// Adds the double field to the message
+func SetDouble(m proto.Message, number protowire.Number, value float64) {
+	r := m.ProtoReflect()
+	unknown := protowire.AppendTag(r.GetUnknown(), number, protowire.Fixed64Type)
+	unknown = protowire.AppendFixed64(unknown, math.Float64bits(value))
+	r.SetUnknown(unknown)
+}
+
+// Finds the double field in the message, the last value wins
+func GetDouble(m proto.Message, number protowire.Number) (float64, bool) {
+	unknown := m.ProtoReflect().GetUnknown()
+	var value float64
+	var found bool
+	for len(unknown) > 0 {
+		num, typ, n := protowire.ConsumeTag(unknown)
+		if n < 0 {
+			return 0, false
+		}
+		unknown = unknown[n:]
+		if num == number && typ == protowire.Fixed64Type {
+			v, n := protowire.ConsumeFixed64(unknown)
+			if n < 0 {
+				return 0, false
+			}
+			value, found = math.Float64frombits(v), true
+			unknown = unknown[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, unknown)
+		if n < 0 {
+			return 0, false
+		}
+		unknown = unknown[n:]
+	}
+	return value, found
+}
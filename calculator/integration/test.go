@@ -13,14 +13,13 @@ import (
 	"github.com/vandi37/Calculator/internal/client"
 	"github.com/vandi37/Calculator/internal/models"
 	"github.com/vandi37/Calculator/internal/status"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func Test(ctx context.Context, t testing.TB, address string) {
 	client := client.New(address)
 	err := client.Ping(ctx)
 	require.NoError(t, err)
-	pavelName := "pavel_" + primitive.NewObjectID().Hex()
+	pavelName := "pavel_" + models.NewID().Hex()
 	pavelPassword := "12345"
 	pavel, err := client.Register(ctx, pavelName, pavelPassword)
 	require.NoError(t, err)
@@ -29,7 +28,7 @@ func Test(ctx context.Context, t testing.TB, address string) {
 		require.NoError(t, err)
 	}()
 
-	ritaName := "rita_" + primitive.NewObjectID().Hex()
+	ritaName := "rita_" + models.NewID().Hex()
 	ritaPassword := "12345"
 	rita, err := client.Register(ctx, ritaName, ritaPassword)
 	require.NoError(t, err)
@@ -56,7 +55,7 @@ func Test(ctx context.Context, t testing.TB, address string) {
 	assert.Nil(t, nilExpression)
 	assert.ErrorContains(t, err, "forbidden")
 
-	err = rita.ChangeUsername(ctx, "rita_"+primitive.NewObjectID().Hex())
+	err = rita.ChangeUsername(ctx, "rita_"+models.NewID().Hex())
 	require.NoError(t, err)
 
 	err = pavel.ChangePassword(ctx, "qwerty")
@@ -7,9 +7,8 @@ import (
 	"time"
 
 	"github.com/vandi37/Calculator/internal/config"
+	"github.com/vandi37/Calculator/internal/fold"
 	"github.com/vandi37/Calculator/internal/ms"
-	"github.com/vandi37/Calculator/internal/repo/expressionrepo"
-	"github.com/vandi37/Calculator/internal/repo/userrepo"
 	"github.com/vandi37/Calculator/internal/service/appservice"
 	"github.com/vandi37/Calculator/internal/transport/handler"
 	"github.com/vandi37/Calculator/internal/transport/server"
@@ -17,9 +16,6 @@ import (
 	"github.com/vandi37/Calculator/pkg/hash"
 	"github.com/vandi37/Calculator/pkg/jwt"
 	"github.com/vandi37/Calculator/pkg/logger"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
-	"go.mongodb.org/mongo-driver/mongo/readpref"
 	"go.uber.org/zap"
 )
 
@@ -44,23 +40,6 @@ func New() *Application {
 }
 
 func (a *Application) Run(ctx context.Context) {
-	// Connect to mongo
-	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(a.config.MongoUri))
-	if err != nil {
-		a.logger.Fatal("error creating client", zap.Error(err))
-	}
-	defer client.Disconnect(ctx)
-	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
-
-	// Ping mongo
-	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
-	err = client.Ping(ctx, readpref.Primary())
-	if err != nil {
-		a.logger.Fatal("error pinging client", zap.Error(err))
-	}
-	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
-
 	// Getting durations
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 	d, err := time.ParseDuration(a.config.ResetTaskDuration)
@@ -77,11 +56,10 @@ func (a *Application) Run(ctx context.Context) {
 	}
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
-	// Creating repos
+	// Connecting to the database and creating repos
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
-	db := client.Database(DB_NAME)
-	userRepo := userrepo.New(db)
-	expressionRepo := expressionrepo.New(db, d)
+	repos, close := a.repos(ctx, d)
+	defer close()
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 	// Creating service
@@ -89,9 +67,11 @@ func (a *Application) Run(ctx context.Context) {
 	service := appservice.New(
 		a.logger,
 		ms.From(a.config.Time),
-		userRepo, expressionRepo,
+		fold.From(a.config.Fold),
+		repos.user, repos.expression, repos.definition, repos.rate,
 		hash.NewPasswordService(nil),
 		jwt.New(a.config.JWT.Secret, expire, notBefore),
+		a.config.AdminToken,
 	)
 	service.Init(ctx)
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
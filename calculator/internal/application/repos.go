@@ -0,0 +1,131 @@
+package application
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/vandi37/Calculator/internal/repo"
+	"github.com/vandi37/Calculator/internal/repo/boltrepo"
+	"github.com/vandi37/Calculator/internal/repo/definitionrepo"
+	"github.com/vandi37/Calculator/internal/repo/expressionrepo"
+	"github.com/vandi37/Calculator/internal/repo/memrepo"
+	"github.com/vandi37/Calculator/internal/repo/pgrepo"
+	"github.com/vandi37/Calculator/internal/repo/raterepo"
+	"github.com/vandi37/Calculator/internal/repo/userrepo"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.uber.org/zap"
+)
+
+type repos struct {
+	user       repo.UserRepo
+	expression repo.ExpressionRepo
+	definition repo.DefinitionRepo
+	rate       repo.RateRepo
+}
+
+// Connects to the database of DB_DRIVER, the returned function closes the connection
+func (a *Application) repos(ctx context.Context, d time.Duration) (repos, func()) {
+	switch a.config.DBDriver {
+	case "mongo":
+		return a.mongoRepos(ctx, d)
+	case "postgres":
+		return a.postgresRepos(ctx, d)
+	case "memory":
+		a.logger.Warn("the data is kept in memory, it is lost after a restart")
+		return memoryRepos(d), func() {}
+	case "bolt":
+		return a.boltRepos(ctx, d)
+	default:
+		a.logger.Fatal("unknown database driver", zap.String("driver", a.config.DBDriver))
+		return repos{}, nil
+	}
+}
+
+func (a *Application) mongoRepos(ctx context.Context, d time.Duration) (repos, func()) {
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(a.config.MongoUri))
+	if err != nil {
+		a.logger.Fatal("error creating client", zap.Error(err))
+	}
+	err = client.Ping(ctx, readpref.Primary())
+	if err != nil {
+		a.logger.Fatal("error pinging client", zap.Error(err))
+	}
+
+	db := client.Database(DB_NAME)
+	result := repos{
+		user:       userrepo.New(db),
+		definition: definitionrepo.New(db),
+		rate:       raterepo.New(db),
+	}
+	switch a.config.ExpressionStorage {
+	case "nodes":
+		nodesRepo := expressionrepo.New(db, d)
+		// Nodes of the expressions that were created or completed before a crash
+		deleted, err := nodesRepo.Sweep(ctx)
+		if err != nil {
+			a.logger.Fatal("error sweeping nodes", zap.Error(err))
+		}
+		a.logger.Info("orphaned nodes deleted", zap.Int64("count", deleted))
+		result.expression = nodesRepo
+	case "tree":
+		treeRepo := expressionrepo.NewTree(db, d)
+		if err := treeRepo.CreateIndexes(ctx); err != nil {
+			a.logger.Fatal("error creating indexes", zap.Error(err))
+		}
+		result.expression = treeRepo
+	default:
+		a.logger.Fatal("unknown expression storage", zap.String("storage", a.config.ExpressionStorage))
+	}
+	return result, func() { client.Disconnect(ctx) }
+}
+
+func (a *Application) postgresRepos(ctx context.Context, d time.Duration) (repos, func()) {
+	pool, err := pgxpool.New(ctx, a.config.PostgresUri)
+	if err != nil {
+		a.logger.Fatal("error creating pool", zap.Error(err))
+	}
+	if err := pool.Ping(ctx); err != nil {
+		a.logger.Fatal("error pinging postgres", zap.Error(err))
+	}
+	if err := pgrepo.Migrate(ctx, pool); err != nil {
+		a.logger.Fatal("error applying migrations", zap.Error(err))
+	}
+	return repos{
+		user:       pgrepo.NewUserRepo(pool),
+		expression: pgrepo.NewExpressionRepo(pool, d),
+		definition: pgrepo.NewDefinitionRepo(pool),
+		rate:       pgrepo.NewRateRepo(pool),
+	}, pool.Close
+}
+
+func (a *Application) boltRepos(ctx context.Context, d time.Duration) (repos, func()) {
+	db, err := boltrepo.Open(a.config.BoltPath)
+	if err != nil {
+		a.logger.Fatal("error opening file", zap.String("path", a.config.BoltPath), zap.Error(err))
+	}
+	expressionRepo := boltrepo.NewExpressionRepo(db, d)
+	// The pending expressions continue after the callback is set
+	resumed, err := expressionRepo.Resume(ctx)
+	if err != nil {
+		a.logger.Fatal("error resuming expressions", zap.Error(err))
+	}
+	a.logger.Info("sent tasks resumed", zap.Int("count", resumed))
+	return repos{
+		user:       boltrepo.NewUserRepo(db),
+		expression: expressionRepo,
+		definition: boltrepo.NewDefinitionRepo(db),
+		rate:       boltrepo.NewRateRepo(db),
+	}, func() { db.Close() }
+}
+
+func memoryRepos(d time.Duration) repos {
+	return repos{
+		user:       memrepo.NewUserRepo(),
+		expression: memrepo.NewExpressionRepo(d),
+		definition: memrepo.NewDefinitionRepo(),
+		rate:       memrepo.NewRateRepo(),
+	}
+}
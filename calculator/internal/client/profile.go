@@ -7,7 +7,6 @@ import (
 	"strings"
 
 	"github.com/vandi37/Calculator/internal/models"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type Profile struct {
@@ -23,29 +22,29 @@ func (p *Profile) AddAuth(req *http.Request) {
 	req.Header.Add("Authorization", p.token)
 }
 
-func (p *Profile) Calculate(ctx context.Context, expression string) (primitive.ObjectID, error) {
+func (p *Profile) Calculate(ctx context.Context, expression string) (models.ID, error) {
 	b, err := json.Marshal(models.CalculationRequest{
 		Expression: expression,
 	})
 	if err != nil {
-		return primitive.NilObjectID, err
+		return models.NilID, err
 	}
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.Address+"/calculate", strings.NewReader(string(b)))
 	if err != nil {
-		return primitive.NilObjectID, err
+		return models.NilID, err
 	}
 	p.AddAuth(req)
 
 	resp, err := p.Request(req)
 	if err != nil {
-		return primitive.NilObjectID, err
+		return models.NilID, err
 	}
 	if resp.StatusCode != http.StatusCreated {
-		return primitive.NilObjectID, p.ReadError(resp)
+		return models.NilID, p.ReadError(resp)
 	}
 	id := new(models.CreatedResponse)
 	if err := p.Read(resp, id); err != nil {
-		return primitive.NilObjectID, err
+		return models.NilID, err
 	}
 	return id.Id, nil
 }
@@ -71,7 +70,7 @@ func (p *Profile) GetExpressions(ctx context.Context) ([]models.Expression, erro
 	return expressions.Expressions, nil
 }
 
-func (p *Profile) GetExpression(ctx context.Context, id primitive.ObjectID) (*models.Expression, error) {
+func (p *Profile) GetExpression(ctx context.Context, id models.ID) (*models.Expression, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.Address+"/expressions/"+id.Hex(), nil)
 	if err != nil {
 		return nil, err
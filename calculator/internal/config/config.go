@@ -14,10 +14,16 @@ type Config struct {
 	Port              int    `env:"PORT" def:"8080"`
 	GRPCProt          int    `env:"GRPC_PORT" def:"50550"`
 	Time              Time   `env:"TIME"`
+	Fold              Fold   `env:"FOLD"`
+	DBDriver          string `env:"DB_DRIVER" def:"mongo"` // "mongo", "postgres", "memory" or "bolt"
 	MongoUri          string `env:"MONGO_URI"`
+	PostgresUri       string `env:"POSTGRES_URI"`
+	BoltPath          string `env:"BOLT_PATH" def:"calculator.db"`
+	ExpressionStorage string `env:"EXPRESSION_STORAGE" def:"nodes"` // Used only by mongo, "nodes" saves a document for every node, "tree" saves the tree in the expression
 	ResetTaskDuration string `env:"RESET_TASK_DURATION" def:"1m"`
 	JWT               JWT    `env:"JWT"`
 	LogFile           string `env:"LOG_FILE" def:"logs.log"`
+	AdminToken        string `env:"ADMIN_TOKEN"` // Empty token turns the admin endpoints off
 }
 
 type JWT struct {
@@ -31,6 +37,27 @@ type Time struct {
 	SubtractionMs    int32 `env:"SUBTRACTION_MS" def:"10"`
 	MultiplicationMs int32 `env:"MULTIPLICATION_MS" def:"10"`
 	DivisionMs       int32 `env:"DIVISION_MS" def:"10"`
+	PowerMs          int32 `env:"POWER_MS" def:"10"`
+	ModuloMs         int32 `env:"MODULO_MS" def:"10"`
+	IntDivisionMs    int32 `env:"INT_DIVISION_MS" def:"10"`
+	FunctionMs       int32 `env:"FUNCTION_MS" def:"10"` // All built-in functions (sqrt, abs, round, log, min, max)
+	NegationMs       int32 `env:"NEGATION_MS" def:"10"`
+	ComparisonMs     int32 `env:"COMPARISON_MS" def:"10"` // Comparisons and logic (<, ==, &&, !)
+}
+
+// Operations that the orchestrator calculates itself if their operands are numbers.
+// They are off by default, because the delays of the agents are a part of the project
+type Fold struct {
+	Addition       bool `env:"ADDITION" def:"false"`
+	Subtraction    bool `env:"SUBTRACTION" def:"false"`
+	Multiplication bool `env:"MULTIPLICATION" def:"false"`
+	Division       bool `env:"DIVISION" def:"false"`
+	Power          bool `env:"POWER" def:"false"`
+	Modulo         bool `env:"MODULO" def:"false"`
+	IntDivision    bool `env:"INT_DIVISION" def:"false"`
+	Function       bool `env:"FUNCTION" def:"false"`
+	Negation       bool `env:"NEGATION" def:"false"`
+	Comparison     bool `env:"COMPARISON" def:"false"`
 }
 
 func LoadConfig() (*Config, error) {
@@ -0,0 +1,46 @@
+package fold
+
+import (
+	pb "github.com/vandi37/Calculator-Models"
+	"github.com/vandi37/Calculator/internal/config"
+	"github.com/vandi37/Calculator/pkg/parsing/tree"
+)
+
+type FoldGetter struct {
+	config.Fold
+}
+
+func From(f config.Fold) *FoldGetter {
+	return &FoldGetter{f}
+}
+
+// Implements simplify.Enabled
+func (g *FoldGetter) Enabled(operation tree.Operation) bool {
+	switch pb.Operation(operation) {
+	case pb.Operation_ADD:
+		return g.Addition
+	case pb.Operation_SUBTRACT:
+		return g.Subtraction
+	case pb.Operation_MULTIPLY:
+		return g.Multiplication
+	case pb.Operation_DIVIDE:
+		return g.Division
+	case pb.Operation(tree.Power):
+		return g.Power
+	case pb.Operation(tree.Modulo):
+		return g.Modulo
+	case pb.Operation(tree.IntDivide):
+		return g.IntDivision
+	case pb.Operation(tree.Sqrt), pb.Operation(tree.Abs), pb.Operation(tree.Round),
+		pb.Operation(tree.Ln), pb.Operation(tree.Log), pb.Operation(tree.Min), pb.Operation(tree.Max),
+		pb.Operation(tree.Sum), pb.Operation(tree.Avg), pb.Operation(tree.Median), pb.Operation(tree.Stddev):
+		return g.Function
+	case pb.Operation(tree.Negate):
+		return g.Negation
+	case pb.Operation(tree.Less), pb.Operation(tree.LessEqual), pb.Operation(tree.Greater), pb.Operation(tree.GreaterEqual),
+		pb.Operation(tree.Equal), pb.Operation(tree.NotEqual), pb.Operation(tree.And), pb.Operation(tree.Or), pb.Operation(tree.Not):
+		return g.Comparison
+	default:
+		return false
+	}
+}
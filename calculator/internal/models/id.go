@@ -0,0 +1,74 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+var InvalidID = errors.New("invalid id")
+
+// The id of the stored values, it has 12 bytes like the ids of mongo: the seconds, a random process value and a counter
+type ID [12]byte
+
+var NilID ID
+
+var (
+	idProcess [5]byte
+	idCounter atomic.Uint32
+)
+
+func init() {
+	var b [9]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	copy(idProcess[:], b[:5])
+	idCounter.Store(binary.BigEndian.Uint32(b[5:]))
+}
+
+// New id, the ids of the same process grow with the time
+func NewID() ID {
+	var id ID
+	binary.BigEndian.PutUint32(id[:4], uint32(time.Now().Unix()))
+	copy(id[4:9], idProcess[:])
+	c := idCounter.Add(1)
+	id[9], id[10], id[11] = byte(c>>16), byte(c>>8), byte(c)
+	return id
+}
+
+func IDFromHex(s string) (ID, error) {
+	var id ID
+	if len(s) != 2*len(id) {
+		return NilID, InvalidID
+	}
+	if _, err := hex.Decode(id[:], []byte(s)); err != nil {
+		return NilID, InvalidID
+	}
+	return id, nil
+}
+
+func (id ID) Hex() string {
+	return hex.EncodeToString(id[:])
+}
+
+func (id ID) IsZero() bool {
+	return id == NilID
+}
+
+// The id is the hex string in json
+func (id ID) MarshalText() ([]byte, error) {
+	return []byte(id.Hex()), nil
+}
+
+func (id *ID) UnmarshalText(text []byte) error {
+	parsed, err := IDFromHex(string(text))
+	if err != nil {
+		return err
+	}
+	*id = parsed
+	return nil
+}
@@ -5,7 +5,7 @@ import (
 
 	pb "github.com/vandi37/Calculator-Models"
 	"github.com/vandi37/Calculator/internal/status"
-	"go.mongodb.org/mongo-driver/bson/primitive"
+	"github.com/vandi37/Calculator/pkg/parsing/tree"
 	"go.uber.org/zap"
 )
 
@@ -17,35 +17,94 @@ const (
 )
 
 type User struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Username  string             `bson:"username" json:"username"`
-	Password  string             `bson:"password" json:"-"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	ID        ID        `bson:"_id,omitempty" json:"id"`
+	Username  string    `bson:"username" json:"username"`
+	Password  string    `bson:"password" json:"-"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+// Value or function saved by the user, values have no parameters
+type Definition struct {
+	ID        ID        `bson:"_id,omitempty" json:"id"`
+	UserID    ID        `bson:"user_id" json:"user_id"`
+	Name      string    `bson:"name" json:"name"`
+	Params    []string  `bson:"params,omitempty" json:"params,omitempty"`
+	Body      string    `bson:"body" json:"body"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+// Exchange rate saved by the admin, it is the price of the base currency in the currency
+type Rate struct {
+	Code      string    `bson:"_id" json:"code"`
+	Rate      float64   `bson:"rate" json:"rate"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
 }
 
 type Node struct {
-	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Type     NodeType           `bson:"type" json:"type"`
-	Tree     *TreeNode          `bson:"tree,omitempty" json:"tree"`
-	Number   *float64           `bson:"number,omitempty" json:"number"`
-	SendedAt *time.Time         `bson:"sended_at,omitempty" json:"sended_at,omitempty"`
+	ID       ID         `bson:"_id,omitempty" json:"id"`
+	Type     NodeType   `bson:"type" json:"type"`
+	Tree     *TreeNode  `bson:"tree,omitempty" json:"tree"`
+	Number   *float64   `bson:"number,omitempty" json:"number"`
+	Decimal  string     `bson:"decimal,omitempty" json:"decimal,omitempty"`   // Exact value in the decimal precision, Number is its approximation
+	Rational string     `bson:"rational,omitempty" json:"rational,omitempty"` // Exact fraction p/q in the rational precision
+	Complex  *Complex   `bson:"complex,omitempty" json:"complex,omitempty"`   // Set for complex numbers, Number is the real part
+	Unit     string     `bson:"unit,omitempty" json:"unit,omitempty"`         // Dimension in the SI base units ("m/s"), Number is in these units
+	Held     ID         `bson:"held,omitempty" json:"held,omitempty"`         // Conditional node that keeps the branch until its condition is calculated
+	SendedAt *time.Time `bson:"sended_at,omitempty" json:"sended_at,omitempty"`
+}
+
+type Complex struct {
+	Re float64 `bson:"re" json:"re"`
+	Im float64 `bson:"im" json:"im"`
 }
 
 type TreeNode struct {
-	Operator pb.Operation       `bson:"operator" json:"operator"`
-	Left     primitive.ObjectID `bson:"left" json:"left"`
-	Right    primitive.ObjectID `bson:"right" json:"right"`
+	Operator pb.Operation `bson:"operator" json:"operator"`
+	Left     ID           `bson:"left" json:"left"`
+	Right    ID           `bson:"right,omitempty" json:"right,omitempty"` // Unary operations (negation, function calls) have no right node
+	Cond     ID           `bson:"cond,omitempty" json:"cond,omitempty"`   // Only for conditionals, left and right are the branches then
+	// Only for the median, it has no left and right
+	Items []ID `bson:"items,omitempty" json:"items,omitempty"`
+}
+
+// Nodes under the tree: the condition, the branches and the operands, or the items of the median
+func (t *TreeNode) Children() []ID {
+	children := []ID{}
+	for _, id := range []ID{t.Cond, t.Left, t.Right} {
+		if id != NilID {
+			children = append(children, id)
+		}
+	}
+	return append(children, t.Items...)
+}
+
+func (t *TreeNode) IsUnary() bool {
+	return t.Right == NilID
 }
 
 type Expression struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
-	Origin    string             `bson:"origin" json:"origin"`
-	Error     string            `bson:"error,omitempty" json:"error,omitempty"`
-	Result    *float64           `bson:"result,omitempty" json:"result,omitempty"`
-	NodeID    primitive.ObjectID `bson:"node_id,omitempty" json:"-"`
-	Status    status.Status      `bson:"status" json:"status"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	ID         ID                 `bson:"_id,omitempty" json:"id"`
+	UserID     ID                 `bson:"user_id" json:"user_id"`
+	Origin     string             `bson:"origin" json:"origin"`
+	Variables  map[string]float64 `bson:"variables,omitempty" json:"variables,omitempty"`   // Values used instead of the variables in the origin
+	Eliminated int                `bson:"eliminated,omitempty" json:"eliminated,omitempty"` // Nodes that were calculated by the orchestrator or dropped
+	Depth      int                `bson:"depth,omitempty" json:"depth,omitempty"`           // Agent rounds on the longest path of the tree, the critical path
+	Precision  tree.Precision     `bson:"precision,omitempty" json:"precision,omitempty"`
+	Error      string             `bson:"error,omitempty" json:"error,omitempty"`
+	ErrorCode  string             `bson:"error_code,omitempty" json:"error_code,omitempty"` // Machine readable code of the error
+	Warnings   []string           `bson:"warnings,omitempty" json:"warnings,omitempty"`     // Codes of the problems that didn't stop the calculation
+	Result     *float64           `bson:"result,omitempty" json:"result,omitempty"`
+	Decimal    string             `bson:"decimal,omitempty" json:"decimal,omitempty"`         // Exact result in the decimal precision
+	Rational   string             `bson:"rational,omitempty" json:"rational,omitempty"`       // Exact result p/q in the rational precision, Result is its approximation
+	Complex    *Complex           `bson:"complex,omitempty" json:"complex,omitempty"`         // Complex result, Result is its real part
+	Unit       string             `bson:"unit,omitempty" json:"unit,omitempty"`               // Dimension of the result in the SI base units
+	TargetUnit string             `bson:"target_unit,omitempty" json:"target_unit,omitempty"` // Unit the user wants the result in ("km/h")
+	Converted  *float64           `bson:"-" json:"converted,omitempty"`                       // Result in the target unit, it isn't saved
+	Currency   string             `bson:"currency,omitempty" json:"currency,omitempty"`       // Currency of the result, all amounts are converted to it. Empty if the result is a number (100 USD > 90 EUR)
+	Rates      map[string]float64 `bson:"rates,omitempty" json:"rates,omitempty"`             // Rates used for the conversion, so the result can be repeated
+	NodeID     ID                 `bson:"node_id,omitempty" json:"-"`
+	Status     status.Status      `bson:"status" json:"status"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
 }
 
 func (e *Expression) ZapField() zap.Field {
@@ -65,14 +124,48 @@ func (e *Expression) ZapField() zap.Field {
 }
 
 type AggregatedNode struct {
-	ID   primitive.ObjectID `bson:"_id"`
+	ID   ID `bson:"_id"`
 	Tree struct {
 		Operator pb.Operation `bson:"operator"`
 	} `bson:"tree"`
-	LeftNode struct {
-		Number float64 `bson:"number"`
-	} `bson:"leftNode"`
-	RightNode struct {
-		Number float64 `bson:"number"`
-	} `bson:"rightNode"`
+	LeftNode  Operand `bson:"leftNode"`
+	RightNode Operand `bson:"rightNode"`
+}
+
+// Calculated operand of the operation, the right one is empty for unary operations
+type Operand struct {
+	Number   float64  `bson:"number"`
+	Decimal  string   `bson:"decimal"`
+	Rational string   `bson:"rational"`
+	Complex  *Complex `bson:"complex"`
+	Unit     string   `bson:"unit"`
+}
+
+type AstState string
+
+const (
+	// The node is a number: a literal or a calculated result
+	Resolved AstState = "resolved"
+	// The operation waits for its operands
+	Pending AstState = "pending"
+	// The operation was sent to an agent
+	Dispatched AstState = "dispatched"
+	// The expression failed, its tree is deleted
+	Failed AstState = "failed"
+)
+
+// Node of the saved tree with its progress, it is built from the nodes collection
+type AstNode struct {
+	ID       string     `json:"id,omitempty"`       // Empty for the result of a finished expression
+	Operator string     `json:"operator,omitempty"` // Only for operations ("+", "sqrt", "if", "median")
+	Value    *float64   `json:"value,omitempty"`
+	Exact    string     `json:"exact,omitempty"` // Exact decimal or fraction of the value
+	Complex  *Complex   `json:"complex,omitempty"`
+	Unit     string     `json:"unit,omitempty"`
+	State    AstState   `json:"state"`
+	Held     bool       `json:"held,omitempty"` // The branch waits for the condition of a conditional
+	SendedAt *time.Time `json:"sended_at,omitempty"`
+	Error    string     `json:"error,omitempty"`
+	// Conditionals have the condition first, then the branches
+	Children []AstNode `json:"children,omitempty"`
 }
@@ -1,12 +1,21 @@
 package models
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import "github.com/vandi37/Calculator/pkg/parsing/tree"
 
 type CalculationRequest struct {
-	Expression string `json:"expression"`
+	Expression string             `json:"expression"`
+	Variables  map[string]float64 `json:"variables,omitempty"`   // Values of the variables in the expression
+	Precision  tree.Precision     `json:"precision,omitempty"`   // "float" (default), "decimal" or "rational"
+	TargetUnit string             `json:"target_unit,omitempty"` // The result is converted to the unit ("km/h"), it must match the expression
+	KeepOrder  bool               `json:"keep_order,omitempty"`  // Chains of + and * aren't rebalanced, the floats are added in the written order
 }
+type ValidationResponse struct {
+	Valid  bool           `json:"valid"`
+	Errors []ErrorDetails `json:"errors"`
+}
+
 type CreatedResponse struct {
-	Id primitive.ObjectID `json:"id"`
+	Id ID `json:"id"`
 }
 
 type TokenResponse struct {
@@ -21,8 +30,43 @@ type ExpressionResponse struct {
 	Expression Expression `json:"expression"`
 }
 
+type AstResponse struct {
+	Ast AstNode `json:"ast"`
+}
+
 type ErrorResponse struct {
-	Error string `json:"error"`
+	Error   string        `json:"error"`
+	Details *ErrorDetails `json:"details,omitempty"`
+}
+
+// Place of a parsing error in the expression, start and end are byte offsets (end is exclusive)
+type ErrorDetails struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+}
+
+type DefinitionRequest struct {
+	Name   string   `json:"name"`
+	Params []string `json:"params,omitempty"`
+	Body   string   `json:"body"`
+}
+
+type DefinitionsResponse struct {
+	Definitions []Definition `json:"definitions"`
+}
+
+type DefinitionResponse struct {
+	Definition Definition `json:"definition"`
+}
+
+type RateRequest struct {
+	Rate float64 `json:"rate"`
+}
+
+type RatesResponse struct {
+	Rates []Rate `json:"rates"`
 }
 
 type UserRequest struct {
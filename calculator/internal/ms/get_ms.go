@@ -24,6 +24,20 @@ func (g *MsGetter) Get(operation tree.Operation) int32 {
 		return g.MultiplicationMs
 	case pb.Operation_DIVIDE:
 		return g.DivisionMs
+	case pb.Operation(tree.Power):
+		return g.PowerMs
+	case pb.Operation(tree.Modulo):
+		return g.ModuloMs
+	case pb.Operation(tree.IntDivide):
+		return g.IntDivisionMs
+	case pb.Operation(tree.Sqrt), pb.Operation(tree.Abs), pb.Operation(tree.Round),
+		pb.Operation(tree.Ln), pb.Operation(tree.Log), pb.Operation(tree.Min), pb.Operation(tree.Max):
+		return g.FunctionMs
+	case pb.Operation(tree.Negate):
+		return g.NegationMs
+	case pb.Operation(tree.Less), pb.Operation(tree.LessEqual), pb.Operation(tree.Greater), pb.Operation(tree.GreaterEqual),
+		pb.Operation(tree.Equal), pb.Operation(tree.NotEqual), pb.Operation(tree.And), pb.Operation(tree.Or), pb.Operation(tree.Not):
+		return g.ComparisonMs
 	default:
 		return -1
 	}
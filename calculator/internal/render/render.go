@@ -0,0 +1,101 @@
+// This package writes the tree of the expression as an S-expression or a Graphviz graph
+package render
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/vandi37/Calculator/internal/models"
+)
+
+// Colors of the node states in the graph
+var colors = map[models.AstState]string{
+	models.Resolved:   "palegreen",
+	models.Pending:    "lightgray",
+	models.Dispatched: "lightskyblue",
+	models.Failed:     "salmon",
+}
+
+// Writes the tree like (+ 1 (* 2 3)), the numbers with units like 5000[m]
+func SExpr(node models.AstNode) string {
+	if node.State == models.Failed {
+		return fmt.Sprintf("(error %q)", node.Error)
+	}
+	if node.Operator == "" {
+		return value(node)
+	}
+	var builder strings.Builder
+	builder.WriteString("(")
+	builder.WriteString(node.Operator)
+	for _, child := range node.Children {
+		builder.WriteString(" ")
+		builder.WriteString(SExpr(child))
+	}
+	builder.WriteString(")")
+	return builder.String()
+}
+
+// Writes the tree as a Graphviz digraph, the nodes are colored by their state
+func Dot(node models.AstNode) string {
+	var builder strings.Builder
+	builder.WriteString("digraph ast {\n\tnode [shape=box, style=filled];\n")
+	count := 0
+	dot(&builder, node, &count)
+	builder.WriteString("}\n")
+	return builder.String()
+}
+
+// Writes the node and its children, the name of the node is returned
+func dot(builder *strings.Builder, node models.AstNode, count *int) string {
+	name := fmt.Sprintf("n%d", *count)
+	*count++
+	label := node.Operator
+	switch {
+	case node.State == models.Failed:
+		label = "error: " + node.Error
+	case label == "":
+		label = value(node)
+	}
+	style := ""
+	if node.Held {
+		style = ", style=\"filled,dashed\""
+	}
+	fmt.Fprintf(builder, "\t%s [label=%q, fillcolor=%s%s];\n", name, label, colors[node.State], style)
+	edges := edgeLabels(node)
+	for i, child := range node.Children {
+		childName := dot(builder, child, count)
+		if edges != nil {
+			fmt.Fprintf(builder, "\t%s -> %s [label=%q];\n", name, childName, edges[i])
+		} else {
+			fmt.Fprintf(builder, "\t%s -> %s;\n", name, childName)
+		}
+	}
+	return name
+}
+
+// The edges of conditionals are named, so the branches can be told apart
+func edgeLabels(node models.AstNode) []string {
+	if node.Operator == "if" && len(node.Children) == 3 {
+		return []string{"cond", "then", "else"}
+	}
+	return nil
+}
+
+func value(node models.AstNode) string {
+	var text string
+	switch {
+	case node.Exact != "":
+		text = node.Exact
+	case node.Complex != nil:
+		text = fmt.Sprintf("(%s%+gi)", strconv.FormatFloat(node.Complex.Re, 'g', -1, 64), node.Complex.Im)
+	case node.Value != nil:
+		text = strconv.FormatFloat(*node.Value, 'g', -1, 64)
+	default:
+		text = "?"
+	}
+	if node.Unit != "" {
+		text += "[" + node.Unit + "]"
+	}
+	return text
+}
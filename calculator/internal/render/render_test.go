@@ -0,0 +1,84 @@
+package render_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/internal/render"
+)
+
+func number(value float64) models.AstNode {
+	return models.AstNode{Value: &value, State: models.Resolved}
+}
+
+func TestSExpr(t *testing.T) {
+	quantity := number(5000)
+	quantity.Unit = "m"
+	tests := []struct {
+		name     string
+		node     models.AstNode
+		expected string
+	}{
+		{"Number", number(2.5), "2.5"},
+		{"Exact", models.AstNode{Value: new(float64), Exact: "1/3", State: models.Resolved}, "1/3"},
+		{"Complex", models.AstNode{Value: new(float64), Complex: &models.Complex{Re: 1, Im: -2}, State: models.Resolved}, "(1-2i)"},
+		{"Unit", quantity, "5000[m]"},
+		{"Failed", models.AstNode{State: models.Failed, Error: "division by zero"}, `(error "division by zero")`},
+		{
+			"Nested",
+			models.AstNode{Operator: "+", State: models.Pending, Children: []models.AstNode{
+				number(1),
+				{Operator: "*", State: models.Pending, Children: []models.AstNode{number(2), number(3)}},
+			}},
+			"(+ 1 (* 2 3))",
+		},
+		{
+			"Call",
+			models.AstNode{Operator: "median", State: models.Pending, Children: []models.AstNode{number(1), number(2), number(3)}},
+			"(median 1 2 3)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, render.SExpr(tt.node))
+		})
+	}
+}
+
+func TestDot(t *testing.T) {
+	sended := time.Now()
+	then := models.AstNode{Operator: "-", State: models.Pending, Held: true, Children: []models.AstNode{number(1)}}
+	otherwise := number(0)
+	otherwise.Held = true
+	node := models.AstNode{Operator: "if", State: models.Pending, Children: []models.AstNode{
+		{Operator: ">", State: models.Dispatched, SendedAt: &sended, Children: []models.AstNode{number(2), number(1)}},
+		then,
+		otherwise,
+	}}
+
+	expected := "digraph ast {\n" +
+		"\tnode [shape=box, style=filled];\n" +
+		"\tn0 [label=\"if\", fillcolor=lightgray];\n" +
+		"\tn1 [label=\">\", fillcolor=lightskyblue];\n" +
+		"\tn2 [label=\"2\", fillcolor=palegreen];\n" +
+		"\tn1 -> n2;\n" +
+		"\tn3 [label=\"1\", fillcolor=palegreen];\n" +
+		"\tn1 -> n3;\n" +
+		"\tn0 -> n1 [label=\"cond\"];\n" +
+		"\tn4 [label=\"-\", fillcolor=lightgray, style=\"filled,dashed\"];\n" +
+		"\tn5 [label=\"1\", fillcolor=palegreen];\n" +
+		"\tn4 -> n5;\n" +
+		"\tn0 -> n4 [label=\"then\"];\n" +
+		"\tn6 [label=\"0\", fillcolor=palegreen, style=\"filled,dashed\"];\n" +
+		"\tn0 -> n6 [label=\"else\"];\n" +
+		"}\n"
+	assert.Equal(t, expected, render.Dot(node))
+}
+
+func TestDot_Failed(t *testing.T) {
+	node := models.AstNode{State: models.Failed, Error: `unknown unit "parsec"`}
+	assert.Equal(t, "digraph ast {\n\tnode [shape=box, style=filled];\n\tn0 [label=\"error: unknown unit \\\"parsec\\\"\", fillcolor=salmon];\n}\n", render.Dot(node))
+}
@@ -0,0 +1,83 @@
+// This package saves the users, the definitions, the rates and the expressions in one bbolt file.
+// It is used by one orchestrator without a database server, the values are saved as bson like in mongo
+package boltrepo
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/vandi37/Calculator/internal/repo"
+	"github.com/vandi37/ferror"
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var (
+	usersBucket       = []byte("users")
+	usernamesBucket   = []byte("usernames")
+	definitionsBucket = []byte("definitions")
+	ratesBucket       = []byte("rates")
+	expressionsBucket = []byte("expressions")
+	// The ids of the pending expressions, only they are read for the tasks
+	pendingBucket = []byte("pending")
+	// The node ids of the pending trees with the ids of their expressions, so a tree is found by its node
+	nodesBucket = []byte("nodes")
+	// The keys are the user id with the id of the value, so the values of the user are found by the prefix
+	userDefinitionsBucket = []byte("user_definitions")
+	userExpressionsBucket = []byte("user_expressions")
+)
+
+// Opens the file and creates the buckets, the file is locked while it is open
+func Open(path string) (*bolt.DB, error) {
+	var save = ferror.Save("boltrepo.Open")
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, save.New(err)
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{
+			usersBucket, usernamesBucket, definitionsBucket, ratesBucket, expressionsBucket,
+			pendingBucket, nodesBucket, userDefinitionsBucket, userExpressionsBucket,
+		} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		db.Close()
+		return nil, save.New(err)
+	}
+	return db, nil
+}
+
+// Returns false if the key isn't in the bucket
+func get(b *bolt.Bucket, key []byte, value any) (bool, error) {
+	data := b.Get(key)
+	if data == nil {
+		return false, nil
+	}
+	return true, bson.Unmarshal(data, value)
+}
+
+func put(b *bolt.Bucket, key []byte, value any) error {
+	data, err := bson.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return b.Put(key, data)
+}
+
+func userKey(userID, id repo.ID) []byte {
+	return append(userID[:len(userID):len(userID)], id[:]...)
+}
+
+// The ids of the values of the user in the order of their creation
+func byUser(b *bolt.Bucket, userID repo.ID) []repo.ID {
+	var ids []repo.ID
+	c := b.Cursor()
+	for k, _ := c.Seek(userID[:]); k != nil && bytes.HasPrefix(k, userID[:]); k, _ = c.Next() {
+		ids = append(ids, repo.ID(k[len(userID):]))
+	}
+	return ids
+}
@@ -0,0 +1,100 @@
+package boltrepo_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/internal/repo"
+	"github.com/vandi37/Calculator/internal/repo/boltrepo"
+	"github.com/vandi37/Calculator/internal/repo/repotest"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Opens a new file, it is closed after the test
+func open(t *testing.T) *bolt.DB {
+	db, err := boltrepo.Open(filepath.Join(t.TempDir(), "calculator.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, db.Close())
+	})
+	return db
+}
+
+func TestUserRepo(t *testing.T) {
+	suite.Run(t, &repotest.UserSuite{
+		New: func(t *testing.T) repo.UserRepo {
+			return boltrepo.NewUserRepo(open(t))
+		},
+	})
+}
+
+func TestDefinitionRepo(t *testing.T) {
+	suite.Run(t, &repotest.DefinitionSuite{
+		New: func(t *testing.T) repo.DefinitionRepo {
+			return boltrepo.NewDefinitionRepo(open(t))
+		},
+	})
+}
+
+func TestRateRepo(t *testing.T) {
+	suite.Run(t, &repotest.RateSuite{
+		New: func(t *testing.T) repo.RateRepo {
+			return boltrepo.NewRateRepo(open(t))
+		},
+	})
+}
+
+func TestExpressionRepo(t *testing.T) {
+	suite.Run(t, &repotest.ExpressionSuite{
+		New: func(t *testing.T, d time.Duration) repo.ExpressionRepo {
+			return boltrepo.NewExpressionRepo(open(t), d)
+		},
+		Exclusive: true,
+	})
+}
+
+// The data is kept in the file after a restart
+type BoltRepoTestSuite struct {
+	suite.Suite
+	path           string
+	db             *bolt.DB
+	userRepo       *boltrepo.UserRepo
+	definitionRepo *boltrepo.DefinitionRepo
+	rateRepo       *boltrepo.RateRepo
+	expressionRepo *boltrepo.ExpressionRepo
+	userId         repo.ID
+}
+
+func (suite *BoltRepoTestSuite) SetupTest() {
+	suite.path = filepath.Join(suite.T().TempDir(), "calculator.db")
+	suite.open()
+	suite.userId = models.NewID()
+}
+
+func (suite *BoltRepoTestSuite) TearDownTest() {
+	require.NoError(suite.T(), suite.db.Close())
+}
+
+func (suite *BoltRepoTestSuite) open() {
+	db, err := boltrepo.Open(suite.path)
+	require.NoError(suite.T(), err)
+	suite.db = db
+	suite.userRepo = boltrepo.NewUserRepo(db)
+	suite.definitionRepo = boltrepo.NewDefinitionRepo(db)
+	suite.rateRepo = boltrepo.NewRateRepo(db)
+	suite.expressionRepo = boltrepo.NewExpressionRepo(db, 5*time.Minute)
+}
+
+// Closes the file and opens it again like after a restart
+func (suite *BoltRepoTestSuite) reopen() {
+	require.NoError(suite.T(), suite.db.Close())
+	suite.open()
+}
+
+func TestBoltRepoTestSuite(t *testing.T) {
+	suite.Run(t, new(BoltRepoTestSuite))
+}
@@ -0,0 +1,169 @@
+package boltrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/internal/repo"
+	"github.com/vandi37/ferror"
+	bolt "go.etcd.io/bbolt"
+)
+
+type DefinitionRepo struct {
+	db *bolt.DB
+}
+
+func NewDefinitionRepo(db *bolt.DB) *DefinitionRepo {
+	return &DefinitionRepo{db: db}
+}
+
+// The definitions of the user in the order of their creation
+func definitionsOf(tx *bolt.Tx, userID repo.ID) ([]models.Definition, error) {
+	definitions := tx.Bucket(definitionsBucket)
+	var result []models.Definition
+	for _, id := range byUser(tx.Bucket(userDefinitionsBucket), userID) {
+		var definition models.Definition
+		if ok, err := get(definitions, id[:], &definition); err != nil {
+			return nil, err
+		} else if ok {
+			result = append(result, definition)
+		}
+	}
+	return result, nil
+}
+
+// Names are unique for every user
+func nameExists(tx *bolt.Tx, userID repo.ID, name string, except repo.ID) (bool, error) {
+	definitions, err := definitionsOf(tx, userID)
+	if err != nil {
+		return false, err
+	}
+	for _, definition := range definitions {
+		if definition.ID != except && definition.Name == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Create implements repo.DefinitionRepo.
+func (r *DefinitionRepo) Create(ctx context.Context, definition models.Definition) (repo.ID, error) {
+	var save = ferror.Save("boltrepo.DefinitionRepo.Create")
+	definition.ID = models.NewID()
+	definition.CreatedAt = time.Now()
+	if err := r.db.Update(func(tx *bolt.Tx) error {
+		if exists, err := nameExists(tx, definition.UserID, definition.Name, repo.ID{}); err != nil {
+			return save.New(err)
+		} else if exists {
+			return repo.DefinitionNameTaken
+		}
+		if err := put(tx.Bucket(definitionsBucket), definition.ID[:], definition); err != nil {
+			return save.New(err)
+		}
+		if err := tx.Bucket(userDefinitionsBucket).Put(userKey(definition.UserID, definition.ID), nil); err != nil {
+			return save.New(err)
+		}
+		return nil
+	}); err != nil {
+		return repo.ID{}, err
+	}
+	return definition.ID, nil
+}
+
+// Get implements repo.DefinitionRepo.
+func (r *DefinitionRepo) Get(ctx context.Context, id repo.ID) (*models.Definition, error) {
+	var save = ferror.Save("boltrepo.DefinitionRepo.Get")
+	var definition models.Definition
+	if err := r.db.View(func(tx *bolt.Tx) error {
+		if ok, err := get(tx.Bucket(definitionsBucket), id[:], &definition); err != nil {
+			return save.New(err)
+		} else if !ok {
+			return repo.DefinitionNotFound
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return &definition, nil
+}
+
+// GetByUser implements repo.DefinitionRepo.
+func (r *DefinitionRepo) GetByUser(ctx context.Context, userID repo.ID) ([]models.Definition, error) {
+	var save = ferror.Save("boltrepo.DefinitionRepo.GetByUser")
+	var definitions []models.Definition
+	if err := r.db.View(func(tx *bolt.Tx) (err error) {
+		definitions, err = definitionsOf(tx, userID)
+		return err
+	}); err != nil {
+		return nil, save.New(err)
+	}
+	return definitions, nil
+}
+
+// Update implements repo.DefinitionRepo.
+func (r *DefinitionRepo) Update(ctx context.Context, definition models.Definition) error {
+	var save = ferror.Save("boltrepo.DefinitionRepo.Update")
+	return r.db.Update(func(tx *bolt.Tx) error {
+		definitions := tx.Bucket(definitionsBucket)
+		var saved models.Definition
+		if ok, err := get(definitions, definition.ID[:], &saved); err != nil {
+			return save.New(err)
+		} else if !ok {
+			return repo.DefinitionNotFound
+		}
+		if exists, err := nameExists(tx, saved.UserID, definition.Name, definition.ID); err != nil {
+			return save.New(err)
+		} else if exists {
+			return repo.DefinitionNameTaken
+		}
+		saved.Name, saved.Params, saved.Body = definition.Name, definition.Params, definition.Body
+		if err := put(definitions, definition.ID[:], saved); err != nil {
+			return save.New(err)
+		}
+		return nil
+	})
+}
+
+// Delete implements repo.DefinitionRepo.
+func (r *DefinitionRepo) Delete(ctx context.Context, id repo.ID) error {
+	var save = ferror.Save("boltrepo.DefinitionRepo.Delete")
+	return r.db.Update(func(tx *bolt.Tx) error {
+		definitions := tx.Bucket(definitionsBucket)
+		var definition models.Definition
+		if ok, err := get(definitions, id[:], &definition); err != nil {
+			return save.New(err)
+		} else if !ok {
+			return repo.DefinitionNotFound
+		}
+		if err := definitions.Delete(id[:]); err != nil {
+			return save.New(err)
+		}
+		if err := tx.Bucket(userDefinitionsBucket).Delete(userKey(definition.UserID, id)); err != nil {
+			return save.New(err)
+		}
+		return nil
+	})
+}
+
+// DeleteByUser implements repo.DefinitionRepo.
+func (r *DefinitionRepo) DeleteByUser(ctx context.Context, userID repo.ID) error {
+	var save = ferror.Save("boltrepo.DefinitionRepo.DeleteByUser")
+	if err := r.db.Update(func(tx *bolt.Tx) error {
+		definitions, index := tx.Bucket(definitionsBucket), tx.Bucket(userDefinitionsBucket)
+		for _, id := range byUser(index, userID) {
+			if err := definitions.Delete(id[:]); err != nil {
+				return err
+			}
+			if err := index.Delete(userKey(userID, id)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return save.New(err)
+	}
+	return nil
+}
+
+var _ repo.DefinitionRepo = (*DefinitionRepo)(nil)
@@ -0,0 +1,431 @@
+package boltrepo
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	pb "github.com/vandi37/Calculator-Models"
+	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/internal/repo"
+	"github.com/vandi37/Calculator/internal/repo/nodetree"
+	"github.com/vandi37/Calculator/internal/status"
+	"github.com/vandi37/Calculator/pkg/parsing/tree"
+	"github.com/vandi37/ferror"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Saves every expression with its whole tree like the tree storage of mongo.
+// The writing transactions of bbolt don't run at the same time, so a task is never sent twice before the duration
+type ExpressionRepo struct {
+	db       *bolt.DB
+	d        time.Duration
+	callback repo.Callback
+	mu       sync.Mutex
+}
+
+func NewExpressionRepo(db *bolt.DB, d time.Duration) *ExpressionRepo {
+	return &ExpressionRepo{db: db, d: d}
+}
+
+// SetCallback implements repo.ExpressionRepo.
+func (r *ExpressionRepo) SetCallback(ctx context.Context, callback repo.Callback) {
+	r.mu.Lock()
+	r.callback = callback
+	r.mu.Unlock()
+	go r.DoCallback()
+}
+
+// The tasks sent before a restart are lost with the connections of the agents, so they are sent again at once.
+// It is called at the start before the callback is set, returns the number of the nodes to send again
+func (r *ExpressionRepo) Resume(ctx context.Context) (int, error) {
+	var save = ferror.Save("boltrepo.ExpressionRepo.Resume")
+	resumed := 0
+	if err := r.db.Update(func(tx *bolt.Tx) error {
+		return forPending(tx, func(t *nodetree.Tree) (bool, error) {
+			changed := false
+			for i := range t.Nodes {
+				// The files of the older versions have no index of the nodes
+				if err := tx.Bucket(nodesBucket).Put(t.Nodes[i].ID[:], t.ID[:]); err != nil {
+					return false, err
+				}
+				if t.Nodes[i].SendedAt != nil {
+					t.Nodes[i].SendedAt = nil
+					resumed++
+					changed = true
+				}
+			}
+			return changed, nil
+		})
+	}); err != nil {
+		return 0, save.New(err)
+	}
+	return resumed, nil
+}
+
+// Create implements repo.ExpressionRepo.
+func (r *ExpressionRepo) Create(ctx context.Context, expression models.Expression, ast tree.Ast) (repo.ID, error) {
+	var save = ferror.Save("boltrepo.ExpressionRepo.Create")
+	num, nodes, err := nodetree.Build(ast.Expression)
+	if err != nil {
+		return repo.ID{}, save.New(err)
+	}
+	var root repo.ID
+	if num == nil {
+		root = nodes[len(nodes)-1].ID
+	}
+	nodetree.Prepare(&expression, num, root)
+	if expression.ID == models.NilID {
+		expression.ID = models.NewID()
+	}
+	t := nodetree.Tree{Expression: expression, Nodes: nodetree.WithPending(nodes)}
+	if err := r.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(userExpressionsBucket).Put(userKey(expression.UserID, expression.ID), nil); err != nil {
+			return err
+		}
+		return saveTree(tx, &t, nil)
+	}); err != nil {
+		return repo.ID{}, save.New(err)
+	}
+	go r.DoCallback()
+	return expression.ID, nil
+}
+
+// The ids of the nodes of the tree, they are compared with the saved ones
+func nodeIds(t *nodetree.Tree) map[repo.ID]bool {
+	ids := make(map[repo.ID]bool, len(t.Nodes))
+	for i := range t.Nodes {
+		ids[t.Nodes[i].ID] = true
+	}
+	return ids
+}
+
+// Saves the tree, the expression isn't pending after its result or error.
+// The index of the nodes is changed by the ids the tree had before
+func saveTree(tx *bolt.Tx, t *nodetree.Tree, before map[repo.ID]bool) error {
+	if err := put(tx.Bucket(expressionsBucket), t.ID[:], t); err != nil {
+		return err
+	}
+	nodes, after := tx.Bucket(nodesBucket), nodeIds(t)
+	for id := range before {
+		if !after[id] {
+			if err := nodes.Delete(id[:]); err != nil {
+				return err
+			}
+		}
+	}
+	for id := range after {
+		if !before[id] {
+			if err := nodes.Put(id[:], t.ID[:]); err != nil {
+				return err
+			}
+		}
+	}
+	if t.Status == status.Pending {
+		return tx.Bucket(pendingBucket).Put(t.ID[:], nil)
+	}
+	return tx.Bucket(pendingBucket).Delete(t.ID[:])
+}
+
+// Calls the function for every pending tree in the order of their creation, the tree is saved if the function returns true
+func forPending(tx *bolt.Tx, f func(t *nodetree.Tree) (bool, error)) error {
+	var ids []repo.ID
+	if err := tx.Bucket(pendingBucket).ForEach(func(k, v []byte) error {
+		ids = append(ids, repo.ID(k))
+		return nil
+	}); err != nil {
+		return err
+	}
+	// The bucket isn't changed while it is iterated
+	for _, id := range ids {
+		var t nodetree.Tree
+		if ok, err := get(tx.Bucket(expressionsBucket), id[:], &t); err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
+		before := nodeIds(&t)
+		if changed, err := f(&t); err != nil {
+			return err
+		} else if changed {
+			if err := saveTree(tx, &t, before); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// The pending tree with the node, nil if there is no such tree
+func byNode(tx *bolt.Tx, id repo.ID) (*nodetree.Tree, error) {
+	expressionID := tx.Bucket(nodesBucket).Get(id[:])
+	if expressionID == nil {
+		return nil, nil
+	}
+	var t nodetree.Tree
+	if ok, err := get(tx.Bucket(expressionsBucket), expressionID, &t); err != nil {
+		return nil, err
+	} else if !ok || t.Find(id) < 0 {
+		return nil, nil
+	}
+	return &t, nil
+}
+
+// Get implements repo.ExpressionRepo.
+func (r *ExpressionRepo) Get(ctx context.Context, id repo.ID) (*models.Expression, error) {
+	var save = ferror.Save("boltrepo.ExpressionRepo.Get")
+	var t nodetree.Tree
+	if err := r.db.View(func(tx *bolt.Tx) error {
+		if ok, err := get(tx.Bucket(expressionsBucket), id[:], &t); err != nil {
+			return save.New(err)
+		} else if !ok {
+			return repo.ExpressionNotFound
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return &t.Expression, nil
+}
+
+// GetByUser implements repo.ExpressionRepo.
+func (r *ExpressionRepo) GetByUser(ctx context.Context, userID repo.ID) ([]models.Expression, error) {
+	var save = ferror.Save("boltrepo.ExpressionRepo.GetByUser")
+	var expressions []models.Expression
+	if err := r.db.View(func(tx *bolt.Tx) error {
+		for _, id := range byUser(tx.Bucket(userExpressionsBucket), userID) {
+			var t nodetree.Tree
+			if ok, err := get(tx.Bucket(expressionsBucket), id[:], &t); err != nil {
+				return err
+			} else if ok {
+				expressions = append(expressions, t.Expression)
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, save.New(err)
+	}
+	return expressions, nil
+}
+
+// GetNode implements repo.ExpressionRepo.
+func (r *ExpressionRepo) GetNode(ctx context.Context, id repo.ID) (*models.Node, error) {
+	var save = ferror.Save("boltrepo.ExpressionRepo.GetNode")
+	var node models.Node
+	if err := r.db.View(func(tx *bolt.Tx) error {
+		t, err := byNode(tx, id)
+		if err != nil {
+			return save.New(err)
+		} else if t == nil {
+			return repo.NodeNotFound
+		}
+		node = t.Nodes[t.Find(id)].Node
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
+
+// GetNodes implements repo.ExpressionRepo.
+func (r *ExpressionRepo) GetNodes(ctx context.Context, id repo.ID) ([]models.Node, error) {
+	var save = ferror.Save("boltrepo.ExpressionRepo.GetNodes")
+	var nodes []models.Node
+	if err := r.db.View(func(tx *bolt.Tx) error {
+		t, err := byNode(tx, id)
+		if err != nil {
+			return save.New(err)
+		} else if t == nil {
+			return repo.NodeNotFound
+		}
+		nodes = t.Subtree(id)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// Deletes the expression with its indexes
+func deleteTree(tx *bolt.Tx, t *nodetree.Tree) error {
+	for i := range t.Nodes {
+		if err := tx.Bucket(nodesBucket).Delete(t.Nodes[i].ID[:]); err != nil {
+			return err
+		}
+	}
+	if err := tx.Bucket(expressionsBucket).Delete(t.ID[:]); err != nil {
+		return err
+	}
+	if err := tx.Bucket(pendingBucket).Delete(t.ID[:]); err != nil {
+		return err
+	}
+	return tx.Bucket(userExpressionsBucket).Delete(userKey(t.UserID, t.ID))
+}
+
+// Delete implements repo.ExpressionRepo.
+func (r *ExpressionRepo) Delete(ctx context.Context, id repo.ID) error {
+	var save = ferror.Save("boltrepo.ExpressionRepo.Delete")
+	return r.db.Update(func(tx *bolt.Tx) error {
+		var t nodetree.Tree
+		if ok, err := get(tx.Bucket(expressionsBucket), id[:], &t); err != nil {
+			return save.New(err)
+		} else if !ok {
+			return repo.ExpressionNotFound
+		}
+		if err := deleteTree(tx, &t); err != nil {
+			return save.New(err)
+		}
+		return nil
+	})
+}
+
+// DeleteByUser implements repo.ExpressionRepo.
+func (r *ExpressionRepo) DeleteByUser(ctx context.Context, userID repo.ID) error {
+	var save = ferror.Save("boltrepo.ExpressionRepo.DeleteByUser")
+	if err := r.db.Update(func(tx *bolt.Tx) error {
+		for _, id := range byUser(tx.Bucket(userExpressionsBucket), userID) {
+			var t nodetree.Tree
+			if ok, err := get(tx.Bucket(expressionsBucket), id[:], &t); err != nil {
+				return err
+			} else if !ok {
+				continue
+			}
+			if err := deleteTree(tx, &t); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return save.New(err)
+	}
+	return nil
+}
+
+// Changes the pending tree of the node in a transaction, the function returns the error of the repo
+func (r *ExpressionRepo) change(save ferror.Save, id repo.ID, notFound error, f func(t *nodetree.Tree) error) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		t, err := byNode(tx, id)
+		if err != nil {
+			return save.New(err)
+		} else if t == nil {
+			return notFound
+		}
+		before := nodeIds(t)
+		if err := f(t); err != nil {
+			return err
+		}
+		if err := saveTree(tx, t, before); err != nil {
+			return save.New(err)
+		}
+		return nil
+	})
+}
+
+// SetToError implements repo.ExpressionRepo.
+func (r *ExpressionRepo) SetToError(ctx context.Context, id repo.ID, code, errVal string) error {
+	return r.change(ferror.Save("boltrepo.ExpressionRepo.SetToError"), id, repo.ExpressionNotFound, func(t *nodetree.Tree) error {
+		t.Status, t.Error, t.ErrorCode = status.Error, errVal, code
+		t.Result, t.NodeID, t.Nodes = nil, models.NilID, nil
+		return nil
+	})
+}
+
+// AddWarning implements repo.ExpressionRepo.
+func (r *ExpressionRepo) AddWarning(ctx context.Context, nodeId repo.ID, code string) error {
+	return r.change(ferror.Save("boltrepo.ExpressionRepo.AddWarning"), nodeId, repo.ExpressionNotFound, func(t *nodetree.Tree) error {
+		if !slices.Contains(t.Warnings, code) {
+			t.Warnings = append(t.Warnings, code)
+		}
+		return nil
+	})
+}
+
+// SetToNum implements repo.ExpressionRepo.
+func (r *ExpressionRepo) SetToNum(ctx context.Context, nodeId repo.ID, result float64) error {
+	return r.complete(ferror.Save("boltrepo.ExpressionRepo.SetToNum"), nodeId, models.Node{Number: &result})
+}
+
+// SetToDecimal implements repo.ExpressionRepo.
+func (r *ExpressionRepo) SetToDecimal(ctx context.Context, nodeId repo.ID, result float64, decimal string) error {
+	return r.complete(ferror.Save("boltrepo.ExpressionRepo.SetToDecimal"), nodeId, models.Node{Number: &result, Decimal: decimal})
+}
+
+// SetToRational implements repo.ExpressionRepo.
+func (r *ExpressionRepo) SetToRational(ctx context.Context, nodeId repo.ID, result float64, rational string) error {
+	return r.complete(ferror.Save("boltrepo.ExpressionRepo.SetToRational"), nodeId, models.Node{Number: &result, Rational: rational})
+}
+
+// SetToUnit implements repo.ExpressionRepo.
+func (r *ExpressionRepo) SetToUnit(ctx context.Context, nodeId repo.ID, result float64, unit string) error {
+	return r.complete(ferror.Save("boltrepo.ExpressionRepo.SetToUnit"), nodeId, models.Node{Number: &result, Unit: unit})
+}
+
+// SetToComplex implements repo.ExpressionRepo.
+func (r *ExpressionRepo) SetToComplex(ctx context.Context, nodeId repo.ID, result models.Complex) error {
+	return r.complete(ferror.Save("boltrepo.ExpressionRepo.SetToComplex"), nodeId, models.Node{Number: &result.Re, Complex: &result})
+}
+
+// The node becomes the number, the result of a node that is already a number isn't saved twice
+func (r *ExpressionRepo) complete(save ferror.Save, nodeId repo.ID, value models.Node) error {
+	if err := r.change(save, nodeId, repo.NodeNotFound, func(t *nodetree.Tree) error {
+		if t.Nodes[t.Find(nodeId)].Type != models.Operation {
+			return repo.NodeNotFound
+		}
+		t.SetNumber(nodeId, value)
+		return nil
+	}); err != nil {
+		return err
+	}
+	go r.DoCallback()
+	return nil
+}
+
+// GetFitNodes implements repo.ExpressionRepo.
+func (r *ExpressionRepo) GetFitNodes(ctx context.Context) ([]pb.Task, error) {
+	var save = ferror.Save("boltrepo.ExpressionRepo.GetFitNodes")
+	now := time.Now()
+	sendedBefore := now.Add(-r.d)
+	tasks := []pb.Task{}
+	if err := r.db.Update(func(tx *bolt.Tx) error {
+		return forPending(tx, func(t *nodetree.Tree) (bool, error) {
+			// A chosen branch can be a conditional with a calculated condition too, it is resolved in the same call
+			changed := t.Resolve()
+			for i := range t.Nodes {
+				left, right, ok := t.Operands(t.Nodes[i], sendedBefore)
+				if !ok {
+					continue
+				}
+				result := models.AggregatedNode{ID: t.Nodes[i].ID, LeftNode: left, RightNode: right}
+				result.Tree.Operator = t.Nodes[i].Tree.Operator
+				tasks = append(tasks, pb.Task{})
+				nodetree.SetTask(&tasks[len(tasks)-1], result)
+				t.Nodes[i].SendedAt = &now
+				changed = true
+			}
+			return changed, nil
+		})
+	}); err != nil {
+		return nil, save.New(err)
+	}
+	return tasks, nil
+}
+
+// Nothing is sent before the callback is set
+func (r *ExpressionRepo) DoCallback() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.callback == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second*20)
+	defer cancel()
+	tasks, err := r.GetFitNodes(ctx)
+	if err != nil {
+		r.callback.SendError(ctx, err)
+		return
+	}
+	r.callback.SendResult(ctx, tasks)
+}
+
+var _ repo.ExpressionRepo = (*ExpressionRepo)(nil)
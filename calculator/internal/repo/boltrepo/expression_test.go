@@ -0,0 +1,108 @@
+package boltrepo_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	pb "github.com/vandi37/Calculator-Models"
+	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/internal/repo"
+	"github.com/vandi37/Calculator/internal/status"
+	"github.com/vandi37/Calculator/pkg/parsing/parser"
+	bolt "go.etcd.io/bbolt"
+)
+
+func (suite *BoltRepoTestSuite) create(expression string) repo.ID {
+	ast, err := parser.Build(expression)
+	require.NoError(suite.T(), err)
+	id, err := suite.expressionRepo.Create(context.Background(), models.Expression{UserID: suite.userId, Origin: expression}, ast)
+	require.NoError(suite.T(), err)
+	return id
+}
+
+func taskId(t *testing.T, task *pb.Task) repo.ID {
+	id, err := models.IDFromHex(task.Id)
+	require.NoError(t, err)
+	return id
+}
+
+func (suite *BoltRepoTestSuite) TestExpressionRestart() {
+	t := suite.T()
+	ctx := context.Background()
+
+	id := suite.create("(1 + 2) * (3 + 4)")
+	finished := suite.create("1 - 2")
+	tasks, err := suite.expressionRepo.GetFitNodes(ctx)
+	require.NoError(t, err)
+	require.Len(t, tasks, 3)
+	for i := range tasks {
+		if tasks[i].Operation == pb.Operation_SUBTRACT {
+			require.NoError(t, suite.expressionRepo.SetToNum(ctx, taskId(t, &tasks[i]), -1))
+		}
+	}
+
+	// The sent tasks are lost with the process, they are sent again after the start
+	suite.reopen()
+	resumed, err := suite.expressionRepo.Resume(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, resumed)
+
+	expr, err := suite.expressionRepo.Get(ctx, finished)
+	require.NoError(t, err)
+	assert.Equal(t, status.Finished, expr.Status)
+	assert.Equal(t, -1.0, *expr.Result)
+
+	tasks, err = suite.expressionRepo.GetFitNodes(ctx)
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+	for i := range tasks {
+		assert.Equal(t, pb.Operation_ADD, tasks[i].Operation)
+		require.NoError(t, suite.expressionRepo.SetToNum(ctx, taskId(t, &tasks[i]), tasks[i].Arg1+tasks[i].Arg2))
+	}
+	tasks, err = suite.expressionRepo.GetFitNodes(ctx)
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	require.NoError(t, suite.expressionRepo.SetToNum(ctx, taskId(t, &tasks[0]), 21))
+
+	expr, err = suite.expressionRepo.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, status.Finished, expr.Status)
+	assert.Equal(t, 21.0, *expr.Result)
+
+	expressions, err := suite.expressionRepo.GetByUser(ctx, suite.userId)
+	require.NoError(t, err)
+	assert.Len(t, expressions, 2)
+}
+
+func (suite *BoltRepoTestSuite) TestExpressionNodeIndex() {
+	t := suite.T()
+	ctx := context.Background()
+
+	indexed := func() int {
+		count := 0
+		require.NoError(t, suite.db.View(func(tx *bolt.Tx) error {
+			return tx.Bucket([]byte("nodes")).ForEach(func(k, v []byte) error {
+				count++
+				return nil
+			})
+		}))
+		return count
+	}
+
+	suite.create("(1 + 2) + 3")
+	deleted := suite.create("4 - 5")
+	assert.Equal(t, 8, indexed())
+	require.NoError(t, suite.expressionRepo.Delete(ctx, deleted))
+	assert.Equal(t, 5, indexed())
+
+	// The nodes of the finished tree are removed from the index
+	for range 2 {
+		tasks, err := suite.expressionRepo.GetFitNodes(ctx)
+		require.NoError(t, err)
+		require.Len(t, tasks, 1)
+		require.NoError(t, suite.expressionRepo.SetToNum(ctx, taskId(t, &tasks[0]), tasks[0].Arg1+tasks[0].Arg2))
+	}
+	assert.Zero(t, indexed())
+}
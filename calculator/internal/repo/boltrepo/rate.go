@@ -0,0 +1,95 @@
+package boltrepo
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/internal/repo"
+	"github.com/vandi37/Calculator/pkg/currency"
+	"github.com/vandi37/ferror"
+	"github.com/vandi37/vanerrors"
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type RateRepo struct {
+	db *bolt.DB
+}
+
+func NewRateRepo(db *bolt.DB) *RateRepo {
+	return &RateRepo{db: db}
+}
+
+// GetRates implements repo.RateRepo.
+func (r *RateRepo) GetRates(ctx context.Context, codes []string) (map[string]float64, error) {
+	var save = ferror.Save("boltrepo.RateRepo.GetRates")
+	rates := make(map[string]float64, len(codes))
+	if err := r.db.View(func(tx *bolt.Tx) error {
+		for _, code := range codes {
+			var rate models.Rate
+			if ok, err := get(tx.Bucket(ratesBucket), []byte(code), &rate); err != nil {
+				return err
+			} else if ok {
+				rates[code] = rate.Rate
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, save.New(err)
+	}
+	if missing := currency.Missing(rates, codes...); len(missing) > 0 {
+		return nil, vanerrors.New(currency.UnknownCurrency, strings.Join(missing, ", "))
+	}
+	return rates, nil
+}
+
+// Set implements repo.RateRepo.
+func (r *RateRepo) Set(ctx context.Context, rate models.Rate) error {
+	var save = ferror.Save("boltrepo.RateRepo.Set")
+	rate.UpdatedAt = time.Now()
+	if err := r.db.Update(func(tx *bolt.Tx) error {
+		return put(tx.Bucket(ratesBucket), []byte(rate.Code), rate)
+	}); err != nil {
+		return save.New(err)
+	}
+	return nil
+}
+
+// GetAll implements repo.RateRepo.
+func (r *RateRepo) GetAll(ctx context.Context) ([]models.Rate, error) {
+	var save = ferror.Save("boltrepo.RateRepo.GetAll")
+	var rates []models.Rate
+	// The keys are sorted, so the rates are in the order of their codes
+	if err := r.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(ratesBucket).ForEach(func(k, v []byte) error {
+			var rate models.Rate
+			if err := bson.Unmarshal(v, &rate); err != nil {
+				return err
+			}
+			rates = append(rates, rate)
+			return nil
+		})
+	}); err != nil {
+		return nil, save.New(err)
+	}
+	return rates, nil
+}
+
+// Delete implements repo.RateRepo.
+func (r *RateRepo) Delete(ctx context.Context, code string) error {
+	var save = ferror.Save("boltrepo.RateRepo.Delete")
+	return r.db.Update(func(tx *bolt.Tx) error {
+		rates := tx.Bucket(ratesBucket)
+		if rates.Get([]byte(code)) == nil {
+			return repo.RateNotFound
+		}
+		if err := rates.Delete([]byte(code)); err != nil {
+			return save.New(err)
+		}
+		return nil
+	})
+}
+
+var _ repo.RateRepo = (*RateRepo)(nil)
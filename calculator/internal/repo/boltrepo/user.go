@@ -0,0 +1,151 @@
+package boltrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/internal/repo"
+	"github.com/vandi37/ferror"
+	bolt "go.etcd.io/bbolt"
+)
+
+// The usernames bucket keeps the id of every username, so they are unique
+type UserRepo struct {
+	db *bolt.DB
+}
+
+func NewUserRepo(db *bolt.DB) *UserRepo {
+	return &UserRepo{db: db}
+}
+
+// Register implements repo.UserRepo.
+func (r *UserRepo) Register(ctx context.Context, user models.User) (repo.ID, error) {
+	var save = ferror.Save("boltrepo.UserRepo.Register")
+	user.ID = models.NewID()
+	user.CreatedAt = time.Now()
+	if err := r.db.Update(func(tx *bolt.Tx) error {
+		usernames := tx.Bucket(usernamesBucket)
+		if usernames.Get([]byte(user.Username)) != nil {
+			return repo.UsernameTaken
+		}
+		if err := usernames.Put([]byte(user.Username), user.ID[:]); err != nil {
+			return save.New(err)
+		}
+		if err := put(tx.Bucket(usersBucket), user.ID[:], user); err != nil {
+			return save.New(err)
+		}
+		return nil
+	}); err != nil {
+		return repo.ID{}, err
+	}
+	return user.ID, nil
+}
+
+// Get implements repo.UserRepo.
+func (r *UserRepo) Get(ctx context.Context, id repo.ID) (*models.User, error) {
+	var save = ferror.Save("boltrepo.UserRepo.Get")
+	var user models.User
+	if err := r.db.View(func(tx *bolt.Tx) error {
+		if ok, err := get(tx.Bucket(usersBucket), id[:], &user); err != nil {
+			return save.New(err)
+		} else if !ok {
+			return repo.UserNotFound
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetByUsername implements repo.UserRepo.
+func (r *UserRepo) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	var save = ferror.Save("boltrepo.UserRepo.GetByUsername")
+	var user models.User
+	if err := r.db.View(func(tx *bolt.Tx) error {
+		id := tx.Bucket(usernamesBucket).Get([]byte(username))
+		if id == nil {
+			return repo.UserNotFound
+		}
+		if ok, err := get(tx.Bucket(usersBucket), id, &user); err != nil {
+			return save.New(err)
+		} else if !ok {
+			return repo.UserNotFound
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Changes the user in a transaction, the function returns the error of the repo
+func (r *UserRepo) update(save ferror.Save, id repo.ID, change func(tx *bolt.Tx, user *models.User) error) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		users := tx.Bucket(usersBucket)
+		var user models.User
+		if ok, err := get(users, id[:], &user); err != nil {
+			return save.New(err)
+		} else if !ok {
+			return repo.UserNotFound
+		}
+		if err := change(tx, &user); err != nil {
+			return err
+		}
+		if err := put(users, id[:], user); err != nil {
+			return save.New(err)
+		}
+		return nil
+	})
+}
+
+// UpdateUsername implements repo.UserRepo.
+func (r *UserRepo) UpdateUsername(ctx context.Context, id repo.ID, username string) error {
+	var save = ferror.Save("boltrepo.UserRepo.UpdateUsername")
+	return r.update(save, id, func(tx *bolt.Tx, user *models.User) error {
+		usernames := tx.Bucket(usernamesBucket)
+		if usernames.Get([]byte(username)) != nil {
+			return repo.UsernameTaken
+		}
+		if err := usernames.Delete([]byte(user.Username)); err != nil {
+			return save.New(err)
+		}
+		if err := usernames.Put([]byte(username), id[:]); err != nil {
+			return save.New(err)
+		}
+		user.Username = username
+		return nil
+	})
+}
+
+// UpdatePassword implements repo.UserRepo.
+func (r *UserRepo) UpdatePassword(ctx context.Context, id repo.ID, password string) error {
+	return r.update(ferror.Save("boltrepo.UserRepo.UpdatePassword"), id, func(tx *bolt.Tx, user *models.User) error {
+		user.Password = password
+		return nil
+	})
+}
+
+// Delete implements repo.UserRepo.
+func (r *UserRepo) Delete(ctx context.Context, id repo.ID) error {
+	var save = ferror.Save("boltrepo.UserRepo.Delete")
+	return r.db.Update(func(tx *bolt.Tx) error {
+		users := tx.Bucket(usersBucket)
+		var user models.User
+		if ok, err := get(users, id[:], &user); err != nil {
+			return save.New(err)
+		} else if !ok {
+			return repo.UserNotFound
+		}
+		if err := tx.Bucket(usernamesBucket).Delete([]byte(user.Username)); err != nil {
+			return save.New(err)
+		}
+		if err := users.Delete(id[:]); err != nil {
+			return save.New(err)
+		}
+		return nil
+	})
+}
+
+var _ repo.UserRepo = (*UserRepo)(nil)
@@ -0,0 +1,37 @@
+package boltrepo_test
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vandi37/Calculator/internal/models"
+)
+
+func (suite *BoltRepoTestSuite) TestUserRestart() {
+	t := suite.T()
+	ctx := context.Background()
+
+	id, err := suite.userRepo.Register(ctx, models.User{Username: "testuser", Password: "password"})
+	require.NoError(t, err)
+	_, err = suite.definitionRepo.Create(ctx, models.Definition{UserID: id, Name: "tax", Body: "0.13"})
+	require.NoError(t, err)
+	require.NoError(t, suite.rateRepo.Set(ctx, models.Rate{Code: "USD", Rate: 1}))
+
+	suite.reopen()
+	user, err := suite.userRepo.GetByUsername(ctx, "testuser")
+	require.NoError(t, err)
+	assert.Equal(t, id, user.ID)
+	definitions, err := suite.definitionRepo.GetByUser(ctx, id)
+	require.NoError(t, err)
+	require.Len(t, definitions, 1)
+	assert.Equal(t, "tax", definitions[0].Name)
+	rates, err := suite.rateRepo.GetRates(ctx, []string{"USD"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"USD": 1}, rates)
+
+	// The old username is free after the change
+	require.NoError(t, suite.userRepo.UpdateUsername(ctx, id, "renamed"))
+	_, err = suite.userRepo.Register(ctx, models.User{Username: "testuser", Password: "password"})
+	require.NoError(t, err)
+}
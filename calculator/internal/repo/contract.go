@@ -6,41 +6,67 @@ import (
 	pb "github.com/vandi37/Calculator-Models"
 	"github.com/vandi37/Calculator/internal/models"
 	"github.com/vandi37/Calculator/pkg/parsing/tree"
-	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-type IntoCollection interface {
-	Collection(name string, opts ...*options.CollectionOptions) *mongo.Collection
-}
-
 type Callback interface {
 	SendError(context.Context, error)
 	SendResult(context.Context, []pb.Task)
 }
 
 type UserRepo interface {
-	Register(ctx context.Context, user models.User) (primitive.ObjectID, error)
-	Get(ctx context.Context, id primitive.ObjectID) (*models.User, error)
+	Register(ctx context.Context, user models.User) (ID, error)
+	Get(ctx context.Context, id ID) (*models.User, error)
 	GetByUsername(ctx context.Context, username string) (*models.User, error)
-	UpdateUsername(ctx context.Context, id primitive.ObjectID, username string) error
-	UpdatePassword(ctx context.Context, id primitive.ObjectID, password string) error
-	Delete(ctx context.Context, id primitive.ObjectID) error
-	GetCollection() *mongo.Collection
+	UpdateUsername(ctx context.Context, id ID, username string) error
+	UpdatePassword(ctx context.Context, id ID, password string) error
+	Delete(ctx context.Context, id ID) error
+}
+
+type DefinitionRepo interface {
+	Create(ctx context.Context, definition models.Definition) (ID, error)
+	Get(ctx context.Context, id ID) (*models.Definition, error)
+	GetByUser(ctx context.Context, userID ID) ([]models.Definition, error)
+	Update(ctx context.Context, definition models.Definition) error
+	Delete(ctx context.Context, id ID) error
+	DeleteByUser(ctx context.Context, userID ID) error
+}
+
+// Source of the exchange rates for the calculation
+type RateProvider interface {
+	// Rates of the codes, the codes without a rate are listed in the error
+	GetRates(ctx context.Context, codes []string) (map[string]float64, error)
+}
+
+// Rates are edited by the admin, the code is the id
+type RateRepo interface {
+	RateProvider
+	Set(ctx context.Context, rate models.Rate) error
+	GetAll(ctx context.Context) ([]models.Rate, error)
+	Delete(ctx context.Context, code string) error
 }
 
 type ExpressionRepo interface {
 	SetCallback(ctx context.Context, callback Callback)
-	Create(ctx context.Context, expression models.Expression, ast tree.Ast) (primitive.ObjectID, error)
-	Get(ctx context.Context, id primitive.ObjectID) (*models.Expression, error)
-	GetByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Expression, error)
-	GetNode(ctx context.Context, id primitive.ObjectID) (*models.Node, error)
+	Create(ctx context.Context, expression models.Expression, ast tree.Ast) (ID, error)
+	Get(ctx context.Context, id ID) (*models.Expression, error)
+	GetByUser(ctx context.Context, userID ID) ([]models.Expression, error)
+	GetNode(ctx context.Context, id ID) (*models.Node, error)
+	// All nodes of the tree under the node, the root is the first
+	GetNodes(ctx context.Context, id ID) ([]models.Node, error)
 	GetFitNodes(ctx context.Context) ([]pb.Task, error)
-	SetToError(ctx context.Context, id primitive.ObjectID, err string) error
-	SetToNum(ctx context.Context, nodeId primitive.ObjectID, result float64) error
-	Delete(ctx context.Context, id primitive.ObjectID) error
-	DeleteByUser(ctx context.Context, userID primitive.ObjectID) error
-	GetCollection() *mongo.Collection
-	GetNodeCollection() *mongo.Collection
+	// The code is machine readable, err is the message
+	SetToError(ctx context.Context, id ID, code, err string) error
+	// Adds the warning code to the expression of the node, it must be called before the node gets its result
+	AddWarning(ctx context.Context, nodeId ID, code string) error
+	SetToNum(ctx context.Context, nodeId ID, result float64) error
+	// The same as SetToNum, but the exact decimal ("0.3") is saved too
+	SetToDecimal(ctx context.Context, nodeId ID, result float64, decimal string) error
+	// The same as SetToNum, but the exact fraction (p/q) is saved too
+	SetToRational(ctx context.Context, nodeId ID, result float64, rational string) error
+	// The same as SetToNum, the real part is saved as the number
+	SetToComplex(ctx context.Context, nodeId ID, result models.Complex) error
+	// The same as SetToNum, the result is in the SI base units of the dimension ("m/s")
+	SetToUnit(ctx context.Context, nodeId ID, result float64, unit string) error
+	Delete(ctx context.Context, id ID) error
+	DeleteByUser(ctx context.Context, userID ID) error
 }
@@ -0,0 +1,135 @@
+package definitionrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/internal/repo"
+	"github.com/vandi37/Calculator/internal/repo/mongoid"
+	"github.com/vandi37/ferror"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	collectionName = "definitions"
+)
+
+type Repo struct {
+	collection *mongo.Collection
+}
+
+// The collection of the definitions, it is used by the tests
+func (r *Repo) GetCollection() *mongo.Collection {
+	return r.collection
+}
+
+// Names are unique for every user
+func (r *Repo) nameExists(ctx context.Context, userID repo.ID, name string, except repo.ID) (bool, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"user_id": userID, "name": name, "_id": bson.M{"$ne": except}})
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// Create implements repo.DefinitionRepo.
+func (r *Repo) Create(ctx context.Context, definition models.Definition) (repo.ID, error) {
+	var save = ferror.Save("definitionrepo.Repo.Create")
+	if exists, err := r.nameExists(ctx, definition.UserID, definition.Name, models.NilID); err != nil {
+		return repo.ID{}, save.New(err)
+	} else if exists {
+		return repo.ID{}, repo.DefinitionNameTaken
+	}
+	definition.ID = models.NilID
+	definition.CreatedAt = time.Now()
+	if res, err := r.collection.InsertOne(ctx, definition); err != nil {
+		return repo.ID{}, save.New(err)
+	} else {
+		return repo.ID(res.InsertedID.(primitive.ObjectID)), nil
+	}
+}
+
+// Get implements repo.DefinitionRepo.
+func (r *Repo) Get(ctx context.Context, id repo.ID) (*models.Definition, error) {
+	var save = ferror.Save("definitionrepo.Repo.Get")
+	var definition models.Definition
+	if err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&definition); err == mongo.ErrNoDocuments {
+		return nil, repo.DefinitionNotFound
+	} else if err != nil {
+		return nil, save.New(err)
+	}
+	return &definition, nil
+}
+
+// GetByUser implements repo.DefinitionRepo.
+func (r *Repo) GetByUser(ctx context.Context, userID repo.ID) ([]models.Definition, error) {
+	var save = ferror.Save("definitionrepo.Repo.GetByUser")
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return nil, save.New(err)
+	}
+	defer cursor.Close(ctx)
+	var definitions []models.Definition
+	if err := cursor.All(ctx, &definitions); err != nil {
+		return nil, save.New(err)
+	}
+	return definitions, nil
+}
+
+// Update implements repo.DefinitionRepo.
+func (r *Repo) Update(ctx context.Context, definition models.Definition) error {
+	var save = ferror.Save("definitionrepo.Repo.Update")
+	if exists, err := r.nameExists(ctx, definition.UserID, definition.Name, definition.ID); err != nil {
+		return save.New(err)
+	} else if exists {
+		return repo.DefinitionNameTaken
+	}
+	if res, err := r.collection.UpdateByID(
+		ctx,
+		definition.ID,
+		bson.M{"$set": bson.M{"name": definition.Name, "params": definition.Params, "body": definition.Body}},
+	); err != nil {
+		return save.New(err)
+	} else if res.MatchedCount == 0 {
+		return repo.DefinitionNotFound
+	}
+	return nil
+}
+
+// Delete implements repo.DefinitionRepo.
+func (r *Repo) Delete(ctx context.Context, id repo.ID) error {
+	var save = ferror.Save("definitionrepo.Repo.Delete")
+	if res, err := r.collection.DeleteOne(ctx, bson.M{"_id": id}); err != nil {
+		return save.New(err)
+	} else if res.DeletedCount == 0 {
+		return repo.DefinitionNotFound
+	}
+	return nil
+}
+
+// DeleteByUser implements repo.DefinitionRepo.
+func (r *Repo) DeleteByUser(ctx context.Context, userID repo.ID) error {
+	var save = ferror.Save("definitionrepo.Repo.DeleteByUser")
+	if _, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID}); err != nil {
+		return save.New(err)
+	}
+	return nil
+}
+
+// The database with the collections, it is *mongo.Database
+type IntoCollection interface {
+	Collection(name string, opts ...*options.CollectionOptions) *mongo.Collection
+}
+
+func New(db IntoCollection) *Repo {
+	return &Repo{
+		collection: db.Collection(collectionName, mongoid.Collection()),
+	}
+}
+
+var _ repo.DefinitionRepo = (*Repo)(nil)
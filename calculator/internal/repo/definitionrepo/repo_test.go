@@ -0,0 +1,282 @@
+package definitionrepo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/internal/repo"
+	"github.com/vandi37/Calculator/internal/repo/definitionrepo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type DefinitionRepoTestSuite struct {
+	suite.Suite
+	mongoC         testcontainers.Container
+	client         *mongo.Client
+	definitionRepo *definitionrepo.Repo
+	testUserID     repo.ID
+	testUserID2    repo.ID
+	testDefID      repo.ID
+	ctx            context.Context
+}
+
+func (suite *DefinitionRepoTestSuite) SetupSuite() {
+	suite.ctx = context.Background()
+
+	req := testcontainers.ContainerRequest{
+		Image:        "mongo:latest",
+		ExposedPorts: []string{"27017/tcp"},
+		WaitingFor:   wait.ForLog("Waiting for connections").WithStartupTimeout(20 * time.Second),
+	}
+
+	mongoC, err := testcontainers.GenericContainer(suite.ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: req,
+		Started:          true,
+	})
+	require.NoError(suite.T(), err)
+	suite.mongoC = mongoC
+
+	endpoint, err := mongoC.Endpoint(suite.ctx, "")
+	require.NoError(suite.T(), err)
+
+	client, err := mongo.Connect(suite.ctx, options.Client().ApplyURI("mongodb://"+endpoint))
+	require.NoError(suite.T(), err)
+	suite.client = client
+
+	db := client.Database("test_db")
+	suite.definitionRepo = definitionrepo.New(db)
+
+	suite.testUserID = models.NewID()
+	suite.testUserID2 = models.NewID()
+	suite.testDefID = models.NewID()
+
+	testDefinitions := []models.Definition{
+		{
+			ID:        suite.testDefID,
+			UserID:    suite.testUserID,
+			Name:      "rate",
+			Body:      "0.2",
+			CreatedAt: time.Now(),
+		},
+		{
+			ID:        models.NewID(),
+			UserID:    suite.testUserID,
+			Name:      "f",
+			Params:    []string{"x"},
+			Body:      "x * rate",
+			CreatedAt: time.Now(),
+		},
+		{
+			ID:        models.NewID(),
+			UserID:    suite.testUserID2,
+			Name:      "rate",
+			Body:      "0.5",
+			CreatedAt: time.Now(),
+		},
+	}
+
+	var docs []interface{}
+	for _, d := range testDefinitions {
+		docs = append(docs, d)
+	}
+
+	_, err = suite.definitionRepo.GetCollection().InsertMany(suite.ctx, docs)
+	require.NoError(suite.T(), err)
+}
+
+func (suite *DefinitionRepoTestSuite) TearDownSuite() {
+	_, err := suite.definitionRepo.GetCollection().DeleteMany(suite.ctx, bson.M{})
+	require.NoError(suite.T(), err)
+
+	err = suite.client.Disconnect(suite.ctx)
+	require.NoError(suite.T(), err)
+
+	err = suite.mongoC.Terminate(suite.ctx)
+	require.NoError(suite.T(), err)
+}
+
+func TestDefinitionRepoTestSuite(t *testing.T) {
+	suite.Run(t, new(DefinitionRepoTestSuite))
+}
+
+func (suite *DefinitionRepoTestSuite) TestCreate() {
+	t := suite.T()
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		definition  models.Definition
+		wantErr     bool
+		expectedErr error
+	}{
+		{
+			name: "successful creation",
+			definition: models.Definition{
+				UserID: suite.testUserID,
+				Name:   "tax",
+				Body:   "0.13",
+			},
+			wantErr: false,
+		},
+		{
+			name: "same name for another user",
+			definition: models.Definition{
+				UserID: suite.testUserID2,
+				Name:   "f",
+				Params: []string{"y"},
+				Body:   "y + 1",
+			},
+			wantErr: false,
+		},
+		{
+			name: "duplicate name",
+			definition: models.Definition{
+				UserID: suite.testUserID,
+				Name:   "rate",
+				Body:   "0.3",
+			},
+			wantErr:     true,
+			expectedErr: repo.DefinitionNameTaken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := suite.definitionRepo.Create(ctx, tt.definition)
+
+			if tt.wantErr {
+				require.Error(t, err)
+				if tt.expectedErr != nil {
+					assert.ErrorIs(t, err, tt.expectedErr)
+				}
+				assert.Equal(t, repo.ID{}, id)
+			} else {
+				require.NoError(t, err)
+				assert.NotEqual(t, repo.ID{}, id)
+
+				definition, err := suite.definitionRepo.Get(ctx, id)
+				require.NoError(t, err)
+				assert.Equal(t, tt.definition.Name, definition.Name)
+				assert.Equal(t, tt.definition.Params, definition.Params)
+				assert.Equal(t, tt.definition.Body, definition.Body)
+				assert.False(t, definition.CreatedAt.IsZero())
+			}
+		})
+	}
+}
+
+func (suite *DefinitionRepoTestSuite) TestGetByUser() {
+	t := suite.T()
+	ctx := context.Background()
+
+	definitions, err := suite.definitionRepo.GetByUser(ctx, suite.testUserID2)
+	require.NoError(t, err)
+	for _, definition := range definitions {
+		assert.Equal(t, suite.testUserID2, definition.UserID)
+	}
+
+	definitions, err = suite.definitionRepo.GetByUser(ctx, models.NewID())
+	require.NoError(t, err)
+	assert.Empty(t, definitions)
+}
+
+func (suite *DefinitionRepoTestSuite) TestUpdate() {
+	t := suite.T()
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		definition  models.Definition
+		wantErr     bool
+		expectedErr error
+	}{
+		{
+			name: "successful update",
+			definition: models.Definition{
+				ID:     suite.testDefID,
+				UserID: suite.testUserID,
+				Name:   "rate",
+				Body:   "0.25",
+			},
+			wantErr: false,
+		},
+		{
+			name: "name of another definition",
+			definition: models.Definition{
+				ID:     suite.testDefID,
+				UserID: suite.testUserID,
+				Name:   "f",
+				Body:   "1",
+			},
+			wantErr:     true,
+			expectedErr: repo.DefinitionNameTaken,
+		},
+		{
+			name: "non-existent definition",
+			definition: models.Definition{
+				ID:     models.NewID(),
+				UserID: suite.testUserID,
+				Name:   "other",
+				Body:   "1",
+			},
+			wantErr:     true,
+			expectedErr: repo.DefinitionNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := suite.definitionRepo.Update(ctx, tt.definition)
+
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+
+				definition, err := suite.definitionRepo.Get(ctx, tt.definition.ID)
+				require.NoError(t, err)
+				assert.Equal(t, tt.definition.Body, definition.Body)
+			}
+		})
+	}
+}
+
+func (suite *DefinitionRepoTestSuite) TestDelete() {
+	t := suite.T()
+	ctx := context.Background()
+
+	id, err := suite.definitionRepo.Create(ctx, models.Definition{UserID: suite.testUserID, Name: "temp", Body: "1"})
+	require.NoError(t, err)
+
+	require.NoError(t, suite.definitionRepo.Delete(ctx, id))
+	_, err = suite.definitionRepo.Get(ctx, id)
+	assert.ErrorIs(t, err, repo.DefinitionNotFound)
+
+	assert.ErrorIs(t, suite.definitionRepo.Delete(ctx, id), repo.DefinitionNotFound)
+}
+
+func (suite *DefinitionRepoTestSuite) TestDeleteByUser() {
+	t := suite.T()
+	ctx := context.Background()
+
+	userID := models.NewID()
+	for _, name := range []string{"a", "b"} {
+		_, err := suite.definitionRepo.Create(ctx, models.Definition{UserID: userID, Name: name, Body: "1"})
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, suite.definitionRepo.DeleteByUser(ctx, userID))
+	definitions, err := suite.definitionRepo.GetByUser(ctx, userID)
+	require.NoError(t, err)
+	assert.Empty(t, definitions)
+}
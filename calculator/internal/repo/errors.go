@@ -5,10 +5,13 @@ import (
 )
 
 var (
-	UsernameTaken      = errors.New("username already taken")
-	UserNotFound       = errors.New("user not found")
-	NodeNotFound       = errors.New("node not found")
-	ExpressionNotFound = errors.New("expression not found")
-	InvalidExpression  = errors.New("invalid expression")
-	InvalidNode        = errors.New("invalid node")
+	UsernameTaken       = errors.New("username already taken")
+	UserNotFound        = errors.New("user not found")
+	NodeNotFound        = errors.New("node not found")
+	ExpressionNotFound  = errors.New("expression not found")
+	InvalidExpression   = errors.New("invalid expression")
+	InvalidNode         = errors.New("invalid node")
+	DefinitionNotFound  = errors.New("definition not found")
+	DefinitionNameTaken = errors.New("definition name already taken")
+	RateNotFound        = errors.New("rate not found")
 )
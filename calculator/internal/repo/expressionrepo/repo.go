@@ -3,18 +3,22 @@ package expressionrepo
 import (
 	"context"
 	"errors"
+	"slices"
 	"sync"
 	"time"
 
 	pb "github.com/vandi37/Calculator-Models"
 	"github.com/vandi37/Calculator/internal/models"
 	"github.com/vandi37/Calculator/internal/repo"
+	"github.com/vandi37/Calculator/internal/repo/mongoid"
+	"github.com/vandi37/Calculator/internal/repo/nodetree"
 	"github.com/vandi37/Calculator/internal/status"
 	"github.com/vandi37/Calculator/pkg/parsing/tree"
 	"github.com/vandi37/ferror"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 const (
@@ -30,12 +34,12 @@ type Repo struct {
 	mu             sync.Mutex
 }
 
-// GetCollection implements repo.ExpressionRepo.
+// The collection of the expressions, it is used by the tests
 func (r *Repo) GetCollection() *mongo.Collection {
 	return r.collection
 }
 
-// GetNodeCollection implements repo.ExpressionRepo.
+// The collection of the nodes, it is used by the tests
 func (r *Repo) GetNodeCollection() *mongo.Collection {
 	return r.nodeCollection
 }
@@ -46,32 +50,64 @@ func (r *Repo) SetCallback(ctx context.Context, callback repo.Callback) {
 	go r.DoCallback()
 }
 
-// SetToError implements repo.ExpressionRepo.
-func (r *Repo) SetToError(ctx context.Context, id primitive.ObjectID, errVal string) error {
-	var save = ferror.Save("expressionrepo.Repo.SetToError")
+// Runs the function in a transaction, so the nodes and the expression are changed together.
+// The function can be called again if the transaction conflicts with another one
+func (r *Repo) transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	session, err := r.collection.Database().Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+	_, err = session.WithTransaction(ctx, func(ctx mongo.SessionContext) (any, error) {
+		return nil, fn(ctx)
+	})
+	return err
+}
+
+// Goes up from the node to the root node of the expression
+func (r *Repo) root(ctx context.Context, id repo.ID) (repo.ID, error) {
 	for {
 		var node models.Node
-		if err := r.nodeCollection.FindOne(ctx, bson.M{"tree.left": id}).Decode(&node); err == mongo.ErrNoDocuments {
+		filter := bson.M{"$or": []bson.M{{"tree.left": id}, {"tree.right": id}, {"tree.cond": id}, {"tree.items": id}}}
+		if err := r.nodeCollection.FindOne(ctx, filter).Decode(&node); err == mongo.ErrNoDocuments {
+			return id, nil
 		} else if err != nil {
-			return save.New(err)
-		} else {
-			id = node.ID
-			continue
+			return models.NilID, err
 		}
+		id = node.ID
+	}
+}
 
-		if err := r.nodeCollection.FindOne(ctx, bson.M{"tree.right": id}).Decode(&node); err == mongo.ErrNoDocuments {
-			return r.setToError(ctx, save, id, errVal)
-		} else if err != nil {
+// SetToError implements repo.ExpressionRepo.
+func (r *Repo) SetToError(ctx context.Context, id repo.ID, code, errVal string) error {
+	var save = ferror.Save("expressionrepo.Repo.SetToError")
+	return r.transaction(ctx, func(ctx context.Context) error {
+		id, err := r.root(ctx, id)
+		if err != nil {
 			return save.New(err)
-		} else {
-			id = node.ID
 		}
+		return r.setToError(ctx, save, id, code, errVal)
+	})
+}
+
+// AddWarning implements repo.ExpressionRepo.
+func (r *Repo) AddWarning(ctx context.Context, nodeId repo.ID, code string) error {
+	var save = ferror.Save("expressionrepo.Repo.AddWarning")
+	id, err := r.root(ctx, nodeId)
+	if err != nil {
+		return save.New(err)
 	}
+	if res, err := r.collection.UpdateMany(ctx, bson.M{"node_id": id}, bson.M{"$addToSet": bson.M{"warnings": code}}); err != nil {
+		return save.New(err)
+	} else if res.MatchedCount == 0 {
+		return repo.ExpressionNotFound
+	}
+	return nil
 }
 
-func (r *Repo) setToError(ctx context.Context, save ferror.Save, id primitive.ObjectID, errVal string) error {
+func (r *Repo) setToError(ctx context.Context, save ferror.Save, id repo.ID, code, errVal string) error {
 	update := bson.M{
-		"$set":   bson.M{"status": status.Error, "error": errVal},
+		"$set":   bson.M{"status": status.Error, "error": errVal, "error_code": code},
 		"$unset": bson.M{"result": 1, "node_id": 1},
 	}
 	if res, err := r.collection.UpdateMany(ctx, bson.M{"node_id": id}, update); err != nil {
@@ -86,12 +122,53 @@ func (r *Repo) setToError(ctx context.Context, save ferror.Save, id primitive.Ob
 }
 
 // SetToNum implements repo.ExpressionRepo.
-func (r *Repo) SetToNum(ctx context.Context, nodeId primitive.ObjectID, result float64) error {
-	var save = ferror.Save("expressionrepo.Repo.SetToNum")
-	if res, err := r.collection.UpdateMany(ctx, bson.M{"node_id": nodeId}, bson.M{"$set": bson.M{
-		"status": status.Finished,
-		"result": result,
-	}, "$unset": bson.M{"node_id": 1}}); err != nil {
+func (r *Repo) SetToNum(ctx context.Context, nodeId repo.ID, result float64) error {
+	return r.complete(ctx, ferror.Save("expressionrepo.Repo.SetToNum"), nodeId, result, nil)
+}
+
+// SetToDecimal implements repo.ExpressionRepo.
+func (r *Repo) SetToDecimal(ctx context.Context, nodeId repo.ID, result float64, decimal string) error {
+	return r.complete(ctx, ferror.Save("expressionrepo.Repo.SetToDecimal"), nodeId, result, bson.M{"decimal": decimal})
+}
+
+// SetToRational implements repo.ExpressionRepo.
+func (r *Repo) SetToRational(ctx context.Context, nodeId repo.ID, result float64, rational string) error {
+	return r.complete(ctx, ferror.Save("expressionrepo.Repo.SetToRational"), nodeId, result, bson.M{"rational": rational})
+}
+
+// SetToUnit implements repo.ExpressionRepo.
+func (r *Repo) SetToUnit(ctx context.Context, nodeId repo.ID, result float64, unit string) error {
+	return r.complete(ctx, ferror.Save("expressionrepo.Repo.SetToUnit"), nodeId, result, bson.M{"unit": unit})
+}
+
+// SetToComplex implements repo.ExpressionRepo.
+func (r *Repo) SetToComplex(ctx context.Context, nodeId repo.ID, result models.Complex) error {
+	return r.complete(ctx, ferror.Save("expressionrepo.Repo.SetToComplex"), nodeId, result.Re, bson.M{"complex": result})
+}
+
+// Saves the result in a transaction, the new tasks are looked for after the commit
+func (r *Repo) complete(ctx context.Context, save ferror.Save, nodeId repo.ID, result float64, exact bson.M) error {
+	if err := r.transaction(ctx, func(ctx context.Context) error {
+		return r.setToNum(ctx, save, nodeId, result, exact)
+	}); err != nil {
+		return err
+	}
+	go r.DoCallback()
+	return nil
+}
+
+// Exact is set both to the expression and the node, it is nil in the float precision
+func (r *Repo) setToNum(ctx context.Context, save ferror.Save, nodeId repo.ID, result float64, exact bson.M) error {
+	expressionSet := bson.M{"status": status.Finished, "result": result}
+	nodeSet := bson.M{"type": models.Number, "number": result}
+	for key, value := range exact {
+		expressionSet[key] = value
+		nodeSet[key] = value
+	}
+	if res, err := r.collection.UpdateMany(ctx, bson.M{"node_id": nodeId}, bson.M{
+		"$set":   expressionSet,
+		"$unset": bson.M{"node_id": 1},
+	}); err != nil {
 		return save.New(err)
 	} else if res.MatchedCount != 0 && res.ModifiedCount != 0 {
 		if err := r.deleteNodes(ctx, nodeId); err != nil {
@@ -101,7 +178,7 @@ func (r *Repo) SetToNum(ctx context.Context, nodeId primitive.ObjectID, result f
 	}
 
 	update := bson.M{
-		"$set":   bson.M{"type": models.Number, "number": result},
+		"$set":   nodeSet,
 		"$unset": bson.M{"tree": 1},
 	}
 
@@ -110,85 +187,50 @@ func (r *Repo) SetToNum(ctx context.Context, nodeId primitive.ObjectID, result f
 	} else if res.MatchedCount == 0 || res.ModifiedCount == 0 {
 		return repo.NodeNotFound
 	}
-	go r.DoCallback()
 	return nil
 }
 
-func (r *Repo) createNodes(ctx context.Context, expr tree.ExpressionType, isFirst bool) (*float64, primitive.ObjectID, error) {
-	if expr == nil {
-		return nil, primitive.NilObjectID, repo.InvalidExpression
-	}
-
-	switch v := expr.(type) {
-	case tree.Num:
-		var num = float64(v)
-		if isFirst {
-			return &num, primitive.NilObjectID, nil
-		}
-		node := models.Node{
-			Type:   models.Number,
-			Number: &num,
-		}
-		if id, err := r.nodeCollection.InsertOne(ctx, node); err != nil {
-			return nil, primitive.NilObjectID, err
-		} else {
-			return nil, id.InsertedID.(primitive.ObjectID), nil
-		}
-	case tree.Expression:
-		_, leftId, err := r.createNodes(ctx, v.Left, false)
-		if err != nil {
-			return nil, primitive.NilObjectID, err
-		}
-		_, rightId, err := r.createNodes(ctx, v.Right, false)
-		if err != nil {
-			return nil, primitive.NilObjectID, err
-		}
-		node := models.Node{
-			Type: models.Operation,
-			Tree: &models.TreeNode{
-				Operator: pb.Operation(v.Operation),
-				Left:     leftId,
-				Right:    rightId,
-			},
-		}
-		if id, err := r.nodeCollection.InsertOne(ctx, node); err != nil {
-			return nil, primitive.NilObjectID, err
-		} else {
-			return nil, id.InsertedID.(primitive.ObjectID), nil
-		}
-	default:
-		return nil, primitive.NilObjectID, repo.InvalidExpression
+// Create implements repo.ExpressionRepo.
+func (r *Repo) Create(ctx context.Context, expression models.Expression, ast tree.Ast) (repo.ID, error) {
+	var save = ferror.Save("expressionrepo.Repo.Create")
+	var id repo.ID
+	// A failure in the middle leaves no nodes without the expression
+	if err := r.transaction(ctx, func(ctx context.Context) error {
+		var err error
+		id, err = r.create(ctx, expression, ast)
+		return err
+	}); err != nil {
+		return repo.ID{}, save.New(err)
 	}
+	go r.DoCallback()
+	return id, nil
 }
 
-// Create implements repo.ExpressionRepo.
-func (r *Repo) Create(ctx context.Context, expression models.Expression, ast tree.Ast) (primitive.ObjectID, error) {
-	var save = ferror.Save("expressionrepo.Repo.Create")
-	num, id, err := r.createNodes(ctx, ast.Expression, true)
+func (r *Repo) create(ctx context.Context, expression models.Expression, ast tree.Ast) (repo.ID, error) {
+	num, nodes, err := nodetree.Build(ast.Expression)
 	if err != nil {
-		return primitive.NilObjectID, save.New(err)
-	}
-	if num != nil {
-		expression.NodeID = primitive.NilObjectID
-		expression.Result = num
-		expression.Error = ""
-		expression.Status = status.Finished
-	} else {
-		expression.NodeID = id
-		expression.Error = ""
-		expression.Result = nil
-		expression.Status = status.Pending
+		return models.NilID, err
+	}
+	var root repo.ID
+	if num == nil {
+		root = nodes[len(nodes)-1].ID
+		documents := make([]any, len(nodes))
+		for i, node := range nodes {
+			documents[i] = node
+		}
+		if _, err := r.nodeCollection.InsertMany(ctx, documents); err != nil {
+			return models.NilID, err
+		}
 	}
-	expression.CreatedAt = time.Now()
+	nodetree.Prepare(&expression, num, root)
 	if res, err := r.collection.InsertOne(ctx, expression); err != nil {
-		return primitive.NilObjectID, save.New(err)
+		return models.NilID, err
 	} else {
-		go r.DoCallback()
-		return res.InsertedID.(primitive.ObjectID), nil
+		return repo.ID(res.InsertedID.(primitive.ObjectID)), nil
 	}
 }
 
-func (r *Repo) deleteNodes(ctx context.Context, nodeID primitive.ObjectID) error {
+func (r *Repo) deleteNodes(ctx context.Context, nodeID repo.ID) error {
 	var save = ferror.Save("expressionrepo.Repo.deleteNodes")
 	var node models.Node
 	if err := r.nodeCollection.FindOneAndDelete(ctx, bson.M{"_id": nodeID}).Decode(&node); err == mongo.ErrNoDocuments {
@@ -197,9 +239,25 @@ func (r *Repo) deleteNodes(ctx context.Context, nodeID primitive.ObjectID) error
 		return save.New(err)
 	}
 	if node.Tree != nil {
+		if len(node.Tree.Items) > 0 {
+			for _, item := range node.Tree.Items {
+				if err := r.deleteNodes(ctx, item); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if node.Tree.Cond != models.NilID {
+			if err := r.deleteNodes(ctx, node.Tree.Cond); err != nil {
+				return err
+			}
+		}
 		if err := r.deleteNodes(ctx, node.Tree.Left); err != nil {
 			return err
 		}
+		if node.Tree.IsUnary() {
+			return nil
+		}
 		if err := r.deleteNodes(ctx, node.Tree.Right); err != nil {
 			return err
 		}
@@ -208,55 +266,60 @@ func (r *Repo) deleteNodes(ctx context.Context, nodeID primitive.ObjectID) error
 }
 
 // Delete implements repo.ExpressionRepo.
-func (r *Repo) Delete(ctx context.Context, id primitive.ObjectID) error {
+func (r *Repo) Delete(ctx context.Context, id repo.ID) error {
 	var save = ferror.Save("expressionrepo.Repo.Delete")
-	var expr models.Expression
-	if err := r.collection.FindOneAndDelete(ctx, bson.M{"_id": id}).Decode(&expr); err == mongo.ErrNoDocuments {
-		return repo.ExpressionNotFound
-	} else if err != nil {
-		return save.New(err)
-	}
-	if expr.NodeID != primitive.NilObjectID {
-		if err := r.deleteNodes(ctx, expr.NodeID); err != nil {
-			return err
+	return r.transaction(ctx, func(ctx context.Context) error {
+		var expr models.Expression
+		if err := r.collection.FindOneAndDelete(ctx, bson.M{"_id": id}).Decode(&expr); err == mongo.ErrNoDocuments {
+			return repo.ExpressionNotFound
+		} else if err != nil {
+			return save.New(err)
 		}
-	}
-	return nil
+		if expr.NodeID != models.NilID {
+			if err := r.deleteNodes(ctx, expr.NodeID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 // DeleteByUser implements repo.ExpressionRepo.
-func (r *Repo) DeleteByUser(ctx context.Context, userID primitive.ObjectID) error {
+func (r *Repo) DeleteByUser(ctx context.Context, userID repo.ID) error {
 	var save = ferror.Save("expressionrepo.Repo.DeleteByUser")
-	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID})
-	if err != nil {
-		return save.New(err)
-	}
-	defer cursor.Close(ctx)
+	// Nothing is deleted if one of the expressions fails
+	return r.transaction(ctx, func(ctx context.Context) error {
+		cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID})
+		if err != nil {
+			return save.New(err)
+		}
+		defer cursor.Close(ctx)
 
-	var expressions []models.Expression
-	if err := cursor.All(ctx, &expressions); err != nil {
-		return save.New(err)
-	}
-	multiErrors := []error{}
+		var expressions []models.Expression
+		if err := cursor.All(ctx, &expressions); err != nil {
+			return save.New(err)
+		}
+		multiErrors := []error{}
 
-	for _, expr := range expressions {
-		if expr.NodeID != primitive.NilObjectID {
-			if err := r.deleteNodes(ctx, expr.NodeID); err != nil {
+		for _, expr := range expressions {
+			if expr.NodeID != models.NilID {
+				if err := r.deleteNodes(ctx, expr.NodeID); err != nil {
+					multiErrors = append(multiErrors, err)
+				}
+			}
+			if _, err := r.collection.DeleteOne(ctx, bson.M{"_id": expr.ID}); err != nil {
 				multiErrors = append(multiErrors, err)
 			}
 		}
-		if _, err := r.collection.DeleteOne(ctx, bson.M{"_id": expr.ID}); err != nil {
-			multiErrors = append(multiErrors, err)
+		if len(multiErrors) > 0 {
+			return save.New(errors.Join(multiErrors...))
 		}
-	}
-	if len(multiErrors) > 0 {
-		return save.New(errors.Join(multiErrors...))
-	}
-	return nil
+		return nil
+	})
 }
 
 // Get implements repo.ExpressionRepo.
-func (r *Repo) Get(ctx context.Context, id primitive.ObjectID) (*models.Expression, error) {
+func (r *Repo) Get(ctx context.Context, id repo.ID) (*models.Expression, error) {
 	var save = ferror.Save("expressionrepo.Repo.Get")
 	var expr models.Expression
 	if err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&expr); err == mongo.ErrNoDocuments {
@@ -268,7 +331,7 @@ func (r *Repo) Get(ctx context.Context, id primitive.ObjectID) (*models.Expressi
 }
 
 // GetByUser implements repo.ExpressionRepo.
-func (r *Repo) GetByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Expression, error) {
+func (r *Repo) GetByUser(ctx context.Context, userID repo.ID) ([]models.Expression, error) {
 	var save = ferror.Save("expressionrepo.Repo.GetByUser")
 	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID})
 	if err != nil {
@@ -283,7 +346,7 @@ func (r *Repo) GetByUser(ctx context.Context, userID primitive.ObjectID) ([]mode
 }
 
 // GetNode implements repo.ExpressionRepo.
-func (r *Repo) GetNode(ctx context.Context, id primitive.ObjectID) (*models.Node, error) {
+func (r *Repo) GetNode(ctx context.Context, id repo.ID) (*models.Node, error) {
 	var save = ferror.Save("expressionrepo.Repo.GetNode")
 	var node models.Node
 	if err := r.nodeCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&node); err == mongo.ErrNoDocuments {
@@ -294,10 +357,87 @@ func (r *Repo) GetNode(ctx context.Context, id primitive.ObjectID) (*models.Node
 	return &node, nil
 }
 
-func New(db repo.IntoCollection, d time.Duration) *Repo {
+// GetNodes implements repo.ExpressionRepo.
+func (r *Repo) GetNodes(ctx context.Context, id repo.ID) ([]models.Node, error) {
+	var save = ferror.Save("expressionrepo.Repo.GetNodes")
+	nodes, err := r.walk(ctx, []repo.ID{id})
+	if err != nil {
+		return nil, save.New(err)
+	}
+	if len(nodes) == 0 {
+		return nil, repo.NodeNotFound
+	}
+	return nodes, nil
+}
+
+// Finds the nodes of the trees under the roots, one query for every level
+func (r *Repo) walk(ctx context.Context, roots []repo.ID) ([]models.Node, error) {
+	nodes := []models.Node{}
+	for level := roots; len(level) > 0; {
+		cursor, err := r.nodeCollection.Find(ctx, bson.M{"_id": bson.M{"$in": level}})
+		if err != nil {
+			return nil, err
+		}
+		var found []models.Node
+		if err := cursor.All(ctx, &found); err != nil {
+			return nil, err
+		}
+		level = nil
+		for _, node := range found {
+			if node.Tree != nil {
+				level = append(level, node.Tree.Children()...)
+			}
+		}
+		nodes = append(nodes, found...)
+	}
+	return nodes, nil
+}
+
+// Deletes the nodes that aren't reachable from any pending expression,
+// they are left by the crashes before the transactions. It is called at the start
+func (r *Repo) Sweep(ctx context.Context) (int64, error) {
+	var save = ferror.Save("expressionrepo.Repo.Sweep")
+	var deleted int64
+	err := r.transaction(ctx, func(ctx context.Context) error {
+		var roots []repo.ID
+		cursor, err := r.collection.Find(ctx, bson.M{"status": status.Pending, "node_id": bson.M{"$exists": true}})
+		if err != nil {
+			return save.New(err)
+		}
+		var expressions []models.Expression
+		if err := cursor.All(ctx, &expressions); err != nil {
+			return save.New(err)
+		}
+		for _, expr := range expressions {
+			roots = append(roots, expr.NodeID)
+		}
+		nodes, err := r.walk(ctx, roots)
+		if err != nil {
+			return save.New(err)
+		}
+		reachable := make(bson.A, len(nodes))
+		for i, node := range nodes {
+			reachable[i] = node.ID
+		}
+		res, err := r.nodeCollection.DeleteMany(ctx, bson.M{"_id": bson.M{"$nin": reachable}})
+		if err != nil {
+			return save.New(err)
+		}
+		deleted = res.DeletedCount
+		return nil
+	})
+	return deleted, err
+}
+
+// The database with the collections, it is *mongo.Database
+type IntoCollection interface {
+	Collection(name string, opts ...*options.CollectionOptions) *mongo.Collection
+}
+
+func New(db IntoCollection, d time.Duration) *Repo {
 	return &Repo{
-		collection:     db.Collection(collectionName),
-		nodeCollection: db.Collection(nodeCollectionName),
+		collection:     db.Collection(collectionName, mongoid.Collection()),
+		nodeCollection: db.Collection(nodeCollectionName, mongoid.Collection()),
 		d:              d,
 	}
 }
@@ -305,9 +445,28 @@ func New(db repo.IntoCollection, d time.Duration) *Repo {
 func (r *Repo) GetFitNodes(ctx context.Context) ([]pb.Task, error) {
 	var save = ferror.Save("expressionrepo.Repo.GetFitNodes")
 
+	// A chosen branch can be a conditional with a calculated condition too,
+	// and a conditional can wait for a median
+	for {
+		conditionals, err := r.resolveConditionals(ctx)
+		if err != nil {
+			return nil, save.New(err)
+		}
+		medians, err := r.resolveMedians(ctx)
+		if err != nil {
+			return nil, save.New(err)
+		}
+		if conditionals == 0 && medians == 0 {
+			break
+		}
+	}
+
 	filter := bson.M{
 		"type": models.Operation,
 		"tree": bson.M{"$exists": true},
+		"held": bson.M{"$exists": false},
+		// Conditionals and medians are never sent, they are resolved above
+		"tree.operator": bson.M{"$nin": bson.A{pb.Operation(tree.Conditional), pb.Operation(tree.Median)}},
 		"$or": []bson.M{
 			{"sended_at": bson.M{"$exists": false}},
 			{"sended_at": bson.M{"$lt": time.Now().Add(-r.d)}},
@@ -329,18 +488,29 @@ func (r *Repo) GetFitNodes(ctx context.Context) ([]pb.Task, error) {
 			"foreignField": "_id",
 			"as":           "rightNode",
 		}},
-		{"$unwind": bson.M{"path": "$rightNode", "preserveNullAndEmptyArrays": false}},
+		// Unary operations don't have the right node
+		{"$unwind": bson.M{"path": "$rightNode", "preserveNullAndEmptyArrays": true}},
 		{"$match": bson.M{
-			"leftNode.type":    models.Number,
-			"rightNode.type":   models.Number,
-			"leftNode.number":  bson.M{"$exists": true},
-			"rightNode.number": bson.M{"$exists": true},
+			"leftNode.type":   models.Number,
+			"leftNode.number": bson.M{"$exists": true},
+			"$or": []bson.M{
+				{"tree.right": bson.M{"$exists": false}},
+				{"rightNode.type": models.Number, "rightNode.number": bson.M{"$exists": true}},
+			},
 		}},
 		{"$project": bson.M{
-			"_id":              1,
-			"tree.operator":    1,
-			"leftNode.number":  1,
-			"rightNode.number": 1,
+			"_id":                1,
+			"tree.operator":      1,
+			"leftNode.number":    1,
+			"rightNode.number":   1,
+			"leftNode.decimal":   1,
+			"rightNode.decimal":  1,
+			"leftNode.rational":  1,
+			"rightNode.rational": 1,
+			"leftNode.complex":   1,
+			"rightNode.complex":  1,
+			"leftNode.unit":      1,
+			"rightNode.unit":     1,
 		}},
 	}
 	cursor, err := r.nodeCollection.Aggregate(ctx, pipeline)
@@ -357,12 +527,7 @@ func (r *Repo) GetFitNodes(ctx context.Context) ([]pb.Task, error) {
 	ids := make(bson.A, len(results))
 	for i, result := range results {
 		ids[i] = result.ID
-		tasks[i] = pb.Task{
-			Id:        result.ID.Hex(),
-			Arg1:      result.LeftNode.Number,
-			Arg2:      result.RightNode.Number,
-			Operation: pb.Operation(result.Tree.Operator),
-		}
+		nodetree.SetTask(&tasks[i], result)
 	}
 	if _, err := r.nodeCollection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}}, bson.M{"$set": bson.M{"sended_at": time.Now()}}); err != nil {
 		return nil, save.New(err)
@@ -371,9 +536,198 @@ func (r *Repo) GetFitNodes(ctx context.Context) ([]pb.Task, error) {
 	return tasks, nil
 }
 
+// Finds the conditionals with calculated conditions and chooses their branches, returns the number of them
+func (r *Repo) resolveConditionals(ctx context.Context) (int, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{
+			"tree.operator": pb.Operation(tree.Conditional),
+			"held":          bson.M{"$exists": false},
+		}},
+		{"$lookup": bson.M{
+			"from":         nodeCollectionName,
+			"localField":   "tree.cond",
+			"foreignField": "_id",
+			"as":           "condNode",
+		}},
+		{"$unwind": bson.M{"path": "$condNode", "preserveNullAndEmptyArrays": false}},
+		{"$match": bson.M{
+			"condNode.type":   models.Number,
+			"condNode.number": bson.M{"$exists": true},
+		}},
+	}
+	cursor, err := r.nodeCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+	var results []struct {
+		models.Node `bson:",inline"`
+		CondNode    models.Node `bson:"condNode"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return 0, err
+	}
+	for _, result := range results {
+		if err := r.transaction(ctx, func(ctx context.Context) error {
+			return r.resolveConditional(ctx, result.Node, result.CondNode)
+		}); err != nil {
+			return 0, err
+		}
+	}
+	return len(results), nil
+}
+
+// The chosen branch takes the place of the conditional node, the condition and the other branch are deleted
+func (r *Repo) resolveConditional(ctx context.Context, node models.Node, cond models.Node) error {
+	var save = ferror.Save("expressionrepo.Repo.resolveConditional")
+	chosen, other := node.Tree.Left, node.Tree.Right
+	if !nodetree.IsTrue(cond) {
+		chosen, other = other, chosen
+	}
+	if err := r.deleteNodes(ctx, other); err != nil {
+		return err
+	}
+	if err := r.deleteNodes(ctx, cond.ID); err != nil {
+		return err
+	}
+	if _, err := r.nodeCollection.UpdateMany(ctx, bson.M{"held": node.ID}, bson.M{"$unset": bson.M{"held": 1}}); err != nil {
+		return save.New(err)
+	}
+
+	var branch models.Node
+	if err := r.nodeCollection.FindOneAndDelete(ctx, bson.M{"_id": chosen}).Decode(&branch); err == mongo.ErrNoDocuments {
+		return repo.NodeNotFound
+	} else if err != nil {
+		return save.New(err)
+	}
+	if branch.Type == models.Number {
+		// The conditional becomes the number, it can be the result of the expression
+		if _, err := r.nodeCollection.UpdateOne(ctx, bson.M{"_id": node.ID}, bson.M{"$unset": bson.M{"tree": 1}}); err != nil {
+			return save.New(err)
+		}
+		return r.setToNum(ctx, save, node.ID, *branch.Number, exactFields(branch))
+	}
+
+	branch.ID = node.ID
+	if _, err := r.nodeCollection.ReplaceOne(ctx, bson.M{"_id": node.ID}, branch); err != nil {
+		return save.New(err)
+	}
+	// The branch of a nested conditional is held by its new id
+	if _, err := r.nodeCollection.UpdateMany(ctx, bson.M{"held": chosen}, bson.M{"$set": bson.M{"held": node.ID}}); err != nil {
+		return save.New(err)
+	}
+	return nil
+}
+
+// Finds the medians with calculated items and takes their middle items, returns the number of them
+func (r *Repo) resolveMedians(ctx context.Context) (int, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{
+			"tree.operator": pb.Operation(tree.Median),
+			"held":          bson.M{"$exists": false},
+		}},
+		{"$lookup": bson.M{
+			"from":         nodeCollectionName,
+			"localField":   "tree.items",
+			"foreignField": "_id",
+			"as":           "itemNodes",
+		}},
+		// Every item is found and is a number
+		{"$match": bson.M{"$expr": bson.M{"$and": bson.A{
+			bson.M{"$eq": bson.A{bson.M{"$size": "$itemNodes"}, bson.M{"$size": "$tree.items"}}},
+			bson.M{"$allElementsTrue": bson.A{bson.M{"$map": bson.M{
+				"input": "$itemNodes",
+				"in":    bson.M{"$eq": bson.A{"$$this.type", models.Number}},
+			}}}},
+		}}}},
+	}
+	cursor, err := r.nodeCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+	var results []struct {
+		models.Node `bson:",inline"`
+		ItemNodes   []models.Node `bson:"itemNodes"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return 0, err
+	}
+	for _, result := range results {
+		if err := r.transaction(ctx, func(ctx context.Context) error {
+			return r.resolveMedian(ctx, result.Node, result.ItemNodes)
+		}); err != nil {
+			return 0, err
+		}
+	}
+	return len(results), nil
+}
+
+// The middle item takes the place of the median node, the other items are deleted.
+// With an even number of items the node becomes (a + b) / 2 for the agents
+func (r *Repo) resolveMedian(ctx context.Context, node models.Node, items []models.Node) error {
+	var save = ferror.Save("expressionrepo.Repo.resolveMedian")
+	middle := nodetree.Middle(items)
+	for _, item := range items {
+		if !slices.ContainsFunc(middle, func(m models.Node) bool { return m.ID == item.ID }) {
+			if err := r.deleteNodes(ctx, item.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(middle) == 1 {
+		if err := r.deleteNodes(ctx, middle[0].ID); err != nil {
+			return err
+		}
+		// The median becomes the number, it can be the result of the expression
+		if _, err := r.nodeCollection.UpdateOne(ctx, bson.M{"_id": node.ID}, bson.M{"$unset": bson.M{"tree": 1}}); err != nil {
+			return save.New(err)
+		}
+		return r.setToNum(ctx, save, node.ID, *middle[0].Number, exactFields(middle[0]))
+	}
+
+	sum := nodetree.Operation(node.Held, models.TreeNode{Operator: pb.Operation_ADD, Left: middle[0].ID, Right: middle[1].ID})
+	count := 2.0
+	two := models.Node{ID: models.NewID(), Type: models.Number, Held: node.Held, Number: &count}
+	if _, err := r.nodeCollection.InsertMany(ctx, []any{sum, two}); err != nil {
+		return save.New(err)
+	}
+	update := bson.M{"$set": bson.M{"tree": models.TreeNode{
+		Operator: pb.Operation_DIVIDE,
+		Left:     sum.ID,
+		Right:    two.ID,
+	}}}
+	if _, err := r.nodeCollection.UpdateOne(ctx, bson.M{"_id": node.ID}, update); err != nil {
+		return save.New(err)
+	}
+	return nil
+}
+
+// The exact fields of the number node in the form of setToNum
+func exactFields(node models.Node) bson.M {
+	exact := bson.M{}
+	if node.Decimal != "" {
+		exact["decimal"] = node.Decimal
+	}
+	if node.Rational != "" {
+		exact["rational"] = node.Rational
+	}
+	if node.Complex != nil {
+		exact["complex"] = *node.Complex
+	}
+	if node.Unit != "" {
+		exact["unit"] = node.Unit
+	}
+	return exact
+}
+
 func (r *Repo) DoCallback() {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	if r.callback == nil {
+		return
+	}
 	ctx, cancel := context.WithTimeout(context.TODO(), time.Second*20)
 	defer cancel()
 	tasks, err := r.GetFitNodes(ctx)
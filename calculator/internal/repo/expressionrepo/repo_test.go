@@ -16,11 +16,11 @@ import (
 	"github.com/vandi37/Calculator/internal/models"
 	"github.com/vandi37/Calculator/internal/repo"
 	"github.com/vandi37/Calculator/internal/repo/expressionrepo"
+	"github.com/vandi37/Calculator/internal/repo/repotest"
 	"github.com/vandi37/Calculator/internal/status"
 	"github.com/vandi37/Calculator/pkg/parsing/parser"
 	"github.com/vandi37/Calculator/pkg/parsing/tree"
 	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -31,7 +31,7 @@ type ExpressionRepoTestSuite struct {
 	client         *mongo.Client
 	expressionRepo *expressionrepo.Repo
 	ctx            context.Context
-	userId         primitive.ObjectID
+	userId         repo.ID
 	mockCallback   *MockCallback
 }
 
@@ -48,7 +48,9 @@ func (suite *ExpressionRepoTestSuite) SetupSuite() {
 	req := testcontainers.ContainerRequest{
 		Image:        "mongo:latest",
 		ExposedPorts: []string{"27017/tcp"},
-		WaitingFor:   wait.ForLog("Waiting for connections").WithStartupTimeout(20 * time.Second),
+		// The transactions need a replica set, one member is enough
+		Cmd:        []string{"--replSet", "rs0", "--bind_ip_all"},
+		WaitingFor: wait.ForLog("Waiting for connections").WithStartupTimeout(20 * time.Second),
 	}
 
 	mongoC, err := testcontainers.GenericContainer(suite.ctx, testcontainers.GenericContainerRequest{
@@ -58,16 +60,27 @@ func (suite *ExpressionRepoTestSuite) SetupSuite() {
 	require.NoError(suite.T(), err)
 	suite.mongoC = mongoC
 
+	code, _, err := mongoC.Exec(suite.ctx, []string{"mongosh", "--quiet", "--eval", `rs.initiate({_id: "rs0", members: [{_id: 0, host: "localhost:27017"}]})`})
+	require.NoError(suite.T(), err)
+	require.Zero(suite.T(), code)
+
 	endpoint, err := mongoC.Endpoint(suite.ctx, "")
 	require.NoError(suite.T(), err)
 
-	client, err := mongo.Connect(suite.ctx, options.Client().ApplyURI("mongodb://"+endpoint))
+	// The member has the address inside the container, so it is connected directly
+	client, err := mongo.Connect(suite.ctx, options.Client().ApplyURI("mongodb://"+endpoint+"/?directConnection=true"))
 	require.NoError(suite.T(), err)
 	suite.client = client
 
+	require.Eventually(suite.T(), func() bool {
+		var hello bson.M
+		err := client.Database("admin").RunCommand(suite.ctx, bson.M{"hello": 1}).Decode(&hello)
+		return err == nil && hello["isWritablePrimary"] == true
+	}, 20*time.Second, 100*time.Millisecond)
+
 	db := client.Database("test_db")
 	suite.expressionRepo = expressionrepo.New(db, 5*time.Minute)
-	suite.userId = primitive.NewObjectID()
+	suite.userId = models.NewID()
 	suite.mockCallback = &MockCallback{}
 	suite.expressionRepo.SetCallback(suite.ctx, suite.mockCallback)
 }
@@ -90,6 +103,15 @@ func TestExpressionRepoTestSuite(t *testing.T) {
 	suite.Run(t, new(ExpressionRepoTestSuite))
 }
 
+func (s *ExpressionRepoTestSuite) TestConformance() {
+	suite.Run(s.T(), &repotest.ExpressionSuite{
+		New: func(t *testing.T, d time.Duration) repo.ExpressionRepo {
+			s.Clear()
+			return expressionrepo.New(s.client.Database("test_db"), d)
+		},
+	})
+}
+
 type MockCallback struct {
 	lastTasks []pb.Task
 	lastError error
@@ -117,6 +139,12 @@ func (suite *ExpressionRepoTestSuite) TestCreate() {
 		{"Subtraction", "5 - 3"},
 		{"Multiplication", "2 * 4"},
 		{"Division", "10 / 2"},
+		{"Power", "2 ^ 10"},
+		{"Modulo", "10 % 3"},
+		{"Integer Division", "10 // 3"},
+		{"Unary Function", "sqrt(16) + 1"},
+		{"Variadic Function", "max(1, 2 * 3, log(8, 2))"},
+		{"Negation", "-(2 + 3) * 4"},
 		{"Addition and Subtraction", "1 + 2 - 3"},
 		{"Multiplication and Division", "6 / 3 * 2"},
 		{"Mixed Operations", "2 + 3 * 4"},
@@ -176,10 +204,10 @@ func (suite *ExpressionRepoTestSuite) TestCreate() {
 			if tt.wantErr {
 				require.Error(t, err)
 				assert.ErrorIs(t, err, tt.expectedErr)
-				assert.Equal(t, primitive.NilObjectID, id)
+				assert.Equal(t, repo.ID{}, id)
 			} else {
 				require.NoError(t, err)
-				assert.NotEqual(t, primitive.NilObjectID, id)
+				assert.NotEqual(t, repo.ID{}, id)
 
 				var expr models.Expression
 				err := suite.expressionRepo.GetCollection().FindOne(ctx, bson.M{"_id": id}).Decode(&expr)
@@ -187,7 +215,7 @@ func (suite *ExpressionRepoTestSuite) TestCreate() {
 				assert.Equal(t, tt.expression.Origin, expr.Origin)
 				assert.Equal(t, tt.expression.UserID, expr.UserID)
 				assert.Equal(t, tt.expression.Status, expr.Status)
-				assert.NotEqual(t, primitive.NilObjectID, expr.NodeID)
+				assert.NotEqual(t, models.NilID, expr.NodeID)
 				assert.False(t, expr.CreatedAt.IsZero())
 
 				if tt.ast != nil {
@@ -199,6 +227,60 @@ func (suite *ExpressionRepoTestSuite) TestCreate() {
 	}
 }
 
+func (suite *ExpressionRepoTestSuite) TestCreateRollback() {
+	suite.Clear()
+	t := suite.T()
+	ctx := context.Background()
+
+	// The left nodes are saved before the right one fails
+	ast := tree.Expression{
+		Left:      tree.Expression{Left: tree.Num(1), Operation: tree.Operation(pb.Operation_ADD), Right: tree.Num(2)},
+		Operation: tree.Operation(pb.Operation_MULTIPLY),
+		Right:     nil,
+	}
+	_, err := suite.expressionRepo.Create(ctx, models.Expression{UserID: suite.userId, Origin: "invalid"}, tree.Ast{Expression: ast})
+	assert.ErrorIs(t, err, repo.InvalidExpression)
+
+	count, err := suite.expressionRepo.GetNodeCollection().CountDocuments(ctx, bson.M{})
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	count, err = suite.expressionRepo.GetCollection().CountDocuments(ctx, bson.M{})
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func (suite *ExpressionRepoTestSuite) TestSweep() {
+	suite.Clear()
+	t := suite.T()
+	ctx := context.Background()
+
+	ast, err := parser.Build("(1 + 2) * 3")
+	require.NoError(t, err)
+	id, err := suite.expressionRepo.Create(ctx, models.Expression{UserID: suite.userId, Origin: "(1 + 2) * 3"}, ast)
+	require.NoError(t, err)
+	expr, err := suite.expressionRepo.Get(ctx, id)
+	require.NoError(t, err)
+
+	// Nodes left by a crash
+	orphan := 4.0
+	_, err = suite.expressionRepo.GetNodeCollection().InsertMany(ctx, []any{
+		models.Node{Type: models.Number, Number: &orphan},
+		models.Node{Type: models.Operation, Tree: &models.TreeNode{Operator: pb.Operation_ADD, Left: models.NewID(), Right: models.NewID()}},
+	})
+	require.NoError(t, err)
+
+	deleted, err := suite.expressionRepo.Sweep(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+
+	nodes, err := suite.expressionRepo.GetNodes(ctx, expr.NodeID)
+	require.NoError(t, err)
+	assert.Len(t, nodes, 5)
+	count, err := suite.expressionRepo.GetNodeCollection().CountDocuments(ctx, bson.M{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), count)
+}
+
 func (suite *ExpressionRepoTestSuite) TestGet() {
 	suite.Clear()
 	t := suite.T()
@@ -211,7 +293,7 @@ func (suite *ExpressionRepoTestSuite) TestGet() {
 	require.NoError(t, err)
 	tests := []struct {
 		name        string
-		id          primitive.ObjectID
+		id          repo.ID
 		wantErr     bool
 		expectedErr error
 		checkResult func(*models.Expression)
@@ -224,12 +306,12 @@ func (suite *ExpressionRepoTestSuite) TestGet() {
 				assert.Equal(t, doingExpr.Origin, expr.Origin)
 				assert.Equal(t, doingExpr.UserID, expr.UserID)
 				assert.Equal(t, status.Pending, expr.Status)
-				assert.NotEqual(t, primitive.NilObjectID, expr.NodeID)
+				assert.NotEqual(t, models.NilID, expr.NodeID)
 			},
 		},
 		{
 			name:        "non-existent expression",
-			id:          primitive.NewObjectID(),
+			id:          models.NewID(),
 			wantErr:     true,
 			expectedErr: repo.ExpressionNotFound,
 		},
@@ -243,7 +325,7 @@ func (suite *ExpressionRepoTestSuite) TestGet() {
 				require.NoError(t, err)
 				assert.Equal(t, 1.0, *result.Result)
 				assert.Equal(t, status.Finished, result.Status)
-				assert.Equal(t, primitive.NilObjectID, result.NodeID)
+				assert.Equal(t, models.NilID, result.NodeID)
 			},
 		},
 	}
@@ -275,7 +357,7 @@ func (suite *ExpressionRepoTestSuite) TestGetByUser() {
 	}
 	tests := []struct {
 		name        string
-		userID      primitive.ObjectID
+		userID      repo.ID
 		wantErr     bool
 		expectedLen int
 	}{
@@ -287,7 +369,7 @@ func (suite *ExpressionRepoTestSuite) TestGetByUser() {
 		},
 		{
 			name:        "user without expressions",
-			userID:      primitive.NewObjectID(),
+			userID:      models.NewID(),
 			wantErr:     false,
 			expectedLen: 0,
 		},
@@ -321,7 +403,7 @@ func (suite *ExpressionRepoTestSuite) TestDelete() {
 
 	tests := []struct {
 		name        string
-		id          primitive.ObjectID
+		id          repo.ID
 		wantErr     bool
 		expectedErr error
 	}{
@@ -332,7 +414,7 @@ func (suite *ExpressionRepoTestSuite) TestDelete() {
 		},
 		{
 			name:        "non-existent expression",
-			id:          primitive.NewObjectID(),
+			id:          models.NewID(),
 			wantErr:     true,
 			expectedErr: repo.ExpressionNotFound,
 		},
@@ -363,7 +445,7 @@ func (suite *ExpressionRepoTestSuite) TestDeleteByUser() {
 
 	tests := []struct {
 		name        string
-		userID      primitive.ObjectID
+		userID      repo.ID
 		wantErr     bool
 		expectedErr error
 	}{
@@ -374,7 +456,7 @@ func (suite *ExpressionRepoTestSuite) TestDeleteByUser() {
 		},
 		{
 			name:    "user without expressions",
-			userID:  primitive.NewObjectID(),
+			userID:  models.NewID(),
 			wantErr: false,
 		},
 	}
@@ -403,12 +485,12 @@ func (suite *ExpressionRepoTestSuite) TestSetToNum() {
 	ctx := context.Background()
 
 	tempNode := models.Node{
-		ID:   primitive.NewObjectID(),
+		ID:   models.NewID(),
 		Type: models.Operation,
 		Tree: &models.TreeNode{
 			Operator: pb.Operation_ADD,
-			Left:     primitive.NewObjectID(),
-			Right:    primitive.NewObjectID(),
+			Left:     models.NewID(),
+			Right:    models.NewID(),
 		},
 	}
 	_, err := suite.expressionRepo.GetNodeCollection().InsertOne(ctx, tempNode)
@@ -416,7 +498,7 @@ func (suite *ExpressionRepoTestSuite) TestSetToNum() {
 
 	tests := []struct {
 		name        string
-		nodeID      primitive.ObjectID
+		nodeID      repo.ID
 		result      float64
 		wantErr     bool
 		expectedErr error
@@ -429,7 +511,7 @@ func (suite *ExpressionRepoTestSuite) TestSetToNum() {
 		},
 		{
 			name:        "non-existent node",
-			nodeID:      primitive.NewObjectID(),
+			nodeID:      models.NewID(),
 			result:      42.0,
 			wantErr:     true,
 			expectedErr: repo.NodeNotFound,
@@ -459,12 +541,12 @@ func (suite *ExpressionRepoTestSuite) TestSetToNum() {
 	}
 }
 
-func createHugeRandomTree(ctx context.Context, userId primitive.ObjectID, expressionRepo *expressionrepo.Repo, max int) (countFit int, nodeNum int, rootExpr *models.Expression, furthestNodeID primitive.ObjectID, expr error) {
+func createHugeRandomTree(ctx context.Context, userId repo.ID, expressionRepo *expressionrepo.Repo, max int) (countFit int, nodeNum int, rootExpr *models.Expression, furthestNodeID repo.ID, expr error) {
 	expressionCollection := expressionRepo.GetCollection()
 	nodeCollection := expressionRepo.GetNodeCollection()
 
-	rootNodeID := primitive.NewObjectID()
-	expressionID := primitive.NewObjectID()
+	rootNodeID := models.NewID()
+	expressionID := models.NewID()
 
 	rootExpr = &models.Expression{
 		ID:     expressionID,
@@ -476,12 +558,12 @@ func createHugeRandomTree(ctx context.Context, userId primitive.ObjectID, expres
 
 	_, err := expressionCollection.InsertOne(ctx, rootExpr)
 	if err != nil {
-		return 0, 0, nil, primitive.NilObjectID, err
+		return 0, 0, nil, models.NilID, err
 	}
 
-	var buildTree func(nodeID primitive.ObjectID, remainingNodes int) error
+	var buildTree func(nodeID repo.ID, remainingNodes int) error
 
-	buildTree = func(nodeID primitive.ObjectID, remainingNodes int) error {
+	buildTree = func(nodeID repo.ID, remainingNodes int) error {
 		nodeNum++
 		if remainingNodes <= 0 {
 			numberNode := models.Node{
@@ -497,8 +579,8 @@ func createHugeRandomTree(ctx context.Context, userId primitive.ObjectID, expres
 			return nil
 		}
 
-		leftNodeID := primitive.NewObjectID()
-		rightNodeID := primitive.NewObjectID()
+		leftNodeID := models.NewID()
+		rightNodeID := models.NewID()
 		operators := []pb.Operation{pb.Operation_ADD, pb.Operation_SUBTRACT, pb.Operation_MULTIPLY, pb.Operation_DIVIDE}
 		operator := operators[rand.Intn(len(operators))]
 		opNode := models.Node{
@@ -540,7 +622,7 @@ func createHugeRandomTree(ctx context.Context, userId primitive.ObjectID, expres
 		return nil
 	}
 	if err := buildTree(rootNodeID, rand.Intn(max/2)); err != nil {
-		return 0, 0, nil, primitive.NilObjectID, err
+		return 0, 0, nil, models.NilID, err
 	}
 	return
 }
@@ -552,7 +634,7 @@ func (suite *ExpressionRepoTestSuite) TestSetToError() {
 
 	tests := []struct {
 		name        string
-		id          primitive.ObjectID
+		id          repo.ID
 		expr        *models.Expression
 		nodeNum     int
 		errVal      string
@@ -562,7 +644,7 @@ func (suite *ExpressionRepoTestSuite) TestSetToError() {
 
 		{
 			name:        "non-existent node",
-			id:          primitive.NewObjectID(),
+			id:          models.NewID(),
 			errVal:      "error",
 			wantErr:     true,
 			expectedErr: repo.ExpressionNotFound,
@@ -574,7 +656,7 @@ func (suite *ExpressionRepoTestSuite) TestSetToError() {
 		require.NoError(t, err)
 		tests = append(tests, struct {
 			name        string
-			id          primitive.ObjectID
+			id          repo.ID
 			expr        *models.Expression
 			nodeNum     int
 			errVal      string
@@ -590,7 +672,7 @@ func (suite *ExpressionRepoTestSuite) TestSetToError() {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := suite.expressionRepo.SetToError(ctx, tt.id, tt.errVal)
+			err := suite.expressionRepo.SetToError(ctx, tt.id, "some_error", tt.errVal)
 
 			if tt.wantErr {
 				require.Error(t, err)
@@ -602,9 +684,10 @@ func (suite *ExpressionRepoTestSuite) TestSetToError() {
 				require.NoError(t, err)
 				assert.Equal(t, status.Error, expr.Status)
 				assert.Equal(t, tt.errVal, expr.Error)
+				assert.Equal(t, "some_error", expr.ErrorCode)
 				assert.Nil(t, expr.Result)
 				assert.Equal(t, tt.expr.Origin, expr.Origin)
-				assert.Equal(t, primitive.NilObjectID, expr.NodeID)
+				assert.Equal(t, models.NilID, expr.NodeID)
 			}
 		})
 	}
@@ -0,0 +1,399 @@
+package expressionrepo
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	pb "github.com/vandi37/Calculator-Models"
+	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/internal/repo"
+	"github.com/vandi37/Calculator/internal/repo/mongoid"
+	"github.com/vandi37/Calculator/internal/repo/nodetree"
+	"github.com/vandi37/Calculator/internal/status"
+	"github.com/vandi37/Calculator/pkg/parsing/tree"
+	"github.com/vandi37/ferror"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const treeCollectionName = "expression_trees"
+
+// The version grows with every change, so the trees changed in go don't overwrite the results
+type treeDocument struct {
+	nodetree.Tree `bson:",inline"`
+	Version       int `bson:"version"`
+}
+
+// Saves the tree in the document of the expression instead of the nodes collection.
+// The results are saved with positional operators and the ready tasks are found by one indexed query
+type TreeRepo struct {
+	collection *mongo.Collection
+	d          time.Duration
+	callback   repo.Callback
+	mu         sync.Mutex
+}
+
+func NewTree(db IntoCollection, d time.Duration) *TreeRepo {
+	return &TreeRepo{
+		collection: db.Collection(treeCollectionName, mongoid.Collection()),
+		d:          d,
+	}
+}
+
+// Creates the indexes for the queries of the nodes, it is called at the start
+func (r *TreeRepo) CreateIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "nodes._id", Value: 1}}},
+		{Keys: bson.D{{Key: "nodes.pending", Value: 1}, {Key: "nodes.type", Value: 1}}},
+		{Keys: bson.D{{Key: "node_id", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+	})
+	return err
+}
+
+// The collection of the expressions with their trees, it is used by the tests
+func (r *TreeRepo) GetCollection() *mongo.Collection {
+	return r.collection
+}
+
+// SetCallback implements repo.ExpressionRepo.
+func (r *TreeRepo) SetCallback(ctx context.Context, callback repo.Callback) {
+	r.callback = callback
+	go r.DoCallback()
+}
+
+// Create implements repo.ExpressionRepo.
+func (r *TreeRepo) Create(ctx context.Context, expression models.Expression, ast tree.Ast) (repo.ID, error) {
+	var save = ferror.Save("expressionrepo.TreeRepo.Create")
+	num, nodes, err := nodetree.Build(ast.Expression)
+	if err != nil {
+		return repo.ID{}, save.New(err)
+	}
+	var root repo.ID
+	if num == nil {
+		root = nodes[len(nodes)-1].ID
+	}
+	nodetree.Prepare(&expression, num, root)
+	res, err := r.collection.InsertOne(ctx, treeDocument{Tree: nodetree.Tree{Expression: expression, Nodes: nodetree.WithPending(nodes)}})
+	if err != nil {
+		return repo.ID{}, save.New(err)
+	}
+	go r.DoCallback()
+	return repo.ID(res.InsertedID.(primitive.ObjectID)), nil
+}
+
+// Get implements repo.ExpressionRepo.
+func (r *TreeRepo) Get(ctx context.Context, id repo.ID) (*models.Expression, error) {
+	var save = ferror.Save("expressionrepo.TreeRepo.Get")
+	var expr models.Expression
+	opts := options.FindOne().SetProjection(bson.M{"nodes": 0})
+	if err := r.collection.FindOne(ctx, bson.M{"_id": id}, opts).Decode(&expr); err == mongo.ErrNoDocuments {
+		return nil, repo.ExpressionNotFound
+	} else if err != nil {
+		return nil, save.New(err)
+	}
+	return &expr, nil
+}
+
+// GetByUser implements repo.ExpressionRepo.
+func (r *TreeRepo) GetByUser(ctx context.Context, userID repo.ID) ([]models.Expression, error) {
+	var save = ferror.Save("expressionrepo.TreeRepo.GetByUser")
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID}, options.Find().SetProjection(bson.M{"nodes": 0}))
+	if err != nil {
+		return nil, save.New(err)
+	}
+	defer cursor.Close(ctx)
+	var expressions []models.Expression
+	if err := cursor.All(ctx, &expressions); err != nil {
+		return nil, save.New(err)
+	}
+	return expressions, nil
+}
+
+// GetNode implements repo.ExpressionRepo.
+func (r *TreeRepo) GetNode(ctx context.Context, id repo.ID) (*models.Node, error) {
+	var save = ferror.Save("expressionrepo.TreeRepo.GetNode")
+	var doc treeDocument
+	opts := options.FindOne().SetProjection(bson.M{"nodes.$": 1})
+	if err := r.collection.FindOne(ctx, bson.M{"nodes._id": id}, opts).Decode(&doc); err == mongo.ErrNoDocuments {
+		return nil, repo.NodeNotFound
+	} else if err != nil {
+		return nil, save.New(err)
+	}
+	if len(doc.Nodes) == 0 {
+		return nil, repo.NodeNotFound
+	}
+	return &doc.Nodes[0].Node, nil
+}
+
+// GetNodes implements repo.ExpressionRepo.
+func (r *TreeRepo) GetNodes(ctx context.Context, id repo.ID) ([]models.Node, error) {
+	var save = ferror.Save("expressionrepo.TreeRepo.GetNodes")
+	var doc treeDocument
+	if err := r.collection.FindOne(ctx, bson.M{"nodes._id": id}).Decode(&doc); err == mongo.ErrNoDocuments {
+		return nil, repo.NodeNotFound
+	} else if err != nil {
+		return nil, save.New(err)
+	}
+	return doc.Subtree(id), nil
+}
+
+// Delete implements repo.ExpressionRepo.
+func (r *TreeRepo) Delete(ctx context.Context, id repo.ID) error {
+	var save = ferror.Save("expressionrepo.TreeRepo.Delete")
+	if res, err := r.collection.DeleteOne(ctx, bson.M{"_id": id}); err != nil {
+		return save.New(err)
+	} else if res.DeletedCount == 0 {
+		return repo.ExpressionNotFound
+	}
+	return nil
+}
+
+// DeleteByUser implements repo.ExpressionRepo.
+func (r *TreeRepo) DeleteByUser(ctx context.Context, userID repo.ID) error {
+	var save = ferror.Save("expressionrepo.TreeRepo.DeleteByUser")
+	if _, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID}); err != nil {
+		return save.New(err)
+	}
+	return nil
+}
+
+// The expression of the node, the root node is found by node_id too
+func byNode(id repo.ID) bson.M {
+	return bson.M{"$or": []bson.M{{"node_id": id}, {"nodes._id": id}}}
+}
+
+// SetToError implements repo.ExpressionRepo.
+func (r *TreeRepo) SetToError(ctx context.Context, id repo.ID, code, errVal string) error {
+	var save = ferror.Save("expressionrepo.TreeRepo.SetToError")
+	update := bson.M{
+		"$set":   bson.M{"status": status.Error, "error": errVal, "error_code": code},
+		"$unset": bson.M{"result": 1, "node_id": 1, "nodes": 1},
+		"$inc":   bson.M{"version": 1},
+	}
+	if res, err := r.collection.UpdateOne(ctx, byNode(id), update); err != nil {
+		return save.New(err)
+	} else if res.MatchedCount == 0 || res.ModifiedCount == 0 {
+		return repo.ExpressionNotFound
+	}
+	return nil
+}
+
+// AddWarning implements repo.ExpressionRepo.
+func (r *TreeRepo) AddWarning(ctx context.Context, nodeId repo.ID, code string) error {
+	var save = ferror.Save("expressionrepo.TreeRepo.AddWarning")
+	if res, err := r.collection.UpdateOne(ctx, byNode(nodeId), bson.M{"$addToSet": bson.M{"warnings": code}}); err != nil {
+		return save.New(err)
+	} else if res.MatchedCount == 0 {
+		return repo.ExpressionNotFound
+	}
+	return nil
+}
+
+// SetToNum implements repo.ExpressionRepo.
+func (r *TreeRepo) SetToNum(ctx context.Context, nodeId repo.ID, result float64) error {
+	return r.setToNum(ctx, ferror.Save("expressionrepo.TreeRepo.SetToNum"), nodeId, result, nil)
+}
+
+// SetToDecimal implements repo.ExpressionRepo.
+func (r *TreeRepo) SetToDecimal(ctx context.Context, nodeId repo.ID, result float64, decimal string) error {
+	return r.setToNum(ctx, ferror.Save("expressionrepo.TreeRepo.SetToDecimal"), nodeId, result, bson.M{"decimal": decimal})
+}
+
+// SetToRational implements repo.ExpressionRepo.
+func (r *TreeRepo) SetToRational(ctx context.Context, nodeId repo.ID, result float64, rational string) error {
+	return r.setToNum(ctx, ferror.Save("expressionrepo.TreeRepo.SetToRational"), nodeId, result, bson.M{"rational": rational})
+}
+
+// SetToUnit implements repo.ExpressionRepo.
+func (r *TreeRepo) SetToUnit(ctx context.Context, nodeId repo.ID, result float64, unit string) error {
+	return r.setToNum(ctx, ferror.Save("expressionrepo.TreeRepo.SetToUnit"), nodeId, result, bson.M{"unit": unit})
+}
+
+// SetToComplex implements repo.ExpressionRepo.
+func (r *TreeRepo) SetToComplex(ctx context.Context, nodeId repo.ID, result models.Complex) error {
+	return r.setToNum(ctx, ferror.Save("expressionrepo.TreeRepo.SetToComplex"), nodeId, result.Re, bson.M{"complex": result})
+}
+
+// Both updates change one document, so they need no transaction.
+// The node becomes the number and its parent waits for one operand less
+func (r *TreeRepo) setToNum(ctx context.Context, save ferror.Save, nodeId repo.ID, result float64, exact bson.M) error {
+	expressionSet := bson.M{"status": status.Finished, "result": result}
+	nodeSet := bson.M{"nodes.$[n].type": models.Number, "nodes.$[n].number": result}
+	for key, value := range exact {
+		expressionSet[key] = value
+		nodeSet["nodes.$[n]."+key] = value
+	}
+	if res, err := r.collection.UpdateOne(ctx, bson.M{"node_id": nodeId}, bson.M{
+		"$set":   expressionSet,
+		"$unset": bson.M{"node_id": 1, "nodes": 1},
+		"$inc":   bson.M{"version": 1},
+	}); err != nil {
+		return save.New(err)
+	} else if res.ModifiedCount != 0 {
+		go r.DoCallback()
+		return nil
+	}
+
+	update := bson.M{
+		"$set":   nodeSet,
+		"$unset": bson.M{"nodes.$[n].tree": 1},
+		"$inc":   bson.M{"nodes.$[p].pending": -1, "version": 1},
+	}
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{Filters: []any{
+		bson.M{"n._id": nodeId},
+		bson.M{"$or": []bson.M{{"p.tree.left": nodeId}, {"p.tree.right": nodeId}, {"p.tree.cond": nodeId}, {"p.tree.items": nodeId}}},
+	}})
+	// The result of a node that is already a number isn't saved twice
+	filter := bson.M{"nodes": bson.M{"$elemMatch": bson.M{"_id": nodeId, "tree": bson.M{"$exists": true}}}}
+	if res, err := r.collection.UpdateOne(ctx, filter, update, opts); err != nil {
+		return save.New(err)
+	} else if res.MatchedCount == 0 || res.ModifiedCount == 0 {
+		return repo.NodeNotFound
+	}
+	go r.DoCallback()
+	return nil
+}
+
+func (r *TreeRepo) GetFitNodes(ctx context.Context) ([]pb.Task, error) {
+	var save = ferror.Save("expressionrepo.TreeRepo.GetFitNodes")
+
+	// A chosen branch can be a conditional with a calculated condition too
+	for {
+		resolved, err := r.resolve(ctx)
+		if err != nil {
+			return nil, save.New(err)
+		}
+		if resolved == 0 {
+			break
+		}
+	}
+
+	sendedBefore := time.Now().Add(-r.d)
+	filter := bson.M{
+		"status": status.Pending,
+		"nodes": bson.M{"$elemMatch": bson.M{
+			"pending": 0,
+			"type":    models.Operation,
+			"held":    bson.M{"$exists": false},
+			// Conditionals and medians are never sent, they are resolved above
+			"tree.operator": bson.M{"$nin": bson.A{pb.Operation(tree.Conditional), pb.Operation(tree.Median)}},
+			"$or": []bson.M{
+				{"sended_at": bson.M{"$exists": false}},
+				{"sended_at": bson.M{"$lt": sendedBefore}},
+			},
+		}},
+	}
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, save.New(err)
+	}
+	defer cursor.Close(ctx)
+	var docs []treeDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, save.New(err)
+	}
+
+	// The query finds the documents, the ready nodes in them are found here
+	tasks := []pb.Task{}
+	ids := bson.A{}
+	for _, doc := range docs {
+		for _, node := range doc.Nodes {
+			left, right, ok := doc.Operands(node, sendedBefore)
+			if !ok {
+				continue
+			}
+			result := models.AggregatedNode{ID: node.ID, LeftNode: left, RightNode: right}
+			result.Tree.Operator = node.Tree.Operator
+			tasks = append(tasks, pb.Task{})
+			nodetree.SetTask(&tasks[len(tasks)-1], result)
+			ids = append(ids, node.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return tasks, nil
+	}
+	// The version grows, so the resolving of another orchestrator doesn't overwrite the sending time
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{Filters: []any{bson.M{"n._id": bson.M{"$in": ids}}}})
+	update := bson.M{"$set": bson.M{"nodes.$[n].sended_at": time.Now()}, "$inc": bson.M{"version": 1}}
+	if _, err := r.collection.UpdateMany(ctx, bson.M{"nodes._id": bson.M{"$in": ids}}, update, opts); err != nil {
+		return nil, save.New(err)
+	}
+	return tasks, nil
+}
+
+// Chooses the branches of the conditionals and the middles of the medians, returns the number of the changed expressions
+func (r *TreeRepo) resolve(ctx context.Context) (int, error) {
+	filter := bson.M{
+		"status": status.Pending,
+		"nodes": bson.M{"$elemMatch": bson.M{
+			"pending":       0,
+			"held":          bson.M{"$exists": false},
+			"tree.operator": bson.M{"$in": bson.A{pb.Operation(tree.Conditional), pb.Operation(tree.Median)}},
+		}},
+	}
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+	var docs []treeDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return 0, err
+	}
+	resolved := 0
+	for i := range docs {
+		if !docs[i].Resolve() {
+			continue
+		}
+		// The document could get a result meanwhile, it is resolved again on the next callback then
+		res, err := r.collection.UpdateOne(ctx, bson.M{"_id": docs[i].ID, "version": docs[i].Version}, docs[i].update())
+		if err != nil {
+			return 0, err
+		}
+		resolved += int(res.ModifiedCount)
+	}
+	return resolved, nil
+}
+
+// The whole tree is saved, or the result if the expression is finished
+func (d *treeDocument) update() bson.M {
+	if d.Status != status.Finished {
+		return bson.M{"$set": bson.M{"nodes": d.Nodes}, "$inc": bson.M{"version": 1}}
+	}
+	set := bson.M{"status": d.Status, "result": d.Result}
+	if d.Decimal != "" {
+		set["decimal"] = d.Decimal
+	}
+	if d.Rational != "" {
+		set["rational"] = d.Rational
+	}
+	if d.Complex != nil {
+		set["complex"] = d.Complex
+	}
+	if d.Unit != "" {
+		set["unit"] = d.Unit
+	}
+	return bson.M{"$set": set, "$unset": bson.M{"node_id": 1, "nodes": 1}, "$inc": bson.M{"version": 1}}
+}
+
+func (r *TreeRepo) DoCallback() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.callback == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second*20)
+	defer cancel()
+	tasks, err := r.GetFitNodes(ctx)
+	if err != nil {
+		r.callback.SendError(ctx, err)
+		return
+	}
+	r.callback.SendResult(ctx, tasks)
+}
+
+var _ repo.ExpressionRepo = (*TreeRepo)(nil)
@@ -0,0 +1,74 @@
+package expressionrepo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"github.com/vandi37/Calculator/internal/repo"
+	"github.com/vandi37/Calculator/internal/repo/expressionrepo"
+	"github.com/vandi37/Calculator/internal/repo/repotest"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The tree storage runs the same tests as the node one, it doesn't need a replica set
+type TreeRepoTestSuite struct {
+	suite.Suite
+	mongoC testcontainers.Container
+	client *mongo.Client
+	ctx    context.Context
+}
+
+func (s *TreeRepoTestSuite) SetupSuite() {
+	s.ctx = context.Background()
+
+	req := testcontainers.ContainerRequest{
+		Image:        "mongo:latest",
+		ExposedPorts: []string{"27017/tcp"},
+		WaitingFor:   wait.ForLog("Waiting for connections").WithStartupTimeout(20 * time.Second),
+	}
+
+	mongoC, err := testcontainers.GenericContainer(s.ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: req,
+		Started:          true,
+	})
+	require.NoError(s.T(), err)
+	s.mongoC = mongoC
+
+	endpoint, err := mongoC.Endpoint(s.ctx, "")
+	require.NoError(s.T(), err)
+
+	client, err := mongo.Connect(s.ctx, options.Client().ApplyURI("mongodb://"+endpoint))
+	require.NoError(s.T(), err)
+	s.client = client
+}
+
+func (s *TreeRepoTestSuite) TearDownSuite() {
+	err := s.client.Disconnect(s.ctx)
+	require.NoError(s.T(), err)
+
+	err = s.mongoC.Terminate(s.ctx)
+	require.NoError(s.T(), err)
+}
+
+func TestTreeRepoTestSuite(t *testing.T) {
+	suite.Run(t, new(TreeRepoTestSuite))
+}
+
+func (s *TreeRepoTestSuite) TestConformance() {
+	suite.Run(s.T(), &repotest.ExpressionSuite{
+		New: func(t *testing.T, d time.Duration) repo.ExpressionRepo {
+			r := expressionrepo.NewTree(s.client.Database("test_db"), d)
+			_, err := r.GetCollection().DeleteMany(s.ctx, bson.M{})
+			require.NoError(t, err)
+			require.NoError(t, r.CreateIndexes(s.ctx))
+			return r
+		},
+	})
+}
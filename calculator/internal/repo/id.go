@@ -0,0 +1,6 @@
+package repo
+
+import "github.com/vandi37/Calculator/internal/models"
+
+// The id of the stored values, every storage keeps it in its own way (an object id in mongo, bytea in postgres)
+type ID = models.ID
@@ -0,0 +1,104 @@
+package memrepo
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/internal/repo"
+)
+
+type DefinitionRepo struct {
+	definitions map[repo.ID]models.Definition
+	mu          sync.RWMutex
+}
+
+func NewDefinitionRepo() *DefinitionRepo {
+	return &DefinitionRepo{definitions: map[repo.ID]models.Definition{}}
+}
+
+// Names are unique for every user
+func (r *DefinitionRepo) nameExists(definition models.Definition) bool {
+	for id, saved := range r.definitions {
+		if id != definition.ID && saved.UserID == definition.UserID && saved.Name == definition.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// Create implements repo.DefinitionRepo.
+func (r *DefinitionRepo) Create(ctx context.Context, definition models.Definition) (repo.ID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	definition.ID = models.NilID
+	if r.nameExists(definition) {
+		return repo.ID{}, repo.DefinitionNameTaken
+	}
+	definition.ID = models.NewID()
+	definition.Params = slices.Clone(definition.Params)
+	definition.CreatedAt = time.Now()
+	r.definitions[definition.ID] = definition
+	return definition.ID, nil
+}
+
+// Get implements repo.DefinitionRepo.
+func (r *DefinitionRepo) Get(ctx context.Context, id repo.ID) (*models.Definition, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	definition, ok := r.definitions[id]
+	if !ok {
+		return nil, repo.DefinitionNotFound
+	}
+	return &definition, nil
+}
+
+// GetByUser implements repo.DefinitionRepo.
+func (r *DefinitionRepo) GetByUser(ctx context.Context, userID repo.ID) ([]models.Definition, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return sorted(r.definitions, func(definition models.Definition) bool { return definition.UserID == userID }), nil
+}
+
+// Update implements repo.DefinitionRepo.
+func (r *DefinitionRepo) Update(ctx context.Context, definition models.Definition) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.nameExists(definition) {
+		return repo.DefinitionNameTaken
+	}
+	saved, ok := r.definitions[definition.ID]
+	if !ok {
+		return repo.DefinitionNotFound
+	}
+	saved.Name, saved.Params, saved.Body = definition.Name, slices.Clone(definition.Params), definition.Body
+	r.definitions[definition.ID] = saved
+	return nil
+}
+
+// Delete implements repo.DefinitionRepo.
+func (r *DefinitionRepo) Delete(ctx context.Context, id repo.ID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.definitions[id]; !ok {
+		return repo.DefinitionNotFound
+	}
+	delete(r.definitions, id)
+	return nil
+}
+
+// DeleteByUser implements repo.DefinitionRepo.
+func (r *DefinitionRepo) DeleteByUser(ctx context.Context, userID repo.ID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, definition := range r.definitions {
+		if definition.UserID == userID {
+			delete(r.definitions, id)
+		}
+	}
+	return nil
+}
+
+var _ repo.DefinitionRepo = (*DefinitionRepo)(nil)
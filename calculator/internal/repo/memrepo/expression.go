@@ -0,0 +1,262 @@
+package memrepo
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	pb "github.com/vandi37/Calculator-Models"
+	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/internal/repo"
+	"github.com/vandi37/Calculator/internal/repo/nodetree"
+	"github.com/vandi37/Calculator/internal/status"
+	"github.com/vandi37/Calculator/pkg/parsing/tree"
+	"github.com/vandi37/ferror"
+)
+
+// Keeps every expression with its whole tree like the tree storage of mongo.
+// All changes are made under one lock, so a task is never sent twice before the duration
+type ExpressionRepo struct {
+	trees    map[repo.ID]*nodetree.Tree
+	lock     sync.RWMutex
+	d        time.Duration
+	callback repo.Callback
+	mu       sync.Mutex
+}
+
+func NewExpressionRepo(d time.Duration) *ExpressionRepo {
+	return &ExpressionRepo{
+		trees: map[repo.ID]*nodetree.Tree{},
+		d:     d,
+	}
+}
+
+// SetCallback implements repo.ExpressionRepo.
+func (r *ExpressionRepo) SetCallback(ctx context.Context, callback repo.Callback) {
+	r.mu.Lock()
+	r.callback = callback
+	r.mu.Unlock()
+	go r.DoCallback()
+}
+
+// Create implements repo.ExpressionRepo.
+func (r *ExpressionRepo) Create(ctx context.Context, expression models.Expression, ast tree.Ast) (repo.ID, error) {
+	var save = ferror.Save("memrepo.ExpressionRepo.Create")
+	num, nodes, err := nodetree.Build(ast.Expression)
+	if err != nil {
+		return repo.ID{}, save.New(err)
+	}
+	var root repo.ID
+	if num == nil {
+		root = nodes[len(nodes)-1].ID
+	}
+	nodetree.Prepare(&expression, num, root)
+	if expression.ID == models.NilID {
+		expression.ID = models.NewID()
+	}
+	expression.Warnings = slices.Clone(expression.Warnings)
+
+	r.lock.Lock()
+	r.trees[expression.ID] = &nodetree.Tree{Expression: expression, Nodes: nodetree.WithPending(nodes)}
+	r.lock.Unlock()
+	go r.DoCallback()
+	return expression.ID, nil
+}
+
+// The expression of the node, the root node is found by node_id too
+func (r *ExpressionRepo) byNode(id repo.ID) *nodetree.Tree {
+	for _, t := range r.trees {
+		if t.Status == status.Pending && (t.NodeID == id || t.Find(id) >= 0) {
+			return t
+		}
+	}
+	return nil
+}
+
+// The warnings are copied, so they aren't changed after the lock
+func expressionOf(t *nodetree.Tree) models.Expression {
+	expression := t.Expression
+	expression.Warnings = slices.Clone(expression.Warnings)
+	return expression
+}
+
+// Get implements repo.ExpressionRepo.
+func (r *ExpressionRepo) Get(ctx context.Context, id repo.ID) (*models.Expression, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	t, ok := r.trees[id]
+	if !ok {
+		return nil, repo.ExpressionNotFound
+	}
+	expression := expressionOf(t)
+	return &expression, nil
+}
+
+// GetByUser implements repo.ExpressionRepo.
+func (r *ExpressionRepo) GetByUser(ctx context.Context, userID repo.ID) ([]models.Expression, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	trees := sorted(r.trees, func(t *nodetree.Tree) bool { return t.UserID == userID })
+	expressions := make([]models.Expression, len(trees))
+	for i, t := range trees {
+		expressions[i] = expressionOf(t)
+	}
+	return expressions, nil
+}
+
+// GetNode implements repo.ExpressionRepo.
+func (r *ExpressionRepo) GetNode(ctx context.Context, id repo.ID) (*models.Node, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	t := r.byNode(id)
+	if t == nil {
+		return nil, repo.NodeNotFound
+	}
+	node := t.Nodes[t.Find(id)].Node
+	return &node, nil
+}
+
+// GetNodes implements repo.ExpressionRepo.
+func (r *ExpressionRepo) GetNodes(ctx context.Context, id repo.ID) ([]models.Node, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	t := r.byNode(id)
+	if t == nil {
+		return nil, repo.NodeNotFound
+	}
+	return t.Subtree(id), nil
+}
+
+// Delete implements repo.ExpressionRepo.
+func (r *ExpressionRepo) Delete(ctx context.Context, id repo.ID) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.trees[id]; !ok {
+		return repo.ExpressionNotFound
+	}
+	delete(r.trees, id)
+	return nil
+}
+
+// DeleteByUser implements repo.ExpressionRepo.
+func (r *ExpressionRepo) DeleteByUser(ctx context.Context, userID repo.ID) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for id, t := range r.trees {
+		if t.UserID == userID {
+			delete(r.trees, id)
+		}
+	}
+	return nil
+}
+
+// SetToError implements repo.ExpressionRepo.
+func (r *ExpressionRepo) SetToError(ctx context.Context, id repo.ID, code, errVal string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	t := r.byNode(id)
+	if t == nil {
+		return repo.ExpressionNotFound
+	}
+	t.Status, t.Error, t.ErrorCode = status.Error, errVal, code
+	t.Result, t.NodeID, t.Nodes = nil, models.NilID, nil
+	return nil
+}
+
+// AddWarning implements repo.ExpressionRepo.
+func (r *ExpressionRepo) AddWarning(ctx context.Context, nodeId repo.ID, code string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	t := r.byNode(nodeId)
+	if t == nil {
+		return repo.ExpressionNotFound
+	}
+	if !slices.Contains(t.Warnings, code) {
+		t.Warnings = append(t.Warnings, code)
+	}
+	return nil
+}
+
+// SetToNum implements repo.ExpressionRepo.
+func (r *ExpressionRepo) SetToNum(ctx context.Context, nodeId repo.ID, result float64) error {
+	return r.complete(nodeId, models.Node{Number: &result})
+}
+
+// SetToDecimal implements repo.ExpressionRepo.
+func (r *ExpressionRepo) SetToDecimal(ctx context.Context, nodeId repo.ID, result float64, decimal string) error {
+	return r.complete(nodeId, models.Node{Number: &result, Decimal: decimal})
+}
+
+// SetToRational implements repo.ExpressionRepo.
+func (r *ExpressionRepo) SetToRational(ctx context.Context, nodeId repo.ID, result float64, rational string) error {
+	return r.complete(nodeId, models.Node{Number: &result, Rational: rational})
+}
+
+// SetToUnit implements repo.ExpressionRepo.
+func (r *ExpressionRepo) SetToUnit(ctx context.Context, nodeId repo.ID, result float64, unit string) error {
+	return r.complete(nodeId, models.Node{Number: &result, Unit: unit})
+}
+
+// SetToComplex implements repo.ExpressionRepo.
+func (r *ExpressionRepo) SetToComplex(ctx context.Context, nodeId repo.ID, result models.Complex) error {
+	return r.complete(nodeId, models.Node{Number: &result.Re, Complex: &result})
+}
+
+// The node becomes the number, the result of a node that is already a number isn't saved twice
+func (r *ExpressionRepo) complete(nodeId repo.ID, value models.Node) error {
+	r.lock.Lock()
+	t := r.byNode(nodeId)
+	if t == nil || t.Nodes[t.Find(nodeId)].Type != models.Operation {
+		r.lock.Unlock()
+		return repo.NodeNotFound
+	}
+	t.SetNumber(nodeId, value)
+	r.lock.Unlock()
+	go r.DoCallback()
+	return nil
+}
+
+// GetFitNodes implements repo.ExpressionRepo.
+func (r *ExpressionRepo) GetFitNodes(ctx context.Context) ([]pb.Task, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	now := time.Now()
+	sendedBefore := now.Add(-r.d)
+	tasks := []pb.Task{}
+	for _, t := range sorted(r.trees, func(t *nodetree.Tree) bool { return t.Status == status.Pending }) {
+		// A chosen branch can be a conditional with a calculated condition too, it is resolved in the same call
+		t.Resolve()
+		for i := range t.Nodes {
+			left, right, ok := t.Operands(t.Nodes[i], sendedBefore)
+			if !ok {
+				continue
+			}
+			result := models.AggregatedNode{ID: t.Nodes[i].ID, LeftNode: left, RightNode: right}
+			result.Tree.Operator = t.Nodes[i].Tree.Operator
+			tasks = append(tasks, pb.Task{})
+			nodetree.SetTask(&tasks[len(tasks)-1], result)
+			t.Nodes[i].SendedAt = &now
+		}
+	}
+	return tasks, nil
+}
+
+// Nothing is sent before the callback is set
+func (r *ExpressionRepo) DoCallback() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.callback == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second*20)
+	defer cancel()
+	tasks, err := r.GetFitNodes(ctx)
+	if err != nil {
+		r.callback.SendError(ctx, err)
+		return
+	}
+	r.callback.SendResult(ctx, tasks)
+}
+
+var _ repo.ExpressionRepo = (*ExpressionRepo)(nil)
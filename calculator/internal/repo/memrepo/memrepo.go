@@ -0,0 +1,26 @@
+// This package keeps the users, the definitions, the rates and the expressions in the memory of the process.
+// Nothing is saved after a restart, so it is used for the local development and the tests without a database
+package memrepo
+
+import (
+	"bytes"
+	"slices"
+
+	"github.com/vandi37/Calculator/internal/repo"
+)
+
+// The ids are sorted, so the values are returned in the order of their creation like in the databases
+func sorted[T any](values map[repo.ID]T, match func(T) bool) []T {
+	ids := make([]repo.ID, 0, len(values))
+	for id, value := range values {
+		if match(value) {
+			ids = append(ids, id)
+		}
+	}
+	slices.SortFunc(ids, func(a, b repo.ID) int { return bytes.Compare(a[:], b[:]) })
+	result := make([]T, len(ids))
+	for i, id := range ids {
+		result[i] = values[id]
+	}
+	return result
+}
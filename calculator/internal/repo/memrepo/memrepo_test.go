@@ -0,0 +1,44 @@
+package memrepo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/vandi37/Calculator/internal/repo"
+	"github.com/vandi37/Calculator/internal/repo/memrepo"
+	"github.com/vandi37/Calculator/internal/repo/repotest"
+)
+
+func TestUserRepo(t *testing.T) {
+	suite.Run(t, &repotest.UserSuite{
+		New: func(t *testing.T) repo.UserRepo {
+			return memrepo.NewUserRepo()
+		},
+	})
+}
+
+func TestDefinitionRepo(t *testing.T) {
+	suite.Run(t, &repotest.DefinitionSuite{
+		New: func(t *testing.T) repo.DefinitionRepo {
+			return memrepo.NewDefinitionRepo()
+		},
+	})
+}
+
+func TestRateRepo(t *testing.T) {
+	suite.Run(t, &repotest.RateSuite{
+		New: func(t *testing.T) repo.RateRepo {
+			return memrepo.NewRateRepo()
+		},
+	})
+}
+
+func TestExpressionRepo(t *testing.T) {
+	suite.Run(t, &repotest.ExpressionSuite{
+		New: func(t *testing.T, d time.Duration) repo.ExpressionRepo {
+			return memrepo.NewExpressionRepo(d)
+		},
+		Exclusive: true,
+	})
+}
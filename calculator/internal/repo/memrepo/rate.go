@@ -0,0 +1,73 @@
+package memrepo
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/internal/repo"
+	"github.com/vandi37/Calculator/pkg/currency"
+	"github.com/vandi37/vanerrors"
+)
+
+type RateRepo struct {
+	rates map[string]models.Rate
+	mu    sync.RWMutex
+}
+
+func NewRateRepo() *RateRepo {
+	return &RateRepo{rates: map[string]models.Rate{}}
+}
+
+// GetRates implements repo.RateRepo.
+func (r *RateRepo) GetRates(ctx context.Context, codes []string) (map[string]float64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rates := make(map[string]float64, len(codes))
+	for _, code := range codes {
+		if rate, ok := r.rates[code]; ok {
+			rates[code] = rate.Rate
+		}
+	}
+	if missing := currency.Missing(rates, codes...); len(missing) > 0 {
+		return nil, vanerrors.New(currency.UnknownCurrency, strings.Join(missing, ", "))
+	}
+	return rates, nil
+}
+
+// Set implements repo.RateRepo.
+func (r *RateRepo) Set(ctx context.Context, rate models.Rate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rate.UpdatedAt = time.Now()
+	r.rates[rate.Code] = rate
+	return nil
+}
+
+// GetAll implements repo.RateRepo.
+func (r *RateRepo) GetAll(ctx context.Context) ([]models.Rate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rates := make([]models.Rate, 0, len(r.rates))
+	for _, code := range slices.Sorted(maps.Keys(r.rates)) {
+		rates = append(rates, r.rates[code])
+	}
+	return rates, nil
+}
+
+// Delete implements repo.RateRepo.
+func (r *RateRepo) Delete(ctx context.Context, code string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.rates[code]; !ok {
+		return repo.RateNotFound
+	}
+	delete(r.rates, code)
+	return nil
+}
+
+var _ repo.RateRepo = (*RateRepo)(nil)
@@ -0,0 +1,106 @@
+package memrepo
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/internal/repo"
+)
+
+type UserRepo struct {
+	users map[repo.ID]models.User
+	mu    sync.RWMutex
+}
+
+func NewUserRepo() *UserRepo {
+	return &UserRepo{users: map[repo.ID]models.User{}}
+}
+
+func (r *UserRepo) usernameExists(username string) bool {
+	for _, user := range r.users {
+		if user.Username == username {
+			return true
+		}
+	}
+	return false
+}
+
+// Register implements repo.UserRepo.
+func (r *UserRepo) Register(ctx context.Context, user models.User) (repo.ID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.usernameExists(user.Username) {
+		return repo.ID{}, repo.UsernameTaken
+	}
+	user.ID = models.NewID()
+	user.CreatedAt = time.Now()
+	r.users[user.ID] = user
+	return user.ID, nil
+}
+
+// Get implements repo.UserRepo.
+func (r *UserRepo) Get(ctx context.Context, id repo.ID) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	user, ok := r.users[id]
+	if !ok {
+		return nil, repo.UserNotFound
+	}
+	return &user, nil
+}
+
+// GetByUsername implements repo.UserRepo.
+func (r *UserRepo) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, user := range r.users {
+		if user.Username == username {
+			return &user, nil
+		}
+	}
+	return nil, repo.UserNotFound
+}
+
+// UpdateUsername implements repo.UserRepo.
+func (r *UserRepo) UpdateUsername(ctx context.Context, id repo.ID, username string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.usernameExists(username) {
+		return repo.UsernameTaken
+	}
+	user, ok := r.users[id]
+	if !ok {
+		return repo.UserNotFound
+	}
+	user.Username = username
+	r.users[id] = user
+	return nil
+}
+
+// UpdatePassword implements repo.UserRepo.
+func (r *UserRepo) UpdatePassword(ctx context.Context, id repo.ID, password string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[id]
+	if !ok {
+		return repo.UserNotFound
+	}
+	user.Password = password
+	r.users[id] = user
+	return nil
+}
+
+// Delete implements repo.UserRepo.
+func (r *UserRepo) Delete(ctx context.Context, id repo.ID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.users[id]; !ok {
+		return repo.UserNotFound
+	}
+	delete(r.users, id)
+	return nil
+}
+
+var _ repo.UserRepo = (*UserRepo)(nil)
@@ -13,53 +13,8 @@ import (
 	models "github.com/vandi37/Calculator/internal/models"
 	repo "github.com/vandi37/Calculator/internal/repo"
 	tree "github.com/vandi37/Calculator/pkg/parsing/tree"
-	primitive "go.mongodb.org/mongo-driver/bson/primitive"
-	mongo "go.mongodb.org/mongo-driver/mongo"
-	options "go.mongodb.org/mongo-driver/mongo/options"
 )
 
-// MockIntoCollection is a mock of IntoCollection interface.
-type MockIntoCollection struct {
-	ctrl     *gomock.Controller
-	recorder *MockIntoCollectionMockRecorder
-}
-
-// MockIntoCollectionMockRecorder is the mock recorder for MockIntoCollection.
-type MockIntoCollectionMockRecorder struct {
-	mock *MockIntoCollection
-}
-
-// NewMockIntoCollection creates a new mock instance.
-func NewMockIntoCollection(ctrl *gomock.Controller) *MockIntoCollection {
-	mock := &MockIntoCollection{ctrl: ctrl}
-	mock.recorder = &MockIntoCollectionMockRecorder{mock}
-	return mock
-}
-
-// EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockIntoCollection) EXPECT() *MockIntoCollectionMockRecorder {
-	return m.recorder
-}
-
-// Collection mocks base method.
-func (m *MockIntoCollection) Collection(name string, opts ...*options.CollectionOptions) *mongo.Collection {
-	m.ctrl.T.Helper()
-	varargs := []interface{}{name}
-	for _, a := range opts {
-		varargs = append(varargs, a)
-	}
-	ret := m.ctrl.Call(m, "Collection", varargs...)
-	ret0, _ := ret[0].(*mongo.Collection)
-	return ret0
-}
-
-// Collection indicates an expected call of Collection.
-func (mr *MockIntoCollectionMockRecorder) Collection(name interface{}, opts ...interface{}) *gomock.Call {
-	mr.mock.ctrl.T.Helper()
-	varargs := append([]interface{}{name}, opts...)
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Collection", reflect.TypeOf((*MockIntoCollection)(nil).Collection), varargs...)
-}
-
 // MockCallback is a mock of Callback interface.
 type MockCallback struct {
 	ctrl     *gomock.Controller
@@ -131,7 +86,7 @@ func (m *MockUserRepo) EXPECT() *MockUserRepoMockRecorder {
 }
 
 // Delete mocks base method.
-func (m *MockUserRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
+func (m *MockUserRepo) Delete(ctx context.Context, id repo.ID) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Delete", ctx, id)
 	ret0, _ := ret[0].(error)
@@ -145,7 +100,7 @@ func (mr *MockUserRepoMockRecorder) Delete(ctx, id interface{}) *gomock.Call {
 }
 
 // Get mocks base method.
-func (m *MockUserRepo) Get(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+func (m *MockUserRepo) Get(ctx context.Context, id repo.ID) (*models.User, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Get", ctx, id)
 	ret0, _ := ret[0].(*models.User)
@@ -174,25 +129,11 @@ func (mr *MockUserRepoMockRecorder) GetByUsername(ctx, username interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUsername", reflect.TypeOf((*MockUserRepo)(nil).GetByUsername), ctx, username)
 }
 
-// GetCollection mocks base method.
-func (m *MockUserRepo) GetCollection() *mongo.Collection {
-	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetCollection")
-	ret0, _ := ret[0].(*mongo.Collection)
-	return ret0
-}
-
-// GetCollection indicates an expected call of GetCollection.
-func (mr *MockUserRepoMockRecorder) GetCollection() *gomock.Call {
-	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCollection", reflect.TypeOf((*MockUserRepo)(nil).GetCollection))
-}
-
 // Register mocks base method.
-func (m *MockUserRepo) Register(ctx context.Context, user models.User) (primitive.ObjectID, error) {
+func (m *MockUserRepo) Register(ctx context.Context, user models.User) (repo.ID, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Register", ctx, user)
-	ret0, _ := ret[0].(primitive.ObjectID)
+	ret0, _ := ret[0].(repo.ID)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -204,7 +145,7 @@ func (mr *MockUserRepoMockRecorder) Register(ctx, user interface{}) *gomock.Call
 }
 
 // UpdatePassword mocks base method.
-func (m *MockUserRepo) UpdatePassword(ctx context.Context, id primitive.ObjectID, password string) error {
+func (m *MockUserRepo) UpdatePassword(ctx context.Context, id repo.ID, password string) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "UpdatePassword", ctx, id, password)
 	ret0, _ := ret[0].(error)
@@ -218,7 +159,7 @@ func (mr *MockUserRepoMockRecorder) UpdatePassword(ctx, id, password interface{}
 }
 
 // UpdateUsername mocks base method.
-func (m *MockUserRepo) UpdateUsername(ctx context.Context, id primitive.ObjectID, username string) error {
+func (m *MockUserRepo) UpdateUsername(ctx context.Context, id repo.ID, username string) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "UpdateUsername", ctx, id, username)
 	ret0, _ := ret[0].(error)
@@ -231,6 +172,235 @@ func (mr *MockUserRepoMockRecorder) UpdateUsername(ctx, id, username interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUsername", reflect.TypeOf((*MockUserRepo)(nil).UpdateUsername), ctx, id, username)
 }
 
+// MockDefinitionRepo is a mock of DefinitionRepo interface.
+type MockDefinitionRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockDefinitionRepoMockRecorder
+}
+
+// MockDefinitionRepoMockRecorder is the mock recorder for MockDefinitionRepo.
+type MockDefinitionRepoMockRecorder struct {
+	mock *MockDefinitionRepo
+}
+
+// NewMockDefinitionRepo creates a new mock instance.
+func NewMockDefinitionRepo(ctrl *gomock.Controller) *MockDefinitionRepo {
+	mock := &MockDefinitionRepo{ctrl: ctrl}
+	mock.recorder = &MockDefinitionRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDefinitionRepo) EXPECT() *MockDefinitionRepoMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockDefinitionRepo) Create(ctx context.Context, definition models.Definition) (repo.ID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, definition)
+	ret0, _ := ret[0].(repo.ID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockDefinitionRepoMockRecorder) Create(ctx, definition interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockDefinitionRepo)(nil).Create), ctx, definition)
+}
+
+// Delete mocks base method.
+func (m *MockDefinitionRepo) Delete(ctx context.Context, id repo.ID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockDefinitionRepoMockRecorder) Delete(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockDefinitionRepo)(nil).Delete), ctx, id)
+}
+
+// DeleteByUser mocks base method.
+func (m *MockDefinitionRepo) DeleteByUser(ctx context.Context, userID repo.ID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteByUser", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteByUser indicates an expected call of DeleteByUser.
+func (mr *MockDefinitionRepoMockRecorder) DeleteByUser(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteByUser", reflect.TypeOf((*MockDefinitionRepo)(nil).DeleteByUser), ctx, userID)
+}
+
+// Get mocks base method.
+func (m *MockDefinitionRepo) Get(ctx context.Context, id repo.ID) (*models.Definition, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, id)
+	ret0, _ := ret[0].(*models.Definition)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockDefinitionRepoMockRecorder) Get(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockDefinitionRepo)(nil).Get), ctx, id)
+}
+
+// GetByUser mocks base method.
+func (m *MockDefinitionRepo) GetByUser(ctx context.Context, userID repo.ID) ([]models.Definition, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByUser", ctx, userID)
+	ret0, _ := ret[0].([]models.Definition)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByUser indicates an expected call of GetByUser.
+func (mr *MockDefinitionRepoMockRecorder) GetByUser(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUser", reflect.TypeOf((*MockDefinitionRepo)(nil).GetByUser), ctx, userID)
+}
+
+// Update mocks base method.
+func (m *MockDefinitionRepo) Update(ctx context.Context, definition models.Definition) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, definition)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockDefinitionRepoMockRecorder) Update(ctx, definition interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockDefinitionRepo)(nil).Update), ctx, definition)
+}
+
+// MockRateProvider is a mock of RateProvider interface.
+type MockRateProvider struct {
+	ctrl     *gomock.Controller
+	recorder *MockRateProviderMockRecorder
+}
+
+// MockRateProviderMockRecorder is the mock recorder for MockRateProvider.
+type MockRateProviderMockRecorder struct {
+	mock *MockRateProvider
+}
+
+// NewMockRateProvider creates a new mock instance.
+func NewMockRateProvider(ctrl *gomock.Controller) *MockRateProvider {
+	mock := &MockRateProvider{ctrl: ctrl}
+	mock.recorder = &MockRateProviderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRateProvider) EXPECT() *MockRateProviderMockRecorder {
+	return m.recorder
+}
+
+// GetRates mocks base method.
+func (m *MockRateProvider) GetRates(ctx context.Context, codes []string) (map[string]float64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRates", ctx, codes)
+	ret0, _ := ret[0].(map[string]float64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRates indicates an expected call of GetRates.
+func (mr *MockRateProviderMockRecorder) GetRates(ctx, codes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRates", reflect.TypeOf((*MockRateProvider)(nil).GetRates), ctx, codes)
+}
+
+// MockRateRepo is a mock of RateRepo interface.
+type MockRateRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockRateRepoMockRecorder
+}
+
+// MockRateRepoMockRecorder is the mock recorder for MockRateRepo.
+type MockRateRepoMockRecorder struct {
+	mock *MockRateRepo
+}
+
+// NewMockRateRepo creates a new mock instance.
+func NewMockRateRepo(ctrl *gomock.Controller) *MockRateRepo {
+	mock := &MockRateRepo{ctrl: ctrl}
+	mock.recorder = &MockRateRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRateRepo) EXPECT() *MockRateRepoMockRecorder {
+	return m.recorder
+}
+
+// Delete mocks base method.
+func (m *MockRateRepo) Delete(ctx context.Context, code string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockRateRepoMockRecorder) Delete(ctx, code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockRateRepo)(nil).Delete), ctx, code)
+}
+
+// GetAll mocks base method.
+func (m *MockRateRepo) GetAll(ctx context.Context) ([]models.Rate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", ctx)
+	ret0, _ := ret[0].([]models.Rate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAll indicates an expected call of GetAll.
+func (mr *MockRateRepoMockRecorder) GetAll(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockRateRepo)(nil).GetAll), ctx)
+}
+
+// GetRates mocks base method.
+func (m *MockRateRepo) GetRates(ctx context.Context, codes []string) (map[string]float64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRates", ctx, codes)
+	ret0, _ := ret[0].(map[string]float64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRates indicates an expected call of GetRates.
+func (mr *MockRateRepoMockRecorder) GetRates(ctx, codes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRates", reflect.TypeOf((*MockRateRepo)(nil).GetRates), ctx, codes)
+}
+
+// Set mocks base method.
+func (m *MockRateRepo) Set(ctx context.Context, rate models.Rate) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Set", ctx, rate)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Set indicates an expected call of Set.
+func (mr *MockRateRepoMockRecorder) Set(ctx, rate interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockRateRepo)(nil).Set), ctx, rate)
+}
+
 // MockExpressionRepo is a mock of ExpressionRepo interface.
 type MockExpressionRepo struct {
 	ctrl     *gomock.Controller
@@ -254,11 +424,25 @@ func (m *MockExpressionRepo) EXPECT() *MockExpressionRepoMockRecorder {
 	return m.recorder
 }
 
+// AddWarning mocks base method.
+func (m *MockExpressionRepo) AddWarning(ctx context.Context, nodeId repo.ID, code string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddWarning", ctx, nodeId, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddWarning indicates an expected call of AddWarning.
+func (mr *MockExpressionRepoMockRecorder) AddWarning(ctx, nodeId, code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddWarning", reflect.TypeOf((*MockExpressionRepo)(nil).AddWarning), ctx, nodeId, code)
+}
+
 // Create mocks base method.
-func (m *MockExpressionRepo) Create(ctx context.Context, expression models.Expression, ast tree.Ast) (primitive.ObjectID, error) {
+func (m *MockExpressionRepo) Create(ctx context.Context, expression models.Expression, ast tree.Ast) (repo.ID, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Create", ctx, expression, ast)
-	ret0, _ := ret[0].(primitive.ObjectID)
+	ret0, _ := ret[0].(repo.ID)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -270,7 +454,7 @@ func (mr *MockExpressionRepoMockRecorder) Create(ctx, expression, ast interface{
 }
 
 // Delete mocks base method.
-func (m *MockExpressionRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
+func (m *MockExpressionRepo) Delete(ctx context.Context, id repo.ID) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Delete", ctx, id)
 	ret0, _ := ret[0].(error)
@@ -284,7 +468,7 @@ func (mr *MockExpressionRepoMockRecorder) Delete(ctx, id interface{}) *gomock.Ca
 }
 
 // DeleteByUser mocks base method.
-func (m *MockExpressionRepo) DeleteByUser(ctx context.Context, userID primitive.ObjectID) error {
+func (m *MockExpressionRepo) DeleteByUser(ctx context.Context, userID repo.ID) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "DeleteByUser", ctx, userID)
 	ret0, _ := ret[0].(error)
@@ -298,7 +482,7 @@ func (mr *MockExpressionRepoMockRecorder) DeleteByUser(ctx, userID interface{})
 }
 
 // Get mocks base method.
-func (m *MockExpressionRepo) Get(ctx context.Context, id primitive.ObjectID) (*models.Expression, error) {
+func (m *MockExpressionRepo) Get(ctx context.Context, id repo.ID) (*models.Expression, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Get", ctx, id)
 	ret0, _ := ret[0].(*models.Expression)
@@ -313,7 +497,7 @@ func (mr *MockExpressionRepoMockRecorder) Get(ctx, id interface{}) *gomock.Call
 }
 
 // GetByUser mocks base method.
-func (m *MockExpressionRepo) GetByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Expression, error) {
+func (m *MockExpressionRepo) GetByUser(ctx context.Context, userID repo.ID) ([]models.Expression, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetByUser", ctx, userID)
 	ret0, _ := ret[0].([]models.Expression)
@@ -327,20 +511,6 @@ func (mr *MockExpressionRepoMockRecorder) GetByUser(ctx, userID interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUser", reflect.TypeOf((*MockExpressionRepo)(nil).GetByUser), ctx, userID)
 }
 
-// GetCollection mocks base method.
-func (m *MockExpressionRepo) GetCollection() *mongo.Collection {
-	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetCollection")
-	ret0, _ := ret[0].(*mongo.Collection)
-	return ret0
-}
-
-// GetCollection indicates an expected call of GetCollection.
-func (mr *MockExpressionRepoMockRecorder) GetCollection() *gomock.Call {
-	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCollection", reflect.TypeOf((*MockExpressionRepo)(nil).GetCollection))
-}
-
 // GetFitNodes mocks base method.
 func (m *MockExpressionRepo) GetFitNodes(ctx context.Context) ([]stream.Task, error) {
 	m.ctrl.T.Helper()
@@ -357,7 +527,7 @@ func (mr *MockExpressionRepoMockRecorder) GetFitNodes(ctx interface{}) *gomock.C
 }
 
 // GetNode mocks base method.
-func (m *MockExpressionRepo) GetNode(ctx context.Context, id primitive.ObjectID) (*models.Node, error) {
+func (m *MockExpressionRepo) GetNode(ctx context.Context, id repo.ID) (*models.Node, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetNode", ctx, id)
 	ret0, _ := ret[0].(*models.Node)
@@ -371,18 +541,19 @@ func (mr *MockExpressionRepoMockRecorder) GetNode(ctx, id interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNode", reflect.TypeOf((*MockExpressionRepo)(nil).GetNode), ctx, id)
 }
 
-// GetNodeCollection mocks base method.
-func (m *MockExpressionRepo) GetNodeCollection() *mongo.Collection {
+// GetNodes mocks base method.
+func (m *MockExpressionRepo) GetNodes(ctx context.Context, id repo.ID) ([]models.Node, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetNodeCollection")
-	ret0, _ := ret[0].(*mongo.Collection)
-	return ret0
+	ret := m.ctrl.Call(m, "GetNodes", ctx, id)
+	ret0, _ := ret[0].([]models.Node)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// GetNodeCollection indicates an expected call of GetNodeCollection.
-func (mr *MockExpressionRepoMockRecorder) GetNodeCollection() *gomock.Call {
+// GetNodes indicates an expected call of GetNodes.
+func (mr *MockExpressionRepoMockRecorder) GetNodes(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNodeCollection", reflect.TypeOf((*MockExpressionRepo)(nil).GetNodeCollection))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNodes", reflect.TypeOf((*MockExpressionRepo)(nil).GetNodes), ctx, id)
 }
 
 // SetCallback mocks base method.
@@ -397,22 +568,50 @@ func (mr *MockExpressionRepoMockRecorder) SetCallback(ctx, callback interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCallback", reflect.TypeOf((*MockExpressionRepo)(nil).SetCallback), ctx, callback)
 }
 
+// SetToComplex mocks base method.
+func (m *MockExpressionRepo) SetToComplex(ctx context.Context, nodeId repo.ID, result models.Complex) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetToComplex", ctx, nodeId, result)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetToComplex indicates an expected call of SetToComplex.
+func (mr *MockExpressionRepoMockRecorder) SetToComplex(ctx, nodeId, result interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetToComplex", reflect.TypeOf((*MockExpressionRepo)(nil).SetToComplex), ctx, nodeId, result)
+}
+
+// SetToDecimal mocks base method.
+func (m *MockExpressionRepo) SetToDecimal(ctx context.Context, nodeId repo.ID, result float64, decimal string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetToDecimal", ctx, nodeId, result, decimal)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetToDecimal indicates an expected call of SetToDecimal.
+func (mr *MockExpressionRepoMockRecorder) SetToDecimal(ctx, nodeId, result, decimal interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetToDecimal", reflect.TypeOf((*MockExpressionRepo)(nil).SetToDecimal), ctx, nodeId, result, decimal)
+}
+
 // SetToError mocks base method.
-func (m *MockExpressionRepo) SetToError(ctx context.Context, id primitive.ObjectID, err string) error {
+func (m *MockExpressionRepo) SetToError(ctx context.Context, id repo.ID, code, err string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SetToError", ctx, id, err)
+	ret := m.ctrl.Call(m, "SetToError", ctx, id, code, err)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // SetToError indicates an expected call of SetToError.
-func (mr *MockExpressionRepoMockRecorder) SetToError(ctx, id, err interface{}) *gomock.Call {
+func (mr *MockExpressionRepoMockRecorder) SetToError(ctx, id, code, err interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetToError", reflect.TypeOf((*MockExpressionRepo)(nil).SetToError), ctx, id, err)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetToError", reflect.TypeOf((*MockExpressionRepo)(nil).SetToError), ctx, id, code, err)
 }
 
 // SetToNum mocks base method.
-func (m *MockExpressionRepo) SetToNum(ctx context.Context, nodeId primitive.ObjectID, result float64) error {
+func (m *MockExpressionRepo) SetToNum(ctx context.Context, nodeId repo.ID, result float64) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "SetToNum", ctx, nodeId, result)
 	ret0, _ := ret[0].(error)
@@ -424,3 +623,31 @@ func (mr *MockExpressionRepoMockRecorder) SetToNum(ctx, nodeId, result interface
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetToNum", reflect.TypeOf((*MockExpressionRepo)(nil).SetToNum), ctx, nodeId, result)
 }
+
+// SetToRational mocks base method.
+func (m *MockExpressionRepo) SetToRational(ctx context.Context, nodeId repo.ID, result float64, rational string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetToRational", ctx, nodeId, result, rational)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetToRational indicates an expected call of SetToRational.
+func (mr *MockExpressionRepoMockRecorder) SetToRational(ctx, nodeId, result, rational interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetToRational", reflect.TypeOf((*MockExpressionRepo)(nil).SetToRational), ctx, nodeId, result, rational)
+}
+
+// SetToUnit mocks base method.
+func (m *MockExpressionRepo) SetToUnit(ctx context.Context, nodeId repo.ID, result float64, unit string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetToUnit", ctx, nodeId, result, unit)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetToUnit indicates an expected call of SetToUnit.
+func (mr *MockExpressionRepoMockRecorder) SetToUnit(ctx, nodeId, result, unit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetToUnit", reflect.TypeOf((*MockExpressionRepo)(nil).SetToUnit), ctx, nodeId, result, unit)
+}
@@ -0,0 +1,66 @@
+// This package saves the ids of the models as the object ids of mongo, so the mongo repos keep their documents
+package mongoid
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/vandi37/Calculator/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var idType = reflect.TypeOf(models.ID{})
+
+// The registry of the mongo repos, it has the codec of the ids
+var Registry = newRegistry()
+
+func newRegistry() *bsoncodec.Registry {
+	registry := bson.NewRegistry()
+	registry.RegisterTypeEncoder(idType, bsoncodec.ValueEncoderFunc(encode))
+	registry.RegisterTypeDecoder(idType, bsoncodec.ValueDecoderFunc(decode))
+	return registry
+}
+
+// The options of the collections of the mongo repos
+func Collection() *options.CollectionOptions {
+	return options.Collection().SetRegistry(Registry)
+}
+
+func encode(_ bsoncodec.EncodeContext, vw bsonrw.ValueWriter, val reflect.Value) error {
+	if !val.IsValid() || val.Type() != idType {
+		return bsoncodec.ValueEncoderError{Name: "mongoid.encode", Types: []reflect.Type{idType}, Received: val}
+	}
+	return vw.WriteObjectID(primitive.ObjectID(val.Interface().(models.ID)))
+}
+
+// Missing ids are read as null
+func decode(_ bsoncodec.DecodeContext, vr bsonrw.ValueReader, val reflect.Value) error {
+	if !val.CanSet() || val.Type() != idType {
+		return bsoncodec.ValueDecoderError{Name: "mongoid.decode", Types: []reflect.Type{idType}, Received: val}
+	}
+	var id primitive.ObjectID
+	switch vr.Type() {
+	case bsontype.ObjectID:
+		var err error
+		if id, err = vr.ReadObjectID(); err != nil {
+			return err
+		}
+	case bsontype.Null:
+		if err := vr.ReadNull(); err != nil {
+			return err
+		}
+	case bsontype.Undefined:
+		if err := vr.ReadUndefined(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("cannot decode %v into an id", vr.Type())
+	}
+	val.Set(reflect.ValueOf(models.ID(id)))
+	return nil
+}
@@ -0,0 +1,188 @@
+// This package has the parts of the expression storage that don't depend on the database:
+// the nodes of the tree, the tasks for the agents and the values of the calculated nodes
+package nodetree
+
+import (
+	"math/big"
+	"time"
+
+	pb "github.com/vandi37/Calculator-Models"
+	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/internal/repo"
+	"github.com/vandi37/Calculator/internal/status"
+	"github.com/vandi37/Calculator/pkg/parsing/reduce"
+	"github.com/vandi37/Calculator/pkg/parsing/tree"
+)
+
+// Builds the nodes of the expression with new ids, the operands are before their operations and the root is the last.
+// If the whole expression is a number, its node is returned instead
+func Build(expr tree.ExpressionType) (*models.Node, []models.Node, error) {
+	num, err := Number(expr)
+	if err != nil || num != nil {
+		return num, nil, err
+	}
+	var b builder
+	if _, err := b.add(expr, models.NilID); err != nil {
+		return nil, nil, err
+	}
+	return nil, b, nil
+}
+
+type builder []models.Node
+
+// Held is the conditional node that keeps the nodes, it is nil outside of the branches
+func (b *builder) add(expr tree.ExpressionType, held repo.ID) (repo.ID, error) {
+	if expr == nil {
+		return models.NilID, repo.InvalidExpression
+	}
+	if node, err := Number(expr); err != nil {
+		return models.NilID, err
+	} else if node != nil {
+		node.ID = models.NewID()
+		node.Held = held
+		*b = append(*b, *node)
+		return node.ID, nil
+	}
+
+	switch v := expr.(type) {
+	case tree.Expression:
+		return b.operation(v.Operation, held, v.Left, v.Right)
+	case tree.Unary:
+		return b.operation(v.Operation, held, v.Value)
+	case tree.Call:
+		// Aggregates and long calls are saved as balanced trees, so the agents calculate them in parallel
+		if expanded, ok := reduce.Expand(v); ok {
+			return b.add(expanded, held)
+		}
+		switch v.Operation {
+		case tree.Conditional:
+			return b.conditional(v, held)
+		case tree.Median:
+			return b.median(v, held)
+		}
+		if len(v.Args) == 0 || len(v.Args) > 2 {
+			return models.NilID, repo.InvalidExpression
+		}
+		return b.operation(v.Operation, held, v.Args...)
+	default:
+		return models.NilID, repo.InvalidExpression
+	}
+}
+
+// Unary operations have one operand
+func (b *builder) operation(operation tree.Operation, held repo.ID, operands ...tree.ExpressionType) (repo.ID, error) {
+	node := Operation(held, models.TreeNode{Operator: pb.Operation(operation)})
+	ids := make([]repo.ID, len(operands))
+	for i, operand := range operands {
+		id, err := b.add(operand, held)
+		if err != nil {
+			return models.NilID, err
+		}
+		ids[i] = id
+	}
+	node.Tree.Left = ids[0]
+	if len(ids) == 2 {
+		node.Tree.Right = ids[1]
+	}
+	*b = append(*b, node)
+	return node.ID, nil
+}
+
+// The branches are held by the conditional node, so they aren't sent to agents before the condition is calculated
+func (b *builder) conditional(c tree.Call, held repo.ID) (repo.ID, error) {
+	if len(c.Args) != 3 {
+		return models.NilID, repo.InvalidExpression
+	}
+	node := Operation(held, models.TreeNode{Operator: pb.Operation(tree.Conditional)})
+	condId, err := b.add(c.Args[0], held)
+	if err != nil {
+		return models.NilID, err
+	}
+	thenId, err := b.add(c.Args[1], node.ID)
+	if err != nil {
+		return models.NilID, err
+	}
+	elseId, err := b.add(c.Args[2], node.ID)
+	if err != nil {
+		return models.NilID, err
+	}
+	node.Tree.Cond, node.Tree.Left, node.Tree.Right = condId, thenId, elseId
+	*b = append(*b, node)
+	return node.ID, nil
+}
+
+// The items are calculated by the agents as usual, the median node waits for all of them
+func (b *builder) median(c tree.Call, held repo.ID) (repo.ID, error) {
+	if len(c.Args) == 0 {
+		return models.NilID, repo.InvalidExpression
+	}
+	node := Operation(held, models.TreeNode{Operator: pb.Operation(tree.Median)})
+	for _, arg := range c.Args {
+		id, err := b.add(arg, held)
+		if err != nil {
+			return models.NilID, err
+		}
+		node.Tree.Items = append(node.Tree.Items, id)
+	}
+	*b = append(*b, node)
+	return node.ID, nil
+}
+
+// New operation node with a new id
+func Operation(held repo.ID, t models.TreeNode) models.Node {
+	return models.Node{ID: models.NewID(), Type: models.Operation, Held: held, Tree: &t}
+}
+
+// The node of the number, nil is returned for other expressions
+func Number(expr tree.ExpressionType) (*models.Node, error) {
+	switch v := expr.(type) {
+	case tree.Num:
+		var num = float64(v)
+		return &models.Node{Type: models.Number, Number: &num}, nil
+	case tree.Decimal:
+		num, ok := tree.Approximate(string(v))
+		if !ok {
+			return nil, repo.InvalidExpression
+		}
+		return &models.Node{Type: models.Number, Number: &num, Decimal: string(v)}, nil
+	case tree.Rational:
+		rational, ok := new(big.Rat).SetString(string(v))
+		if !ok {
+			return nil, repo.InvalidExpression
+		}
+		num, _ := tree.Approximate(string(v))
+		return &models.Node{Type: models.Number, Number: &num, Rational: rational.RatString()}, nil
+	case tree.Complex:
+		var re = v.Re
+		return &models.Node{Type: models.Number, Number: &re, Complex: &models.Complex{Re: v.Re, Im: v.Im}}, nil
+	case tree.Quantity:
+		var value = v.Value
+		return &models.Node{Type: models.Number, Number: &value, Unit: v.Dimension.String()}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// The expression is finished if it is the number, otherwise it waits for the root node
+func Prepare(expression *models.Expression, num *models.Node, root repo.ID) {
+	if num != nil {
+		expression.NodeID = models.NilID
+		expression.Result = num.Number
+		expression.Decimal = num.Decimal
+		expression.Rational = num.Rational
+		expression.Complex = num.Complex
+		expression.Unit = num.Unit
+		expression.Error = ""
+		expression.Status = status.Finished
+	} else {
+		expression.NodeID = root
+		expression.Error = ""
+		expression.Result = nil
+		expression.Decimal = ""
+		expression.Rational = ""
+		expression.Complex = nil
+		expression.Unit = ""
+		expression.Status = status.Pending
+	}
+	expression.CreatedAt = time.Now()
+}
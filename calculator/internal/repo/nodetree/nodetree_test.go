@@ -0,0 +1,161 @@
+package nodetree_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	pb "github.com/vandi37/Calculator-Models"
+	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/internal/repo"
+	"github.com/vandi37/Calculator/internal/repo/nodetree"
+	"github.com/vandi37/Calculator/internal/status"
+	"github.com/vandi37/Calculator/pkg/parsing/parser"
+	"github.com/vandi37/Calculator/pkg/parsing/tree"
+	"github.com/vandi37/Calculator/pkg/wire"
+)
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		name, expression string
+		nodes            int
+		root             pb.Operation
+	}{
+		{"Addition", "1 + 2", 3, pb.Operation_ADD},
+		{"Nested", "(1 + 2) * (3 - 4)", 7, pb.Operation_MULTIPLY},
+		{"Unary Function", "sqrt(16)", 2, pb.Operation(tree.Sqrt)},
+		{"Variadic Function", "max(1, 2, 3)", 5, pb.Operation(tree.Max)},
+		{"Median", "median([3, 1, 2 + 5])", 6, pb.Operation(tree.Median)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, err := parser.Build(tt.expression)
+			require.NoError(t, err)
+			num, nodes, err := nodetree.Build(ast.Expression)
+			require.NoError(t, err)
+			assert.Nil(t, num)
+			require.Len(t, nodes, tt.nodes)
+
+			root := nodes[len(nodes)-1]
+			assert.Equal(t, models.Operation, root.Type)
+			assert.Equal(t, tt.root, root.Tree.Operator)
+
+			// The operands are before their operations
+			seen := map[repo.ID]bool{}
+			for _, node := range nodes {
+				assert.NotEqual(t, models.NilID, node.ID)
+				if node.Tree != nil {
+					for _, child := range node.Tree.Children() {
+						assert.True(t, seen[child])
+					}
+				}
+				seen[node.ID] = true
+			}
+		})
+	}
+
+	t.Run("Number", func(t *testing.T) {
+		num, nodes, err := nodetree.Build(tree.Rational("1/3"))
+		require.NoError(t, err)
+		assert.Empty(t, nodes)
+		require.NotNil(t, num)
+		assert.Equal(t, "1/3", num.Rational)
+	})
+
+	t.Run("Decimal out of the float range", func(t *testing.T) {
+		num, _, err := nodetree.Build(tree.Decimal("1e400"))
+		require.NoError(t, err)
+		require.NotNil(t, num)
+		assert.Equal(t, "1e400", num.Decimal)
+		assert.Equal(t, math.MaxFloat64, *num.Number)
+	})
+
+	t.Run("Conditional", func(t *testing.T) {
+		ast, err := parser.Build("1 < 2 ? 3 + 4 : 5")
+		require.NoError(t, err)
+		_, nodes, err := nodetree.Build(ast.Expression)
+		require.NoError(t, err)
+		require.Len(t, nodes, 8)
+		root := nodes[len(nodes)-1]
+		assert.Equal(t, pb.Operation(tree.Conditional), root.Tree.Operator)
+		held := 0
+		for _, node := range nodes {
+			if node.Held == root.ID {
+				held++
+			}
+		}
+		// 3 + 4 and 5, the condition isn't held
+		assert.Equal(t, 4, held)
+	})
+
+	t.Run("Invalid expression", func(t *testing.T) {
+		_, _, err := nodetree.Build(tree.Expression{Left: tree.Num(1), Operation: tree.Operation(pb.Operation_ADD)})
+		assert.ErrorIs(t, err, repo.InvalidExpression)
+	})
+}
+
+func TestPrepare(t *testing.T) {
+	five := 5.0
+	var expression models.Expression
+	nodetree.Prepare(&expression, &models.Node{Type: models.Number, Number: &five, Unit: "m"}, models.NilID)
+	assert.Equal(t, status.Finished, expression.Status)
+	assert.Equal(t, &five, expression.Result)
+	assert.Equal(t, "m", expression.Unit)
+	assert.False(t, expression.CreatedAt.IsZero())
+
+	root := models.NewID()
+	nodetree.Prepare(&expression, nil, root)
+	assert.Equal(t, status.Pending, expression.Status)
+	assert.Nil(t, expression.Result)
+	assert.Empty(t, expression.Unit)
+	assert.Equal(t, root, expression.NodeID)
+}
+
+func number(value float64) models.Node {
+	return models.Node{ID: models.NewID(), Type: models.Number, Number: &value}
+}
+
+func TestMiddle(t *testing.T) {
+	odd := []models.Node{number(3), number(1), number(7)}
+	middle := nodetree.Middle(odd)
+	require.Len(t, middle, 1)
+	assert.Equal(t, 3.0, *middle[0].Number)
+
+	even := []models.Node{number(10), number(4), number(1), number(3)}
+	middle = nodetree.Middle(even)
+	require.Len(t, middle, 2)
+	assert.Equal(t, 3.0, *middle[0].Number)
+	assert.Equal(t, 4.0, *middle[1].Number)
+}
+
+func TestIsTrue(t *testing.T) {
+	assert.True(t, nodetree.IsTrue(number(2)))
+	assert.False(t, nodetree.IsTrue(number(0)))
+	zero := number(0)
+	zero.Complex = &models.Complex{Im: 1}
+	assert.True(t, nodetree.IsTrue(zero))
+	zero = number(0.1)
+	zero.Rational = "0/1"
+	assert.False(t, nodetree.IsTrue(zero))
+}
+
+func TestSetTask(t *testing.T) {
+	left := number(1.5)
+	left.Unit = "m"
+	result := models.AggregatedNode{ID: models.NewID(), LeftNode: nodetree.OperandOf(left), RightNode: nodetree.OperandOf(number(2))}
+	result.Tree.Operator = pb.Operation_MULTIPLY
+
+	var task pb.Task
+	nodetree.SetTask(&task, result)
+	assert.Equal(t, result.ID.Hex(), task.Id)
+	assert.Equal(t, pb.Operation_MULTIPLY, task.Operation)
+	assert.Equal(t, 1.5, task.Arg1)
+	assert.Equal(t, 2.0, task.Arg2)
+	unit, ok := wire.GetString(&task, wire.Arg1Unit)
+	assert.True(t, ok)
+	assert.Equal(t, "m", unit)
+	_, ok = wire.GetString(&task, wire.Arg1Rational)
+	assert.False(t, ok)
+}
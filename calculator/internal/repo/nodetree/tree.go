@@ -0,0 +1,260 @@
+package nodetree
+
+import (
+	"slices"
+	"time"
+
+	pb "github.com/vandi37/Calculator-Models"
+	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/internal/repo"
+	"github.com/vandi37/Calculator/internal/status"
+	"github.com/vandi37/Calculator/pkg/parsing/tree"
+)
+
+// Expression with its whole tree, the nodes are a flat array and reference each other by their ids
+type Tree struct {
+	models.Expression `bson:",inline"`
+	Nodes             []PendingNode `bson:"nodes,omitempty"`
+}
+
+// Pending is the number of the operands that aren't numbers yet, the node is ready with zero.
+// The branches aren't counted, the conditional waits only for its condition
+type PendingNode struct {
+	models.Node `bson:",inline"`
+	Pending     int `bson:"pending"`
+}
+
+// Counts the operands that aren't numbers yet, the conditional counts only its condition
+func WithPending(nodes []models.Node) []PendingNode {
+	operations := map[repo.ID]bool{}
+	for _, node := range nodes {
+		operations[node.ID] = node.Type == models.Operation
+	}
+	result := make([]PendingNode, len(nodes))
+	for i, node := range nodes {
+		result[i].Node = node
+		if node.Tree == nil {
+			continue
+		}
+		operands := node.Tree.Children()
+		if node.Tree.Cond != models.NilID {
+			operands = []repo.ID{node.Tree.Cond}
+		}
+		for _, id := range operands {
+			if operations[id] {
+				result[i].Pending++
+			}
+		}
+	}
+	return result
+}
+
+// The node with the nodes under it, level by level like the nodes collection
+func (d *Tree) Subtree(id repo.ID) []models.Node {
+	nodes := []models.Node{}
+	for level := []repo.ID{id}; len(level) > 0; {
+		var next []repo.ID
+		for _, id := range level {
+			if i := d.Find(id); i >= 0 {
+				nodes = append(nodes, d.Nodes[i].Node)
+				if d.Nodes[i].Tree != nil {
+					next = append(next, d.Nodes[i].Tree.Children()...)
+				}
+			}
+		}
+		level = next
+	}
+	return nodes
+}
+
+// The operands of the node if it can be sent to the agents
+func (d *Tree) Operands(node PendingNode, sendedBefore time.Time) (models.Operand, models.Operand, bool) {
+	if node.Type != models.Operation || node.Tree == nil || node.Pending != 0 || node.Held != models.NilID {
+		return models.Operand{}, models.Operand{}, false
+	}
+	if tree.Operation(node.Tree.Operator) == tree.Conditional || tree.Operation(node.Tree.Operator) == tree.Median {
+		return models.Operand{}, models.Operand{}, false
+	}
+	if node.SendedAt != nil && !node.SendedAt.Before(sendedBefore) {
+		return models.Operand{}, models.Operand{}, false
+	}
+	left, ok := d.operand(node.Tree.Left)
+	if !ok {
+		return models.Operand{}, models.Operand{}, false
+	}
+	// Unary operations don't have the right node
+	if node.Tree.IsUnary() {
+		return left, models.Operand{}, true
+	}
+	right, ok := d.operand(node.Tree.Right)
+	return left, right, ok
+}
+
+func (d *Tree) operand(id repo.ID) (models.Operand, bool) {
+	i := d.Find(id)
+	if i < 0 || d.Nodes[i].Type != models.Number || d.Nodes[i].Number == nil {
+		return models.Operand{}, false
+	}
+	return OperandOf(d.Nodes[i].Node), true
+}
+
+// Resolves the ready conditionals and medians in the tree, returns if it was changed
+func (d *Tree) Resolve() bool {
+	changed := false
+	for d.Status == status.Pending {
+		i := slices.IndexFunc(d.Nodes, func(node PendingNode) bool {
+			return node.Tree != nil && node.Pending == 0 && node.Held == models.NilID &&
+				(tree.Operation(node.Tree.Operator) == tree.Conditional || tree.Operation(node.Tree.Operator) == tree.Median)
+		})
+		if i < 0 {
+			break
+		}
+		var ok bool
+		if tree.Operation(d.Nodes[i].Tree.Operator) == tree.Conditional {
+			ok = d.resolveConditional(d.Nodes[i].Node)
+		} else {
+			ok = d.resolveMedian(d.Nodes[i].Node)
+		}
+		if !ok {
+			break
+		}
+		changed = true
+	}
+	return changed
+}
+
+// The chosen branch takes the place of the conditional node, the condition and the other branch are deleted
+func (d *Tree) resolveConditional(node models.Node) bool {
+	c := d.Find(node.Tree.Cond)
+	if c < 0 || d.Nodes[c].Type != models.Number {
+		return false
+	}
+	chosen, other := node.Tree.Left, node.Tree.Right
+	if !IsTrue(d.Nodes[c].Node) {
+		chosen, other = other, chosen
+	}
+	d.removeTree(other)
+	d.removeTree(node.Tree.Cond)
+	for i := range d.Nodes {
+		if d.Nodes[i].Held == node.ID {
+			d.Nodes[i].Held = models.NilID
+		}
+	}
+
+	b := d.Find(chosen)
+	if b < 0 {
+		return false
+	}
+	branch := d.Nodes[b]
+	d.remove(chosen)
+	if branch.Type == models.Number {
+		// The conditional becomes the number, it can be the result of the expression
+		d.SetNumber(node.ID, branch.Node)
+		return true
+	}
+	branch.ID = node.ID
+	d.Nodes[d.Find(node.ID)] = branch
+	// The branch of a nested conditional is held by its new id
+	for i := range d.Nodes {
+		if d.Nodes[i].Held == chosen {
+			d.Nodes[i].Held = node.ID
+		}
+	}
+	return true
+}
+
+// The middle item takes the place of the median node, the other items are deleted.
+// With an even number of items the node becomes (a + b) / 2 for the agents
+func (d *Tree) resolveMedian(node models.Node) bool {
+	items := make([]models.Node, len(node.Tree.Items))
+	for i, id := range node.Tree.Items {
+		j := d.Find(id)
+		if j < 0 || d.Nodes[j].Type != models.Number {
+			return false
+		}
+		items[i] = d.Nodes[j].Node
+	}
+	middle := Middle(items)
+	for _, item := range items {
+		if item.ID != middle[0].ID && item.ID != middle[len(middle)-1].ID {
+			d.remove(item.ID)
+		}
+	}
+
+	if len(middle) == 1 {
+		d.remove(middle[0].ID)
+		// The median becomes the number, it can be the result of the expression
+		d.SetNumber(node.ID, middle[0])
+		return true
+	}
+	sum := PendingNode{Node: Operation(node.Held, models.TreeNode{Operator: pb.Operation_ADD, Left: middle[0].ID, Right: middle[1].ID})}
+	count := 2.0
+	two := PendingNode{Node: models.Node{ID: models.NewID(), Type: models.Number, Held: node.Held, Number: &count}}
+	d.Nodes = append(d.Nodes, sum, two)
+	m := d.Find(node.ID)
+	d.Nodes[m].Tree = &models.TreeNode{Operator: pb.Operation_DIVIDE, Left: sum.ID, Right: two.ID}
+	d.Nodes[m].Pending = 1
+	return true
+}
+
+// The node gets the value of the number, the root finishes the expression
+func (d *Tree) SetNumber(id repo.ID, value models.Node) {
+	if id == d.NodeID {
+		d.Status = status.Finished
+		d.Result = value.Number
+		d.Decimal = value.Decimal
+		d.Rational = value.Rational
+		d.Complex = value.Complex
+		d.Unit = value.Unit
+		d.NodeID = models.NilID
+		d.Nodes = nil
+		return
+	}
+	i := d.Find(id)
+	node := &d.Nodes[i]
+	node.Type = models.Number
+	node.Tree = nil
+	node.Pending = 0
+	node.Number = value.Number
+	node.Decimal = value.Decimal
+	node.Rational = value.Rational
+	node.Complex = value.Complex
+	node.Unit = value.Unit
+	// The conditional waits only for its condition, the branches are held
+	if p := d.parent(id); p >= 0 && (tree.Operation(d.Nodes[p].Tree.Operator) != tree.Conditional || d.Nodes[p].Tree.Cond == id) {
+		d.Nodes[p].Pending--
+	}
+}
+
+// The index of the node, -1 if the tree doesn't have it
+func (d *Tree) Find(id repo.ID) int {
+	return slices.IndexFunc(d.Nodes, func(node PendingNode) bool { return node.ID == id })
+}
+
+func (d *Tree) parent(id repo.ID) int {
+	return slices.IndexFunc(d.Nodes, func(node PendingNode) bool {
+		return node.Tree != nil && slices.Contains(node.Tree.Children(), id)
+	})
+}
+
+// Deletes only the node
+func (d *Tree) remove(id repo.ID) {
+	if i := d.Find(id); i >= 0 {
+		d.Nodes = append(d.Nodes[:i], d.Nodes[i+1:]...)
+	}
+}
+
+// Deletes the node with all nodes under it
+func (d *Tree) removeTree(id repo.ID) {
+	i := d.Find(id)
+	if i < 0 {
+		return
+	}
+	node := d.Nodes[i]
+	d.remove(id)
+	if node.Tree != nil {
+		for _, child := range node.Tree.Children() {
+			d.removeTree(child)
+		}
+	}
+}
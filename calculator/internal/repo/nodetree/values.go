@@ -0,0 +1,114 @@
+package nodetree
+
+import (
+	"math/big"
+	"slices"
+	"strconv"
+
+	pb "github.com/vandi37/Calculator-Models"
+	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/pkg/parsing/tree"
+	"github.com/vandi37/Calculator/pkg/wire"
+)
+
+// Fills the task of the operation with its calculated operands, the exact values are sent as the extensions
+func SetTask(task *pb.Task, result models.AggregatedNode) {
+	task.Id = result.ID.Hex()
+	task.Arg1 = result.LeftNode.Number
+	task.Arg2 = result.RightNode.Number
+	task.Operation = pb.Operation(result.Tree.Operator)
+	// The exact arguments are sent if one of the operands is a decimal
+	if result.LeftNode.Decimal != "" || result.RightNode.Decimal != "" {
+		wire.SetString(task, wire.Arg1Decimal, decimalString(result.LeftNode.Decimal, result.LeftNode.Number))
+		wire.SetString(task, wire.Arg2Decimal, decimalString(result.RightNode.Decimal, result.RightNode.Number))
+	}
+	if result.LeftNode.Rational != "" || result.RightNode.Rational != "" {
+		wire.SetString(task, wire.Arg1Rational, rationalString(result.LeftNode.Rational, result.LeftNode.Number))
+		wire.SetString(task, wire.Arg2Rational, rationalString(result.RightNode.Rational, result.RightNode.Number))
+	}
+	if result.LeftNode.Complex != nil || result.RightNode.Complex != nil {
+		wire.SetDouble(task, wire.Arg1Imag, imagPart(result.LeftNode.Complex))
+		wire.SetDouble(task, wire.Arg2Imag, imagPart(result.RightNode.Complex))
+	}
+	// A plain number has the empty unit
+	if result.LeftNode.Unit != "" || result.RightNode.Unit != "" {
+		wire.SetString(task, wire.Arg1Unit, result.LeftNode.Unit)
+		wire.SetString(task, wire.Arg2Unit, result.RightNode.Unit)
+	}
+}
+
+// Sorts the items and returns the middle one, or the two middle ones for an even number of items
+func Middle(items []models.Node) []models.Node {
+	slices.SortStableFunc(items, func(a, b models.Node) int {
+		return exactValue(a).Cmp(exactValue(b))
+	})
+	half := len(items) / 2
+	if len(items)%2 == 0 {
+		return items[half-1 : half+1]
+	}
+	return items[half : half+1]
+}
+
+// The exact value of the number node, the float is used in the float precision
+func exactValue(node models.Node) *big.Rat {
+	switch {
+	case node.Rational != "":
+		if r, ok := new(big.Rat).SetString(node.Rational); ok {
+			return r
+		}
+	case node.Decimal != "":
+		if r, ok := new(big.Rat).SetString(node.Decimal); ok {
+			return r
+		}
+	}
+	r, _ := new(big.Rat).SetString(strconv.FormatFloat(*node.Number, 'g', -1, 64))
+	return r
+}
+
+// Any number except zero is true
+func IsTrue(node models.Node) bool {
+	switch {
+	case node.Complex != nil:
+		return node.Complex.Re != 0 || node.Complex.Im != 0
+	case node.Rational != "":
+		r, ok := new(big.Rat).SetString(node.Rational)
+		return ok && r.Sign() != 0
+	case node.Decimal != "":
+		r, ok := new(big.Rat).SetString(node.Decimal)
+		return ok && r.Sign() != 0
+	default:
+		return *node.Number != 0
+	}
+}
+
+// Numbers that were saved without the exact value use their approximation
+func decimalString(decimal string, number float64) string {
+	if decimal != "" {
+		return decimal
+	}
+	return tree.DecimalFrom(number).String()
+}
+
+// Real numbers have no imaginary part
+func imagPart(c *models.Complex) float64 {
+	if c == nil {
+		return 0
+	}
+	return c.Im
+}
+
+func rationalString(rational string, number float64) string {
+	if rational != "" {
+		return rational
+	}
+	return tree.RationalFrom(number).String()
+}
+
+// The operand of the calculated node for the task
+func OperandOf(node models.Node) models.Operand {
+	operand := models.Operand{Decimal: node.Decimal, Rational: node.Rational, Complex: node.Complex, Unit: node.Unit}
+	if node.Number != nil {
+		operand.Number = *node.Number
+	}
+	return operand
+}
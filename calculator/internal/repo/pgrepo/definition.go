@@ -0,0 +1,125 @@
+package pgrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/internal/repo"
+	"github.com/vandi37/ferror"
+)
+
+const definitionColumns = "id, user_id, name, params, body, created_at"
+
+type DefinitionRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewDefinitionRepo(db *pgxpool.Pool) *DefinitionRepo {
+	return &DefinitionRepo{db: db}
+}
+
+func scanDefinition(row pgx.Row) (models.Definition, error) {
+	var definition models.Definition
+	var id, userID []byte
+	if err := row.Scan(&id, &userID, &definition.Name, &definition.Params, &definition.Body, &definition.CreatedAt); err != nil {
+		return definition, err
+	}
+	definition.ID, definition.UserID = toID(id), toID(userID)
+	// Values have no parameters
+	if len(definition.Params) == 0 {
+		definition.Params = nil
+	}
+	return definition, nil
+}
+
+// Create implements repo.DefinitionRepo.
+func (r *DefinitionRepo) Create(ctx context.Context, definition models.Definition) (repo.ID, error) {
+	var save = ferror.Save("pgrepo.DefinitionRepo.Create")
+	id := models.NewID()
+	params := definition.Params
+	if params == nil {
+		params = []string{}
+	}
+	if _, err := r.db.Exec(ctx,
+		"INSERT INTO definitions ("+definitionColumns+") VALUES ($1, $2, $3, $4, $5, $6)",
+		id[:], definition.UserID[:], definition.Name, params, definition.Body, time.Now(),
+	); isUniqueViolation(err) {
+		return repo.ID{}, repo.DefinitionNameTaken
+	} else if err != nil {
+		return repo.ID{}, save.New(err)
+	}
+	return id, nil
+}
+
+// Get implements repo.DefinitionRepo.
+func (r *DefinitionRepo) Get(ctx context.Context, id repo.ID) (*models.Definition, error) {
+	var save = ferror.Save("pgrepo.DefinitionRepo.Get")
+	definition, err := scanDefinition(r.db.QueryRow(ctx, "SELECT "+definitionColumns+" FROM definitions WHERE id = $1", id[:]))
+	if err == pgx.ErrNoRows {
+		return nil, repo.DefinitionNotFound
+	} else if err != nil {
+		return nil, save.New(err)
+	}
+	return &definition, nil
+}
+
+// GetByUser implements repo.DefinitionRepo.
+func (r *DefinitionRepo) GetByUser(ctx context.Context, userID repo.ID) ([]models.Definition, error) {
+	var save = ferror.Save("pgrepo.DefinitionRepo.GetByUser")
+	rows, err := r.db.Query(ctx, "SELECT "+definitionColumns+" FROM definitions WHERE user_id = $1 ORDER BY id", userID[:])
+	if err != nil {
+		return nil, save.New(err)
+	}
+	definitions, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Definition, error) {
+		return scanDefinition(row)
+	})
+	if err != nil {
+		return nil, save.New(err)
+	}
+	return definitions, nil
+}
+
+// Update implements repo.DefinitionRepo.
+func (r *DefinitionRepo) Update(ctx context.Context, definition models.Definition) error {
+	var save = ferror.Save("pgrepo.DefinitionRepo.Update")
+	params := definition.Params
+	if params == nil {
+		params = []string{}
+	}
+	if res, err := r.db.Exec(ctx,
+		"UPDATE definitions SET name = $2, params = $3, body = $4 WHERE id = $1",
+		definition.ID[:], definition.Name, params, definition.Body,
+	); isUniqueViolation(err) {
+		return repo.DefinitionNameTaken
+	} else if err != nil {
+		return save.New(err)
+	} else if res.RowsAffected() == 0 {
+		return repo.DefinitionNotFound
+	}
+	return nil
+}
+
+// Delete implements repo.DefinitionRepo.
+func (r *DefinitionRepo) Delete(ctx context.Context, id repo.ID) error {
+	var save = ferror.Save("pgrepo.DefinitionRepo.Delete")
+	if res, err := r.db.Exec(ctx, "DELETE FROM definitions WHERE id = $1", id[:]); err != nil {
+		return save.New(err)
+	} else if res.RowsAffected() == 0 {
+		return repo.DefinitionNotFound
+	}
+	return nil
+}
+
+// DeleteByUser implements repo.DefinitionRepo.
+func (r *DefinitionRepo) DeleteByUser(ctx context.Context, userID repo.ID) error {
+	var save = ferror.Save("pgrepo.DefinitionRepo.DeleteByUser")
+	if _, err := r.db.Exec(ctx, "DELETE FROM definitions WHERE user_id = $1", userID[:]); err != nil {
+		return save.New(err)
+	}
+	return nil
+}
+
+var _ repo.DefinitionRepo = (*DefinitionRepo)(nil)
@@ -0,0 +1,542 @@
+package pgrepo
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	pb "github.com/vandi37/Calculator-Models"
+	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/internal/repo"
+	"github.com/vandi37/Calculator/internal/repo/nodetree"
+	"github.com/vandi37/Calculator/internal/status"
+	"github.com/vandi37/Calculator/pkg/parsing/tree"
+	"github.com/vandi37/ferror"
+)
+
+var (
+	expressionColumns = "id, user_id, origin, variables, eliminated, depth, precision, error, error_code, warnings, result, decimal, rational, complex_re, complex_im, unit, target_unit, currency, rates, node_id, status, created_at"
+	nodeColumnNames   = []string{"id", "type", "operator", "left_id", "right_id", "cond_id", "items", "number", "decimal", "rational", "complex_re", "complex_im", "unit", "held", "sended_at"}
+	nodeColumns       = strings.Join(nodeColumnNames, ", ")
+	// The nodes of the trees under the nodes $1, the roots have the depth 0
+	subtree = "WITH RECURSIVE tree AS (" +
+		"SELECT " + nodeColumns + ", 0 AS depth FROM nodes WHERE id = ANY($1) " +
+		"UNION ALL SELECT " + prefixed("n.") + ", t.depth + 1 FROM nodes n JOIN tree t " +
+		"ON n.id IN (t.left_id, t.right_id, t.cond_id) OR n.id = ANY(t.items))"
+)
+
+func prefixed(prefix string) string {
+	return prefix + strings.Join(nodeColumnNames, ", "+prefix)
+}
+
+// Saves the nodes in the table with the references to the operands.
+// The tasks are claimed with SELECT ... FOR UPDATE SKIP LOCKED, so several orchestrators don't send the same task
+type ExpressionRepo struct {
+	db       *pgxpool.Pool
+	d        time.Duration
+	callback repo.Callback
+	mu       sync.Mutex
+}
+
+func NewExpressionRepo(db *pgxpool.Pool, d time.Duration) *ExpressionRepo {
+	return &ExpressionRepo{db: db, d: d}
+}
+
+// SetCallback implements repo.ExpressionRepo.
+func (r *ExpressionRepo) SetCallback(ctx context.Context, callback repo.Callback) {
+	r.mu.Lock()
+	r.callback = callback
+	r.mu.Unlock()
+	go r.DoCallback()
+}
+
+// Create implements repo.ExpressionRepo.
+func (r *ExpressionRepo) Create(ctx context.Context, expression models.Expression, ast tree.Ast) (repo.ID, error) {
+	var save = ferror.Save("pgrepo.ExpressionRepo.Create")
+	num, nodes, err := nodetree.Build(ast.Expression)
+	if err != nil {
+		return repo.ID{}, save.New(err)
+	}
+	var root repo.ID
+	if num == nil {
+		root = nodes[len(nodes)-1].ID
+	}
+	nodetree.Prepare(&expression, num, root)
+	if expression.ID == models.NilID {
+		expression.ID = models.NewID()
+	}
+	// A failure in the middle leaves no nodes without the expression
+	if err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx,
+			"INSERT INTO expressions ("+expressionColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22)",
+			expressionValues(expression)...,
+		); err != nil {
+			return err
+		}
+		rows := make([][]any, len(nodes))
+		for i, node := range nodes {
+			rows[i] = append([]any{expression.ID[:]}, nodeValues(node)...)
+		}
+		_, err := tx.CopyFrom(ctx, pgx.Identifier{"nodes"}, append([]string{"expression_id"}, nodeColumnNames...), pgx.CopyFromRows(rows))
+		return err
+	}); err != nil {
+		return repo.ID{}, save.New(err)
+	}
+	go r.DoCallback()
+	return expression.ID, nil
+}
+
+// Get implements repo.ExpressionRepo.
+func (r *ExpressionRepo) Get(ctx context.Context, id repo.ID) (*models.Expression, error) {
+	var save = ferror.Save("pgrepo.ExpressionRepo.Get")
+	var row expressionRow
+	if err := r.db.QueryRow(ctx, "SELECT "+expressionColumns+" FROM expressions WHERE id = $1", id[:]).Scan(row.dest()...); err == pgx.ErrNoRows {
+		return nil, repo.ExpressionNotFound
+	} else if err != nil {
+		return nil, save.New(err)
+	}
+	expression := row.expression()
+	return &expression, nil
+}
+
+// GetByUser implements repo.ExpressionRepo.
+func (r *ExpressionRepo) GetByUser(ctx context.Context, userID repo.ID) ([]models.Expression, error) {
+	var save = ferror.Save("pgrepo.ExpressionRepo.GetByUser")
+	rows, err := r.db.Query(ctx, "SELECT "+expressionColumns+" FROM expressions WHERE user_id = $1 ORDER BY id", userID[:])
+	if err != nil {
+		return nil, save.New(err)
+	}
+	expressions, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Expression, error) {
+		var e expressionRow
+		err := row.Scan(e.dest()...)
+		return e.expression(), err
+	})
+	if err != nil {
+		return nil, save.New(err)
+	}
+	return expressions, nil
+}
+
+// GetNode implements repo.ExpressionRepo.
+func (r *ExpressionRepo) GetNode(ctx context.Context, id repo.ID) (*models.Node, error) {
+	var save = ferror.Save("pgrepo.ExpressionRepo.GetNode")
+	var row nodeRow
+	if err := r.db.QueryRow(ctx, "SELECT "+nodeColumns+" FROM nodes WHERE id = $1", id[:]).Scan(row.dest()...); err == pgx.ErrNoRows {
+		return nil, repo.NodeNotFound
+	} else if err != nil {
+		return nil, save.New(err)
+	}
+	node := row.node()
+	return &node, nil
+}
+
+// GetNodes implements repo.ExpressionRepo.
+func (r *ExpressionRepo) GetNodes(ctx context.Context, id repo.ID) ([]models.Node, error) {
+	var save = ferror.Save("pgrepo.ExpressionRepo.GetNodes")
+	nodes, err := queryNodes(ctx, r.db, subtree+" SELECT "+nodeColumns+" FROM tree ORDER BY depth", [][]byte{id[:]})
+	if err != nil {
+		return nil, save.New(err)
+	}
+	if len(nodes) == 0 {
+		return nil, repo.NodeNotFound
+	}
+	return nodes, nil
+}
+
+// Delete implements repo.ExpressionRepo.
+// The nodes are deleted by the foreign key
+func (r *ExpressionRepo) Delete(ctx context.Context, id repo.ID) error {
+	var save = ferror.Save("pgrepo.ExpressionRepo.Delete")
+	if res, err := r.db.Exec(ctx, "DELETE FROM expressions WHERE id = $1", id[:]); err != nil {
+		return save.New(err)
+	} else if res.RowsAffected() == 0 {
+		return repo.ExpressionNotFound
+	}
+	return nil
+}
+
+// DeleteByUser implements repo.ExpressionRepo.
+func (r *ExpressionRepo) DeleteByUser(ctx context.Context, userID repo.ID) error {
+	var save = ferror.Save("pgrepo.ExpressionRepo.DeleteByUser")
+	if _, err := r.db.Exec(ctx, "DELETE FROM expressions WHERE user_id = $1", userID[:]); err != nil {
+		return save.New(err)
+	}
+	return nil
+}
+
+// SetToError implements repo.ExpressionRepo.
+func (r *ExpressionRepo) SetToError(ctx context.Context, id repo.ID, code, errVal string) error {
+	var save = ferror.Save("pgrepo.ExpressionRepo.SetToError")
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		var expressionID []byte
+		if err := tx.QueryRow(ctx,
+			"UPDATE expressions SET status = $2, error = $3, error_code = $4, result = NULL, node_id = NULL "+
+				"WHERE id = (SELECT expression_id FROM nodes WHERE id = $1) RETURNING id",
+			id[:], int16(status.Error), errVal, code,
+		).Scan(&expressionID); err == pgx.ErrNoRows {
+			return repo.ExpressionNotFound
+		} else if err != nil {
+			return save.New(err)
+		}
+		if _, err := tx.Exec(ctx, "DELETE FROM nodes WHERE expression_id = $1", expressionID); err != nil {
+			return save.New(err)
+		}
+		return nil
+	})
+}
+
+// AddWarning implements repo.ExpressionRepo.
+func (r *ExpressionRepo) AddWarning(ctx context.Context, nodeId repo.ID, code string) error {
+	var save = ferror.Save("pgrepo.ExpressionRepo.AddWarning")
+	if res, err := r.db.Exec(ctx,
+		"UPDATE expressions SET warnings = CASE WHEN $2 = ANY(warnings) THEN warnings ELSE array_append(warnings, $2) END "+
+			"WHERE id = (SELECT expression_id FROM nodes WHERE id = $1)",
+		nodeId[:], code,
+	); err != nil {
+		return save.New(err)
+	} else if res.RowsAffected() == 0 {
+		return repo.ExpressionNotFound
+	}
+	return nil
+}
+
+// SetToNum implements repo.ExpressionRepo.
+func (r *ExpressionRepo) SetToNum(ctx context.Context, nodeId repo.ID, result float64) error {
+	return r.complete(ctx, ferror.Save("pgrepo.ExpressionRepo.SetToNum"), nodeId, models.Node{Number: &result})
+}
+
+// SetToDecimal implements repo.ExpressionRepo.
+func (r *ExpressionRepo) SetToDecimal(ctx context.Context, nodeId repo.ID, result float64, decimal string) error {
+	return r.complete(ctx, ferror.Save("pgrepo.ExpressionRepo.SetToDecimal"), nodeId, models.Node{Number: &result, Decimal: decimal})
+}
+
+// SetToRational implements repo.ExpressionRepo.
+func (r *ExpressionRepo) SetToRational(ctx context.Context, nodeId repo.ID, result float64, rational string) error {
+	return r.complete(ctx, ferror.Save("pgrepo.ExpressionRepo.SetToRational"), nodeId, models.Node{Number: &result, Rational: rational})
+}
+
+// SetToUnit implements repo.ExpressionRepo.
+func (r *ExpressionRepo) SetToUnit(ctx context.Context, nodeId repo.ID, result float64, unit string) error {
+	return r.complete(ctx, ferror.Save("pgrepo.ExpressionRepo.SetToUnit"), nodeId, models.Node{Number: &result, Unit: unit})
+}
+
+// SetToComplex implements repo.ExpressionRepo.
+func (r *ExpressionRepo) SetToComplex(ctx context.Context, nodeId repo.ID, result models.Complex) error {
+	return r.complete(ctx, ferror.Save("pgrepo.ExpressionRepo.SetToComplex"), nodeId, models.Node{Number: &result.Re, Complex: &result})
+}
+
+// Saves the result in a transaction, the new tasks are looked for after the commit
+func (r *ExpressionRepo) complete(ctx context.Context, save ferror.Save, nodeId repo.ID, value models.Node) error {
+	if err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		return setNumber(ctx, save, tx, nodeId, value)
+	}); err != nil {
+		return err
+	}
+	go r.DoCallback()
+	return nil
+}
+
+// The root node finishes the expression and its nodes are deleted.
+// Other nodes become the numbers and their operands are deleted, a node that is already a number isn't changed
+func setNumber(ctx context.Context, save ferror.Save, q querier, id repo.ID, value models.Node) error {
+	re, im := complexParts(value.Complex)
+	var expressionID []byte
+	if err := q.QueryRow(ctx,
+		"UPDATE expressions SET status = $2, result = $3, decimal = $4, rational = $5, complex_re = $6, complex_im = $7, unit = $8, node_id = NULL "+
+			"WHERE node_id = $1 RETURNING id",
+		id[:], int16(status.Finished), value.Number, decimalText(value.Decimal), value.Rational, re, im, value.Unit,
+	).Scan(&expressionID); err == nil {
+		if _, err := q.Exec(ctx, "DELETE FROM nodes WHERE expression_id = $1", expressionID); err != nil {
+			return save.New(err)
+		}
+		return nil
+	} else if err != pgx.ErrNoRows {
+		return save.New(err)
+	}
+
+	var left, right, cond []byte
+	var items [][]byte
+	if err := q.QueryRow(ctx,
+		"SELECT left_id, right_id, cond_id, items FROM nodes WHERE id = $1 AND operator IS NOT NULL FOR UPDATE", id[:],
+	).Scan(&left, &right, &cond, &items); err == pgx.ErrNoRows {
+		return repo.NodeNotFound
+	} else if err != nil {
+		return save.New(err)
+	}
+	if err := deleteTrees(ctx, q, append(items, left, right, cond)); err != nil {
+		return save.New(err)
+	}
+	value.Type, value.Tree = models.Number, nil
+	if err := updateNode(ctx, q, id, value); err != nil {
+		return save.New(err)
+	}
+	return nil
+}
+
+// Deletes the nodes with the trees under them, the nil ids are skipped
+func deleteTrees(ctx context.Context, q querier, ids [][]byte) error {
+	_, err := q.Exec(ctx, subtree+" DELETE FROM nodes WHERE id IN (SELECT id FROM tree)", ids)
+	return err
+}
+
+// Replaces the node with the other one, the held conditional of the node stays
+func updateNode(ctx context.Context, q querier, id repo.ID, node models.Node) error {
+	values := nodeValues(node)
+	_, err := q.Exec(ctx,
+		"UPDATE nodes SET ("+strings.Join(nodeColumnNames[1:13], ", ")+", sended_at) = ($2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NULL) WHERE id = $1",
+		append([]any{id[:]}, values[1:13]...)...,
+	)
+	return err
+}
+
+func insertNode(ctx context.Context, q querier, expressionID repo.ID, node models.Node) error {
+	_, err := q.Exec(ctx,
+		"INSERT INTO nodes (expression_id, "+nodeColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)",
+		append([]any{expressionID[:]}, nodeValues(node)...)...,
+	)
+	return err
+}
+
+func queryNodes(ctx context.Context, q querier, sql string, args ...any) ([]models.Node, error) {
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Node, error) {
+		var n nodeRow
+		err := row.Scan(n.dest()...)
+		return n.node(), err
+	})
+}
+
+func (r *ExpressionRepo) GetFitNodes(ctx context.Context) ([]pb.Task, error) {
+	var save = ferror.Save("pgrepo.ExpressionRepo.GetFitNodes")
+
+	// A chosen branch can be a conditional with a calculated condition too,
+	// and a conditional can wait for a median
+	for {
+		conditionals, err := r.resolveConditionals(ctx)
+		if err != nil {
+			return nil, save.New(err)
+		}
+		medians, err := r.resolveMedians(ctx)
+		if err != nil {
+			return nil, save.New(err)
+		}
+		if conditionals == 0 && medians == 0 {
+			break
+		}
+	}
+
+	now := time.Now()
+	tasks := []pb.Task{}
+	if err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		// The operations with calculated operands, the rows locked by another orchestrator are skipped
+		rows, err := tx.Query(ctx,
+			"SELECT n.id, n.operator, "+
+				"l.number, l.decimal, l.rational, l.complex_re, l.complex_im, l.unit, "+
+				"r.number, r.decimal, r.rational, r.complex_re, r.complex_im, r.unit "+
+				"FROM nodes n JOIN nodes l ON l.id = n.left_id LEFT JOIN nodes r ON r.id = n.right_id "+
+				"WHERE n.operator IS NOT NULL AND n.held IS NULL AND n.operator <> ALL($1) "+
+				"AND (n.sended_at IS NULL OR n.sended_at < $2) "+
+				// A missing right node is joined as nulls, so the right node must be found
+				"AND l.operator IS NULL AND (n.right_id IS NULL OR (r.id IS NOT NULL AND r.operator IS NULL)) "+
+				"FOR UPDATE OF n SKIP LOCKED",
+			// Conditionals and medians are never sent, they are resolved above
+			[]int32{int32(tree.Conditional), int32(tree.Median)}, now.Add(-r.d),
+		)
+		if err != nil {
+			return err
+		}
+		results, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.AggregatedNode, error) {
+			var result models.AggregatedNode
+			var id []byte
+			var operator int32
+			var left, right operandRow
+			err := row.Scan(append(append([]any{&id, &operator}, left.dest()...), right.dest()...)...)
+			result.ID, result.LeftNode, result.RightNode = toID(id), left.operand(), right.operand()
+			result.Tree.Operator = pb.Operation(operator)
+			return result, err
+		})
+		if err != nil {
+			return err
+		}
+		ids := make([][]byte, len(results))
+		for i, result := range results {
+			ids[i] = nullID(result.ID)
+			tasks = append(tasks, pb.Task{})
+			nodetree.SetTask(&tasks[i], result)
+		}
+		_, err = tx.Exec(ctx, "UPDATE nodes SET sended_at = $2 WHERE id = ANY($1)", ids, now)
+		return err
+	}); err != nil {
+		return nil, save.New(err)
+	}
+	return tasks, nil
+}
+
+// Finds the conditionals with calculated conditions and chooses their branches, returns the number of them
+func (r *ExpressionRepo) resolveConditionals(ctx context.Context) (int, error) {
+	var count int
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx,
+			"SELECT "+prefixed("n.")+", "+prefixed("c.")+" FROM nodes n JOIN nodes c ON c.id = n.cond_id "+
+				"WHERE n.operator = $1 AND n.held IS NULL AND c.operator IS NULL FOR UPDATE OF n SKIP LOCKED",
+			int32(tree.Conditional),
+		)
+		if err != nil {
+			return err
+		}
+		type result struct{ node, cond models.Node }
+		results, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (result, error) {
+			var node, cond nodeRow
+			err := row.Scan(append(node.dest(), cond.dest()...)...)
+			return result{node.node(), cond.node()}, err
+		})
+		if err != nil {
+			return err
+		}
+		for _, result := range results {
+			if err := resolveConditional(ctx, tx, result.node, result.cond); err != nil {
+				return err
+			}
+		}
+		count = len(results)
+		return nil
+	})
+	return count, err
+}
+
+// The chosen branch takes the place of the conditional node, the condition and the other branch are deleted
+func resolveConditional(ctx context.Context, q querier, node models.Node, cond models.Node) error {
+	var save = ferror.Save("pgrepo.resolveConditional")
+	chosen, other := node.Tree.Left, node.Tree.Right
+	if !nodetree.IsTrue(cond) {
+		chosen, other = other, chosen
+	}
+	if err := deleteTrees(ctx, q, [][]byte{nullID(other), nullID(cond.ID)}); err != nil {
+		return save.New(err)
+	}
+	if _, err := q.Exec(ctx, "UPDATE nodes SET held = NULL WHERE held = $1", node.ID[:]); err != nil {
+		return save.New(err)
+	}
+
+	var branch nodeRow
+	if err := q.QueryRow(ctx, "DELETE FROM nodes WHERE id = $1 RETURNING "+nodeColumns, chosen[:]).Scan(branch.dest()...); err == pgx.ErrNoRows {
+		return repo.NodeNotFound
+	} else if err != nil {
+		return save.New(err)
+	}
+	if branch.node().Type == models.Number {
+		// The conditional becomes the number, it can be the result of the expression
+		return setNumber(ctx, save, q, node.ID, branch.node())
+	}
+
+	if err := updateNode(ctx, q, node.ID, branch.node()); err != nil {
+		return save.New(err)
+	}
+	// The branch of a nested conditional is held by its new id
+	if _, err := q.Exec(ctx, "UPDATE nodes SET held = $2 WHERE held = $1", chosen[:], node.ID[:]); err != nil {
+		return save.New(err)
+	}
+	return nil
+}
+
+// Finds the medians with calculated items and takes their middle items, returns the number of them
+func (r *ExpressionRepo) resolveMedians(ctx context.Context) (int, error) {
+	var count int
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx,
+			"SELECT n.expression_id, "+prefixed("n.")+" FROM nodes n WHERE n.operator = $1 AND n.held IS NULL "+
+				"AND NOT EXISTS (SELECT 1 FROM nodes i WHERE i.id = ANY(n.items) AND i.operator IS NOT NULL) FOR UPDATE SKIP LOCKED",
+			int32(tree.Median),
+		)
+		if err != nil {
+			return err
+		}
+		type result struct {
+			expressionID repo.ID
+			node         models.Node
+		}
+		results, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (result, error) {
+			var expressionID []byte
+			var node nodeRow
+			err := row.Scan(append([]any{&expressionID}, node.dest()...)...)
+			return result{toID(expressionID), node.node()}, err
+		})
+		if err != nil {
+			return err
+		}
+		for _, result := range results {
+			items, err := queryNodes(ctx, tx, "SELECT "+nodeColumns+" FROM nodes WHERE id = ANY($1)", fromIDs(result.node.Tree.Items))
+			if err != nil {
+				return err
+			}
+			// Every item is found and is a number
+			if len(items) != len(result.node.Tree.Items) {
+				continue
+			}
+			if err := resolveMedian(ctx, tx, result.expressionID, result.node, items); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// The middle item takes the place of the median node, the other items are deleted.
+// With an even number of items the node becomes (a + b) / 2 for the agents
+func resolveMedian(ctx context.Context, q querier, expressionID repo.ID, node models.Node, items []models.Node) error {
+	var save = ferror.Save("pgrepo.resolveMedian")
+	middle := nodetree.Middle(items)
+	if len(middle) == 1 {
+		// The median becomes the number, it can be the result of the expression
+		return setNumber(ctx, save, q, node.ID, middle[0])
+	}
+
+	var others [][]byte
+	for _, item := range items {
+		if item.ID != middle[0].ID && item.ID != middle[1].ID {
+			others = append(others, nullID(item.ID))
+		}
+	}
+	if err := deleteTrees(ctx, q, others); err != nil {
+		return save.New(err)
+	}
+	sum := nodetree.Operation(node.Held, models.TreeNode{Operator: pb.Operation_ADD, Left: middle[0].ID, Right: middle[1].ID})
+	count := 2.0
+	two := models.Node{ID: models.NewID(), Type: models.Number, Held: node.Held, Number: &count}
+	for _, n := range []models.Node{sum, two} {
+		if err := insertNode(ctx, q, expressionID, n); err != nil {
+			return save.New(err)
+		}
+	}
+	division := models.Node{Type: models.Operation, Tree: &models.TreeNode{Operator: pb.Operation_DIVIDE, Left: sum.ID, Right: two.ID}}
+	if err := updateNode(ctx, q, node.ID, division); err != nil {
+		return save.New(err)
+	}
+	return nil
+}
+
+func (r *ExpressionRepo) DoCallback() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.callback == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second*20)
+	defer cancel()
+	tasks, err := r.GetFitNodes(ctx)
+	if err != nil {
+		r.callback.SendError(ctx, err)
+		return
+	}
+	r.callback.SendResult(ctx, tasks)
+}
+
+var _ repo.ExpressionRepo = (*ExpressionRepo)(nil)
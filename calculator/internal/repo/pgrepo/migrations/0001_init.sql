@@ -0,0 +1,76 @@
+-- The ids are the object ids of the mongo repos (12 bytes), so the api doesn't depend on the database
+
+CREATE TABLE users (
+    id bytea PRIMARY KEY,
+    username text NOT NULL UNIQUE,
+    password text NOT NULL,
+    created_at timestamptz NOT NULL
+);
+
+CREATE TABLE definitions (
+    id bytea PRIMARY KEY,
+    user_id bytea NOT NULL,
+    name text NOT NULL,
+    params text[] NOT NULL DEFAULT '{}',
+    body text NOT NULL,
+    created_at timestamptz NOT NULL,
+    UNIQUE (user_id, name)
+);
+
+CREATE TABLE rates (
+    code text PRIMARY KEY,
+    rate double precision NOT NULL,
+    updated_at timestamptz NOT NULL
+);
+
+CREATE TABLE expressions (
+    id bytea PRIMARY KEY,
+    user_id bytea NOT NULL,
+    origin text NOT NULL,
+    variables jsonb,
+    eliminated integer NOT NULL DEFAULT 0,
+    depth integer NOT NULL DEFAULT 0,
+    precision text NOT NULL DEFAULT '',
+    error text NOT NULL DEFAULT '',
+    error_code text NOT NULL DEFAULT '',
+    warnings text[] NOT NULL DEFAULT '{}',
+    result double precision,
+    decimal text,
+    rational text NOT NULL DEFAULT '',
+    complex_re double precision,
+    complex_im double precision,
+    unit text NOT NULL DEFAULT '',
+    target_unit text NOT NULL DEFAULT '',
+    currency text NOT NULL DEFAULT '',
+    rates jsonb,
+    -- The root node while the expression is pending
+    node_id bytea UNIQUE,
+    status smallint NOT NULL,
+    created_at timestamptz NOT NULL
+);
+
+CREATE INDEX expressions_user_id ON expressions (user_id);
+
+-- Numbers have no operator, the operations reference their operands by the ids
+CREATE TABLE nodes (
+    id bytea PRIMARY KEY,
+    expression_id bytea NOT NULL REFERENCES expressions (id) ON DELETE CASCADE,
+    type boolean NOT NULL,
+    operator integer,
+    left_id bytea,
+    right_id bytea,
+    cond_id bytea,
+    items bytea[],
+    number double precision,
+    decimal text,
+    rational text NOT NULL DEFAULT '',
+    complex_re double precision,
+    complex_im double precision,
+    unit text NOT NULL DEFAULT '',
+    held bytea,
+    sended_at timestamptz
+);
+
+CREATE INDEX nodes_expression_id ON nodes (expression_id);
+CREATE INDEX nodes_held ON nodes (held) WHERE held IS NOT NULL;
+CREATE INDEX nodes_operations ON nodes (sended_at) WHERE operator IS NOT NULL AND held IS NULL;
@@ -0,0 +1,114 @@
+// This package saves the users, the definitions, the rates and the expressions in postgres.
+// The ids are object ids like in the mongo repos, they are saved as bytea
+package pgrepo
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/internal/repo"
+	"github.com/vandi37/ferror"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Key of the advisory lock, so the orchestrators that start together don't apply the migrations twice
+const migrationLock = 37_0001
+
+// Code of the unique constraint violation
+const uniqueViolation = "23505"
+
+// Queries of the pool and of the transaction
+type querier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// Applies the migrations that aren't applied yet in the order of their names.
+// Every migration is applied in its own transaction, the applied ones are saved in schema_migrations
+func Migrate(ctx context.Context, db *pgxpool.Pool) error {
+	var save = ferror.Save("pgrepo.Migrate")
+	entries, err := migrations.ReadDir("migrations")
+	if err != nil {
+		return save.New(err)
+	}
+	for _, entry := range entries {
+		version := strings.TrimSuffix(entry.Name(), ".sql")
+		sql, err := migrations.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			return save.New(err)
+		}
+		if err := pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", migrationLock); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(ctx, "CREATE TABLE IF NOT EXISTS schema_migrations (version text PRIMARY KEY, applied_at timestamptz NOT NULL DEFAULT now())"); err != nil {
+				return err
+			}
+			var applied bool
+			if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)", version).Scan(&applied); err != nil {
+				return err
+			} else if applied {
+				return nil
+			}
+			if _, err := tx.Exec(ctx, string(sql)); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version) VALUES ($1)", version)
+			return err
+		}); err != nil {
+			return save.New(err)
+		}
+	}
+	return nil
+}
+
+// The nil id is saved as null
+func nullID(id repo.ID) []byte {
+	if id == models.NilID {
+		return nil
+	}
+	return id[:]
+}
+
+// Null is the nil id
+func toID(b []byte) repo.ID {
+	var id repo.ID
+	copy(id[:], b)
+	return id
+}
+
+func toIDs(ids [][]byte) []repo.ID {
+	if ids == nil {
+		return nil
+	}
+	result := make([]repo.ID, len(ids))
+	for i, id := range ids {
+		result[i] = toID(id)
+	}
+	return result
+}
+
+func fromIDs(ids []repo.ID) [][]byte {
+	if ids == nil {
+		return nil
+	}
+	result := make([][]byte, len(ids))
+	for i, id := range ids {
+		result[i] = nullID(id)
+	}
+	return result
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
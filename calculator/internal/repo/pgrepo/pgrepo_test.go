@@ -0,0 +1,140 @@
+package pgrepo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/internal/repo"
+	"github.com/vandi37/Calculator/internal/repo/pgrepo"
+	"github.com/vandi37/Calculator/internal/repo/repotest"
+	"github.com/vandi37/Calculator/pkg/parsing/parser"
+)
+
+type PgRepoTestSuite struct {
+	suite.Suite
+	postgresC testcontainers.Container
+	pool      *pgxpool.Pool
+	ctx       context.Context
+}
+
+func (suite *PgRepoTestSuite) Clear() {
+	_, err := suite.pool.Exec(suite.ctx, "TRUNCATE users, definitions, rates, expressions, nodes")
+	require.NoError(suite.T(), err)
+}
+
+func (suite *PgRepoTestSuite) SetupSuite() {
+	suite.ctx = context.Background()
+
+	req := testcontainers.ContainerRequest{
+		Image:        "postgres:16-alpine",
+		ExposedPorts: []string{"5432/tcp"},
+		Env: map[string]string{
+			"POSTGRES_USER":     "test",
+			"POSTGRES_PASSWORD": "test",
+			"POSTGRES_DB":       "test_db",
+		},
+		// The server is restarted once after the init scripts
+		WaitingFor: wait.ForLog("database system is ready to accept connections").WithOccurrence(2).WithStartupTimeout(20 * time.Second),
+	}
+
+	postgresC, err := testcontainers.GenericContainer(suite.ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: req,
+		Started:          true,
+	})
+	require.NoError(suite.T(), err)
+	suite.postgresC = postgresC
+
+	endpoint, err := postgresC.Endpoint(suite.ctx, "")
+	require.NoError(suite.T(), err)
+
+	pool, err := pgxpool.New(suite.ctx, "postgres://test:test@"+endpoint+"/test_db?sslmode=disable")
+	require.NoError(suite.T(), err)
+	suite.pool = pool
+
+	require.NoError(suite.T(), pgrepo.Migrate(suite.ctx, pool))
+}
+
+func (suite *PgRepoTestSuite) TearDownSuite() {
+	suite.Clear()
+	suite.pool.Close()
+
+	err := suite.postgresC.Terminate(suite.ctx)
+	require.NoError(suite.T(), err)
+}
+
+func TestPgRepoTestSuite(t *testing.T) {
+	suite.Run(t, new(PgRepoTestSuite))
+}
+
+func (suite *PgRepoTestSuite) TestMigrate() {
+	t := suite.T()
+
+	// The applied migrations are skipped
+	require.NoError(t, pgrepo.Migrate(suite.ctx, suite.pool))
+	var count int
+	require.NoError(t, suite.pool.QueryRow(suite.ctx, "SELECT count(*) FROM schema_migrations").Scan(&count))
+	require.Equal(t, 1, count)
+}
+
+// The operation isn't sent if its right node is missing, the join gives nulls for it
+func (suite *PgRepoTestSuite) TestMissingRightNode() {
+	suite.Clear()
+	t := suite.T()
+	expressionRepo := pgrepo.NewExpressionRepo(suite.pool, time.Minute)
+
+	ast, err := parser.Build("1 + 2")
+	require.NoError(t, err)
+	_, err = expressionRepo.Create(suite.ctx, models.Expression{UserID: models.NewID(), Origin: "1 + 2"}, ast)
+	require.NoError(t, err)
+	_, err = suite.pool.Exec(suite.ctx, "DELETE FROM nodes WHERE id = (SELECT right_id FROM nodes WHERE operator IS NOT NULL)")
+	require.NoError(t, err)
+
+	tasks, err := expressionRepo.GetFitNodes(suite.ctx)
+	require.NoError(t, err)
+	assert.Empty(t, tasks)
+}
+
+func (s *PgRepoTestSuite) TestUserRepo() {
+	suite.Run(s.T(), &repotest.UserSuite{
+		New: func(t *testing.T) repo.UserRepo {
+			s.Clear()
+			return pgrepo.NewUserRepo(s.pool)
+		},
+	})
+}
+
+func (s *PgRepoTestSuite) TestDefinitionRepo() {
+	suite.Run(s.T(), &repotest.DefinitionSuite{
+		New: func(t *testing.T) repo.DefinitionRepo {
+			s.Clear()
+			return pgrepo.NewDefinitionRepo(s.pool)
+		},
+	})
+}
+
+func (s *PgRepoTestSuite) TestRateRepo() {
+	suite.Run(s.T(), &repotest.RateSuite{
+		New: func(t *testing.T) repo.RateRepo {
+			s.Clear()
+			return pgrepo.NewRateRepo(s.pool)
+		},
+	})
+}
+
+func (s *PgRepoTestSuite) TestExpressionRepo() {
+	suite.Run(s.T(), &repotest.ExpressionSuite{
+		New: func(t *testing.T, d time.Duration) repo.ExpressionRepo {
+			s.Clear()
+			return pgrepo.NewExpressionRepo(s.pool, d)
+		},
+		Exclusive: true,
+	})
+}
@@ -0,0 +1,83 @@
+package pgrepo
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/internal/repo"
+	"github.com/vandi37/Calculator/pkg/currency"
+	"github.com/vandi37/ferror"
+	"github.com/vandi37/vanerrors"
+)
+
+type RateRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewRateRepo(db *pgxpool.Pool) *RateRepo {
+	return &RateRepo{db: db}
+}
+
+// GetRates implements repo.RateRepo.
+func (r *RateRepo) GetRates(ctx context.Context, codes []string) (map[string]float64, error) {
+	var save = ferror.Save("pgrepo.RateRepo.GetRates")
+	rows, err := r.db.Query(ctx, "SELECT code, rate, updated_at FROM rates WHERE code = ANY($1)", codes)
+	if err != nil {
+		return nil, save.New(err)
+	}
+	found, err := pgx.CollectRows(rows, pgx.RowToStructByPos[models.Rate])
+	if err != nil {
+		return nil, save.New(err)
+	}
+	rates := make(map[string]float64, len(found))
+	for _, rate := range found {
+		rates[rate.Code] = rate.Rate
+	}
+	if missing := currency.Missing(rates, codes...); len(missing) > 0 {
+		return nil, vanerrors.New(currency.UnknownCurrency, strings.Join(missing, ", "))
+	}
+	return rates, nil
+}
+
+// Set implements repo.RateRepo.
+func (r *RateRepo) Set(ctx context.Context, rate models.Rate) error {
+	var save = ferror.Save("pgrepo.RateRepo.Set")
+	if _, err := r.db.Exec(ctx,
+		"INSERT INTO rates (code, rate, updated_at) VALUES ($1, $2, $3) ON CONFLICT (code) DO UPDATE SET rate = EXCLUDED.rate, updated_at = EXCLUDED.updated_at",
+		rate.Code, rate.Rate, time.Now(),
+	); err != nil {
+		return save.New(err)
+	}
+	return nil
+}
+
+// GetAll implements repo.RateRepo.
+func (r *RateRepo) GetAll(ctx context.Context) ([]models.Rate, error) {
+	var save = ferror.Save("pgrepo.RateRepo.GetAll")
+	rows, err := r.db.Query(ctx, "SELECT code, rate, updated_at FROM rates ORDER BY code")
+	if err != nil {
+		return nil, save.New(err)
+	}
+	rates, err := pgx.CollectRows(rows, pgx.RowToStructByPos[models.Rate])
+	if err != nil {
+		return nil, save.New(err)
+	}
+	return rates, nil
+}
+
+// Delete implements repo.RateRepo.
+func (r *RateRepo) Delete(ctx context.Context, code string) error {
+	var save = ferror.Save("pgrepo.RateRepo.Delete")
+	if res, err := r.db.Exec(ctx, "DELETE FROM rates WHERE code = $1", code); err != nil {
+		return save.New(err)
+	} else if res.RowsAffected() == 0 {
+		return repo.RateNotFound
+	}
+	return nil
+}
+
+var _ repo.RateRepo = (*RateRepo)(nil)
@@ -0,0 +1,170 @@
+package pgrepo
+
+import (
+	"time"
+
+	pb "github.com/vandi37/Calculator-Models"
+	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/internal/status"
+	"github.com/vandi37/Calculator/pkg/parsing/tree"
+)
+
+// Columns of expressionColumns
+type expressionRow struct {
+	id, userID, nodeID []byte
+	value              models.Expression
+	precision          string
+	warnings           []string
+	decimal            *string
+	re, im             *float64
+	status             int16
+}
+
+func (e *expressionRow) dest() []any {
+	x := &e.value
+	return []any{
+		&e.id, &e.userID, &x.Origin, &x.Variables, &x.Eliminated, &x.Depth, &e.precision, &x.Error, &x.ErrorCode, &e.warnings,
+		&x.Result, &e.decimal, &x.Rational, &e.re, &e.im, &x.Unit, &x.TargetUnit, &x.Currency, &x.Rates, &e.nodeID, &e.status, &x.CreatedAt,
+	}
+}
+
+func (e *expressionRow) expression() models.Expression {
+	x := e.value
+	x.ID, x.UserID, x.NodeID = toID(e.id), toID(e.userID), toID(e.nodeID)
+	x.Precision = tree.Precision(e.precision)
+	x.Status = status.Status(e.status)
+	x.Decimal = textDecimal(e.decimal)
+	x.Complex = toComplex(e.re, e.im)
+	if len(e.warnings) > 0 {
+		x.Warnings = e.warnings
+	}
+	return x
+}
+
+func expressionValues(x models.Expression) []any {
+	re, im := complexParts(x.Complex)
+	warnings := x.Warnings
+	if warnings == nil {
+		warnings = []string{}
+	}
+	return []any{
+		x.ID[:], x.UserID[:], x.Origin, x.Variables, x.Eliminated, x.Depth, string(x.Precision), x.Error, x.ErrorCode, warnings,
+		x.Result, decimalText(x.Decimal), x.Rational, re, im, x.Unit, x.TargetUnit, x.Currency, x.Rates, nullID(x.NodeID), int16(x.Status), x.CreatedAt,
+	}
+}
+
+// Columns of nodeColumns
+type nodeRow struct {
+	id, left, right, cond, held []byte
+	number                      bool
+	operator                    *int32
+	items                       [][]byte
+	value, re, im               *float64
+	decimal                     *string
+	rational, unit              string
+	sendedAt                    *time.Time
+}
+
+func (n *nodeRow) dest() []any {
+	return []any{
+		&n.id, &n.number, &n.operator, &n.left, &n.right, &n.cond, &n.items,
+		&n.value, &n.decimal, &n.rational, &n.re, &n.im, &n.unit, &n.held, &n.sendedAt,
+	}
+}
+
+func (n *nodeRow) node() models.Node {
+	node := models.Node{
+		ID:       toID(n.id),
+		Type:     models.NodeType(n.number),
+		Number:   n.value,
+		Decimal:  textDecimal(n.decimal),
+		Rational: n.rational,
+		Complex:  toComplex(n.re, n.im),
+		Unit:     n.unit,
+		Held:     toID(n.held),
+		SendedAt: n.sendedAt,
+	}
+	if n.operator != nil {
+		node.Tree = &models.TreeNode{
+			Operator: pb.Operation(*n.operator),
+			Left:     toID(n.left),
+			Right:    toID(n.right),
+			Cond:     toID(n.cond),
+			Items:    toIDs(n.items),
+		}
+	}
+	return node
+}
+
+// Numbers have no operator and no operands
+func nodeValues(node models.Node) []any {
+	var operator *int32
+	var left, right, cond []byte
+	var items [][]byte
+	if node.Tree != nil {
+		op := int32(node.Tree.Operator)
+		operator = &op
+		left, right, cond = nullID(node.Tree.Left), nullID(node.Tree.Right), nullID(node.Tree.Cond)
+		items = fromIDs(node.Tree.Items)
+	}
+	re, im := complexParts(node.Complex)
+	return []any{
+		nullID(node.ID), bool(node.Type), operator, left, right, cond, items,
+		node.Number, decimalText(node.Decimal), node.Rational, re, im, node.Unit, nullID(node.Held), node.SendedAt,
+	}
+}
+
+// Calculated operand of the task, the right one is null for unary operations
+type operandRow struct {
+	number, re, im          *float64
+	decimal, rational, unit *string
+}
+
+func (o *operandRow) dest() []any {
+	return []any{&o.number, &o.decimal, &o.rational, &o.re, &o.im, &o.unit}
+}
+
+func (o *operandRow) operand() models.Operand {
+	var operand models.Operand
+	if o.number != nil {
+		operand.Number = *o.number
+	}
+	if o.rational != nil {
+		operand.Rational = *o.rational
+	}
+	if o.unit != nil {
+		operand.Unit = *o.unit
+	}
+	operand.Decimal = textDecimal(o.decimal)
+	operand.Complex = toComplex(o.re, o.im)
+	return operand
+}
+
+// The decimals are saved as text, it is null for the other numbers
+func decimalText(decimal string) *string {
+	if decimal == "" {
+		return nil
+	}
+	return &decimal
+}
+
+func textDecimal(text *string) string {
+	if text == nil {
+		return ""
+	}
+	return *text
+}
+
+func complexParts(c *models.Complex) (*float64, *float64) {
+	if c == nil {
+		return nil, nil
+	}
+	return &c.Re, &c.Im
+}
+
+func toComplex(re, im *float64) *models.Complex {
+	if re == nil || im == nil {
+		return nil
+	}
+	return &models.Complex{Re: *re, Im: *im}
+}
@@ -0,0 +1,95 @@
+package pgrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/internal/repo"
+	"github.com/vandi37/ferror"
+)
+
+type UserRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewUserRepo(db *pgxpool.Pool) *UserRepo {
+	return &UserRepo{db: db}
+}
+
+// Register implements repo.UserRepo.
+func (r *UserRepo) Register(ctx context.Context, user models.User) (repo.ID, error) {
+	var save = ferror.Save("pgrepo.UserRepo.Register")
+	id := models.NewID()
+	if _, err := r.db.Exec(ctx,
+		"INSERT INTO users (id, username, password, created_at) VALUES ($1, $2, $3, $4)",
+		id[:], user.Username, user.Password, time.Now(),
+	); isUniqueViolation(err) {
+		return repo.ID{}, repo.UsernameTaken
+	} else if err != nil {
+		return repo.ID{}, save.New(err)
+	}
+	return id, nil
+}
+
+func (r *UserRepo) get(ctx context.Context, save ferror.Save, where string, arg any) (*models.User, error) {
+	var user models.User
+	var id []byte
+	if err := r.db.QueryRow(ctx, "SELECT id, username, password, created_at FROM users WHERE "+where+" = $1", arg).
+		Scan(&id, &user.Username, &user.Password, &user.CreatedAt); err == pgx.ErrNoRows {
+		return nil, repo.UserNotFound
+	} else if err != nil {
+		return nil, save.New(err)
+	}
+	user.ID = toID(id)
+	return &user, nil
+}
+
+// Get implements repo.UserRepo.
+func (r *UserRepo) Get(ctx context.Context, id repo.ID) (*models.User, error) {
+	return r.get(ctx, ferror.Save("pgrepo.UserRepo.Get"), "id", id[:])
+}
+
+// GetByUsername implements repo.UserRepo.
+func (r *UserRepo) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	return r.get(ctx, ferror.Save("pgrepo.UserRepo.GetByUsername"), "username", username)
+}
+
+// UpdateUsername implements repo.UserRepo.
+func (r *UserRepo) UpdateUsername(ctx context.Context, id repo.ID, username string) error {
+	var save = ferror.Save("pgrepo.UserRepo.UpdateUsername")
+	if res, err := r.db.Exec(ctx, "UPDATE users SET username = $2 WHERE id = $1", id[:], username); isUniqueViolation(err) {
+		return repo.UsernameTaken
+	} else if err != nil {
+		return save.New(err)
+	} else if res.RowsAffected() == 0 {
+		return repo.UserNotFound
+	}
+	return nil
+}
+
+// UpdatePassword implements repo.UserRepo.
+func (r *UserRepo) UpdatePassword(ctx context.Context, id repo.ID, password string) error {
+	var save = ferror.Save("pgrepo.UserRepo.UpdatePassword")
+	if res, err := r.db.Exec(ctx, "UPDATE users SET password = $2 WHERE id = $1", id[:], password); err != nil {
+		return save.New(err)
+	} else if res.RowsAffected() == 0 {
+		return repo.UserNotFound
+	}
+	return nil
+}
+
+// Delete implements repo.UserRepo.
+func (r *UserRepo) Delete(ctx context.Context, id repo.ID) error {
+	var save = ferror.Save("pgrepo.UserRepo.Delete")
+	if res, err := r.db.Exec(ctx, "DELETE FROM users WHERE id = $1", id[:]); err != nil {
+		return save.New(err)
+	} else if res.RowsAffected() == 0 {
+		return repo.UserNotFound
+	}
+	return nil
+}
+
+var _ repo.UserRepo = (*UserRepo)(nil)
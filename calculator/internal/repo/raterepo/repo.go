@@ -0,0 +1,101 @@
+package raterepo
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/internal/repo"
+	"github.com/vandi37/Calculator/internal/repo/mongoid"
+	"github.com/vandi37/Calculator/pkg/currency"
+	"github.com/vandi37/ferror"
+	"github.com/vandi37/vanerrors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	collectionName = "rates"
+)
+
+type Repo struct {
+	collection *mongo.Collection
+}
+
+// The collection of the rates, it is used by the tests
+func (r *Repo) GetCollection() *mongo.Collection {
+	return r.collection
+}
+
+// GetRates implements repo.RateRepo.
+func (r *Repo) GetRates(ctx context.Context, codes []string) (map[string]float64, error) {
+	var save = ferror.Save("raterepo.Repo.GetRates")
+	cursor, err := r.collection.Find(ctx, bson.M{"_id": bson.M{"$in": codes}})
+	if err != nil {
+		return nil, save.New(err)
+	}
+	defer cursor.Close(ctx)
+	var found []models.Rate
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, save.New(err)
+	}
+	rates := make(map[string]float64, len(found))
+	for _, rate := range found {
+		rates[rate.Code] = rate.Rate
+	}
+	if missing := currency.Missing(rates, codes...); len(missing) > 0 {
+		return nil, vanerrors.New(currency.UnknownCurrency, strings.Join(missing, ", "))
+	}
+	return rates, nil
+}
+
+// Set implements repo.RateRepo.
+func (r *Repo) Set(ctx context.Context, rate models.Rate) error {
+	var save = ferror.Save("raterepo.Repo.Set")
+	rate.UpdatedAt = time.Now()
+	if _, err := r.collection.ReplaceOne(ctx, bson.M{"_id": rate.Code}, rate, options.Replace().SetUpsert(true)); err != nil {
+		return save.New(err)
+	}
+	return nil
+}
+
+// GetAll implements repo.RateRepo.
+func (r *Repo) GetAll(ctx context.Context) ([]models.Rate, error) {
+	var save = ferror.Save("raterepo.Repo.GetAll")
+	cursor, err := r.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, save.New(err)
+	}
+	defer cursor.Close(ctx)
+	var rates []models.Rate
+	if err := cursor.All(ctx, &rates); err != nil {
+		return nil, save.New(err)
+	}
+	return rates, nil
+}
+
+// Delete implements repo.RateRepo.
+func (r *Repo) Delete(ctx context.Context, code string) error {
+	var save = ferror.Save("raterepo.Repo.Delete")
+	if res, err := r.collection.DeleteOne(ctx, bson.M{"_id": code}); err != nil {
+		return save.New(err)
+	} else if res.DeletedCount == 0 {
+		return repo.RateNotFound
+	}
+	return nil
+}
+
+// The database with the collections, it is *mongo.Database
+type IntoCollection interface {
+	Collection(name string, opts ...*options.CollectionOptions) *mongo.Collection
+}
+
+func New(db IntoCollection) *Repo {
+	return &Repo{
+		collection: db.Collection(collectionName, mongoid.Collection()),
+	}
+}
+
+var _ repo.RateRepo = (*Repo)(nil)
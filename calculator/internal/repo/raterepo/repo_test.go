@@ -0,0 +1,162 @@
+package raterepo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/internal/repo"
+	"github.com/vandi37/Calculator/internal/repo/raterepo"
+	"github.com/vandi37/Calculator/pkg/currency"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RateRepoTestSuite struct {
+	suite.Suite
+	mongoC   testcontainers.Container
+	client   *mongo.Client
+	rateRepo *raterepo.Repo
+	ctx      context.Context
+}
+
+func (suite *RateRepoTestSuite) SetupSuite() {
+	suite.ctx = context.Background()
+
+	req := testcontainers.ContainerRequest{
+		Image:        "mongo:latest",
+		ExposedPorts: []string{"27017/tcp"},
+		WaitingFor:   wait.ForLog("Waiting for connections").WithStartupTimeout(20 * time.Second),
+	}
+
+	mongoC, err := testcontainers.GenericContainer(suite.ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: req,
+		Started:          true,
+	})
+	require.NoError(suite.T(), err)
+	suite.mongoC = mongoC
+
+	endpoint, err := mongoC.Endpoint(suite.ctx, "")
+	require.NoError(suite.T(), err)
+
+	client, err := mongo.Connect(suite.ctx, options.Client().ApplyURI("mongodb://"+endpoint))
+	require.NoError(suite.T(), err)
+	suite.client = client
+
+	db := client.Database("test_db")
+	suite.rateRepo = raterepo.New(db)
+
+	testRates := []models.Rate{
+		{Code: "USD", Rate: 1, UpdatedAt: time.Now()},
+		{Code: "EUR", Rate: 0.92, UpdatedAt: time.Now()},
+		{Code: "GBP", Rate: 0.8, UpdatedAt: time.Now()},
+	}
+
+	var docs []interface{}
+	for _, r := range testRates {
+		docs = append(docs, r)
+	}
+
+	_, err = suite.rateRepo.GetCollection().InsertMany(suite.ctx, docs)
+	require.NoError(suite.T(), err)
+}
+
+func (suite *RateRepoTestSuite) TearDownSuite() {
+	_, err := suite.rateRepo.GetCollection().DeleteMany(suite.ctx, bson.M{})
+	require.NoError(suite.T(), err)
+
+	err = suite.client.Disconnect(suite.ctx)
+	require.NoError(suite.T(), err)
+
+	err = suite.mongoC.Terminate(suite.ctx)
+	require.NoError(suite.T(), err)
+}
+
+func TestRateRepoTestSuite(t *testing.T) {
+	suite.Run(t, new(RateRepoTestSuite))
+}
+
+func (suite *RateRepoTestSuite) TestGetRates() {
+	t := suite.T()
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		codes    []string
+		expected map[string]float64
+		wantErr  bool
+	}{
+		{
+			name:     "known codes",
+			codes:    []string{"USD", "GBP"},
+			expected: map[string]float64{"USD": 1, "GBP": 0.8},
+		},
+		{
+			name:    "unknown code",
+			codes:   []string{"USD", "XYZ"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rates, err := suite.rateRepo.GetRates(ctx, tt.codes)
+
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), currency.UnknownCurrency)
+				assert.Nil(t, rates)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, rates)
+			}
+		})
+	}
+}
+
+func (suite *RateRepoTestSuite) TestSet() {
+	t := suite.T()
+	ctx := context.Background()
+
+	err := suite.rateRepo.Set(ctx, models.Rate{Code: "JPY", Rate: 150})
+	require.NoError(t, err)
+	err = suite.rateRepo.Set(ctx, models.Rate{Code: "JPY", Rate: 155})
+	require.NoError(t, err)
+
+	rates, err := suite.rateRepo.GetRates(ctx, []string{"JPY"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"JPY": 155}, rates)
+}
+
+func (suite *RateRepoTestSuite) TestGetAll() {
+	t := suite.T()
+	ctx := context.Background()
+
+	rates, err := suite.rateRepo.GetAll(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, rates)
+	for i := 1; i < len(rates); i++ {
+		assert.Less(t, rates[i-1].Code, rates[i].Code)
+	}
+}
+
+func (suite *RateRepoTestSuite) TestDelete() {
+	t := suite.T()
+	ctx := context.Background()
+
+	err := suite.rateRepo.Set(ctx, models.Rate{Code: "CHF", Rate: 0.9})
+	require.NoError(t, err)
+
+	err = suite.rateRepo.Delete(ctx, "CHF")
+	require.NoError(t, err)
+
+	err = suite.rateRepo.Delete(ctx, "CHF")
+	assert.ErrorIs(t, err, repo.RateNotFound)
+}
@@ -0,0 +1,104 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/internal/repo"
+)
+
+type DefinitionSuite struct {
+	suite.Suite
+	// Returns the repo without definitions
+	New func(t *testing.T) repo.DefinitionRepo
+
+	repo   repo.DefinitionRepo
+	userId repo.ID
+}
+
+func (s *DefinitionSuite) SetupTest() {
+	s.repo = s.New(s.T())
+	s.userId = models.NewID()
+}
+
+func (s *DefinitionSuite) TestCreate() {
+	t := s.T()
+	ctx := context.Background()
+
+	id, err := s.repo.Create(ctx, models.Definition{UserID: s.userId, Name: "tax", Body: "0.13"})
+	require.NoError(t, err)
+	definition, err := s.repo.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "tax", definition.Name)
+	assert.Empty(t, definition.Params)
+	assert.False(t, definition.CreatedAt.IsZero())
+
+	id, err = s.repo.Create(ctx, models.Definition{UserID: s.userId, Name: "f", Params: []string{"x", "y"}, Body: "x + y"})
+	require.NoError(t, err)
+	definition, err = s.repo.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"x", "y"}, definition.Params)
+
+	// The names are unique for every user
+	_, err = s.repo.Create(ctx, models.Definition{UserID: models.NewID(), Name: "tax", Body: "0.2"})
+	require.NoError(t, err)
+	id, err = s.repo.Create(ctx, models.Definition{UserID: s.userId, Name: "tax", Body: "0.2"})
+	assert.ErrorIs(t, err, repo.DefinitionNameTaken)
+	assert.Equal(t, repo.ID{}, id)
+
+	definitions, err := s.repo.GetByUser(ctx, s.userId)
+	require.NoError(t, err)
+	assert.Len(t, definitions, 2)
+
+	_, err = s.repo.Get(ctx, newId())
+	assert.ErrorIs(t, err, repo.DefinitionNotFound)
+}
+
+func (s *DefinitionSuite) TestUpdate() {
+	t := s.T()
+	ctx := context.Background()
+
+	id, err := s.repo.Create(ctx, models.Definition{UserID: s.userId, Name: "rate", Body: "0.3"})
+	require.NoError(t, err)
+	_, err = s.repo.Create(ctx, models.Definition{UserID: s.userId, Name: "tax", Body: "0.13"})
+	require.NoError(t, err)
+
+	require.NoError(t, s.repo.Update(ctx, models.Definition{ID: id, UserID: s.userId, Name: "rate", Params: []string{"x"}, Body: "x * 0.3"}))
+	definition, err := s.repo.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"x"}, definition.Params)
+	assert.Equal(t, "x * 0.3", definition.Body)
+
+	err = s.repo.Update(ctx, models.Definition{ID: id, UserID: s.userId, Name: "tax", Body: "1"})
+	assert.ErrorIs(t, err, repo.DefinitionNameTaken)
+	err = s.repo.Update(ctx, models.Definition{ID: models.NewID(), UserID: s.userId, Name: "other", Body: "1"})
+	assert.ErrorIs(t, err, repo.DefinitionNotFound)
+}
+
+func (s *DefinitionSuite) TestDelete() {
+	t := s.T()
+	ctx := context.Background()
+
+	id, err := s.repo.Create(ctx, models.Definition{UserID: s.userId, Name: "rate", Body: "0.3"})
+	require.NoError(t, err)
+	_, err = s.repo.Create(ctx, models.Definition{UserID: s.userId, Name: "tax", Body: "0.13"})
+	require.NoError(t, err)
+	other, err := s.repo.Create(ctx, models.Definition{UserID: models.NewID(), Name: "tax", Body: "0.2"})
+	require.NoError(t, err)
+
+	require.NoError(t, s.repo.Delete(ctx, id))
+	assert.ErrorIs(t, s.repo.Delete(ctx, id), repo.DefinitionNotFound)
+
+	require.NoError(t, s.repo.DeleteByUser(ctx, s.userId))
+	definitions, err := s.repo.GetByUser(ctx, s.userId)
+	require.NoError(t, err)
+	assert.Empty(t, definitions)
+
+	// The definitions of other users are kept
+	_, err = s.repo.Get(ctx, other)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,491 @@
+package repotest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	pb "github.com/vandi37/Calculator-Models"
+	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/internal/repo"
+	"github.com/vandi37/Calculator/internal/status"
+	"github.com/vandi37/Calculator/pkg/parsing/parser"
+	"github.com/vandi37/Calculator/pkg/parsing/tree"
+	"github.com/vandi37/Calculator/pkg/wire"
+)
+
+type ExpressionSuite struct {
+	suite.Suite
+	// Returns the repo without expressions, the sent tasks are sent again after d
+	New func(t *testing.T, d time.Duration) repo.ExpressionRepo
+	// GetFitNodes never gives the same task to the calls at the same time
+	Exclusive bool
+
+	repo   repo.ExpressionRepo
+	userId repo.ID
+}
+
+func (s *ExpressionSuite) SetupTest() {
+	s.repo = s.New(s.T(), 5*time.Minute)
+	s.userId = models.NewID()
+}
+
+func (s *ExpressionSuite) create(expression string) repo.ID {
+	ast, err := parser.Build(expression)
+	require.NoError(s.T(), err)
+	id, err := s.repo.Create(context.Background(), models.Expression{UserID: s.userId, Origin: expression}, ast)
+	require.NoError(s.T(), err)
+	return id
+}
+
+func (s *ExpressionSuite) TestCreate() {
+	t := s.T()
+	ctx := context.Background()
+
+	tests := []struct {
+		name, expression string
+		nodes            int
+	}{
+		{"Addition", "1 + 1", 3},
+		{"Unary Function", "sqrt(16) + 1", 4},
+		{"Variadic Function", "max(1, 2 * 3, log(8, 2))", 9},
+		{"Negation", "-(2 + 3) * 4", 6},
+		{"Conditional", "1 < 2 ? 2 + 3 : 4", 8},
+		{"Median", "median([3, 1, 2 + 5])", 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := s.create(tt.expression)
+			expr, err := s.repo.Get(ctx, id)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expression, expr.Origin)
+			assert.Equal(t, s.userId, expr.UserID)
+			assert.Equal(t, status.Pending, expr.Status)
+			assert.False(t, expr.CreatedAt.IsZero())
+
+			nodes, err := s.repo.GetNodes(ctx, expr.NodeID)
+			require.NoError(t, err)
+			assert.Len(t, nodes, tt.nodes)
+			assert.Equal(t, expr.NodeID, nodes[0].ID)
+		})
+	}
+
+	t.Run("Number", func(t *testing.T) {
+		id, err := s.repo.Create(ctx, models.Expression{UserID: s.userId, Origin: "5"}, tree.Ast{Expression: tree.Num(5)})
+		require.NoError(t, err)
+		expr, err := s.repo.Get(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, status.Finished, expr.Status)
+		assert.Equal(t, 5.0, *expr.Result)
+		assert.Equal(t, models.NilID, expr.NodeID)
+	})
+
+	t.Run("Invalid expression", func(t *testing.T) {
+		ast := tree.Expression{Left: tree.Num(1), Operation: tree.Operation(pb.Operation_ADD), Right: nil}
+		id, err := s.repo.Create(ctx, models.Expression{UserID: s.userId, Origin: "invalid"}, tree.Ast{Expression: ast})
+		assert.ErrorIs(t, err, repo.InvalidExpression)
+		assert.Equal(t, repo.ID{}, id)
+	})
+
+	expressions, err := s.repo.GetByUser(ctx, s.userId)
+	require.NoError(t, err)
+	assert.Len(t, expressions, len(tests)+1)
+	expressions, err = s.repo.GetByUser(ctx, newId())
+	require.NoError(t, err)
+	assert.Empty(t, expressions)
+}
+
+func (s *ExpressionSuite) TestGetNode() {
+	t := s.T()
+	ctx := context.Background()
+
+	expr, err := s.repo.Get(ctx, s.create("2 * 3"))
+	require.NoError(t, err)
+
+	node, err := s.repo.GetNode(ctx, expr.NodeID)
+	require.NoError(t, err)
+	assert.Equal(t, pb.Operation_MULTIPLY, node.Tree.Operator)
+
+	_, err = s.repo.GetNode(ctx, newId())
+	assert.ErrorIs(t, err, repo.NodeNotFound)
+	_, err = s.repo.GetNodes(ctx, newId())
+	assert.ErrorIs(t, err, repo.NodeNotFound)
+	_, err = s.repo.Get(ctx, newId())
+	assert.ErrorIs(t, err, repo.ExpressionNotFound)
+}
+
+func (s *ExpressionSuite) TestDelete() {
+	t := s.T()
+	ctx := context.Background()
+
+	id := s.create("1 + 2")
+	expr, err := s.repo.Get(ctx, id)
+	require.NoError(t, err)
+	s.create("3 + 4")
+	require.NoError(t, s.repo.Delete(ctx, id))
+	assert.ErrorIs(t, s.repo.Delete(ctx, id), repo.ExpressionNotFound)
+	_, err = s.repo.GetNode(ctx, expr.NodeID)
+	assert.ErrorIs(t, err, repo.NodeNotFound)
+
+	require.NoError(t, s.repo.DeleteByUser(ctx, s.userId))
+	expressions, err := s.repo.GetByUser(ctx, s.userId)
+	require.NoError(t, err)
+	assert.Empty(t, expressions)
+	tasks, err := s.repo.GetFitNodes(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, tasks)
+}
+
+func (s *ExpressionSuite) TestSetToNum() {
+	t := s.T()
+	ctx := context.Background()
+
+	id := s.create("(1 + 2) * (3 + 4)")
+
+	tasks, err := s.repo.GetFitNodes(ctx)
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+	for i := range tasks {
+		assert.Equal(t, pb.Operation_ADD, tasks[i].Operation)
+		require.NoError(t, s.repo.SetToNum(ctx, taskId(t, &tasks[i]), tasks[i].Arg1+tasks[i].Arg2))
+	}
+	// The result isn't saved twice
+	assert.ErrorIs(t, s.repo.SetToNum(ctx, taskId(t, &tasks[0]), tasks[0].Arg1+tasks[0].Arg2), repo.NodeNotFound)
+
+	tasks, err = s.repo.GetFitNodes(ctx)
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, pb.Operation_MULTIPLY, tasks[0].Operation)
+	assert.ElementsMatch(t, []float64{3, 7}, []float64{tasks[0].Arg1, tasks[0].Arg2})
+
+	// The sent task isn't sent again before the duration
+	again, err := s.repo.GetFitNodes(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, again)
+
+	require.NoError(t, s.repo.SetToNum(ctx, taskId(t, &tasks[0]), 21))
+	expr, err := s.repo.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, status.Finished, expr.Status)
+	assert.Equal(t, 21.0, *expr.Result)
+	assert.Equal(t, models.NilID, expr.NodeID)
+
+	assert.ErrorIs(t, s.repo.SetToNum(ctx, newId(), 1), repo.NodeNotFound)
+}
+
+func (s *ExpressionSuite) TestSetToExact() {
+	t := s.T()
+	ctx := context.Background()
+
+	ids := map[pb.Operation]repo.ID{}
+	for operation, expression := range map[pb.Operation]string{
+		pb.Operation_ADD:      "0.1 + 0.2",
+		pb.Operation_DIVIDE:   "1 / 3",
+		pb.Operation_MULTIPLY: "2i * 3",
+	} {
+		ids[operation] = s.create(expression)
+	}
+
+	tasks, err := s.repo.GetFitNodes(ctx)
+	require.NoError(t, err)
+	require.Len(t, tasks, 3)
+	for i := range tasks {
+		switch tasks[i].Operation {
+		case pb.Operation_ADD:
+			require.NoError(t, s.repo.SetToDecimal(ctx, taskId(t, &tasks[i]), 0.3, "0.3"))
+		case pb.Operation_DIVIDE:
+			require.NoError(t, s.repo.SetToRational(ctx, taskId(t, &tasks[i]), 1.0/3, "1/3"))
+		default:
+			require.NoError(t, s.repo.SetToComplex(ctx, taskId(t, &tasks[i]), models.Complex{Im: 6}))
+		}
+	}
+
+	expr, err := s.repo.Get(ctx, ids[pb.Operation_ADD])
+	require.NoError(t, err)
+	assert.Equal(t, 0.3, *expr.Result)
+	assert.Equal(t, "0.3", expr.Decimal)
+	expr, err = s.repo.Get(ctx, ids[pb.Operation_DIVIDE])
+	require.NoError(t, err)
+	assert.Equal(t, "1/3", expr.Rational)
+	expr, err = s.repo.Get(ctx, ids[pb.Operation_MULTIPLY])
+	require.NoError(t, err)
+	assert.Equal(t, &models.Complex{Im: 6}, expr.Complex)
+}
+
+func (s *ExpressionSuite) TestSetToError() {
+	t := s.T()
+	ctx := context.Background()
+
+	id := s.create("(1 + 2) / 0")
+	tasks, err := s.repo.GetFitNodes(ctx)
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+
+	// The same warning is saved once
+	require.NoError(t, s.repo.AddWarning(ctx, taskId(t, &tasks[0]), wire.UnknownError))
+	require.NoError(t, s.repo.AddWarning(ctx, taskId(t, &tasks[0]), wire.UnknownError))
+	require.NoError(t, s.repo.SetToError(ctx, taskId(t, &tasks[0]), wire.UnknownError, "some error"))
+	expr, err := s.repo.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, status.Error, expr.Status)
+	assert.Equal(t, "some error", expr.Error)
+	assert.Equal(t, wire.UnknownError, expr.ErrorCode)
+	assert.Equal(t, []string{wire.UnknownError}, expr.Warnings)
+	assert.Nil(t, expr.Result)
+	assert.Equal(t, models.NilID, expr.NodeID)
+
+	assert.ErrorIs(t, s.repo.SetToError(ctx, taskId(t, &tasks[0]), wire.UnknownError, "some error"), repo.ExpressionNotFound)
+	assert.ErrorIs(t, s.repo.AddWarning(ctx, taskId(t, &tasks[0]), wire.UnknownError), repo.ExpressionNotFound)
+	_, err = s.repo.GetNode(ctx, taskId(t, &tasks[0]))
+	assert.ErrorIs(t, err, repo.NodeNotFound)
+}
+
+func (s *ExpressionSuite) TestGetFitNodesUnary() {
+	t := s.T()
+	ctx := context.Background()
+
+	s.create("sqrt(16) + abs(2 - 3)")
+
+	tasks, err := s.repo.GetFitNodes(ctx)
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+	for i := range tasks {
+		task := &tasks[i]
+		switch tree.Operation(task.Operation) {
+		case tree.Sqrt:
+			assert.Equal(t, 16.0, task.Arg1)
+			assert.Equal(t, 0.0, task.Arg2)
+		case tree.Operation(pb.Operation_SUBTRACT):
+			assert.Equal(t, 2.0, task.Arg1)
+			assert.Equal(t, 3.0, task.Arg2)
+		default:
+			t.Errorf("unexpected task operation %d", task.Operation)
+		}
+	}
+}
+
+func (s *ExpressionSuite) TestGetFitNodesUnits() {
+	t := s.T()
+	ctx := context.Background()
+
+	id := s.create("5 km / 2")
+
+	tasks, err := s.repo.GetFitNodes(ctx)
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, 5000.0, tasks[0].Arg1)
+	unit, ok := wire.GetString(&tasks[0], wire.Arg1Unit)
+	assert.True(t, ok)
+	assert.Equal(t, "m", unit)
+	unit, ok = wire.GetString(&tasks[0], wire.Arg2Unit)
+	assert.True(t, ok)
+	assert.Equal(t, "", unit)
+
+	require.NoError(t, s.repo.SetToUnit(ctx, taskId(t, &tasks[0]), 2500, "m"))
+	expr, err := s.repo.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, status.Finished, expr.Status)
+	assert.Equal(t, 2500.0, *expr.Result)
+	assert.Equal(t, "m", expr.Unit)
+}
+
+func (s *ExpressionSuite) TestGetFitNodesConditional() {
+	t := s.T()
+	ctx := context.Background()
+
+	id := s.create("1 < 2 ? 2 + 3 : 4 * 5")
+
+	// The branches wait for the condition
+	tasks, err := s.repo.GetFitNodes(ctx)
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, tree.Less, tree.Operation(tasks[0].Operation))
+
+	expr, err := s.repo.Get(ctx, id)
+	require.NoError(t, err)
+	require.NoError(t, s.repo.SetToNum(ctx, taskId(t, &tasks[0]), 1))
+
+	// 4 * 5 is deleted and 2 + 3 takes the place of the conditional
+	tasks, err = s.repo.GetFitNodes(ctx)
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, pb.Operation_ADD, tasks[0].Operation)
+	assert.Equal(t, expr.NodeID, taskId(t, &tasks[0]))
+
+	nodes, err := s.repo.GetNodes(ctx, expr.NodeID)
+	require.NoError(t, err)
+	assert.Len(t, nodes, 3)
+	for _, node := range nodes {
+		assert.Equal(t, models.NilID, node.Held)
+	}
+
+	require.NoError(t, s.repo.SetToNum(ctx, expr.NodeID, 5))
+	expr, err = s.repo.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, status.Finished, expr.Status)
+	assert.Equal(t, 5.0, *expr.Result)
+}
+
+func (s *ExpressionSuite) TestGetFitNodesNumberBranch() {
+	t := s.T()
+	ctx := context.Background()
+
+	id := s.create("(1 > 2 ? 3 : 4) * (5 - 1)")
+
+	tasks, err := s.repo.GetFitNodes(ctx)
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+	for i := range tasks {
+		switch tree.Operation(tasks[i].Operation) {
+		case tree.Greater:
+			require.NoError(t, s.repo.SetToNum(ctx, taskId(t, &tasks[i]), 0))
+		default:
+			require.NoError(t, s.repo.SetToNum(ctx, taskId(t, &tasks[i]), 4))
+		}
+	}
+
+	// The conditional becomes 4, so the multiplication is ready
+	tasks, err = s.repo.GetFitNodes(ctx)
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, pb.Operation_MULTIPLY, tasks[0].Operation)
+	assert.Equal(t, 4.0, tasks[0].Arg1)
+	assert.Equal(t, 4.0, tasks[0].Arg2)
+
+	require.NoError(t, s.repo.SetToNum(ctx, taskId(t, &tasks[0]), 16))
+	expr, err := s.repo.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, 16.0, *expr.Result)
+}
+
+func (s *ExpressionSuite) TestGetFitNodesSum() {
+	t := s.T()
+	ctx := context.Background()
+
+	s.create("sum([1, 2, 3, 4])")
+
+	// The balanced tree gives two additions at once instead of a chain
+	tasks, err := s.repo.GetFitNodes(ctx)
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+	for i := range tasks {
+		assert.Equal(t, pb.Operation_ADD, tasks[i].Operation)
+	}
+}
+
+func (s *ExpressionSuite) TestGetFitNodesMedian() {
+	t := s.T()
+	ctx := context.Background()
+
+	odd := s.create("median([3, 1, 2 + 5])")
+	even := s.create("median([4, 1, 3, 10])")
+
+	// The median waits for its items, the even one becomes (3 + 4) / 2
+	tasks, err := s.repo.GetFitNodes(ctx)
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+	for i := range tasks {
+		assert.Equal(t, pb.Operation_ADD, tasks[i].Operation)
+		require.NoError(t, s.repo.SetToNum(ctx, taskId(t, &tasks[i]), tasks[i].Arg1+tasks[i].Arg2))
+	}
+
+	tasks, err = s.repo.GetFitNodes(ctx)
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, pb.Operation_DIVIDE, tasks[0].Operation)
+	assert.Equal(t, 7.0, tasks[0].Arg1)
+	assert.Equal(t, 2.0, tasks[0].Arg2)
+	require.NoError(t, s.repo.SetToNum(ctx, taskId(t, &tasks[0]), 3.5))
+
+	expr, err := s.repo.Get(ctx, odd)
+	require.NoError(t, err)
+	assert.Equal(t, status.Finished, expr.Status)
+	assert.Equal(t, 3.0, *expr.Result)
+	expr, err = s.repo.Get(ctx, even)
+	require.NoError(t, err)
+	assert.Equal(t, status.Finished, expr.Status)
+	assert.Equal(t, 3.5, *expr.Result)
+}
+
+func (s *ExpressionSuite) TestGetFitNodesConcurrent() {
+	t := s.T()
+	ctx := context.Background()
+	if !s.Exclusive {
+		t.Skip("the storage doesn't claim the tasks")
+	}
+
+	s.create("(1 + 2) * (3 + 4) - (5 + 6) * (7 + 8)")
+
+	// The calls at the same time never get the same task
+	var wg sync.WaitGroup
+	results := make([][]pb.Task, 8)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tasks, err := s.repo.GetFitNodes(ctx)
+			assert.NoError(t, err)
+			results[i] = tasks
+		}()
+	}
+	wg.Wait()
+
+	ids := map[string]bool{}
+	for _, tasks := range results {
+		for i := range tasks {
+			assert.False(t, ids[tasks[i].Id])
+			ids[tasks[i].Id] = true
+		}
+	}
+	assert.Len(t, ids, 4)
+}
+
+func (s *ExpressionSuite) TestRedispatch() {
+	t := s.T()
+	ctx := context.Background()
+
+	r := s.New(t, 50*time.Millisecond)
+	ast, err := parser.Build("1 + 2")
+	require.NoError(t, err)
+	id, err := r.Create(ctx, models.Expression{UserID: s.userId, Origin: "1 + 2"}, ast)
+	require.NoError(t, err)
+
+	tasks, err := r.GetFitNodes(ctx)
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	again, err := r.GetFitNodes(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, again)
+
+	// The task without a result is sent again after the duration
+	time.Sleep(100 * time.Millisecond)
+	again, err = r.GetFitNodes(ctx)
+	require.NoError(t, err)
+	require.Len(t, again, 1)
+	assert.Equal(t, tasks[0].Id, again[0].Id)
+
+	require.NoError(t, r.SetToNum(ctx, taskId(t, &tasks[0]), 3))
+	expr, err := r.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, 3.0, *expr.Result)
+}
+
+func (s *ExpressionSuite) TestSetCallback() {
+	t := s.T()
+	ctx := context.Background()
+
+	s.create("1 + 2")
+	callback := &Callback{}
+	s.repo.SetCallback(ctx, callback)
+
+	// The ready tasks are sent right after the callback is set
+	assert.Eventually(t, func() bool {
+		return len(callback.Tasks()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.NoError(t, callback.Err())
+}
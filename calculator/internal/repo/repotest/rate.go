@@ -0,0 +1,73 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/internal/repo"
+	"github.com/vandi37/Calculator/pkg/currency"
+)
+
+type RateSuite struct {
+	suite.Suite
+	// Returns the repo without rates
+	New func(t *testing.T) repo.RateRepo
+
+	repo repo.RateRepo
+}
+
+func (s *RateSuite) SetupTest() {
+	s.repo = s.New(s.T())
+}
+
+func (s *RateSuite) TestSet() {
+	t := s.T()
+	ctx := context.Background()
+
+	require.NoError(t, s.repo.Set(ctx, models.Rate{Code: "USD", Rate: 1}))
+	require.NoError(t, s.repo.Set(ctx, models.Rate{Code: "GBP", Rate: 0.7}))
+	require.NoError(t, s.repo.Set(ctx, models.Rate{Code: "GBP", Rate: 0.8}))
+
+	all, err := s.repo.GetAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, "GBP", all[0].Code)
+	assert.Equal(t, 0.8, all[0].Rate)
+	assert.False(t, all[0].UpdatedAt.IsZero())
+	assert.Equal(t, "USD", all[1].Code)
+}
+
+func (s *RateSuite) TestGetRates() {
+	t := s.T()
+	ctx := context.Background()
+
+	require.NoError(t, s.repo.Set(ctx, models.Rate{Code: "USD", Rate: 1}))
+	require.NoError(t, s.repo.Set(ctx, models.Rate{Code: "GBP", Rate: 0.8}))
+
+	rates, err := s.repo.GetRates(ctx, []string{"USD", "GBP"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"USD": 1, "GBP": 0.8}, rates)
+
+	rates, err = s.repo.GetRates(ctx, []string{"USD", "XYZ"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), currency.UnknownCurrency)
+	assert.Contains(t, err.Error(), "XYZ")
+	assert.Nil(t, rates)
+}
+
+func (s *RateSuite) TestDelete() {
+	t := s.T()
+	ctx := context.Background()
+
+	require.NoError(t, s.repo.Set(ctx, models.Rate{Code: "GBP", Rate: 0.8}))
+	require.NoError(t, s.repo.Delete(ctx, "GBP"))
+	assert.ErrorIs(t, s.repo.Delete(ctx, "GBP"), repo.RateNotFound)
+
+	all, err := s.repo.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, all)
+}
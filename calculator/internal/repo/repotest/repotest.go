@@ -0,0 +1,59 @@
+// This package has the tests that every storage of the repo interfaces must pass
+//
+// A storage runs the suite with the constructor of its repo, the constructor gives an empty storage for every test
+package repotest
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	pb "github.com/vandi37/Calculator-Models"
+	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/internal/repo"
+)
+
+// The callbacks are sent from other goroutines, an empty result can follow the tasks, so all sent tasks are kept
+type Callback struct {
+	mu    sync.Mutex
+	tasks []pb.Task
+	err   error
+}
+
+func (c *Callback) SendResult(ctx context.Context, tasks []pb.Task) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tasks = append(c.tasks, tasks...)
+}
+
+func (c *Callback) SendError(ctx context.Context, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.err = err
+}
+
+// All sent tasks
+func (c *Callback) Tasks() []pb.Task {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.tasks)
+}
+
+func (c *Callback) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func taskId(t *testing.T, task *pb.Task) repo.ID {
+	id, err := models.IDFromHex(task.Id)
+	require.NoError(t, err)
+	return id
+}
+
+// An id that isn't saved
+func newId() repo.ID {
+	return models.NewID()
+}
@@ -0,0 +1,91 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/internal/repo"
+)
+
+type UserSuite struct {
+	suite.Suite
+	// Returns the repo without users
+	New func(t *testing.T) repo.UserRepo
+
+	repo repo.UserRepo
+}
+
+func (s *UserSuite) SetupTest() {
+	s.repo = s.New(s.T())
+}
+
+func (s *UserSuite) TestRegister() {
+	t := s.T()
+	ctx := context.Background()
+
+	id, err := s.repo.Register(ctx, models.User{Username: "testuser", Password: "password", CreatedAt: time.Now()})
+	require.NoError(t, err)
+	assert.NotEqual(t, repo.ID{}, id)
+
+	user, err := s.repo.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "testuser", user.Username)
+	assert.Equal(t, "password", user.Password)
+
+	user, err = s.repo.GetByUsername(ctx, "testuser")
+	require.NoError(t, err)
+	assert.Equal(t, id, user.ID)
+
+	id, err = s.repo.Register(ctx, models.User{Username: "testuser", Password: "other"})
+	assert.ErrorIs(t, err, repo.UsernameTaken)
+	assert.Equal(t, repo.ID{}, id)
+
+	_, err = s.repo.Get(ctx, newId())
+	assert.ErrorIs(t, err, repo.UserNotFound)
+	_, err = s.repo.GetByUsername(ctx, "nonexistent")
+	assert.ErrorIs(t, err, repo.UserNotFound)
+}
+
+func (s *UserSuite) TestUpdate() {
+	t := s.T()
+	ctx := context.Background()
+
+	id, err := s.repo.Register(ctx, models.User{Username: "testuser1", Password: "password"})
+	require.NoError(t, err)
+	_, err = s.repo.Register(ctx, models.User{Username: "testuser2", Password: "password"})
+	require.NoError(t, err)
+
+	require.NoError(t, s.repo.UpdateUsername(ctx, id, "renamed"))
+	assert.ErrorIs(t, s.repo.UpdateUsername(ctx, id, "testuser2"), repo.UsernameTaken)
+	assert.ErrorIs(t, s.repo.UpdateUsername(ctx, newId(), "free"), repo.UserNotFound)
+
+	require.NoError(t, s.repo.UpdatePassword(ctx, id, "new password"))
+	assert.ErrorIs(t, s.repo.UpdatePassword(ctx, newId(), "new password"), repo.UserNotFound)
+
+	user, err := s.repo.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "renamed", user.Username)
+	assert.Equal(t, "new password", user.Password)
+
+	// The old name is free again
+	_, err = s.repo.Register(ctx, models.User{Username: "testuser1", Password: "password"})
+	assert.NoError(t, err)
+}
+
+func (s *UserSuite) TestDelete() {
+	t := s.T()
+	ctx := context.Background()
+
+	id, err := s.repo.Register(ctx, models.User{Username: "testuser", Password: "password"})
+	require.NoError(t, err)
+
+	require.NoError(t, s.repo.Delete(ctx, id))
+	assert.ErrorIs(t, s.repo.Delete(ctx, id), repo.UserNotFound)
+	_, err = s.repo.GetByUsername(ctx, "testuser")
+	assert.ErrorIs(t, err, repo.UserNotFound)
+}
@@ -6,10 +6,12 @@ import (
 
 	"github.com/vandi37/Calculator/internal/models"
 	"github.com/vandi37/Calculator/internal/repo"
+	"github.com/vandi37/Calculator/internal/repo/mongoid"
 	"github.com/vandi37/ferror"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 const (
@@ -20,7 +22,7 @@ type Repo struct {
 	collection *mongo.Collection
 }
 
-// GetCollection implements repo.UserRepo.
+// The collection of the users, it is used by the tests
 func (r *Repo) GetCollection() *mongo.Collection {
 	return r.collection
 }
@@ -35,7 +37,7 @@ func (r *Repo) usernameExists(ctx context.Context, username string) (bool, error
 }
 
 // UpdatePassword implements repo.UserRepo.
-func (r *Repo) UpdatePassword(ctx context.Context, id primitive.ObjectID, password string) error {
+func (r *Repo) UpdatePassword(ctx context.Context, id repo.ID, password string) error {
 	save := ferror.Save("userrepo.Repo.UpdatePassword")
 	if res, err := r.collection.UpdateByID(
 		ctx,
@@ -50,7 +52,7 @@ func (r *Repo) UpdatePassword(ctx context.Context, id primitive.ObjectID, passwo
 }
 
 // UpdateUsername implements repo.UserRepo.
-func (r *Repo) UpdateUsername(ctx context.Context, id primitive.ObjectID, username string) error {
+func (r *Repo) UpdateUsername(ctx context.Context, id repo.ID, username string) error {
 	save := ferror.Save("userrepo.Repo.UpdateUsername")
 	if exists, err := r.usernameExists(ctx, username); err != nil {
 		return save.New(err)
@@ -70,7 +72,7 @@ func (r *Repo) UpdateUsername(ctx context.Context, id primitive.ObjectID, userna
 }
 
 // Delete implements repo.UserRepo.
-func (r *Repo) Delete(ctx context.Context, id primitive.ObjectID) error {
+func (r *Repo) Delete(ctx context.Context, id repo.ID) error {
 	var save = ferror.Save("userrepo.Repo.Delete")
 	if res, err := r.collection.DeleteOne(ctx, bson.M{"_id": id}); err != nil {
 		return save.New(err)
@@ -81,7 +83,7 @@ func (r *Repo) Delete(ctx context.Context, id primitive.ObjectID) error {
 }
 
 // Get implements repo.UserRepo.
-func (r *Repo) Get(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+func (r *Repo) Get(ctx context.Context, id repo.ID) (*models.User, error) {
 	var save = ferror.Save("userrepo.Repo.Get")
 	var user models.User
 	if err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&user); err == mongo.ErrNoDocuments {
@@ -105,24 +107,29 @@ func (r *Repo) GetByUsername(ctx context.Context, username string) (*models.User
 }
 
 // Register implements repo.UserRepo.
-func (r *Repo) Register(ctx context.Context, user models.User) (primitive.ObjectID, error) {
+func (r *Repo) Register(ctx context.Context, user models.User) (repo.ID, error) {
 	var save = ferror.Save("userrepo.Repo.Register")
 	if exists, err := r.usernameExists(ctx, user.Username); err != nil {
-		return primitive.NilObjectID, save.New(err)
+		return repo.ID{}, save.New(err)
 	} else if exists {
-		return primitive.NilObjectID, repo.UsernameTaken
+		return repo.ID{}, repo.UsernameTaken
 	}
 	user.CreatedAt = time.Now()
 	if res, err := r.collection.InsertOne(ctx, user); err != nil {
-		return primitive.NilObjectID, save.New(err)
+		return repo.ID{}, save.New(err)
 	} else {
-		return res.InsertedID.(primitive.ObjectID), nil
+		return repo.ID(res.InsertedID.(primitive.ObjectID)), nil
 	}
 }
 
-func New(db repo.IntoCollection) *Repo {
+// The database with the collections, it is *mongo.Database
+type IntoCollection interface {
+	Collection(name string, opts ...*options.CollectionOptions) *mongo.Collection
+}
+
+func New(db IntoCollection) *Repo {
 	return &Repo{
-		collection: db.Collection(collectionName),
+		collection: db.Collection(collectionName, mongoid.Collection()),
 	}
 }
 
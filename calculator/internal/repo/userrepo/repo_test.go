@@ -14,7 +14,6 @@ import (
 	"github.com/vandi37/Calculator/internal/repo"
 	"github.com/vandi37/Calculator/internal/repo/userrepo"
 	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -24,8 +23,8 @@ type UserRepoTestSuite struct {
 	mongoC      testcontainers.Container
 	client      *mongo.Client
 	userRepo    *userrepo.Repo
-	testUserID  primitive.ObjectID
-	testUserID2 primitive.ObjectID
+	testUserID  repo.ID
+	testUserID2 repo.ID
 	ctx         context.Context
 }
 
@@ -55,8 +54,8 @@ func (suite *UserRepoTestSuite) SetupSuite() {
 	db := client.Database("test_db")
 	suite.userRepo = userrepo.New(db)
 
-	suite.testUserID = primitive.NewObjectID()
-	suite.testUserID2 = primitive.NewObjectID()
+	suite.testUserID = models.NewID()
+	suite.testUserID2 = models.NewID()
 
 	testUsers := []models.User{
 		{
@@ -135,10 +134,10 @@ func (suite *UserRepoTestSuite) TestRegister() {
 				if tt.expectedErr != nil {
 					assert.ErrorIs(t, err, tt.expectedErr)
 				}
-				assert.Equal(t, primitive.NilObjectID, id)
+				assert.Equal(t, repo.ID{}, id)
 			} else {
 				require.NoError(t, err)
-				assert.NotEqual(t, primitive.NilObjectID, id)
+				assert.NotEqual(t, repo.ID{}, id)
 
 				var user models.User
 				err := suite.userRepo.GetCollection().FindOne(ctx, bson.M{"_id": id}).Decode(&user)
@@ -157,7 +156,7 @@ func (suite *UserRepoTestSuite) TestGet() {
 
 	tests := []struct {
 		name        string
-		id          primitive.ObjectID
+		id          repo.ID
 		wantErr     bool
 		expectedErr error
 	}{
@@ -168,13 +167,13 @@ func (suite *UserRepoTestSuite) TestGet() {
 		},
 		{
 			name:        "non-existent user",
-			id:          primitive.NewObjectID(),
+			id:          models.NewID(),
 			wantErr:     true,
 			expectedErr: repo.UserNotFound,
 		},
 		{
 			name:        "nil object id",
-			id:          primitive.NilObjectID,
+			id:          models.NilID,
 			wantErr:     true,
 			expectedErr: repo.UserNotFound,
 		},
@@ -256,7 +255,7 @@ func (suite *UserRepoTestSuite) TestDelete() {
 	ctx := context.Background()
 
 	tempUser := models.User{
-		ID:        primitive.NewObjectID(),
+		ID:        models.NewID(),
 		Username:  "tempuser",
 		Password:  "temppass",
 		CreatedAt: time.Now(),
@@ -266,7 +265,7 @@ func (suite *UserRepoTestSuite) TestDelete() {
 
 	tests := []struct {
 		name        string
-		id          primitive.ObjectID
+		id          repo.ID
 		wantErr     bool
 		expectedErr error
 	}{
@@ -277,13 +276,13 @@ func (suite *UserRepoTestSuite) TestDelete() {
 		},
 		{
 			name:        "non-existent user",
-			id:          primitive.NewObjectID(),
+			id:          models.NewID(),
 			wantErr:     true,
 			expectedErr: repo.UserNotFound,
 		},
 		{
 			name:        "nil object id",
-			id:          primitive.NilObjectID,
+			id:          models.NilID,
 			wantErr:     true,
 			expectedErr: repo.UserNotFound,
 		},
@@ -312,7 +311,7 @@ func (suite *UserRepoTestSuite) TestUpdateUsername() {
 	ctx := context.Background()
 
 	tempUser := models.User{
-		ID:        primitive.NewObjectID(),
+		ID:        models.NewID(),
 		Username:  "updateuser",
 		Password:  "updatepass",
 		CreatedAt: time.Now(),
@@ -322,7 +321,7 @@ func (suite *UserRepoTestSuite) TestUpdateUsername() {
 
 	tests := []struct {
 		name        string
-		id          primitive.ObjectID
+		id          repo.ID
 		newUsername string
 		wantErr     bool
 		expectedErr error
@@ -342,7 +341,7 @@ func (suite *UserRepoTestSuite) TestUpdateUsername() {
 		},
 		{
 			name:        "non-existent user",
-			id:          primitive.NewObjectID(),
+			id:          models.NewID(),
 			newUsername: "newusername",
 			wantErr:     true,
 			expectedErr: repo.UserNotFound,
@@ -375,7 +374,7 @@ func (suite *UserRepoTestSuite) TestUpdatePassword() {
 	ctx := context.Background()
 
 	tempUser := models.User{
-		ID:        primitive.NewObjectID(),
+		ID:        models.NewID(),
 		Username:  "passuser",
 		Password:  "oldpassword",
 		CreatedAt: time.Now(),
@@ -385,7 +384,7 @@ func (suite *UserRepoTestSuite) TestUpdatePassword() {
 
 	tests := []struct {
 		name        string
-		id          primitive.ObjectID
+		id          repo.ID
 		newPassword string
 		wantErr     bool
 		expectedErr error
@@ -398,14 +397,14 @@ func (suite *UserRepoTestSuite) TestUpdatePassword() {
 		},
 		{
 			name:        "non-existent user",
-			id:          primitive.NewObjectID(),
+			id:          models.NewID(),
 			newPassword: "newpassword",
 			wantErr:     true,
 			expectedErr: repo.UserNotFound,
 		},
 		{
 			name:        "nil object id",
-			id:          primitive.NilObjectID,
+			id:          models.NilID,
 			newPassword: "newpassword",
 			wantErr:     true,
 			expectedErr: repo.UserNotFound,
@@ -0,0 +1,80 @@
+package appservice
+
+import (
+	"context"
+
+	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/internal/repo"
+	"github.com/vandi37/Calculator/internal/status"
+	"github.com/vandi37/Calculator/pkg/parsing/tree"
+	"go.uber.org/zap"
+)
+
+// GetAst implements service.Service.
+func (s *Service) GetAst(ctx context.Context, expr models.Expression) (*models.AstNode, error) {
+	switch expr.Status {
+	case status.Finished:
+		// The tree is deleted, only the result is left
+		node := models.AstNode{Value: expr.Result, Complex: expr.Complex, Unit: expr.Unit, State: models.Resolved}
+		node.Exact = exact(expr.Decimal, expr.Rational)
+		return &node, nil
+	case status.Error:
+		return &models.AstNode{State: models.Failed, Error: expr.Error}, nil
+	}
+	nodes, err := s.expressionRepo.GetNodes(ctx, expr.NodeID)
+	if err != nil {
+		s.logger.Debug("error while getting nodes", zap.Error(err))
+		return nil, err
+	}
+	byId := make(map[models.ID]models.Node, len(nodes))
+	for _, node := range nodes {
+		byId[node.ID] = node
+	}
+	ast, err := build(byId, expr.NodeID)
+	if err != nil {
+		s.logger.Debug("error while building tree", zap.Error(err))
+		return nil, err
+	}
+	s.logger.Debug("tree got", zap.String("id", expr.ID.Hex()), zap.Int("nodes", len(nodes)))
+	return &ast, nil
+}
+
+func build(nodes map[models.ID]models.Node, id models.ID) (models.AstNode, error) {
+	node, ok := nodes[id]
+	if !ok {
+		return models.AstNode{}, repo.NodeNotFound
+	}
+	ast := models.AstNode{
+		ID:       id.Hex(),
+		Held:     node.Held != models.NilID,
+		SendedAt: node.SendedAt,
+	}
+	if node.Type == models.Number || node.Tree == nil {
+		ast.State = models.Resolved
+		ast.Value = node.Number
+		ast.Exact = exact(node.Decimal, node.Rational)
+		ast.Complex = node.Complex
+		ast.Unit = node.Unit
+		return ast, nil
+	}
+	ast.Operator = tree.Operation(node.Tree.Operator).String()
+	ast.State = models.Pending
+	if node.SendedAt != nil {
+		ast.State = models.Dispatched
+	}
+	for _, child := range node.Tree.Children() {
+		childAst, err := build(nodes, child)
+		if err != nil {
+			return models.AstNode{}, err
+		}
+		ast.Children = append(ast.Children, childAst)
+	}
+	return ast, nil
+}
+
+func exact(decimal string, rational string) string {
+	if decimal != "" {
+		return decimal
+	}
+	return rational
+}
@@ -0,0 +1,132 @@
+package appservice
+
+import (
+	"context"
+
+	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/pkg/parsing/parser"
+	"go.uber.org/zap"
+)
+
+// Parses the saved definitions of the user.
+// If changed isn't nil it is used instead of the definition with the same id (or added if it is new), so it can be checked before saving
+func (s *Service) definitions(ctx context.Context, userId models.ID, changed *models.Definition) (*parser.Definitions, error) {
+	saved, err := s.definitionRepo.GetByUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	definitions := make([]parser.Definition, 0, len(saved)+1)
+	for _, definition := range saved {
+		if changed != nil && definition.ID == changed.ID {
+			continue
+		}
+		definitions = append(definitions, parser.Definition{Name: definition.Name, Params: definition.Params, Body: definition.Body})
+	}
+	if changed != nil {
+		definitions = append(definitions, parser.Definition{Name: changed.Name, Params: changed.Params, Body: changed.Body})
+	}
+	return parser.NewDefinitions(definitions)
+}
+
+// Checks the definition with all other definitions of the user
+func (s *Service) checkDefinition(ctx context.Context, definition models.Definition) error {
+	definitions, err := s.definitions(ctx, definition.UserID, &definition)
+	if err != nil {
+		return err
+	}
+	return definitions.Check()
+}
+
+// AddDefinition implements service.Service.
+func (s *Service) AddDefinition(ctx context.Context, userId models.ID, req models.DefinitionRequest) (models.ID, error) {
+	definition := models.Definition{
+		UserID: userId,
+		Name:   req.Name,
+		Params: req.Params,
+		Body:   req.Body,
+	}
+	if err := s.checkDefinition(ctx, definition); err != nil {
+		s.logger.Debug("error while checking definition", zap.Error(err))
+		return models.NilID, err
+	}
+	id, err := s.definitionRepo.Create(ctx, definition)
+	if err != nil {
+		s.logger.Debug("error while creating definition", zap.Error(err))
+		return models.NilID, err
+	}
+	s.logger.Debug("definition created", zap.String("id", id.Hex()), zap.String("name", definition.Name))
+	return id, nil
+}
+
+// GetDefinition implements service.Service.
+func (s *Service) GetDefinition(ctx context.Context, id models.ID) (*models.Definition, error) {
+	definition, err := s.definitionRepo.Get(ctx, id)
+	if err != nil {
+		s.logger.Debug("error while getting definition", zap.Error(err))
+		return nil, err
+	}
+	s.logger.Debug("definition got", zap.String("id", id.Hex()))
+	return definition, nil
+}
+
+// GetDefinitions implements service.Service.
+func (s *Service) GetDefinitions(ctx context.Context, userId models.ID) ([]models.Definition, error) {
+	definitions, err := s.definitionRepo.GetByUser(ctx, userId)
+	if err != nil {
+		s.logger.Debug("error while getting definitions", zap.Error(err))
+		return nil, err
+	}
+	s.logger.Debug("definitions got", zap.String("user_id", userId.Hex()), zap.Int("count", len(definitions)))
+	return definitions, nil
+}
+
+// UpdateDefinition implements service.Service.
+func (s *Service) UpdateDefinition(ctx context.Context, id models.ID, req models.DefinitionRequest) error {
+	definition, err := s.definitionRepo.Get(ctx, id)
+	if err != nil {
+		s.logger.Debug("error while getting definition", zap.Error(err))
+		return err
+	}
+	definition.Name, definition.Params, definition.Body = req.Name, req.Params, req.Body
+	if err := s.checkDefinition(ctx, *definition); err != nil {
+		s.logger.Debug("error while checking definition", zap.Error(err))
+		return err
+	}
+	if err := s.definitionRepo.Update(ctx, *definition); err != nil {
+		s.logger.Debug("error while updating definition", zap.Error(err))
+		return err
+	}
+	s.logger.Debug("definition updated", zap.String("id", id.Hex()))
+	return nil
+}
+
+// DeleteDefinition implements service.Service.
+func (s *Service) DeleteDefinition(ctx context.Context, id models.ID) error {
+	definition, err := s.definitionRepo.Get(ctx, id)
+	if err != nil {
+		s.logger.Debug("error while getting definition", zap.Error(err))
+		return err
+	}
+	// The other definitions must not call the deleted function, deleted values just become variables
+	saved, err := s.definitionRepo.GetByUser(ctx, definition.UserID)
+	if err != nil {
+		s.logger.Debug("error while getting definitions", zap.Error(err))
+		return err
+	}
+	left := make([]parser.Definition, 0, len(saved))
+	for _, other := range saved {
+		if other.ID != id {
+			left = append(left, parser.Definition{Name: other.Name, Params: other.Params, Body: other.Body})
+		}
+	}
+	if _, err := parser.NewDefinitions(left); err != nil {
+		s.logger.Debug("definition is used", zap.Error(err))
+		return err
+	}
+	if err := s.definitionRepo.Delete(ctx, id); err != nil {
+		s.logger.Debug("error while deleting definition", zap.Error(err))
+		return err
+	}
+	s.logger.Debug("definition deleted", zap.String("id", id.Hex()))
+	return nil
+}
@@ -0,0 +1,79 @@
+package appservice
+
+import (
+	"context"
+	"crypto/subtle"
+	"slices"
+
+	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/internal/service"
+	"github.com/vandi37/Calculator/pkg/currency"
+	"github.com/vandi37/Calculator/pkg/parsing/parser"
+	"github.com/vandi37/Calculator/pkg/parsing/tree"
+	"go.uber.org/zap"
+)
+
+// Finds the currency of the result and the rates of all amounts.
+// The result is in the currency after "in" or in the currency of the first amount
+func (s *Service) rates(ctx context.Context, ast tree.Ast) (string, map[string]float64, error) {
+	codes := parser.Currencies(ast)
+	if len(codes) == 0 {
+		return "", nil, nil
+	}
+	to := ast.Currency
+	if to == "" {
+		to = codes[0]
+	} else if !slices.Contains(codes, to) {
+		codes = append(codes, to)
+	}
+	rates, err := s.rateRepo.GetRates(ctx, codes)
+	if err != nil {
+		return "", nil, err
+	}
+	return to, rates, nil
+}
+
+// GetRates implements service.Service.
+func (s *Service) GetRates(ctx context.Context) ([]models.Rate, error) {
+	rates, err := s.rateRepo.GetAll(ctx)
+	if err != nil {
+		s.logger.Debug("error while getting rates", zap.Error(err))
+		return nil, err
+	}
+	return rates, nil
+}
+
+// SetRate implements service.Service.
+func (s *Service) SetRate(ctx context.Context, code string, rate float64) error {
+	if !currency.IsCode(code) {
+		return service.InvalidCurrency
+	}
+	if !currency.IsRate(rate) {
+		return service.InvalidRate
+	}
+	if err := s.rateRepo.Set(ctx, models.Rate{Code: code, Rate: rate}); err != nil {
+		s.logger.Debug("error while setting rate", zap.Error(err))
+		return err
+	}
+	s.logger.Debug("rate set", zap.String("code", code), zap.Float64("rate", rate))
+	return nil
+}
+
+// DeleteRate implements service.Service.
+func (s *Service) DeleteRate(ctx context.Context, code string) error {
+	if err := s.rateRepo.Delete(ctx, code); err != nil {
+		s.logger.Debug("error while deleting rate", zap.Error(err))
+		return err
+	}
+	s.logger.Debug("rate deleted", zap.String("code", code))
+	return nil
+}
+
+// CheckAdmin implements service.Service.
+func (s *Service) CheckAdmin(ctx context.Context, token string) error {
+	if s.adminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) != 1 {
+		s.logger.Debug("invalid admin token")
+		return service.NotAdmin
+	}
+	return nil
+}
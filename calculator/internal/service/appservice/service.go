@@ -2,16 +2,25 @@ package appservice
 
 import (
 	"context"
+	"fmt"
+	"math"
 
 	pb "github.com/vandi37/Calculator-Models"
+	"github.com/vandi37/Calculator/internal/fold"
 	"github.com/vandi37/Calculator/internal/models"
 	"github.com/vandi37/Calculator/internal/ms"
 	"github.com/vandi37/Calculator/internal/repo"
 	"github.com/vandi37/Calculator/internal/service"
 	"github.com/vandi37/Calculator/pkg/hash"
 	"github.com/vandi37/Calculator/pkg/jwt"
+	"github.com/vandi37/Calculator/pkg/parsing"
 	"github.com/vandi37/Calculator/pkg/parsing/parser"
-	"go.mongodb.org/mongo-driver/bson/primitive"
+	"github.com/vandi37/Calculator/pkg/parsing/reduce"
+	"github.com/vandi37/Calculator/pkg/parsing/simplify"
+	"github.com/vandi37/Calculator/pkg/parsing/tree"
+	"github.com/vandi37/Calculator/pkg/units"
+	"github.com/vandi37/Calculator/pkg/wire"
+	"github.com/vandi37/vanerrors"
 	"go.uber.org/zap"
 )
 
@@ -22,23 +31,32 @@ const (
 func New(
 	logger *zap.Logger,
 	msGetter *ms.MsGetter,
+	foldGetter *fold.FoldGetter,
 	userRepo repo.UserRepo,
 	expressionRepo repo.ExpressionRepo,
+	definitionRepo repo.DefinitionRepo,
+	rateRepo repo.RateRepo,
 	passwordService *hash.PasswordService,
 	tokenService *jwt.TokenService,
+	adminToken string,
 ) *Service {
-	return &Service{msGetter, userRepo, expressionRepo, make(chan *pb.Task, TASK_CAPACITY), logger, passwordService, tokenService, false}
+	return &Service{msGetter, foldGetter, userRepo, expressionRepo, definitionRepo, rateRepo, make(chan *pb.Task, TASK_CAPACITY), logger, passwordService, tokenService, adminToken, false}
 }
 
 type Service struct {
 	msGetter        *ms.MsGetter
+	foldGetter      *fold.FoldGetter
 	userRepo        repo.UserRepo
 	expressionRepo  repo.ExpressionRepo
+	definitionRepo  repo.DefinitionRepo
+	rateRepo        repo.RateRepo
 	tasks           chan *pb.Task
 	logger          *zap.Logger
 	passwordService *hash.PasswordService
 	tokenService    *jwt.TokenService
-	closed          bool
+	// Empty token turns the admin endpoints off
+	adminToken string
+	closed     bool
 }
 
 // Init implements service.Service.
@@ -58,11 +76,11 @@ func (s *Service) Close() error {
 var _ service.Service = (*Service)(nil)
 
 // Register implements service.Service.
-func (s *Service) Register(ctx context.Context, username string, password string) (primitive.ObjectID, error) {
+func (s *Service) Register(ctx context.Context, username string, password string) (models.ID, error) {
 	hash, err := s.passwordService.HashPassword(password)
 	if err != nil {
 		s.logger.Debug("error while hashing password", zap.Error(err))
-		return primitive.NilObjectID, err
+		return models.NilID, err
 	}
 	id, err := s.userRepo.Register(ctx, models.User{
 		Username: username,
@@ -70,7 +88,7 @@ func (s *Service) Register(ctx context.Context, username string, password string
 	})
 	if err != nil {
 		s.logger.Debug("error while registering user", zap.Error(err))
-		return primitive.NilObjectID, err
+		return models.NilID, err
 	}
 	s.logger.Debug("user registered", zap.String("id", id.Hex()))
 	return id, nil
@@ -100,35 +118,35 @@ func (s *Service) Login(ctx context.Context, username string, password string) (
 }
 
 // CheckToken implements service.Service.
-func (s *Service) CheckToken(ctx context.Context, token string) (primitive.ObjectID, error) {
+func (s *Service) CheckToken(ctx context.Context, token string) (models.ID, error) {
 	parsedToken, err := s.tokenService.Parse(token)
 	if err != nil {
 		s.logger.Debug("error while parsing token", zap.Error(err))
 		// Making error less specific
-		return primitive.NilObjectID, service.InvalidToken
+		return models.NilID, service.InvalidToken
 	}
 	if !parsedToken.Valid {
 		s.logger.Debug("invalid token")
-		return primitive.NilObjectID, service.InvalidToken
+		return models.NilID, service.InvalidToken
 	}
 	sub, err := parsedToken.Claims.GetSubject()
 	if err != nil {
 		s.logger.Debug("error while getting subject", zap.Error(err))
 		// Making error less specific
-		return primitive.NilObjectID, service.InvalidToken
+		return models.NilID, service.InvalidToken
 	}
-	id, err := primitive.ObjectIDFromHex(sub)
+	id, err := models.IDFromHex(sub)
 	if err != nil {
 		s.logger.Debug("error while parsing id", zap.Error(err))
 		// Making error less specific
-		return primitive.NilObjectID, service.InvalidToken
+		return models.NilID, service.InvalidToken
 	}
 	s.logger.Debug("token checked", zap.String("id", id.Hex()))
 	return id, nil
 }
 
 // UpdatePassword implements service.Service.
-func (s *Service) UpdatePassword(ctx context.Context, id primitive.ObjectID, password string) error {
+func (s *Service) UpdatePassword(ctx context.Context, id models.ID, password string) error {
 	hash, err := s.passwordService.HashPassword(password)
 	if err != nil {
 		s.logger.Debug("error while hashing password", zap.Error(err))
@@ -144,7 +162,7 @@ func (s *Service) UpdatePassword(ctx context.Context, id primitive.ObjectID, pas
 }
 
 // UpdateUsername implements service.Service.
-func (s *Service) UpdateUsername(ctx context.Context, id primitive.ObjectID, username string) error {
+func (s *Service) UpdateUsername(ctx context.Context, id models.ID, username string) error {
 	err := s.userRepo.UpdateUsername(ctx, id, username)
 	if err != nil {
 		s.logger.Debug("error while updating username", zap.Error(err))
@@ -155,8 +173,19 @@ func (s *Service) UpdateUsername(ctx context.Context, id primitive.ObjectID, use
 }
 
 // Delete implements service.Service.
-func (s *Service) Delete(ctx context.Context, id primitive.ObjectID) error {
-	err := s.userRepo.Delete(ctx, id)
+// The user is deleted last, so the deletion can be repeated if a call fails
+func (s *Service) Delete(ctx context.Context, id models.ID) error {
+	err := s.definitionRepo.DeleteByUser(ctx, id)
+	if err != nil {
+		s.logger.Debug("error while deleting definitions", zap.Error(err))
+		return err
+	}
+	err = s.expressionRepo.DeleteByUser(ctx, id)
+	if err != nil {
+		s.logger.Debug("error while deleting expressions", zap.Error(err))
+		return err
+	}
+	err = s.userRepo.Delete(ctx, id)
 	if err != nil {
 		s.logger.Debug("error while deleting user", zap.Error(err))
 		return err
@@ -166,33 +195,186 @@ func (s *Service) Delete(ctx context.Context, id primitive.ObjectID) error {
 }
 
 // Add implements service.Service.
-func (s *Service) Add(ctx context.Context, expression string, userId primitive.ObjectID) (primitive.ObjectID, error) {
-	ast, err := parser.Build(expression)
+func (s *Service) Add(ctx context.Context, req models.CalculationRequest, userId models.ID) (models.ID, error) {
+	expression, variables := req.Expression, req.Variables
+	if !req.Precision.IsValid() {
+		s.logger.Debug("invalid precision", zap.String("precision", string(req.Precision)))
+		return models.NilID, service.InvalidPrecision
+	}
+	definitions, err := s.definitions(ctx, userId, nil)
+	if err != nil {
+		s.logger.Debug("error while getting definitions", zap.Error(err))
+		return models.NilID, err
+	}
+	ast, err := parser.BuildWithPrecision(expression, definitions, req.Precision)
 	if err != nil {
 		s.logger.Debug("error while parsing expression", zap.Error(err))
-		return primitive.NilObjectID, err
+		return models.NilID, err
+	}
+	ast, err = definitions.Expand(ast, variables)
+	if err != nil {
+		s.logger.Debug("error while expanding definitions", zap.Error(err))
+		return models.NilID, err
+	}
+	ast, err = parser.Bind(ast, variables)
+	if err != nil {
+		s.logger.Debug("error while binding variables", zap.Error(err))
+		return models.NilID, err
+	}
+	// Incompatible units are found before the folding, 5 m + 0 would lose the check
+	amount, err := checkUnits(ast, req)
+	if err != nil {
+		s.logger.Debug("error while checking units", zap.Error(err))
+		return models.NilID, err
+	}
+	code, rates, err := s.rates(ctx, ast)
+	if err != nil {
+		s.logger.Debug("error while getting rates", zap.Error(err))
+		return models.NilID, err
+	}
+	if code != "" {
+		if ast, err = parser.ApplyRates(ast, rates, code, req.Precision); err != nil {
+			s.logger.Debug("error while converting amounts", zap.Error(err))
+			return models.NilID, err
+		}
+		// Amounts are still converted to compare them, but the result is a number
+		if !amount {
+			code = ""
+		}
+	}
+	// Values of the variables and the saved definitions are floats
+	switch req.Precision {
+	case tree.DecimalPrecision:
+		ast = parser.ToDecimal(ast)
+	case tree.RationalPrecision:
+		ast = parser.ToRational(ast)
+	}
+	ast, eliminated := simplify.Simplify(ast, s.foldGetter.Enabled)
+	if !req.KeepOrder {
+		ast = reduce.Rebalance(ast)
 	}
 	expr := models.Expression{
-		UserID: userId,
-		Origin: expression,
+		UserID:     userId,
+		Origin:     expression,
+		Variables:  variables,
+		Eliminated: eliminated,
+		Depth:      reduce.Depth(ast.Expression),
+		Precision:  req.Precision,
+		TargetUnit: req.TargetUnit,
+		Currency:   code,
+		Rates:      rates,
 	}
 	id, err := s.expressionRepo.Create(ctx, expr, ast)
 	if err != nil {
 		s.logger.Debug("error while creating expression", zap.Error(err))
-		return primitive.NilObjectID, err
+		return models.NilID, err
 	}
 	s.logger.Debug("expression created", zap.String("id", id.Hex()), zap.String("expression", expression))
 	return id, nil
 }
 
+// The target unit and the currency must have the dimension of the result, if it is known before the calculation
+func checkUnits(ast tree.Ast, req models.CalculationRequest) (bool, error) {
+	dimension, known, err := parser.Dimension(ast)
+	if err != nil {
+		return false, err
+	}
+	amount := !known || dimension == units.Money
+	// The result of amounts is an amount, it gets the currency.
+	// Comparisons and ratios of amounts are numbers
+	codes := parser.Currencies(ast)
+	if ast.Currency != "" && !amount {
+		return false, incompatible(dimension, ast.Currency)
+	}
+	if len(codes) > 0 && !amount && !dimension.IsZero() {
+		return false, incompatible(dimension, codes[0])
+	}
+	if req.TargetUnit == "" {
+		return amount && len(codes) > 0, nil
+	}
+	if req.Precision == tree.DecimalPrecision || req.Precision == tree.RationalPrecision {
+		return false, vanerrors.New(parser.UnitPrecision, fmt.Sprintf("target unit %s in the %s precision", req.TargetUnit, req.Precision))
+	}
+	target, err := units.Parse(req.TargetUnit)
+	if err != nil {
+		return false, err
+	}
+	if known && target.Dimension != dimension {
+		return false, incompatible(dimension, req.TargetUnit)
+	}
+	return amount && len(codes) > 0, nil
+}
+
+func incompatible(dimension units.Dimension, target string) error {
+	result := dimension.String()
+	if result == "" {
+		result = "a number"
+	}
+	return vanerrors.New(units.IncompatibleUnits, fmt.Sprintf("the result is %s, it can't be converted to %s", result, target))
+}
+
+// The result is saved in the SI base units, it is converted only if its unit matches the target
+func convert(expr *models.Expression) {
+	if expr.TargetUnit == "" || expr.Result == nil {
+		return
+	}
+	target, err := units.Parse(expr.TargetUnit)
+	if err != nil || target.Dimension.String() != expr.Unit {
+		return
+	}
+	converted := *expr.Result / target.Factor
+	expr.Converted = &converted
+}
+
+// Validate implements service.Service.
+func (s *Service) Validate(ctx context.Context, expression string, userId models.ID) ([]*parsing.Error, error) {
+	definitions, err := s.definitions(ctx, userId, nil)
+	if err != nil {
+		s.logger.Debug("error while getting definitions", zap.Error(err))
+		return nil, err
+	}
+	errs := parser.Validate(expression, definitions)
+	s.logger.Debug("expression validated", zap.String("expression", expression), zap.Int("errors", len(errs)))
+	return errs, nil
+}
+
 // DoTask implements service.Service.
 func (s *Service) DoTask(ctx context.Context, result *pb.Result) error {
-	realId, err := primitive.ObjectIDFromHex(result.Id)
+	realId, err := nodeId(result.Id)
 	if err != nil {
 		s.logger.Debug("error while converting id", zap.Error(err))
 		return err
 	}
-	err = s.expressionRepo.SetToNum(ctx, realId, result.Result)
+	im, isComplex := wire.GetDouble(result, wire.ResultImag)
+	rational, isRational := wire.GetString(result, wire.ResultRational)
+	decimal, isDecimal := wire.GetString(result, wire.ResultDecimal)
+	// Old agents send infinities as results, they can't be saved.
+	// Exact results are finite, only their float can be out of its range
+	if code, message, ok := notFinite(result.Result, im); ok && !isRational && !isDecimal {
+		s.logger.Debug("result is not finite", zap.String("id", result.Id), zap.String("code", code))
+		if err := s.expressionRepo.SetToError(ctx, realId, code, message); err != nil {
+			s.logger.Debug("error while setting error", zap.Error(err))
+			return err
+		}
+		return nil
+	}
+	if warning, ok := wire.GetString(result, wire.ResultWarning); ok {
+		if err := s.expressionRepo.AddWarning(ctx, realId, warning); err != nil {
+			s.logger.Debug("error while adding warning", zap.Error(err))
+			return err
+		}
+	}
+	if isComplex {
+		err = s.expressionRepo.SetToComplex(ctx, realId, models.Complex{Re: result.Result, Im: im})
+	} else if unit, ok := wire.GetString(result, wire.ResultUnit); ok {
+		err = s.expressionRepo.SetToUnit(ctx, realId, result.Result, unit)
+	} else if isRational {
+		err = s.expressionRepo.SetToRational(ctx, realId, approximate(rational, result.Result), rational)
+	} else if isDecimal {
+		err = s.expressionRepo.SetToDecimal(ctx, realId, approximate(decimal, result.Result), decimal)
+	} else {
+		err = s.expressionRepo.SetToNum(ctx, realId, result.Result)
+	}
 	if err != nil {
 		s.logger.Debug("error while setting result", zap.Error(err))
 		return err
@@ -201,14 +383,46 @@ func (s *Service) DoTask(ctx context.Context, result *pb.Result) error {
 	return nil
 }
 
+// The ids of the tasks are the ids of the nodes
+func nodeId(hex string) (repo.ID, error) {
+	return models.IDFromHex(hex)
+}
+
+// The float sent beside an exact result is ±Inf when it is out of the range, the clamped one is saved instead
+func approximate(exact string, f float64) float64 {
+	if approximated, ok := tree.Approximate(exact); ok {
+		return approximated
+	}
+	return f
+}
+
+// The messages are the same as the agent sends
+func notFinite(parts ...float64) (string, string, bool) {
+	for _, f := range parts {
+		if math.IsNaN(f) {
+			return wire.NaN, "result is not a number", true
+		}
+	}
+	for _, f := range parts {
+		if math.IsInf(f, 0) {
+			return wire.Overflow, "result is too big", true
+		}
+	}
+	return "", "", false
+}
+
 // DoError implements service.Service.
 func (s *Service) DoError(ctx context.Context, res *pb.Error) error {
-	realId, err := primitive.ObjectIDFromHex(res.Id)
+	realId, err := nodeId(res.Id)
 	if err != nil {
 		s.logger.Debug("error while converting id", zap.Error(err))
 		return err
 	}
-	err = s.expressionRepo.SetToError(ctx, realId, res.Error)
+	code, ok := wire.GetString(res, wire.ErrorCode)
+	if !ok {
+		code = wire.UnknownError
+	}
+	err = s.expressionRepo.SetToError(ctx, realId, code, res.Error)
 	if err != nil {
 		s.logger.Debug("error while setting error", zap.Error(err))
 		return err
@@ -218,23 +432,27 @@ func (s *Service) DoError(ctx context.Context, res *pb.Error) error {
 }
 
 // Get implements service.Service.
-func (s *Service) Get(ctx context.Context, id primitive.ObjectID) (*models.Expression, error) {
+func (s *Service) Get(ctx context.Context, id models.ID) (*models.Expression, error) {
 	expr, err := s.expressionRepo.Get(ctx, id)
 	if err != nil {
 		s.logger.Debug("error while getting expression", zap.Error(err))
 		return nil, err
 	}
+	convert(expr)
 	s.logger.Debug("expression got", expr.ZapField())
 	return expr, nil
 }
 
 // GetAll implements service.Service.
-func (s *Service) GetByUSer(ctx context.Context, userId primitive.ObjectID) ([]models.Expression, error) {
+func (s *Service) GetByUSer(ctx context.Context, userId models.ID) ([]models.Expression, error) {
 	expressions, err := s.expressionRepo.GetByUser(ctx, userId)
 	if err != nil {
 		s.logger.Debug("error while getting expressions", zap.Error(err))
 		return nil, err
 	}
+	for i := range expressions {
+		convert(&expressions[i])
+	}
 	s.logger.Debug("expressions got", zap.String("user_id", userId.Hex()), zap.Int("count", len(expressions)))
 	return expressions, nil
 }
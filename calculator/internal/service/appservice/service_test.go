@@ -3,21 +3,30 @@ package appservice_test
 import (
 	"context"
 	"errors"
+	"math"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	pb "github.com/vandi37/Calculator-Models"
 	"github.com/vandi37/Calculator/internal/config"
+	"github.com/vandi37/Calculator/internal/fold"
 	"github.com/vandi37/Calculator/internal/models"
 	"github.com/vandi37/Calculator/internal/ms"
+	"github.com/vandi37/Calculator/internal/repo"
 	"github.com/vandi37/Calculator/internal/repo/mock_repo"
+	"github.com/vandi37/Calculator/internal/service"
 	"github.com/vandi37/Calculator/internal/service/appservice"
 	"github.com/vandi37/Calculator/internal/status"
+	"github.com/vandi37/Calculator/pkg/currency"
 	"github.com/vandi37/Calculator/pkg/hash"
 	"github.com/vandi37/Calculator/pkg/jwt"
-	"go.mongodb.org/mongo-driver/bson/primitive"
+	"github.com/vandi37/Calculator/pkg/parsing/tree"
+	"github.com/vandi37/Calculator/pkg/units"
+	"github.com/vandi37/Calculator/pkg/wire"
 	"go.uber.org/zap"
 )
 
@@ -27,6 +36,7 @@ func TestService_Register(t *testing.T) {
 
 	mockUserRepo := mock_repo.NewMockUserRepo(ctrl)
 	mockExprRepo := mock_repo.NewMockExpressionRepo(ctrl)
+	mockDefRepo := mock_repo.NewMockDefinitionRepo(ctrl)
 
 	passwordService := hash.NewPasswordService(nil)
 	tokenService := jwt.New("secret", time.Hour, 0)
@@ -37,7 +47,7 @@ func TestService_Register(t *testing.T) {
 		DivisionMs:       400,
 	})
 
-	svc := appservice.New(zap.NewNop(), msGetter, mockUserRepo, mockExprRepo, passwordService, tokenService)
+	svc := appservice.New(zap.NewNop(), msGetter, fold.From(config.Fold{}), mockUserRepo, mockExprRepo, mockDefRepo, mock_repo.NewMockRateRepo(ctrl), passwordService, tokenService, "")
 
 	tests := []struct {
 		name        string
@@ -52,7 +62,7 @@ func TestService_Register(t *testing.T) {
 			password: "testpass",
 			mockSetup: func() {
 				mockUserRepo.EXPECT().Register(gomock.Any(), gomock.Any()).
-					Return(primitive.NewObjectID(), nil)
+					Return(models.NewID(), nil)
 			},
 		},
 		{
@@ -70,7 +80,7 @@ func TestService_Register(t *testing.T) {
 			password: "testpass",
 			mockSetup: func() {
 				mockUserRepo.EXPECT().Register(gomock.Any(), gomock.Any()).
-					Return(primitive.NilObjectID, errors.New("repo error"))
+					Return(repo.ID{}, errors.New("repo error"))
 			},
 			expectError: true,
 		},
@@ -86,10 +96,65 @@ func TestService_Register(t *testing.T) {
 
 			if tt.expectError {
 				assert.Error(t, err)
-				assert.Equal(t, primitive.NilObjectID, id)
+				assert.Equal(t, models.NilID, id)
+			} else {
+				assert.NoError(t, err)
+				assert.NotEqual(t, models.NilID, id)
+			}
+		})
+	}
+}
+
+func TestService_Delete(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserRepo := mock_repo.NewMockUserRepo(ctrl)
+	mockExprRepo := mock_repo.NewMockExpressionRepo(ctrl)
+	mockDefRepo := mock_repo.NewMockDefinitionRepo(ctrl)
+	svc := appservice.New(zap.NewNop(), ms.From(config.Time{}), fold.From(config.Fold{}), mockUserRepo, mockExprRepo, mockDefRepo, mock_repo.NewMockRateRepo(ctrl), hash.NewPasswordService(nil), jwt.New("secret", time.Hour, 0), "")
+
+	id := models.NewID()
+	tests := []struct {
+		name        string
+		mockSetup   func()
+		expectError bool
+	}{
+		{
+			name: "Successful deletion",
+			mockSetup: func() {
+				gomock.InOrder(
+					mockDefRepo.EXPECT().DeleteByUser(gomock.Any(), id).Return(nil),
+					mockExprRepo.EXPECT().DeleteByUser(gomock.Any(), id).Return(nil),
+					mockUserRepo.EXPECT().Delete(gomock.Any(), id).Return(nil),
+				)
+			},
+		},
+		{
+			name: "Definitions error keeps the user",
+			mockSetup: func() {
+				mockDefRepo.EXPECT().DeleteByUser(gomock.Any(), id).Return(errors.New("repo error"))
+			},
+			expectError: true,
+		},
+		{
+			name: "Expressions error keeps the user",
+			mockSetup: func() {
+				mockDefRepo.EXPECT().DeleteByUser(gomock.Any(), id).Return(nil)
+				mockExprRepo.EXPECT().DeleteByUser(gomock.Any(), id).Return(errors.New("repo error"))
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+			err := svc.Delete(context.Background(), id)
+			if tt.expectError {
+				assert.Error(t, err)
 			} else {
 				assert.NoError(t, err)
-				assert.NotEqual(t, primitive.NilObjectID, id)
 			}
 		})
 	}
@@ -101,6 +166,7 @@ func TestService_Login(t *testing.T) {
 
 	mockUserRepo := mock_repo.NewMockUserRepo(ctrl)
 	mockExprRepo := mock_repo.NewMockExpressionRepo(ctrl)
+	mockDefRepo := mock_repo.NewMockDefinitionRepo(ctrl)
 
 	passwordService := hash.NewPasswordService(nil)
 	tokenService := jwt.New("secret", time.Hour, 0)
@@ -111,9 +177,9 @@ func TestService_Login(t *testing.T) {
 		DivisionMs:       400,
 	})
 
-	svc := appservice.New(zap.NewNop(), msGetter, mockUserRepo, mockExprRepo, passwordService, tokenService)
+	svc := appservice.New(zap.NewNop(), msGetter, fold.From(config.Fold{}), mockUserRepo, mockExprRepo, mockDefRepo, mock_repo.NewMockRateRepo(ctrl), passwordService, tokenService, "")
 
-	userID := primitive.NewObjectID()
+	userID := models.NewID()
 	hashedPass, _ := passwordService.HashPassword("correctpass")
 
 	tests := []struct {
@@ -187,6 +253,7 @@ func TestService_CheckToken(t *testing.T) {
 
 	mockUserRepo := mock_repo.NewMockUserRepo(ctrl)
 	mockExprRepo := mock_repo.NewMockExpressionRepo(ctrl)
+	mockDefRepo := mock_repo.NewMockDefinitionRepo(ctrl)
 
 	passwordService := hash.NewPasswordService(nil)
 	tokenService := jwt.New("secret", time.Hour, 0)
@@ -197,16 +264,16 @@ func TestService_CheckToken(t *testing.T) {
 		DivisionMs:       400,
 	})
 
-	svc := appservice.New(zap.NewNop(), msGetter, mockUserRepo, mockExprRepo, passwordService, tokenService)
+	svc := appservice.New(zap.NewNop(), msGetter, fold.From(config.Fold{}), mockUserRepo, mockExprRepo, mockDefRepo, mock_repo.NewMockRateRepo(ctrl), passwordService, tokenService, "")
 
-	userID := primitive.NewObjectID()
+	userID := models.NewID()
 	validToken, _ := tokenService.Generate(userID.Hex())
 
 	tests := []struct {
 		name        string
 		token       string
 		expectError bool
-		expectedID  primitive.ObjectID
+		expectedID  models.ID
 	}{
 		{
 			name:       "Valid token",
@@ -231,7 +298,7 @@ func TestService_CheckToken(t *testing.T) {
 
 			if tt.expectError {
 				assert.Error(t, err)
-				assert.Equal(t, primitive.NilObjectID, id)
+				assert.Equal(t, models.NilID, id)
 			} else {
 				assert.NoError(t, err)
 				assert.Equal(t, tt.expectedID, id)
@@ -246,6 +313,8 @@ func TestService_AddExpression(t *testing.T) {
 
 	mockUserRepo := mock_repo.NewMockUserRepo(ctrl)
 	mockExprRepo := mock_repo.NewMockExpressionRepo(ctrl)
+	mockDefRepo := mock_repo.NewMockDefinitionRepo(ctrl)
+	mockRateRepo := mock_repo.NewMockRateRepo(ctrl)
 
 	passwordService := hash.NewPasswordService(nil)
 	tokenService := jwt.New("secret", time.Hour, 0)
@@ -256,13 +325,18 @@ func TestService_AddExpression(t *testing.T) {
 		DivisionMs:       400,
 	})
 
-	svc := appservice.New(zap.NewNop(), msGetter, mockUserRepo, mockExprRepo, passwordService, tokenService)
+	svc := appservice.New(zap.NewNop(), msGetter, fold.From(config.Fold{}), mockUserRepo, mockExprRepo, mockDefRepo, mockRateRepo, passwordService, tokenService, "")
 
-	userID := primitive.NewObjectID()
+	userID := models.NewID()
 
 	tests := []struct {
 		name        string
 		expression  string
+		variables   map[string]float64
+		precision   tree.Precision
+		targetUnit  string
+		keepOrder   bool
+		definitions []models.Definition
 		mockSetup   func()
 		expectError bool
 	}{
@@ -271,9 +345,260 @@ func TestService_AddExpression(t *testing.T) {
 			expression: "2+2",
 			mockSetup: func() {
 				mockExprRepo.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any()).
-					Return(primitive.NewObjectID(), nil)
+					Return(models.NewID(), nil)
+			},
+		},
+		{
+			name:       "Variables",
+			expression: "price * qty",
+			variables:  map[string]float64{"price": 2.5, "qty": 4},
+			mockSetup: func() {
+				mockExprRepo.EXPECT().Create(gomock.Any(), models.Expression{
+					UserID:    userID,
+					Origin:    "price * qty",
+					Depth:     1,
+					Variables: map[string]float64{"price": 2.5, "qty": 4},
+				}, tree.Ast{Expression: tree.Expression{
+					Left:      tree.Num(2.5),
+					Operation: tree.Operation(pb.Operation_MULTIPLY),
+					Right:     tree.Num(4),
+				}}).Return(models.NewID(), nil)
+			},
+		},
+		{
+			name:       "Saved definitions",
+			expression: "f(10)",
+			definitions: []models.Definition{
+				{UserID: userID, Name: "rate", Body: "0.2"},
+				{UserID: userID, Name: "f", Params: []string{"x"}, Body: "x * rate"},
+			},
+			mockSetup: func() {
+				mockExprRepo.EXPECT().Create(gomock.Any(), gomock.Any(), tree.Ast{Expression: tree.Expression{
+					Left:      tree.Num(10),
+					Operation: tree.Operation(pb.Operation_MULTIPLY),
+					Right:     tree.Num(0.2),
+				}}).Return(models.NewID(), nil)
+			},
+		},
+		{
+			name:       "Variables are stronger than saved values",
+			expression: "rate * 2",
+			variables:  map[string]float64{"rate": 0.5},
+			definitions: []models.Definition{
+				{UserID: userID, Name: "rate", Body: "0.2"},
+			},
+			mockSetup: func() {
+				mockExprRepo.EXPECT().Create(gomock.Any(), gomock.Any(), tree.Ast{Expression: tree.Expression{
+					Left:      tree.Num(0.5),
+					Operation: tree.Operation(pb.Operation_MULTIPLY),
+					Right:     tree.Num(2),
+				}}).Return(models.NewID(), nil)
+			},
+		},
+		{
+			name:       "Decimal precision",
+			expression: "0.1 + x",
+			variables:  map[string]float64{"x": 0.2},
+			precision:  tree.DecimalPrecision,
+			mockSetup: func() {
+				mockExprRepo.EXPECT().Create(gomock.Any(), models.Expression{
+					UserID:    userID,
+					Origin:    "0.1 + x",
+					Depth:     1,
+					Variables: map[string]float64{"x": 0.2},
+					Precision: tree.DecimalPrecision,
+				}, tree.Ast{Expression: tree.Expression{
+					Left:      tree.Decimal("0.1"),
+					Operation: tree.Operation(pb.Operation_ADD),
+					Right:     tree.Decimal("0.2"),
+				}}).Return(models.NewID(), nil)
+			},
+		},
+		{
+			name:       "Rational precision",
+			expression: "1/3 * x",
+			variables:  map[string]float64{"x": 0.3},
+			precision:  tree.RationalPrecision,
+			mockSetup: func() {
+				mockExprRepo.EXPECT().Create(gomock.Any(), models.Expression{
+					UserID:    userID,
+					Origin:    "1/3 * x",
+					Depth:     2,
+					Variables: map[string]float64{"x": 0.3},
+					Precision: tree.RationalPrecision,
+				}, tree.Ast{Expression: tree.Expression{
+					Left: tree.Expression{
+						Left:      tree.Rational("1"),
+						Operation: tree.Operation(pb.Operation_DIVIDE),
+						Right:     tree.Rational("3"),
+					},
+					Operation: tree.Operation(pb.Operation_MULTIPLY),
+					Right:     tree.Rational("3/10"),
+				}}).Return(models.NewID(), nil)
+			},
+		},
+		{
+			name:       "Target unit",
+			expression: "100 km / 2 h",
+			targetUnit: "km/h",
+			mockSetup: func() {
+				mockExprRepo.EXPECT().Create(gomock.Any(), models.Expression{
+					UserID:     userID,
+					Origin:     "100 km / 2 h",
+					Depth:      1,
+					TargetUnit: "km/h",
+				}, tree.Ast{Expression: tree.Expression{
+					Left:      tree.Quantity{Value: 100000, Dimension: units.Dimension{1}},
+					Operation: tree.Operation(pb.Operation_DIVIDE),
+					Right:     tree.Quantity{Value: 7200, Dimension: units.Dimension{0, 0, 1}},
+				}}).Return(models.NewID(), nil)
+			},
+		},
+		{
+			name:        "Incompatible units",
+			expression:  "5 m + 2 s",
+			expectError: true,
+		},
+		{
+			name:       "Currency",
+			expression: "100 USD + 50 EUR in GBP",
+			mockSetup: func() {
+				rates := map[string]float64{"USD": 1, "EUR": 0.5, "GBP": 0.8}
+				mockRateRepo.EXPECT().GetRates(gomock.Any(), []string{"USD", "EUR", "GBP"}).Return(rates, nil)
+				mockExprRepo.EXPECT().Create(gomock.Any(), models.Expression{
+					UserID:   userID,
+					Origin:   "100 USD + 50 EUR in GBP",
+					Depth:    1,
+					Currency: "GBP",
+					Rates:    rates,
+				}, tree.Ast{Expression: tree.Expression{
+					Left:      tree.Quantity{Value: 80, Dimension: units.Money},
+					Operation: tree.Operation(pb.Operation_ADD),
+					Right:     tree.Quantity{Value: 80, Dimension: units.Money},
+				}, Currency: "GBP"}).Return(models.NewID(), nil)
+			},
+		},
+		{
+			name:       "Currency in rational precision",
+			expression: "10 USD / 3",
+			precision:  tree.RationalPrecision,
+			mockSetup: func() {
+				rates := map[string]float64{"USD": 1}
+				mockRateRepo.EXPECT().GetRates(gomock.Any(), []string{"USD"}).Return(rates, nil)
+				mockExprRepo.EXPECT().Create(gomock.Any(), models.Expression{
+					UserID:    userID,
+					Origin:    "10 USD / 3",
+					Depth:     1,
+					Precision: tree.RationalPrecision,
+					Currency:  "USD",
+					Rates:     rates,
+				}, tree.Ast{Expression: tree.Expression{
+					Left:      tree.Rational("10"),
+					Operation: tree.Operation(pb.Operation_DIVIDE),
+					Right:     tree.Rational("3"),
+				}}).Return(models.NewID(), nil)
+			},
+		},
+		{
+			name:       "Comparison of amounts",
+			expression: "100 USD > 90 EUR",
+			mockSetup: func() {
+				rates := map[string]float64{"USD": 1, "EUR": 0.5}
+				mockRateRepo.EXPECT().GetRates(gomock.Any(), []string{"USD", "EUR"}).Return(rates, nil)
+				mockExprRepo.EXPECT().Create(gomock.Any(), models.Expression{
+					UserID: userID,
+					Origin: "100 USD > 90 EUR",
+					Depth:  1,
+					Rates:  rates,
+				}, tree.Ast{Expression: tree.Expression{
+					Left:      tree.Quantity{Value: 100, Dimension: units.Money},
+					Operation: tree.Greater,
+					Right:     tree.Quantity{Value: 180, Dimension: units.Money},
+				}}).Return(models.NewID(), nil)
 			},
 		},
+		{
+			name:        "Comparison in a currency",
+			expression:  "100 USD > 90 EUR in USD",
+			expectError: true,
+		},
+		{
+			name:       "Unknown currency",
+			expression: "100 USD + 50 XYZ",
+			mockSetup: func() {
+				mockRateRepo.EXPECT().GetRates(gomock.Any(), gomock.Any()).
+					Return(nil, errors.New(currency.UnknownCurrency))
+			},
+			expectError: true,
+		},
+		{
+			name:        "Number in a currency",
+			expression:  "2 + 3 in USD",
+			expectError: true,
+		},
+		{
+			name:        "Product of amounts",
+			expression:  "100 USD * 2 USD",
+			expectError: true,
+		},
+		{
+			name:       "Rebalanced chain",
+			expression: "1 + 2 + 3 + 4",
+			mockSetup: func() {
+				mockExprRepo.EXPECT().Create(gomock.Any(), models.Expression{
+					UserID: userID,
+					Origin: "1 + 2 + 3 + 4",
+					Depth:  2,
+				}, tree.Ast{Expression: tree.Expression{
+					Left:      tree.Expression{Left: tree.Num(1), Operation: tree.Operation(pb.Operation_ADD), Right: tree.Num(2)},
+					Operation: tree.Operation(pb.Operation_ADD),
+					Right:     tree.Expression{Left: tree.Num(3), Operation: tree.Operation(pb.Operation_ADD), Right: tree.Num(4)},
+				}}).Return(models.NewID(), nil)
+			},
+		},
+		{
+			name:       "Chain in the written order",
+			expression: "1 + 2 + 3 + 4",
+			keepOrder:  true,
+			mockSetup: func() {
+				mockExprRepo.EXPECT().Create(gomock.Any(), models.Expression{
+					UserID: userID,
+					Origin: "1 + 2 + 3 + 4",
+					Depth:  3,
+				}, gomock.Any()).Return(models.NewID(), nil)
+			},
+		},
+		{
+			name:        "Target unit of another dimension",
+			expression:  "5 km",
+			targetUnit:  "s",
+			expectError: true,
+		},
+		{
+			name:        "Unknown target unit",
+			expression:  "5 km",
+			targetUnit:  "parsec",
+			expectError: true,
+		},
+		{
+			name:        "Target unit in exact precision",
+			expression:  "5",
+			precision:   tree.DecimalPrecision,
+			targetUnit:  "m/m",
+			expectError: true,
+		},
+		{
+			name:        "Unknown precision",
+			expression:  "2+2",
+			precision:   "double",
+			expectError: true,
+		},
+		{
+			name:        "Unbound variables",
+			expression:  "price * qty",
+			variables:   map[string]float64{"price": 2.5},
+			expectError: true,
+		},
 		{
 			name:        "Invalid expression",
 			expression:  "2+",
@@ -284,26 +609,97 @@ func TestService_AddExpression(t *testing.T) {
 			expression: "2+2",
 			mockSetup: func() {
 				mockExprRepo.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any()).
-					Return(primitive.NilObjectID, errors.New("repo error"))
+					Return(repo.ID{}, errors.New("repo error"))
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDefRepo.EXPECT().GetByUser(gomock.Any(), userID).Return(tt.definitions, nil).MaxTimes(1)
+			if tt.mockSetup != nil {
+				tt.mockSetup()
+			}
+
+			id, err := svc.Add(context.Background(), models.CalculationRequest{Expression: tt.expression, Variables: tt.variables, Precision: tt.precision, TargetUnit: tt.targetUnit, KeepOrder: tt.keepOrder}, userID)
+
+			if tt.expectError {
+				assert.Error(t, err)
+				assert.Equal(t, models.NilID, id)
+			} else {
+				assert.NoError(t, err)
+				assert.NotEqual(t, models.NilID, id)
+			}
+		})
+	}
+}
+
+func TestService_AddDefinition(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserRepo := mock_repo.NewMockUserRepo(ctrl)
+	mockExprRepo := mock_repo.NewMockExpressionRepo(ctrl)
+	mockDefRepo := mock_repo.NewMockDefinitionRepo(ctrl)
+
+	passwordService := hash.NewPasswordService(nil)
+	tokenService := jwt.New("secret", time.Hour, 0)
+	msGetter := ms.From(config.Time{})
+
+	svc := appservice.New(zap.NewNop(), msGetter, fold.From(config.Fold{}), mockUserRepo, mockExprRepo, mockDefRepo, mock_repo.NewMockRateRepo(ctrl), passwordService, tokenService, "")
+
+	userID := models.NewID()
+	saved := []models.Definition{
+		{ID: models.NewID(), UserID: userID, Name: "a", Body: "b + 1"},
+	}
+
+	tests := []struct {
+		name        string
+		req         models.DefinitionRequest
+		mockSetup   func()
+		expectError bool
+	}{
+		{
+			name: "Valid definition",
+			req:  models.DefinitionRequest{Name: "b", Body: "2"},
+			mockSetup: func() {
+				mockDefRepo.EXPECT().Create(gomock.Any(), models.Definition{UserID: userID, Name: "b", Body: "2"}).
+					Return(models.NewID(), nil)
 			},
+		},
+		{
+			name:        "Cycle",
+			req:         models.DefinitionRequest{Name: "b", Body: "a * 2"},
+			expectError: true,
+		},
+		{
+			name:        "Built-in name",
+			req:         models.DefinitionRequest{Name: "sqrt", Params: []string{"x"}, Body: "x"},
+			expectError: true,
+		},
+		{
+			name:        "Invalid body",
+			req:         models.DefinitionRequest{Name: "b", Body: "2 +"},
 			expectError: true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			mockDefRepo.EXPECT().GetByUser(gomock.Any(), userID).Return(saved, nil)
 			if tt.mockSetup != nil {
 				tt.mockSetup()
 			}
 
-			id, err := svc.Add(context.Background(), tt.expression, userID)
+			id, err := svc.AddDefinition(context.Background(), userID, tt.req)
 
 			if tt.expectError {
 				assert.Error(t, err)
-				assert.Equal(t, primitive.NilObjectID, id)
+				assert.Equal(t, models.NilID, id)
 			} else {
 				assert.NoError(t, err)
-				assert.NotEqual(t, primitive.NilObjectID, id)
+				assert.NotEqual(t, models.NilID, id)
 			}
 		})
 	}
@@ -315,6 +711,7 @@ func TestService_DoTask(t *testing.T) {
 
 	mockUserRepo := mock_repo.NewMockUserRepo(ctrl)
 	mockExprRepo := mock_repo.NewMockExpressionRepo(ctrl)
+	mockDefRepo := mock_repo.NewMockDefinitionRepo(ctrl)
 
 	passwordService := hash.NewPasswordService(nil)
 	tokenService := jwt.New("secret", time.Hour, 0)
@@ -325,7 +722,7 @@ func TestService_DoTask(t *testing.T) {
 		DivisionMs:       400,
 	})
 
-	svc := appservice.New(zap.NewNop(), msGetter, mockUserRepo, mockExprRepo, passwordService, tokenService)
+	svc := appservice.New(zap.NewNop(), msGetter, fold.From(config.Fold{}), mockUserRepo, mockExprRepo, mockDefRepo, mock_repo.NewMockRateRepo(ctrl), passwordService, tokenService, "")
 
 	tests := []struct {
 		name        string
@@ -336,7 +733,7 @@ func TestService_DoTask(t *testing.T) {
 		{
 			name: "Successful result processing",
 			result: &pb.Result{
-				Id:     primitive.NewObjectID().Hex(),
+				Id:     models.NewID().Hex(),
 				Result: 42.0,
 			},
 			mockSetup: func() {
@@ -344,6 +741,116 @@ func TestService_DoTask(t *testing.T) {
 					Return(nil)
 			},
 		},
+		{
+			name: "Rational result",
+			result: func() *pb.Result {
+				result := &pb.Result{Id: models.NewID().Hex(), Result: 1.0 / 3}
+				wire.SetString(result, wire.ResultRational, "1/3")
+				return result
+			}(),
+			mockSetup: func() {
+				mockExprRepo.EXPECT().SetToRational(gomock.Any(), gomock.Any(), 1.0/3, "1/3").
+					Return(nil)
+			},
+		},
+		{
+			name: "Complex result",
+			result: func() *pb.Result {
+				result := &pb.Result{Id: models.NewID().Hex(), Result: 3}
+				wire.SetDouble(result, wire.ResultImag, 4)
+				return result
+			}(),
+			mockSetup: func() {
+				mockExprRepo.EXPECT().SetToComplex(gomock.Any(), gomock.Any(), models.Complex{Re: 3, Im: 4}).
+					Return(nil)
+			},
+		},
+		{
+			name: "Result with unit",
+			result: func() *pb.Result {
+				result := &pb.Result{Id: models.NewID().Hex(), Result: 13.9}
+				wire.SetString(result, wire.ResultUnit, "m/s")
+				return result
+			}(),
+			mockSetup: func() {
+				mockExprRepo.EXPECT().SetToUnit(gomock.Any(), gomock.Any(), 13.9, "m/s").
+					Return(nil)
+			},
+		},
+		{
+			name: "Infinite result of an old agent",
+			result: &pb.Result{
+				Id:     models.NewID().Hex(),
+				Result: math.Inf(1),
+			},
+			mockSetup: func() {
+				mockExprRepo.EXPECT().SetToError(gomock.Any(), gomock.Any(), wire.Overflow, "result is too big").
+					Return(nil)
+			},
+		},
+		{
+			name: "Complex NaN",
+			result: func() *pb.Result {
+				result := &pb.Result{Id: models.NewID().Hex(), Result: 1}
+				wire.SetDouble(result, wire.ResultImag, math.NaN())
+				return result
+			}(),
+			mockSetup: func() {
+				mockExprRepo.EXPECT().SetToError(gomock.Any(), gomock.Any(), wire.NaN, "result is not a number").
+					Return(nil)
+			},
+		},
+		{
+			name: "Underflow warning",
+			result: func() *pb.Result {
+				result := &pb.Result{Id: models.NewID().Hex(), Result: 0}
+				wire.SetString(result, wire.ResultWarning, wire.Underflow)
+				return result
+			}(),
+			mockSetup: func() {
+				gomock.InOrder(
+					mockExprRepo.EXPECT().AddWarning(gomock.Any(), gomock.Any(), wire.Underflow).Return(nil),
+					mockExprRepo.EXPECT().SetToNum(gomock.Any(), gomock.Any(), 0.0).Return(nil),
+				)
+			},
+		},
+		{
+			name: "Decimal out of the float range",
+			result: func() *pb.Result {
+				result := &pb.Result{Id: models.NewID().Hex(), Result: math.Inf(1)}
+				wire.SetString(result, wire.ResultDecimal, "1E+400")
+				return result
+			}(),
+			mockSetup: func() {
+				mockExprRepo.EXPECT().SetToDecimal(gomock.Any(), gomock.Any(), math.MaxFloat64, "1E+400").
+					Return(nil)
+			},
+		},
+		{
+			name: "Rational out of the float range",
+			result: func() *pb.Result {
+				result := &pb.Result{Id: models.NewID().Hex(), Result: math.Inf(-1)}
+				wire.SetString(result, wire.ResultRational, "-1"+strings.Repeat("0", 400))
+				return result
+			}(),
+			mockSetup: func() {
+				mockExprRepo.EXPECT().SetToRational(gomock.Any(), gomock.Any(), -math.MaxFloat64, "-1"+strings.Repeat("0", 400)).
+					Return(nil)
+			},
+		},
+		{
+			name: "Decimal repository error",
+			result: func() *pb.Result {
+				result := &pb.Result{Id: models.NewID().Hex(), Result: 0.3}
+				wire.SetString(result, wire.ResultDecimal, "0.3")
+				return result
+			}(),
+			mockSetup: func() {
+				mockExprRepo.EXPECT().SetToDecimal(gomock.Any(), gomock.Any(), 0.3, "0.3").
+					Return(errors.New("repo error"))
+			},
+			expectError: true,
+		},
 		{
 			name: "Invalid ID format",
 			result: &pb.Result{
@@ -355,7 +862,7 @@ func TestService_DoTask(t *testing.T) {
 		{
 			name: "Repository error",
 			result: &pb.Result{
-				Id:     primitive.NewObjectID().Hex(),
+				Id:     models.NewID().Hex(),
 				Result: 42.0,
 			},
 			mockSetup: func() {
@@ -389,6 +896,7 @@ func TestService_DoError(t *testing.T) {
 
 	mockUserRepo := mock_repo.NewMockUserRepo(ctrl)
 	mockExprRepo := mock_repo.NewMockExpressionRepo(ctrl)
+	mockDefRepo := mock_repo.NewMockDefinitionRepo(ctrl)
 
 	passwordService := hash.NewPasswordService(nil)
 	tokenService := jwt.New("secret", time.Hour, 0)
@@ -399,7 +907,7 @@ func TestService_DoError(t *testing.T) {
 		DivisionMs:       400,
 	})
 
-	svc := appservice.New(zap.NewNop(), msGetter, mockUserRepo, mockExprRepo, passwordService, tokenService)
+	svc := appservice.New(zap.NewNop(), msGetter, fold.From(config.Fold{}), mockUserRepo, mockExprRepo, mockDefRepo, mock_repo.NewMockRateRepo(ctrl), passwordService, tokenService, "")
 
 	tests := []struct {
 		name        string
@@ -410,11 +918,23 @@ func TestService_DoError(t *testing.T) {
 		{
 			name: "Successful error processing",
 			errorRes: &pb.Error{
-				Id:    primitive.NewObjectID().Hex(),
+				Id:    models.NewID().Hex(),
 				Error: "division by zero",
 			},
 			mockSetup: func() {
-				mockExprRepo.EXPECT().SetToError(gomock.Any(), gomock.Any(), "division by zero").
+				mockExprRepo.EXPECT().SetToError(gomock.Any(), gomock.Any(), wire.UnknownError, "division by zero").
+					Return(nil)
+			},
+		},
+		{
+			name: "Error with code",
+			errorRes: func() *pb.Error {
+				res := &pb.Error{Id: models.NewID().Hex(), Error: "result is too big"}
+				wire.SetString(res, wire.ErrorCode, wire.Overflow)
+				return res
+			}(),
+			mockSetup: func() {
+				mockExprRepo.EXPECT().SetToError(gomock.Any(), gomock.Any(), wire.Overflow, "result is too big").
 					Return(nil)
 			},
 		},
@@ -429,11 +949,11 @@ func TestService_DoError(t *testing.T) {
 		{
 			name: "Repository error",
 			errorRes: &pb.Error{
-				Id:    primitive.NewObjectID().Hex(),
+				Id:    models.NewID().Hex(),
 				Error: "division by zero",
 			},
 			mockSetup: func() {
-				mockExprRepo.EXPECT().SetToError(gomock.Any(), gomock.Any(), "division by zero").
+				mockExprRepo.EXPECT().SetToError(gomock.Any(), gomock.Any(), wire.UnknownError, "division by zero").
 					Return(errors.New("repo error"))
 			},
 			expectError: true,
@@ -463,6 +983,7 @@ func TestService_GetExpressions(t *testing.T) {
 
 	mockUserRepo := mock_repo.NewMockUserRepo(ctrl)
 	mockExprRepo := mock_repo.NewMockExpressionRepo(ctrl)
+	mockDefRepo := mock_repo.NewMockDefinitionRepo(ctrl)
 
 	passwordService := hash.NewPasswordService(nil)
 	tokenService := jwt.New("secret", time.Hour, 0)
@@ -473,12 +994,12 @@ func TestService_GetExpressions(t *testing.T) {
 		DivisionMs:       400,
 	})
 
-	svc := appservice.New(zap.NewNop(), msGetter, mockUserRepo, mockExprRepo, passwordService, tokenService)
+	svc := appservice.New(zap.NewNop(), msGetter, fold.From(config.Fold{}), mockUserRepo, mockExprRepo, mockDefRepo, mock_repo.NewMockRateRepo(ctrl), passwordService, tokenService, "")
 
-	userID := primitive.NewObjectID()
+	userID := models.NewID()
 	result := 4.0
 	expr := models.Expression{
-		ID:     primitive.NewObjectID(),
+		ID:     models.NewID(),
 		UserID: userID,
 		Origin: "2+2",
 		Status: status.Finished,
@@ -528,12 +1049,175 @@ func TestService_GetExpressions(t *testing.T) {
 	}
 }
 
+func TestService_Get_Converted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockExprRepo := mock_repo.NewMockExpressionRepo(ctrl)
+	svc := appservice.New(zap.NewNop(), ms.From(config.Time{}), fold.From(config.Fold{}), mock_repo.NewMockUserRepo(ctrl), mockExprRepo, mock_repo.NewMockDefinitionRepo(ctrl), mock_repo.NewMockRateRepo(ctrl), hash.NewPasswordService(nil), jwt.New("secret", time.Hour, 0), "")
+
+	tests := []struct {
+		name       string
+		unit       string
+		targetUnit string
+		expected   *float64
+	}{
+		{"Matching unit", "m/s", "km/h", func() *float64 { f := 90.0; return &f }()},
+		{"Other unit", "m", "km/h", nil},
+		{"No target unit", "m/s", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := models.NewID()
+			result := 25.0
+			mockExprRepo.EXPECT().Get(gomock.Any(), id).Return(&models.Expression{
+				ID:         id,
+				Status:     status.Finished,
+				Result:     &result,
+				Unit:       tt.unit,
+				TargetUnit: tt.targetUnit,
+			}, nil)
+
+			expr, err := svc.Get(context.Background(), id)
+			require.NoError(t, err)
+			if tt.expected == nil {
+				assert.Nil(t, expr.Converted)
+			} else {
+				require.NotNil(t, expr.Converted)
+				assert.InDelta(t, *tt.expected, *expr.Converted, 1e-9)
+			}
+		})
+	}
+}
+
+func TestService_GetAst(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockExprRepo := mock_repo.NewMockExpressionRepo(ctrl)
+	svc := appservice.New(zap.NewNop(), ms.From(config.Time{}), fold.From(config.Fold{}), mock_repo.NewMockUserRepo(ctrl), mockExprRepo, mock_repo.NewMockDefinitionRepo(ctrl), mock_repo.NewMockRateRepo(ctrl), hash.NewPasswordService(nil), jwt.New("secret", time.Hour, 0), "")
+
+	t.Run("Finished", func(t *testing.T) {
+		result := 0.3
+		ast, err := svc.GetAst(context.Background(), models.Expression{Status: status.Finished, Result: &result, Decimal: "0.3"})
+		require.NoError(t, err)
+		assert.Equal(t, &models.AstNode{Value: &result, Exact: "0.3", State: models.Resolved}, ast)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		ast, err := svc.GetAst(context.Background(), models.Expression{Status: status.Error, Error: "division by zero"})
+		require.NoError(t, err)
+		assert.Equal(t, &models.AstNode{State: models.Failed, Error: "division by zero"}, ast)
+	})
+
+	t.Run("Pending", func(t *testing.T) {
+		// if(x > 1, x * 2, 0) with x = 3, the condition was sent
+		root, cond, then, otherwise := models.NewID(), models.NewID(), models.NewID(), models.NewID()
+		x, one, two := models.NewID(), models.NewID(), models.NewID()
+		three, oneValue, twoValue, zeroValue := 3.0, 1.0, 2.0, 0.0
+		sended := time.Now()
+		mockExprRepo.EXPECT().GetNodes(gomock.Any(), root).Return([]models.Node{
+			{ID: root, Tree: &models.TreeNode{Operator: pb.Operation(tree.Conditional), Cond: cond, Left: then, Right: otherwise}},
+			{ID: cond, Tree: &models.TreeNode{Operator: pb.Operation(tree.Greater), Left: x, Right: one}, SendedAt: &sended},
+			{ID: then, Tree: &models.TreeNode{Operator: pb.Operation_MULTIPLY, Left: x, Right: two}, Held: root},
+			{ID: otherwise, Type: models.Number, Number: &zeroValue, Held: root},
+			{ID: x, Type: models.Number, Number: &three, Unit: "m"},
+			{ID: one, Type: models.Number, Number: &oneValue},
+			{ID: two, Type: models.Number, Number: &twoValue},
+		}, nil)
+
+		ast, err := svc.GetAst(context.Background(), models.Expression{Status: status.Pending, NodeID: root})
+		require.NoError(t, err)
+		number := func(id models.ID, value *float64, unit string, held bool) models.AstNode {
+			return models.AstNode{ID: id.Hex(), Value: value, Unit: unit, State: models.Resolved, Held: held}
+		}
+		assert.Equal(t, &models.AstNode{
+			ID:       root.Hex(),
+			Operator: "if",
+			State:    models.Pending,
+			Children: []models.AstNode{
+				{ID: cond.Hex(), Operator: ">", State: models.Dispatched, SendedAt: &sended, Children: []models.AstNode{
+					number(x, &three, "m", false), number(one, &oneValue, "", false),
+				}},
+				{ID: then.Hex(), Operator: "*", State: models.Pending, Held: true, Children: []models.AstNode{
+					number(x, &three, "m", false), number(two, &twoValue, "", false),
+				}},
+				number(otherwise, &zeroValue, "", true),
+			},
+		}, ast)
+	})
+
+	t.Run("Missing node", func(t *testing.T) {
+		root := models.NewID()
+		mockExprRepo.EXPECT().GetNodes(gomock.Any(), root).Return([]models.Node{
+			{ID: root, Tree: &models.TreeNode{Operator: pb.Operation(tree.Sqrt), Left: models.NewID()}},
+		}, nil)
+
+		_, err := svc.GetAst(context.Background(), models.Expression{Status: status.Pending, NodeID: root})
+		assert.ErrorIs(t, err, repo.NodeNotFound)
+	})
+}
+
+func TestService_SetRate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRateRepo := mock_repo.NewMockRateRepo(ctrl)
+	svc := appservice.New(zap.NewNop(), ms.From(config.Time{}), fold.From(config.Fold{}), mock_repo.NewMockUserRepo(ctrl), mock_repo.NewMockExpressionRepo(ctrl), mock_repo.NewMockDefinitionRepo(ctrl), mockRateRepo, hash.NewPasswordService(nil), jwt.New("secret", time.Hour, 0), "admin")
+
+	tests := []struct {
+		name        string
+		code        string
+		rate        float64
+		mockSetup   func()
+		expectError error
+	}{
+		{
+			name: "Valid rate",
+			code: "EUR",
+			rate: 0.92,
+			mockSetup: func() {
+				mockRateRepo.EXPECT().Set(gomock.Any(), models.Rate{Code: "EUR", Rate: 0.92}).Return(nil)
+			},
+		},
+		{"Lowercase code", "eur", 0.92, nil, service.InvalidCurrency},
+		{"Long code", "EURO", 0.92, nil, service.InvalidCurrency},
+		{"Zero rate", "EUR", 0, nil, service.InvalidRate},
+		{"Negative rate", "EUR", -1, nil, service.InvalidRate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mockSetup != nil {
+				tt.mockSetup()
+			}
+			err := svc.SetRate(context.Background(), tt.code, tt.rate)
+			assert.ErrorIs(t, err, tt.expectError)
+		})
+	}
+}
+
+func TestService_CheckAdmin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	newService := func(token string) *appservice.Service {
+		return appservice.New(zap.NewNop(), ms.From(config.Time{}), fold.From(config.Fold{}), mock_repo.NewMockUserRepo(ctrl), mock_repo.NewMockExpressionRepo(ctrl), mock_repo.NewMockDefinitionRepo(ctrl), mock_repo.NewMockRateRepo(ctrl), hash.NewPasswordService(nil), jwt.New("secret", time.Hour, 0), token)
+	}
+
+	assert.NoError(t, newService("admin").CheckAdmin(context.Background(), "admin"))
+	assert.ErrorIs(t, newService("admin").CheckAdmin(context.Background(), "user"), service.NotAdmin)
+	assert.ErrorIs(t, newService("").CheckAdmin(context.Background(), ""), service.NotAdmin)
+}
+
 func TestService_SendResult(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockUserRepo := mock_repo.NewMockUserRepo(ctrl)
 	mockExprRepo := mock_repo.NewMockExpressionRepo(ctrl)
+	mockDefRepo := mock_repo.NewMockDefinitionRepo(ctrl)
 
 	passwordService := hash.NewPasswordService(nil)
 	tokenService := jwt.New("secret", time.Hour, 0)
@@ -544,17 +1228,17 @@ func TestService_SendResult(t *testing.T) {
 		DivisionMs:       400,
 	})
 
-	svc := appservice.New(zap.NewNop(), msGetter, mockUserRepo, mockExprRepo, passwordService, tokenService)
+	svc := appservice.New(zap.NewNop(), msGetter, fold.From(config.Fold{}), mockUserRepo, mockExprRepo, mockDefRepo, mock_repo.NewMockRateRepo(ctrl), passwordService, tokenService, "")
 
 	tasks := []pb.Task{
 		{
-			Id:        primitive.NewObjectID().Hex(),
+			Id:        models.NewID().Hex(),
 			Operation: pb.Operation_ADD,
 			Arg1:      1,
 			Arg2:      2,
 		},
 		{
-			Id:        primitive.NewObjectID().Hex(),
+			Id:        models.NewID().Hex(),
 			Operation: pb.Operation_DIVIDE,
 			Arg1:      10,
 			Arg2:      2,
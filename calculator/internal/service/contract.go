@@ -5,20 +5,42 @@ import (
 
 	pb "github.com/vandi37/Calculator-Models"
 	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/pkg/parsing"
 	"github.com/vandi37/Calculator/pkg/parsing/tree"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // Function to get a task
 type PostFunc func(float64, float64, tree.Operation) (float64, error)
 
 type Service interface {
-	// Adds a new expression
-	Add(ctx context.Context, expression string, userId primitive.ObjectID) (primitive.ObjectID, error)
+	// Adds a new expression, the variables are replaced with their values
+	Add(ctx context.Context, req models.CalculationRequest, userId models.ID) (models.ID, error)
+	// Finds all parsing errors without adding the expression
+	Validate(ctx context.Context, expression string, userId models.ID) ([]*parsing.Error, error)
 	// Getting the expression
-	Get(ctx context.Context, id primitive.ObjectID) (*models.Expression, error)
+	Get(ctx context.Context, id models.ID) (*models.Expression, error)
 	// Getting expressions by user id
-	GetByUSer(ctx context.Context, userId primitive.ObjectID) ([]models.Expression, error)
+	GetByUSer(ctx context.Context, userId models.ID) ([]models.Expression, error)
+	// Getting the saved tree of the expression with the state of every node
+	GetAst(ctx context.Context, expr models.Expression) (*models.AstNode, error)
+	// Saves a value or a function of the user
+	AddDefinition(ctx context.Context, userId models.ID, req models.DefinitionRequest) (models.ID, error)
+	// Getting the definition
+	GetDefinition(ctx context.Context, id models.ID) (*models.Definition, error)
+	// Getting definitions by user id
+	GetDefinitions(ctx context.Context, userId models.ID) ([]models.Definition, error)
+	// Changes the definition
+	UpdateDefinition(ctx context.Context, id models.ID, req models.DefinitionRequest) error
+	// Deletes the definition if other definitions don't use it
+	DeleteDefinition(ctx context.Context, id models.ID) error
+	// Getting the exchange rates
+	GetRates(ctx context.Context) ([]models.Rate, error)
+	// Saves the exchange rate of the currency
+	SetRate(ctx context.Context, code string, rate float64) error
+	// Deletes the exchange rate, the expressions keep the rates they used
+	DeleteRate(ctx context.Context, code string) error
+	// Checks the token of the admin
+	CheckAdmin(ctx context.Context, token string) error
 	// Sending task result
 	DoTask(ctx context.Context, result *pb.Result) error
 	// Sending task error
@@ -26,17 +48,17 @@ type Service interface {
 	// Getting task chan
 	Tasks() <-chan *pb.Task
 	// Create a new user
-	Register(ctx context.Context, username, password string) (primitive.ObjectID, error)
+	Register(ctx context.Context, username, password string) (models.ID, error)
 	// Get jwt token
 	Login(ctx context.Context, username, password string) (string, error)
 	// Checks the token
-	CheckToken(ctx context.Context, token string) (primitive.ObjectID, error)
+	CheckToken(ctx context.Context, token string) (models.ID, error)
 	// Update username
-	UpdateUsername(ctx context.Context, id primitive.ObjectID, username string) error
+	UpdateUsername(ctx context.Context, id models.ID, username string) error
 	// Update password
-	UpdatePassword(ctx context.Context, id primitive.ObjectID, password string) error
+	UpdatePassword(ctx context.Context, id models.ID, password string) error
 	// Delete
-	Delete(ctx context.Context, id primitive.ObjectID) error
+	Delete(ctx context.Context, id models.ID) error
 	// Close tasks
 	Close() error
 	// Init service
@@ -5,40 +5,74 @@ import (
 	"net/http"
 
 	"github.com/vandi37/Calculator/internal/repo"
+	"github.com/vandi37/Calculator/pkg/currency"
 	"github.com/vandi37/Calculator/pkg/hash"
 	"github.com/vandi37/Calculator/pkg/parsing/lexer"
 	"github.com/vandi37/Calculator/pkg/parsing/parser"
+	"github.com/vandi37/Calculator/pkg/units"
 	"github.com/vandi37/vanerrors"
 )
 
 var (
 	InvalidToken = errors.New("invalid token")
 	Closed       = errors.New("closed")
+	// The precision of the request isn't known
+	InvalidPrecision = errors.New("invalid precision")
+	// Rates must be positive and codes are three capital letters
+	InvalidRate     = errors.New("invalid rate")
+	InvalidCurrency = errors.New("invalid currency code")
+	NotAdmin        = errors.New("not admin")
 )
 
 var unprocessableEntity []string = []string{
 	lexer.ItIsNotANumber,
 	lexer.UnexpectedChar,
+	lexer.OutOfRange,
 	parser.UnexpectedTokenKind,
 	parser.UnexpectedEOF,
 	parser.UnexpectedToken,
+	parser.EmptyBrackets,
+	parser.UnopenedBracket,
 	parser.ExpectedKind,
+	parser.UnknownFunction,
+	parser.UnboundVariables,
+	parser.InvalidName,
+	parser.InvalidDefinition,
+	parser.CyclicDefinition,
+	parser.WrongArgumentsCount,
+	parser.TooManyDigits,
+	parser.ComplexPrecision,
+	parser.UnitPrecision,
+	units.UnknownUnit,
+	units.IncompatibleUnits,
+	currency.UnknownCurrency,
+	parser.InvalidAmount,
+	parser.ListOutsideCall,
+	parser.ComplexArguments,
 }
 
 func GetCode(target error) int {
-	if errors.Is(target, repo.UsernameTaken) {
+	if errors.Is(target, repo.UsernameTaken) ||
+		errors.Is(target, repo.DefinitionNameTaken) {
 		return http.StatusConflict
 	} else if errors.Is(target, repo.UserNotFound) ||
 		errors.Is(target, repo.NodeNotFound) ||
-		errors.Is(target, repo.ExpressionNotFound) {
+		errors.Is(target, repo.ExpressionNotFound) ||
+		errors.Is(target, repo.DefinitionNotFound) ||
+		errors.Is(target, repo.RateNotFound) {
 		return http.StatusNotFound
 	} else if errors.Is(target, repo.InvalidExpression) ||
 		errors.Is(target, repo.InvalidNode) ||
 		errors.Is(target, hash.InvalidBase64) ||
-		errors.Is(target, InvalidToken) {
+		errors.Is(target, InvalidToken) ||
+		errors.Is(target, InvalidPrecision) ||
+		errors.Is(target, InvalidRate) ||
+		errors.Is(target, InvalidCurrency) {
 		return http.StatusBadRequest
 	} else if errors.Is(target, hash.InvalidPassword) {
 		return http.StatusUnauthorized
+	} else if errors.Is(target, NotAdmin) {
+		return http.StatusForbidden
 	}
 	for _, s := range unprocessableEntity {
 		err := vanerrors.Simple(s)
@@ -11,7 +11,7 @@ import (
 	gomock "github.com/golang/mock/gomock"
 	stream "github.com/vandi37/Calculator-Models"
 	models "github.com/vandi37/Calculator/internal/models"
-	primitive "go.mongodb.org/mongo-driver/bson/primitive"
+	parsing "github.com/vandi37/Calculator/pkg/parsing"
 )
 
 // MockService is a mock of Service interface.
@@ -38,25 +38,54 @@ func (m *MockService) EXPECT() *MockServiceMockRecorder {
 }
 
 // Add mocks base method.
-func (m *MockService) Add(ctx context.Context, expression string, userId primitive.ObjectID) (primitive.ObjectID, error) {
+func (m *MockService) Add(ctx context.Context, req models.CalculationRequest, userId models.ID) (models.ID, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Add", ctx, expression, userId)
-	ret0, _ := ret[0].(primitive.ObjectID)
+	ret := m.ctrl.Call(m, "Add", ctx, req, userId)
+	ret0, _ := ret[0].(models.ID)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Add indicates an expected call of Add.
-func (mr *MockServiceMockRecorder) Add(ctx, expression, userId interface{}) *gomock.Call {
+func (mr *MockServiceMockRecorder) Add(ctx, req, userId interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockService)(nil).Add), ctx, expression, userId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockService)(nil).Add), ctx, req, userId)
+}
+
+// AddDefinition mocks base method.
+func (m *MockService) AddDefinition(ctx context.Context, userId models.ID, req models.DefinitionRequest) (models.ID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddDefinition", ctx, userId, req)
+	ret0, _ := ret[0].(models.ID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddDefinition indicates an expected call of AddDefinition.
+func (mr *MockServiceMockRecorder) AddDefinition(ctx, userId, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddDefinition", reflect.TypeOf((*MockService)(nil).AddDefinition), ctx, userId, req)
+}
+
+// CheckAdmin mocks base method.
+func (m *MockService) CheckAdmin(ctx context.Context, token string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckAdmin", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckAdmin indicates an expected call of CheckAdmin.
+func (mr *MockServiceMockRecorder) CheckAdmin(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckAdmin", reflect.TypeOf((*MockService)(nil).CheckAdmin), ctx, token)
 }
 
 // CheckToken mocks base method.
-func (m *MockService) CheckToken(ctx context.Context, token string) (primitive.ObjectID, error) {
+func (m *MockService) CheckToken(ctx context.Context, token string) (models.ID, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CheckToken", ctx, token)
-	ret0, _ := ret[0].(primitive.ObjectID)
+	ret0, _ := ret[0].(models.ID)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -82,7 +111,7 @@ func (mr *MockServiceMockRecorder) Close() *gomock.Call {
 }
 
 // Delete mocks base method.
-func (m *MockService) Delete(ctx context.Context, id primitive.ObjectID) error {
+func (m *MockService) Delete(ctx context.Context, id models.ID) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Delete", ctx, id)
 	ret0, _ := ret[0].(error)
@@ -95,6 +124,34 @@ func (mr *MockServiceMockRecorder) Delete(ctx, id interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockService)(nil).Delete), ctx, id)
 }
 
+// DeleteDefinition mocks base method.
+func (m *MockService) DeleteDefinition(ctx context.Context, id models.ID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteDefinition", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteDefinition indicates an expected call of DeleteDefinition.
+func (mr *MockServiceMockRecorder) DeleteDefinition(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDefinition", reflect.TypeOf((*MockService)(nil).DeleteDefinition), ctx, id)
+}
+
+// DeleteRate mocks base method.
+func (m *MockService) DeleteRate(ctx context.Context, code string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRate", ctx, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteRate indicates an expected call of DeleteRate.
+func (mr *MockServiceMockRecorder) DeleteRate(ctx, code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRate", reflect.TypeOf((*MockService)(nil).DeleteRate), ctx, code)
+}
+
 // DoError mocks base method.
 func (m *MockService) DoError(ctx context.Context, err *stream.Error) error {
 	m.ctrl.T.Helper()
@@ -124,7 +181,7 @@ func (mr *MockServiceMockRecorder) DoTask(ctx, result interface{}) *gomock.Call
 }
 
 // Get mocks base method.
-func (m *MockService) Get(ctx context.Context, id primitive.ObjectID) (*models.Expression, error) {
+func (m *MockService) Get(ctx context.Context, id models.ID) (*models.Expression, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Get", ctx, id)
 	ret0, _ := ret[0].(*models.Expression)
@@ -138,8 +195,23 @@ func (mr *MockServiceMockRecorder) Get(ctx, id interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockService)(nil).Get), ctx, id)
 }
 
+// GetAst mocks base method.
+func (m *MockService) GetAst(ctx context.Context, expr models.Expression) (*models.AstNode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAst", ctx, expr)
+	ret0, _ := ret[0].(*models.AstNode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAst indicates an expected call of GetAst.
+func (mr *MockServiceMockRecorder) GetAst(ctx, expr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAst", reflect.TypeOf((*MockService)(nil).GetAst), ctx, expr)
+}
+
 // GetByUSer mocks base method.
-func (m *MockService) GetByUSer(ctx context.Context, userId primitive.ObjectID) ([]models.Expression, error) {
+func (m *MockService) GetByUSer(ctx context.Context, userId models.ID) ([]models.Expression, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetByUSer", ctx, userId)
 	ret0, _ := ret[0].([]models.Expression)
@@ -153,6 +225,51 @@ func (mr *MockServiceMockRecorder) GetByUSer(ctx, userId interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUSer", reflect.TypeOf((*MockService)(nil).GetByUSer), ctx, userId)
 }
 
+// GetDefinition mocks base method.
+func (m *MockService) GetDefinition(ctx context.Context, id models.ID) (*models.Definition, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDefinition", ctx, id)
+	ret0, _ := ret[0].(*models.Definition)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDefinition indicates an expected call of GetDefinition.
+func (mr *MockServiceMockRecorder) GetDefinition(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDefinition", reflect.TypeOf((*MockService)(nil).GetDefinition), ctx, id)
+}
+
+// GetDefinitions mocks base method.
+func (m *MockService) GetDefinitions(ctx context.Context, userId models.ID) ([]models.Definition, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDefinitions", ctx, userId)
+	ret0, _ := ret[0].([]models.Definition)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDefinitions indicates an expected call of GetDefinitions.
+func (mr *MockServiceMockRecorder) GetDefinitions(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDefinitions", reflect.TypeOf((*MockService)(nil).GetDefinitions), ctx, userId)
+}
+
+// GetRates mocks base method.
+func (m *MockService) GetRates(ctx context.Context) ([]models.Rate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRates", ctx)
+	ret0, _ := ret[0].([]models.Rate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRates indicates an expected call of GetRates.
+func (mr *MockServiceMockRecorder) GetRates(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRates", reflect.TypeOf((*MockService)(nil).GetRates), ctx)
+}
+
 // Init mocks base method.
 func (m *MockService) Init(ctx context.Context) {
 	m.ctrl.T.Helper()
@@ -181,10 +298,10 @@ func (mr *MockServiceMockRecorder) Login(ctx, username, password interface{}) *g
 }
 
 // Register mocks base method.
-func (m *MockService) Register(ctx context.Context, username, password string) (primitive.ObjectID, error) {
+func (m *MockService) Register(ctx context.Context, username, password string) (models.ID, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Register", ctx, username, password)
-	ret0, _ := ret[0].(primitive.ObjectID)
+	ret0, _ := ret[0].(models.ID)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -195,6 +312,20 @@ func (mr *MockServiceMockRecorder) Register(ctx, username, password interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Register", reflect.TypeOf((*MockService)(nil).Register), ctx, username, password)
 }
 
+// SetRate mocks base method.
+func (m *MockService) SetRate(ctx context.Context, code string, rate float64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetRate", ctx, code, rate)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetRate indicates an expected call of SetRate.
+func (mr *MockServiceMockRecorder) SetRate(ctx, code, rate interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRate", reflect.TypeOf((*MockService)(nil).SetRate), ctx, code, rate)
+}
+
 // Tasks mocks base method.
 func (m *MockService) Tasks() <-chan *stream.Task {
 	m.ctrl.T.Helper()
@@ -209,8 +340,22 @@ func (mr *MockServiceMockRecorder) Tasks() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Tasks", reflect.TypeOf((*MockService)(nil).Tasks))
 }
 
+// UpdateDefinition mocks base method.
+func (m *MockService) UpdateDefinition(ctx context.Context, id models.ID, req models.DefinitionRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateDefinition", ctx, id, req)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateDefinition indicates an expected call of UpdateDefinition.
+func (mr *MockServiceMockRecorder) UpdateDefinition(ctx, id, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDefinition", reflect.TypeOf((*MockService)(nil).UpdateDefinition), ctx, id, req)
+}
+
 // UpdatePassword mocks base method.
-func (m *MockService) UpdatePassword(ctx context.Context, id primitive.ObjectID, password string) error {
+func (m *MockService) UpdatePassword(ctx context.Context, id models.ID, password string) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "UpdatePassword", ctx, id, password)
 	ret0, _ := ret[0].(error)
@@ -224,7 +369,7 @@ func (mr *MockServiceMockRecorder) UpdatePassword(ctx, id, password interface{})
 }
 
 // UpdateUsername mocks base method.
-func (m *MockService) UpdateUsername(ctx context.Context, id primitive.ObjectID, username string) error {
+func (m *MockService) UpdateUsername(ctx context.Context, id models.ID, username string) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "UpdateUsername", ctx, id, username)
 	ret0, _ := ret[0].(error)
@@ -236,3 +381,18 @@ func (mr *MockServiceMockRecorder) UpdateUsername(ctx, id, username interface{})
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUsername", reflect.TypeOf((*MockService)(nil).UpdateUsername), ctx, id, username)
 }
+
+// Validate mocks base method.
+func (m *MockService) Validate(ctx context.Context, expression string, userId models.ID) ([]*parsing.Error, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Validate", ctx, expression, userId)
+	ret0, _ := ret[0].([]*parsing.Error)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Validate indicates an expected call of Validate.
+func (mr *MockServiceMockRecorder) Validate(ctx, expression, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Validate", reflect.TypeOf((*MockService)(nil).Validate), ctx, expression, userId)
+}
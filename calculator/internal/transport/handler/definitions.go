@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vandi37/Calculator/internal/models"
+)
+
+func (h *Handler) DefinitionsHandler(ctx *gin.Context) {
+	userId, ok := ctx.Get(UserIDKey)
+	if !ok {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, models.ErrorResponse{Error: Unauthorized})
+		return
+	}
+	definitions, err := h.Service.GetDefinitions(ctx.Request.Context(), userId.(models.ID))
+	if err != nil {
+		SendError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, models.DefinitionsResponse{Definitions: definitions})
+}
+
+func (h *Handler) AddDefinitionHandler(ctx *gin.Context) {
+	req := new(models.DefinitionRequest)
+	err := json.NewDecoder(ctx.Request.Body).Decode(req)
+	if err != nil || req.Name == "" || req.Body == "" {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, models.ErrorResponse{Error: InvalidBody})
+		return
+	}
+	userId, ok := ctx.Get(UserIDKey)
+	if !ok {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, models.ErrorResponse{Error: Unauthorized})
+		return
+	}
+	id, err := h.Service.AddDefinition(ctx.Request.Context(), userId.(models.ID), *req)
+	if err != nil {
+		SendError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, models.CreatedResponse{Id: id})
+}
+
+// Gets the definition from the id param and checks that it belongs to the user.
+// If false is returned the response is already sent
+func (h *Handler) ownDefinition(ctx *gin.Context) (*models.Definition, bool) {
+	id, err := models.IDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusNotFound, models.ErrorResponse{Error: InvalidId})
+		return nil, false
+	}
+	userId, ok := ctx.Get(UserIDKey)
+	if !ok {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, models.ErrorResponse{Error: Unauthorized})
+		return nil, false
+	}
+	definition, err := h.Service.GetDefinition(ctx.Request.Context(), id)
+	if err != nil {
+		SendError(ctx, err)
+		return nil, false
+	}
+	if definition.UserID != userId.(models.ID) {
+		ctx.AbortWithStatusJSON(http.StatusForbidden, models.ErrorResponse{Error: Forbidden})
+		return nil, false
+	}
+	return definition, true
+}
+
+func (h *Handler) GetDefinitionHandler(ctx *gin.Context) {
+	definition, ok := h.ownDefinition(ctx)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, models.DefinitionResponse{Definition: *definition})
+}
+
+func (h *Handler) UpdateDefinitionHandler(ctx *gin.Context) {
+	req := new(models.DefinitionRequest)
+	err := json.NewDecoder(ctx.Request.Body).Decode(req)
+	if err != nil || req.Name == "" || req.Body == "" {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, models.ErrorResponse{Error: InvalidBody})
+		return
+	}
+	definition, ok := h.ownDefinition(ctx)
+	if !ok {
+		return
+	}
+	err = h.Service.UpdateDefinition(ctx.Request.Context(), definition.ID, *req)
+	if err != nil {
+		SendError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+func (h *Handler) DeleteDefinitionHandler(ctx *gin.Context) {
+	definition, ok := h.ownDefinition(ctx)
+	if !ok {
+		return
+	}
+	err := h.Service.DeleteDefinition(ctx.Request.Context(), definition.ID)
+	if err != nil {
+		SendError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusNoContent, nil)
+}
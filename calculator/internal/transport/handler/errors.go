@@ -9,4 +9,5 @@ const (
 	JobDoesNotExist    = "job does not exist"
 	Unauthorized       = "unauthorized"
 	Forbidden          = "forbidden"
+	InvalidFormat      = "invalid format"
 )
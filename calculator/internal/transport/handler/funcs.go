@@ -2,12 +2,14 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/internal/render"
 	"github.com/vandi37/Calculator/internal/service"
-	"go.mongodb.org/mongo-driver/bson/primitive"
+	"github.com/vandi37/Calculator/pkg/parsing"
 )
 
 func SendError(ctx *gin.Context, err error) {
@@ -16,10 +18,29 @@ func SendError(ctx *gin.Context, err error) {
 	if code == http.StatusInternalServerError {
 		text = InternalError
 	}
-	ctx.AbortWithStatusJSON(code, models.ErrorResponse{Error: text})
+	ctx.AbortWithStatusJSON(code, models.ErrorResponse{Error: text, Details: GetDetails(err)})
 	return
 }
 
+// Returns nil if the error doesn't have a place in the expression
+func GetDetails(err error) *models.ErrorDetails {
+	var parsingErr *parsing.Error
+	if !errors.As(err, &parsingErr) {
+		return nil
+	}
+	details := DetailsFrom(parsingErr)
+	return &details
+}
+
+func DetailsFrom(err *parsing.Error) models.ErrorDetails {
+	return models.ErrorDetails{
+		Code:    err.Code(),
+		Message: err.Error(),
+		Start:   err.Start,
+		End:     err.End,
+	}
+}
+
 func (h *Handler) CalcHandler(ctx *gin.Context) {
 	req := new(models.CalculationRequest)
 	err := json.NewDecoder(ctx.Request.Body).Decode(req)
@@ -33,7 +54,7 @@ func (h *Handler) CalcHandler(ctx *gin.Context) {
 		return
 	}
 
-	id, err := h.Service.Add(ctx.Request.Context(), req.Expression, userId.(primitive.ObjectID))
+	id, err := h.Service.Add(ctx.Request.Context(), *req, userId.(models.ID))
 	if err != nil {
 		SendError(ctx, err)
 		return
@@ -42,13 +63,40 @@ func (h *Handler) CalcHandler(ctx *gin.Context) {
 	ctx.JSON(http.StatusCreated, models.CreatedResponse{Id: id})
 }
 
+// Parses the expression without creating it and returns all found errors
+func (h *Handler) ValidateHandler(ctx *gin.Context) {
+	req := new(models.CalculationRequest)
+	err := json.NewDecoder(ctx.Request.Body).Decode(req)
+	if err != nil || req.Expression == "" {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, models.ErrorResponse{Error: InvalidBody})
+		return
+	}
+
+	userId, ok := ctx.Get(UserIDKey)
+	if !ok {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, models.ErrorResponse{Error: Unauthorized})
+		return
+	}
+
+	errs, err := h.Service.Validate(ctx.Request.Context(), req.Expression, userId.(models.ID))
+	if err != nil {
+		SendError(ctx, err)
+		return
+	}
+	res := models.ValidationResponse{Valid: len(errs) == 0, Errors: make([]models.ErrorDetails, len(errs))}
+	for i, err := range errs {
+		res.Errors[i] = DetailsFrom(err)
+	}
+	ctx.JSON(http.StatusOK, res)
+}
+
 func (h *Handler) ExpressionsHandler(ctx *gin.Context) {
 	userId, ok := ctx.Get(UserIDKey)
 	if !ok {
 		ctx.AbortWithStatusJSON(http.StatusUnauthorized, models.ErrorResponse{Error: Unauthorized})
 		return
 	}
-	expressions, err := h.Service.GetByUSer(ctx.Request.Context(), userId.(primitive.ObjectID))
+	expressions, err := h.Service.GetByUSer(ctx.Request.Context(), userId.(models.ID))
 	if err != nil {
 		SendError(ctx, err)
 		return
@@ -57,7 +105,7 @@ func (h *Handler) ExpressionsHandler(ctx *gin.Context) {
 }
 
 func (h *Handler) GetByIdHandler(ctx *gin.Context) {
-	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	id, err := models.IDFromHex(ctx.Param("id"))
 	if err != nil {
 		ctx.AbortWithStatusJSON(http.StatusNotFound, models.ErrorResponse{Error: InvalidId})
 		return
@@ -73,13 +121,58 @@ func (h *Handler) GetByIdHandler(ctx *gin.Context) {
 		return
 	}
 
-	if expr.UserID != userId.(primitive.ObjectID) {
+	if expr.UserID != userId.(models.ID) {
 		ctx.AbortWithStatusJSON(http.StatusForbidden, models.ErrorResponse{Error: Forbidden})
 		return
 	}
 	ctx.JSON(http.StatusOK, models.ExpressionResponse{Expression: *expr})
 }
 
+// Sends the tree of the expression, the format is json (default), sexpr or dot
+func (h *Handler) AstHandler(ctx *gin.Context) {
+	id, err := models.IDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusNotFound, models.ErrorResponse{Error: InvalidId})
+		return
+	}
+	format := ctx.DefaultQuery("format", "json")
+	if format != "json" && format != "sexpr" && format != "dot" {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, models.ErrorResponse{Error: InvalidFormat})
+		return
+	}
+	userId, ok := ctx.Get(UserIDKey)
+	if !ok {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, models.ErrorResponse{Error: Unauthorized})
+		return
+	}
+	expr, err := h.Service.Get(ctx.Request.Context(), id)
+	if err != nil {
+		SendError(ctx, err)
+		return
+	}
+
+	if expr.UserID != userId.(models.ID) {
+		ctx.AbortWithStatusJSON(http.StatusForbidden, models.ErrorResponse{Error: Forbidden})
+		return
+	}
+	ast, err := h.Service.GetAst(ctx.Request.Context(), *expr)
+	if err != nil {
+		SendError(ctx, err)
+		return
+	}
+	// The content type middleware sets json, it is replaced for the text formats
+	switch format {
+	case "sexpr":
+		ctx.Header("Content-Type", "text/plain; charset=utf-8")
+		ctx.String(http.StatusOK, render.SExpr(*ast))
+	case "dot":
+		ctx.Header("Content-Type", "text/vnd.graphviz; charset=utf-8")
+		ctx.String(http.StatusOK, render.Dot(*ast))
+	default:
+		ctx.JSON(http.StatusOK, models.AstResponse{Ast: *ast})
+	}
+}
+
 func (h *Handler) RegisterHandler(ctx *gin.Context) {
 	req := new(models.UserRequest)
 	err := json.NewDecoder(ctx.Request.Body).Decode(req)
@@ -122,7 +215,7 @@ func (h *Handler) ChangeUsernameHandler(ctx *gin.Context) {
 		ctx.AbortWithStatusJSON(http.StatusUnauthorized, models.ErrorResponse{Error: Unauthorized})
 		return
 	}
-	err = h.Service.UpdateUsername(ctx.Request.Context(), userId.(primitive.ObjectID), req.Username)
+	err = h.Service.UpdateUsername(ctx.Request.Context(), userId.(models.ID), req.Username)
 	if err != nil {
 		SendError(ctx, err)
 		return
@@ -142,7 +235,7 @@ func (h *Handler) ChangePasswordHandler(ctx *gin.Context) {
 		ctx.AbortWithStatusJSON(http.StatusUnauthorized, models.ErrorResponse{Error: Unauthorized})
 		return
 	}
-	err = h.Service.UpdatePassword(ctx.Request.Context(), userId.(primitive.ObjectID), req.Password)
+	err = h.Service.UpdatePassword(ctx.Request.Context(), userId.(models.ID), req.Password)
 	if err != nil {
 		SendError(ctx, err)
 		return
@@ -157,7 +250,7 @@ func (h *Handler) DeleteHandler(ctx *gin.Context) {
 		ctx.AbortWithStatusJSON(http.StatusUnauthorized, models.ErrorResponse{Error: Unauthorized})
 		return
 	}
-	err := h.Service.Delete(ctx.Request.Context(), userId.(primitive.ObjectID))
+	err := h.Service.Delete(ctx.Request.Context(), userId.(models.ID))
 	if err != nil {
 		SendError(ctx, err)
 		return
@@ -31,8 +31,19 @@ func New(service service.Service, logger *zap.Logger) *Handler {
 	v1.HEAD("/ping", router.PingHandler)
 	withAuth := v1.Group("/", router.AuthMiddleware())
 	withAuth.POST("/calculate", router.CalcHandler)
+	withAuth.POST("/validate", router.ValidateHandler)
 	withAuth.GET("/expressions", router.ExpressionsHandler)
 	withAuth.GET("/expressions/:id", router.GetByIdHandler)
+	withAuth.GET("/expressions/:id/ast", router.AstHandler)
+	withAuth.GET("/definitions", router.DefinitionsHandler)
+	withAuth.POST("/definitions", router.AddDefinitionHandler)
+	withAuth.GET("/definitions/:id", router.GetDefinitionHandler)
+	withAuth.PUT("/definitions/:id", router.UpdateDefinitionHandler)
+	withAuth.DELETE("/definitions/:id", router.DeleteDefinitionHandler)
+	withAuth.GET("/rates", router.RatesHandler)
+	admin := v1.Group("/admin", router.AdminMiddleware())
+	admin.PUT("/rates/:code", router.SetRateHandler)
+	admin.DELETE("/rates/:code", router.DeleteRateHandler)
 	v1.POST("/register", router.RegisterHandler)
 	v1.POST("/login", router.LoginHandler)
 	withAuth.PATCH("/username", router.ChangeUsernameHandler)
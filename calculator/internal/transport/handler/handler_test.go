@@ -12,12 +12,17 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/vandi37/Calculator/internal/models"
+	"github.com/vandi37/Calculator/internal/render"
 	"github.com/vandi37/Calculator/internal/repo"
+	"github.com/vandi37/Calculator/internal/service"
 	"github.com/vandi37/Calculator/internal/service/mock_service"
 	"github.com/vandi37/Calculator/internal/status"
 	"github.com/vandi37/Calculator/internal/transport/handler"
 	"github.com/vandi37/Calculator/pkg/hash"
-	"go.mongodb.org/mongo-driver/bson/primitive"
+	"github.com/vandi37/Calculator/pkg/parsing"
+	"github.com/vandi37/Calculator/pkg/parsing/lexer"
+	"github.com/vandi37/Calculator/pkg/parsing/parser"
+	"github.com/vandi37/vanerrors"
 	"go.uber.org/zap"
 )
 
@@ -29,7 +34,7 @@ func TestCalcHandler(t *testing.T) {
 	tests := []struct {
 		name           string
 		requestBody    interface{}
-		setupMock      func(*mock_service.MockService, primitive.ObjectID)
+		setupMock      func(*mock_service.MockService, models.ID)
 		userId         interface{}
 		expectedStatus int
 		expectedBody   interface{}
@@ -39,26 +44,39 @@ func TestCalcHandler(t *testing.T) {
 			requestBody: models.CalculationRequest{
 				Expression: "2+2",
 			},
-			setupMock: func(m *mock_service.MockService, userId primitive.ObjectID) {
-				m.EXPECT().Add(gomock.Any(), "2+2", userId).Return(primitive.NewObjectID(), nil)
+			setupMock: func(m *mock_service.MockService, userId models.ID) {
+				m.EXPECT().Add(gomock.Any(), models.CalculationRequest{Expression: "2+2"}, userId).Return(models.NewID(), nil)
 			},
-			userId:         primitive.NewObjectID(),
+			userId:         models.NewID(),
 			expectedStatus: http.StatusCreated,
-			expectedBody:   models.CreatedResponse{Id: primitive.NewObjectID()},
+			expectedBody:   models.CreatedResponse{Id: models.NewID()},
+		},
+		{
+			name: "Success with variables",
+			requestBody: models.CalculationRequest{
+				Expression: "price * qty",
+				Variables:  map[string]float64{"price": 2.5, "qty": 4},
+			},
+			setupMock: func(m *mock_service.MockService, userId models.ID) {
+				m.EXPECT().Add(gomock.Any(), models.CalculationRequest{Expression: "price * qty", Variables: map[string]float64{"price": 2.5, "qty": 4}}, userId).Return(models.NewID(), nil)
+			},
+			userId:         models.NewID(),
+			expectedStatus: http.StatusCreated,
+			expectedBody:   models.CreatedResponse{Id: models.NewID()},
 		},
 		{
 			name:           "Empty expression",
 			requestBody:    models.CalculationRequest{Expression: ""},
-			setupMock:      func(m *mock_service.MockService, userId primitive.ObjectID) {},
-			userId:         primitive.NewObjectID(),
+			setupMock:      func(m *mock_service.MockService, userId models.ID) {},
+			userId:         models.NewID(),
 			expectedStatus: http.StatusBadRequest,
 			expectedBody:   models.ErrorResponse{Error: handler.InvalidBody},
 		},
 		{
 			name:           "Invalid body",
 			requestBody:    "invalid",
-			setupMock:      func(m *mock_service.MockService, userId primitive.ObjectID) {},
-			userId:         primitive.NewObjectID(),
+			setupMock:      func(m *mock_service.MockService, userId models.ID) {},
+			userId:         models.NewID(),
 			expectedStatus: http.StatusBadRequest,
 			expectedBody:   models.ErrorResponse{Error: handler.InvalidBody},
 		},
@@ -67,19 +85,81 @@ func TestCalcHandler(t *testing.T) {
 			requestBody: models.CalculationRequest{
 				Expression: "2+2",
 			},
-			setupMock: func(m *mock_service.MockService, userId primitive.ObjectID) {
-				m.EXPECT().Add(gomock.Any(), "2+2", userId).Return(primitive.ObjectID{}, errors.New("some error"))
+			setupMock: func(m *mock_service.MockService, userId models.ID) {
+				m.EXPECT().Add(gomock.Any(), models.CalculationRequest{Expression: "2+2"}, userId).Return(models.ID{}, errors.New("some error"))
 			},
-			userId:         primitive.NewObjectID(),
+			userId:         models.NewID(),
 			expectedStatus: http.StatusInternalServerError,
 			expectedBody:   models.ErrorResponse{Error: handler.InternalError},
 		},
+		{
+			name: "Parsing error",
+			requestBody: models.CalculationRequest{
+				Expression: "2 & 2",
+			},
+			setupMock: func(m *mock_service.MockService, userId models.ID) {
+				m.EXPECT().Add(gomock.Any(), models.CalculationRequest{Expression: "2 & 2"}, userId).Return(models.ID{}, parsing.NewError(lexer.UnexpectedChar, "&", 2, 3))
+			},
+			userId:         models.NewID(),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody: models.ErrorResponse{
+				Error: "unexpected char - &",
+				Details: &models.ErrorDetails{
+					Code:    "unexpected_char",
+					Message: "unexpected char - &",
+					Start:   2,
+					End:     3,
+				},
+			},
+		},
+		{
+			name: "Empty brackets",
+			requestBody: models.CalculationRequest{
+				Expression: "()",
+			},
+			setupMock: func(m *mock_service.MockService, userId models.ID) {
+				_, err := parser.Build("()")
+				m.EXPECT().Add(gomock.Any(), models.CalculationRequest{Expression: "()"}, userId).Return(models.ID{}, err)
+			},
+			userId:         models.NewID(),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody: models.ErrorResponse{
+				Error: parser.EmptyBrackets,
+				Details: &models.ErrorDetails{
+					Code:    "empty_brackets",
+					Message: parser.EmptyBrackets,
+					Start:   0,
+					End:     2,
+				},
+			},
+		},
+		{
+			name: "Closing bracket without opening",
+			requestBody: models.CalculationRequest{
+				Expression: "1+2)",
+			},
+			setupMock: func(m *mock_service.MockService, userId models.ID) {
+				_, err := parser.Build("1+2)")
+				m.EXPECT().Add(gomock.Any(), models.CalculationRequest{Expression: "1+2)"}, userId).Return(models.ID{}, err)
+			},
+			userId:         models.NewID(),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody: models.ErrorResponse{
+				Error: "closing bracket without opening - [closing bracket]",
+				Details: &models.ErrorDetails{
+					Code:    "closing_bracket_without_opening",
+					Message: "closing bracket without opening - [closing bracket]",
+					Start:   3,
+					End:     4,
+				},
+			},
+		},
 		{
 			name: "Unauthorized",
 			requestBody: models.CalculationRequest{
 				Expression: "2+2",
 			},
-			setupMock:      func(m *mock_service.MockService, userId primitive.ObjectID) {},
+			setupMock:      func(m *mock_service.MockService, userId models.ID) {},
 			userId:         nil,
 			expectedStatus: http.StatusUnauthorized,
 			expectedBody:   models.ErrorResponse{Error: handler.Unauthorized},
@@ -105,7 +185,7 @@ func TestCalcHandler(t *testing.T) {
 			}
 
 			if tt.setupMock != nil && tt.userId != nil {
-				tt.setupMock(mockService, tt.userId.(primitive.ObjectID))
+				tt.setupMock(mockService, tt.userId.(models.ID))
 			}
 
 			h.CalcHandler(ctx)
@@ -129,51 +209,130 @@ func TestCalcHandler(t *testing.T) {
 	}
 }
 
+func TestValidateHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		requestBody    interface{}
+		setupMock      func(*mock_service.MockService)
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:        "Valid",
+			requestBody: models.CalculationRequest{Expression: "2+2"},
+			setupMock: func(m *mock_service.MockService) {
+				m.EXPECT().Validate(gomock.Any(), "2+2", gomock.Any()).Return(nil, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   models.ValidationResponse{Valid: true, Errors: []models.ErrorDetails{}},
+		},
+		{
+			name:        "Errors",
+			requestBody: models.CalculationRequest{Expression: "() + 2 +"},
+			setupMock: func(m *mock_service.MockService) {
+				m.EXPECT().Validate(gomock.Any(), "() + 2 +", gomock.Any()).Return([]*parsing.Error{
+					parsing.SimpleError(parser.EmptyBrackets, 0, 2),
+					parsing.SimpleError(parser.UnexpectedEOF, 8, 8),
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: models.ValidationResponse{
+				Valid: false,
+				Errors: []models.ErrorDetails{
+					{Code: "empty_brackets", Message: parser.EmptyBrackets, Start: 0, End: 2},
+					{Code: "unexpected_eof", Message: parser.UnexpectedEOF, Start: 8, End: 8},
+				},
+			},
+		},
+		{
+			name:           "Empty expression",
+			requestBody:    models.CalculationRequest{Expression: ""},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   models.ErrorResponse{Error: handler.InvalidBody},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockService := mock_service.NewMockService(ctrl)
+			h := handler.New(mockService, zap.NewNop())
+
+			body, _ := json.Marshal(tt.requestBody)
+			req, _ := http.NewRequest(http.MethodPost, "/validate", bytes.NewBuffer(body))
+			w := httptest.NewRecorder()
+
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Request = req
+			ctx.Set(handler.UserIDKey, models.NewID())
+
+			if tt.setupMock != nil {
+				tt.setupMock(mockService)
+			}
+
+			h.ValidateHandler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if expected, ok := tt.expectedBody.(models.ValidationResponse); ok {
+				var response models.ValidationResponse
+				_ = json.Unmarshal(w.Body.Bytes(), &response)
+				assert.Equal(t, expected, response)
+			} else {
+				var response models.ErrorResponse
+				_ = json.Unmarshal(w.Body.Bytes(), &response)
+				assert.Equal(t, tt.expectedBody, response)
+			}
+		})
+	}
+}
+
 func TestExpressionsHandler(t *testing.T) {
 	tests := []struct {
 		name           string
-		setupMock      func(*mock_service.MockService, primitive.ObjectID)
+		setupMock      func(*mock_service.MockService, models.ID)
 		userId         interface{}
 		expectedStatus int
 		expectedBody   interface{}
 	}{
 		{
 			name: "Success",
-			setupMock: func(m *mock_service.MockService, userId primitive.ObjectID) {
+			setupMock: func(m *mock_service.MockService, userId models.ID) {
 				m.EXPECT().GetByUSer(gomock.Any(), userId).Return([]models.Expression{
-					{ID: primitive.NewObjectID(), Origin: "2+2", Status: status.Finished},
+					{ID: models.NewID(), Origin: "2+2", Status: status.Finished},
 				}, nil)
 			},
-			userId:         primitive.NewObjectID(),
+			userId:         models.NewID(),
 			expectedStatus: http.StatusOK,
 			expectedBody: models.ExpressionsResponse{
 				Expressions: []models.Expression{
-					{ID: primitive.NewObjectID(), Origin: "2+2", Status: status.Finished},
+					{ID: models.NewID(), Origin: "2+2", Status: status.Finished},
 				},
 			},
 		},
 		{
 			name:           "Unauthorized",
-			setupMock:      func(m *mock_service.MockService, userId primitive.ObjectID) {},
+			setupMock:      func(m *mock_service.MockService, userId models.ID) {},
 			userId:         nil,
 			expectedStatus: http.StatusUnauthorized,
 			expectedBody:   models.ErrorResponse{Error: handler.Unauthorized},
 		},
 		{
 			name: "Service error",
-			setupMock: func(m *mock_service.MockService, userId primitive.ObjectID) {
+			setupMock: func(m *mock_service.MockService, userId models.ID) {
 				m.EXPECT().GetByUSer(gomock.Any(), userId).Return(nil, errors.New("some error"))
 			},
-			userId:         primitive.NewObjectID(),
+			userId:         models.NewID(),
 			expectedStatus: http.StatusInternalServerError,
 			expectedBody:   models.ErrorResponse{Error: handler.InternalError},
 		},
 		{
 			name: "Empty expressions",
-			setupMock: func(m *mock_service.MockService, userId primitive.ObjectID) {
+			setupMock: func(m *mock_service.MockService, userId models.ID) {
 				m.EXPECT().GetByUSer(gomock.Any(), userId).Return([]models.Expression{}, nil)
 			},
-			userId:         primitive.NewObjectID(),
+			userId:         models.NewID(),
 			expectedStatus: http.StatusOK,
 			expectedBody: models.ExpressionsResponse{
 				Expressions: []models.Expression{},
@@ -199,7 +358,7 @@ func TestExpressionsHandler(t *testing.T) {
 			}
 
 			if tt.setupMock != nil && tt.userId != nil {
-				tt.setupMock(mockService, tt.userId.(primitive.ObjectID))
+				tt.setupMock(mockService, tt.userId.(models.ID))
 			}
 
 			h.ExpressionsHandler(ctx)
@@ -228,9 +387,9 @@ func TestExpressionsHandler(t *testing.T) {
 }
 
 func TestGetByIdHandler(t *testing.T) {
-	validId := primitive.NewObjectID()
+	validId := models.NewID()
 	invalidId := "invalid"
-	validUserId := primitive.NewObjectID()
+	validUserId := models.NewID()
 
 	tests := []struct {
 		name           string
@@ -266,7 +425,7 @@ func TestGetByIdHandler(t *testing.T) {
 		{
 			name:           "Invalid ID",
 			idParam:        invalidId,
-			userId:         primitive.NewObjectID(),
+			userId:         models.NewID(),
 			setupMock:      func(m *mock_service.MockService) {},
 			expectedStatus: http.StatusNotFound,
 			expectedBody:   models.ErrorResponse{Error: handler.InvalidId},
@@ -274,7 +433,7 @@ func TestGetByIdHandler(t *testing.T) {
 		{
 			name:    "Not found",
 			idParam: validId.Hex(),
-			userId:  primitive.NewObjectID(),
+			userId:  models.NewID(),
 			setupMock: func(m *mock_service.MockService) {
 				m.EXPECT().Get(gomock.Any(), validId).Return(nil, repo.ExpressionNotFound)
 			},
@@ -284,7 +443,7 @@ func TestGetByIdHandler(t *testing.T) {
 		{
 			name:    "Service error",
 			idParam: validId.Hex(),
-			userId:  primitive.NewObjectID(),
+			userId:  models.NewID(),
 			setupMock: func(m *mock_service.MockService) {
 				m.EXPECT().Get(gomock.Any(), validId).Return(nil, errors.New("some error"))
 			},
@@ -347,6 +506,401 @@ func TestGetByIdHandler(t *testing.T) {
 	}
 }
 
+func TestAstHandler(t *testing.T) {
+	validId := models.NewID()
+	validUserId := models.NewID()
+	one, two := 1.0, 2.0
+	ast := &models.AstNode{
+		ID:       validId.Hex(),
+		Operator: "+",
+		State:    models.Pending,
+		Children: []models.AstNode{
+			{ID: models.NewID().Hex(), State: models.Resolved, Value: &one},
+			{ID: models.NewID().Hex(), State: models.Resolved, Value: &two},
+		},
+	}
+	expr := &models.Expression{ID: validId, UserID: validUserId, Origin: "1+2", Status: status.Pending}
+
+	tests := []struct {
+		name                string
+		idParam             string
+		format              string
+		userId              interface{}
+		setupMock           func(*mock_service.MockService)
+		expectedStatus      int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:    "Json",
+			idParam: validId.Hex(),
+			userId:  validUserId,
+			setupMock: func(m *mock_service.MockService) {
+				m.EXPECT().Get(gomock.Any(), validId).Return(expr, nil)
+				m.EXPECT().GetAst(gomock.Any(), *expr).Return(ast, nil)
+			},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedBody: func() string {
+				data, _ := json.Marshal(models.AstResponse{Ast: *ast})
+				return string(data)
+			}(),
+		},
+		{
+			name:    "S-expression",
+			idParam: validId.Hex(),
+			format:  "sexpr",
+			userId:  validUserId,
+			setupMock: func(m *mock_service.MockService) {
+				m.EXPECT().Get(gomock.Any(), validId).Return(expr, nil)
+				m.EXPECT().GetAst(gomock.Any(), *expr).Return(ast, nil)
+			},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/plain; charset=utf-8",
+			expectedBody:        "(+ 1 2)",
+		},
+		{
+			name:    "Dot",
+			idParam: validId.Hex(),
+			format:  "dot",
+			userId:  validUserId,
+			setupMock: func(m *mock_service.MockService) {
+				m.EXPECT().Get(gomock.Any(), validId).Return(expr, nil)
+				m.EXPECT().GetAst(gomock.Any(), *expr).Return(ast, nil)
+			},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/vnd.graphviz; charset=utf-8",
+			expectedBody:        render.Dot(*ast),
+		},
+		{
+			name:                "Invalid format",
+			idParam:             validId.Hex(),
+			format:              "xml",
+			userId:              validUserId,
+			setupMock:           func(m *mock_service.MockService) {},
+			expectedStatus:      http.StatusBadRequest,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedBody:        `{"error":"invalid format"}`,
+		},
+		{
+			name:                "Invalid ID",
+			idParam:             "invalid",
+			userId:              validUserId,
+			setupMock:           func(m *mock_service.MockService) {},
+			expectedStatus:      http.StatusNotFound,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedBody:        `{"error":"invalid id"}`,
+		},
+		{
+			name:    "Forbidden",
+			idParam: validId.Hex(),
+			userId:  models.NewID(),
+			setupMock: func(m *mock_service.MockService) {
+				m.EXPECT().Get(gomock.Any(), validId).Return(expr, nil)
+			},
+			expectedStatus:      http.StatusForbidden,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedBody:        `{"error":"forbidden"}`,
+		},
+		{
+			name:    "Nodes not found",
+			idParam: validId.Hex(),
+			userId:  validUserId,
+			setupMock: func(m *mock_service.MockService) {
+				m.EXPECT().Get(gomock.Any(), validId).Return(expr, nil)
+				m.EXPECT().GetAst(gomock.Any(), *expr).Return(nil, repo.NodeNotFound)
+			},
+			expectedStatus:      http.StatusNotFound,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedBody:        `{"error":"node not found"}`,
+		},
+		{
+			name:                "Unauthorized",
+			idParam:             validId.Hex(),
+			userId:              nil,
+			setupMock:           func(m *mock_service.MockService) {},
+			expectedStatus:      http.StatusUnauthorized,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedBody:        `{"error":"unauthorized"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockService := mock_service.NewMockService(ctrl)
+			h := handler.New(mockService, zap.NewNop())
+
+			url := "/expressions/" + tt.idParam + "/ast"
+			if tt.format != "" {
+				url += "?format=" + tt.format
+			}
+			req, _ := http.NewRequest(http.MethodGet, url, nil)
+			w := httptest.NewRecorder()
+
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Request = req
+			ctx.Params = gin.Params{{Key: "id", Value: tt.idParam}}
+			if tt.userId != nil {
+				ctx.Set(handler.UserIDKey, tt.userId)
+			}
+
+			tt.setupMock(mockService)
+
+			h.AstHandler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestAddDefinitionHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		requestBody    interface{}
+		setupMock      func(*mock_service.MockService)
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:        "Success",
+			requestBody: models.DefinitionRequest{Name: "f", Params: []string{"x"}, Body: "x^2"},
+			setupMock: func(m *mock_service.MockService) {
+				m.EXPECT().AddDefinition(gomock.Any(), gomock.Any(), models.DefinitionRequest{Name: "f", Params: []string{"x"}, Body: "x^2"}).
+					Return(models.NewID(), nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Empty body",
+			requestBody:    models.DefinitionRequest{Name: "rate"},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   models.ErrorResponse{Error: handler.InvalidBody},
+		},
+		{
+			name:        "Name taken",
+			requestBody: models.DefinitionRequest{Name: "rate", Body: "0.2"},
+			setupMock: func(m *mock_service.MockService) {
+				m.EXPECT().AddDefinition(gomock.Any(), gomock.Any(), gomock.Any()).Return(models.NilID, repo.DefinitionNameTaken)
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   models.ErrorResponse{Error: repo.DefinitionNameTaken.Error()},
+		},
+		{
+			name:        "Cycle",
+			requestBody: models.DefinitionRequest{Name: "a", Body: "b + 1"},
+			setupMock: func(m *mock_service.MockService) {
+				m.EXPECT().AddDefinition(gomock.Any(), gomock.Any(), gomock.Any()).Return(models.NilID, vanerrors.New(parser.CyclicDefinition, "a -> b -> a"))
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockService := mock_service.NewMockService(ctrl)
+			h := handler.New(mockService, zap.NewNop())
+
+			body, _ := json.Marshal(tt.requestBody)
+			req, _ := http.NewRequest(http.MethodPost, "/definitions", bytes.NewBuffer(body))
+			w := httptest.NewRecorder()
+
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Request = req
+			ctx.Set(handler.UserIDKey, models.NewID())
+
+			if tt.setupMock != nil {
+				tt.setupMock(mockService)
+			}
+
+			h.AddDefinitionHandler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != nil {
+				var response models.ErrorResponse
+				_ = json.Unmarshal(w.Body.Bytes(), &response)
+				assert.Equal(t, tt.expectedBody, response)
+			}
+		})
+	}
+}
+
+func TestDeleteDefinitionHandler(t *testing.T) {
+	validId := models.NewID()
+	validUserId := models.NewID()
+
+	tests := []struct {
+		name           string
+		idParam        string
+		setupMock      func(*mock_service.MockService)
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:    "Success",
+			idParam: validId.Hex(),
+			setupMock: func(m *mock_service.MockService) {
+				m.EXPECT().GetDefinition(gomock.Any(), validId).Return(&models.Definition{ID: validId, UserID: validUserId, Name: "rate", Body: "0.2"}, nil)
+				m.EXPECT().DeleteDefinition(gomock.Any(), validId).Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "Invalid ID",
+			idParam:        "invalid",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   models.ErrorResponse{Error: handler.InvalidId},
+		},
+		{
+			name:    "Forbidden",
+			idParam: validId.Hex(),
+			setupMock: func(m *mock_service.MockService) {
+				m.EXPECT().GetDefinition(gomock.Any(), validId).Return(&models.Definition{ID: validId, UserID: models.NewID()}, nil)
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   models.ErrorResponse{Error: handler.Forbidden},
+		},
+		{
+			name:    "Not found",
+			idParam: validId.Hex(),
+			setupMock: func(m *mock_service.MockService) {
+				m.EXPECT().GetDefinition(gomock.Any(), validId).Return(nil, repo.DefinitionNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   models.ErrorResponse{Error: repo.DefinitionNotFound.Error()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockService := mock_service.NewMockService(ctrl)
+			h := handler.New(mockService, zap.NewNop())
+
+			req, _ := http.NewRequest(http.MethodDelete, "/definitions/"+tt.idParam, nil)
+			w := httptest.NewRecorder()
+
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Request = req
+			ctx.Params = gin.Params{{Key: "id", Value: tt.idParam}}
+			ctx.Set(handler.UserIDKey, validUserId)
+
+			if tt.setupMock != nil {
+				tt.setupMock(mockService)
+			}
+
+			h.DeleteDefinitionHandler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != nil {
+				var response models.ErrorResponse
+				_ = json.Unmarshal(w.Body.Bytes(), &response)
+				assert.Equal(t, tt.expectedBody, response)
+			}
+		})
+	}
+}
+
+func TestSetRateHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		code           string
+		body           string
+		setupMock      func(*mock_service.MockService)
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name: "Success",
+			code: "EUR",
+			body: `{"rate": 0.92}`,
+			setupMock: func(m *mock_service.MockService) {
+				m.EXPECT().SetRate(gomock.Any(), "EUR", 0.92).Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "Invalid body",
+			code:           "EUR",
+			body:           `{"rate": "high"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   models.ErrorResponse{Error: handler.InvalidBody},
+		},
+		{
+			name: "Invalid rate",
+			code: "EUR",
+			body: `{"rate": -1}`,
+			setupMock: func(m *mock_service.MockService) {
+				m.EXPECT().SetRate(gomock.Any(), "EUR", -1.0).Return(service.InvalidRate)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   models.ErrorResponse{Error: service.InvalidRate.Error()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockService := mock_service.NewMockService(ctrl)
+			h := handler.New(mockService, zap.NewNop())
+
+			req, _ := http.NewRequest(http.MethodPut, "/admin/rates/"+tt.code, bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Request = req
+			ctx.Params = gin.Params{{Key: "code", Value: tt.code}}
+
+			if tt.setupMock != nil {
+				tt.setupMock(mockService)
+			}
+
+			h.SetRateHandler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != nil {
+				var response models.ErrorResponse
+				_ = json.Unmarshal(w.Body.Bytes(), &response)
+				assert.Equal(t, tt.expectedBody, response)
+			}
+		})
+	}
+}
+
+func TestDeleteRateHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mock_service.NewMockService(ctrl)
+	h := handler.New(mockService, zap.NewNop())
+	mockService.EXPECT().DeleteRate(gomock.Any(), "EUR").Return(repo.RateNotFound)
+
+	req, _ := http.NewRequest(http.MethodDelete, "/admin/rates/EUR", nil)
+	w := httptest.NewRecorder()
+
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = req
+	ctx.Params = gin.Params{{Key: "code", Value: "EUR"}}
+
+	h.DeleteRateHandler(ctx)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	var response models.ErrorResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, models.ErrorResponse{Error: repo.RateNotFound.Error()}, response)
+}
+
 func TestRegisterHandler(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -362,10 +916,10 @@ func TestRegisterHandler(t *testing.T) {
 				Password: "pass",
 			},
 			setupMock: func(m *mock_service.MockService) {
-				m.EXPECT().Register(gomock.Any(), "user", "pass").Return(primitive.NewObjectID(), nil)
+				m.EXPECT().Register(gomock.Any(), "user", "pass").Return(models.NewID(), nil)
 			},
 			expectedStatus: http.StatusCreated,
-			expectedBody:   models.CreatedResponse{Id: primitive.NewObjectID()},
+			expectedBody:   models.CreatedResponse{Id: models.NewID()},
 		},
 		{
 			name:           "Empty username",
@@ -395,7 +949,7 @@ func TestRegisterHandler(t *testing.T) {
 				Password: "pass",
 			},
 			setupMock: func(m *mock_service.MockService) {
-				m.EXPECT().Register(gomock.Any(), "user", "pass").Return(primitive.ObjectID{}, repo.UsernameTaken)
+				m.EXPECT().Register(gomock.Any(), "user", "pass").Return(models.ID{}, repo.UsernameTaken)
 			},
 			expectedStatus: http.StatusConflict,
 			expectedBody:   models.ErrorResponse{Error: repo.UsernameTaken.Error()},
@@ -407,7 +961,7 @@ func TestRegisterHandler(t *testing.T) {
 				Password: "pass",
 			},
 			setupMock: func(m *mock_service.MockService) {
-				m.EXPECT().Register(gomock.Any(), "user", "pass").Return(primitive.ObjectID{}, errors.New("some error"))
+				m.EXPECT().Register(gomock.Any(), "user", "pass").Return(models.ID{}, errors.New("some error"))
 			},
 			expectedStatus: http.StatusInternalServerError,
 			expectedBody:   models.ErrorResponse{Error: handler.InternalError},
@@ -556,12 +1110,12 @@ func TestLoginHandler(t *testing.T) {
 }
 
 func TestChangeUsernameHandler(t *testing.T) {
-	userId := primitive.NewObjectID()
+	userId := models.NewID()
 
 	tests := []struct {
 		name           string
 		requestBody    interface{}
-		setupMock      func(*mock_service.MockService, primitive.ObjectID)
+		setupMock      func(*mock_service.MockService, models.ID)
 		userId         interface{}
 		expectedStatus int
 		expectedBody   interface{}
@@ -571,7 +1125,7 @@ func TestChangeUsernameHandler(t *testing.T) {
 			requestBody: models.UsernameRequest{
 				Username: "newuser",
 			},
-			setupMock: func(m *mock_service.MockService, userId primitive.ObjectID) {
+			setupMock: func(m *mock_service.MockService, userId models.ID) {
 				m.EXPECT().UpdateUsername(gomock.Any(), userId, "newuser").Return(nil)
 			},
 			userId:         userId,
@@ -580,7 +1134,7 @@ func TestChangeUsernameHandler(t *testing.T) {
 		{
 			name:           "Unauthorized",
 			requestBody:    models.UsernameRequest{Username: "newuser"},
-			setupMock:      func(m *mock_service.MockService, userId primitive.ObjectID) {},
+			setupMock:      func(m *mock_service.MockService, userId models.ID) {},
 			userId:         nil,
 			expectedStatus: http.StatusUnauthorized,
 			expectedBody:   models.ErrorResponse{Error: handler.Unauthorized},
@@ -588,7 +1142,7 @@ func TestChangeUsernameHandler(t *testing.T) {
 		{
 			name:           "Empty username",
 			requestBody:    models.UsernameRequest{Username: ""},
-			setupMock:      func(m *mock_service.MockService, userId primitive.ObjectID) {},
+			setupMock:      func(m *mock_service.MockService, userId models.ID) {},
 			userId:         userId,
 			expectedStatus: http.StatusBadRequest,
 			expectedBody:   models.ErrorResponse{Error: handler.InvalidBody},
@@ -596,7 +1150,7 @@ func TestChangeUsernameHandler(t *testing.T) {
 		{
 			name:           "Invalid body",
 			requestBody:    "invalid",
-			setupMock:      func(m *mock_service.MockService, userId primitive.ObjectID) {},
+			setupMock:      func(m *mock_service.MockService, userId models.ID) {},
 			userId:         userId,
 			expectedStatus: http.StatusBadRequest,
 			expectedBody:   models.ErrorResponse{Error: handler.InvalidBody},
@@ -606,7 +1160,7 @@ func TestChangeUsernameHandler(t *testing.T) {
 			requestBody: models.UsernameRequest{
 				Username: "existing",
 			},
-			setupMock: func(m *mock_service.MockService, userId primitive.ObjectID) {
+			setupMock: func(m *mock_service.MockService, userId models.ID) {
 				m.EXPECT().UpdateUsername(gomock.Any(), userId, "existing").Return(repo.UsernameTaken)
 			},
 			userId:         userId,
@@ -618,7 +1172,7 @@ func TestChangeUsernameHandler(t *testing.T) {
 			requestBody: models.UsernameRequest{
 				Username: "newuser",
 			},
-			setupMock: func(m *mock_service.MockService, userId primitive.ObjectID) {
+			setupMock: func(m *mock_service.MockService, userId models.ID) {
 				m.EXPECT().UpdateUsername(gomock.Any(), userId, "newuser").Return(errors.New("some error"))
 			},
 			userId:         userId,
@@ -646,7 +1200,7 @@ func TestChangeUsernameHandler(t *testing.T) {
 			}
 
 			if tt.setupMock != nil && tt.userId != nil {
-				tt.setupMock(mockService, tt.userId.(primitive.ObjectID))
+				tt.setupMock(mockService, tt.userId.(models.ID))
 			}
 
 			h.ChangeUsernameHandler(ctx)
@@ -662,12 +1216,12 @@ func TestChangeUsernameHandler(t *testing.T) {
 }
 
 func TestChangePasswordHandler(t *testing.T) {
-	userId := primitive.NewObjectID()
+	userId := models.NewID()
 
 	tests := []struct {
 		name           string
 		requestBody    interface{}
-		setupMock      func(*mock_service.MockService, primitive.ObjectID)
+		setupMock      func(*mock_service.MockService, models.ID)
 		userId         interface{}
 		expectedStatus int
 		expectedBody   interface{}
@@ -677,7 +1231,7 @@ func TestChangePasswordHandler(t *testing.T) {
 			requestBody: models.PasswordRequest{
 				Password: "newpass",
 			},
-			setupMock: func(m *mock_service.MockService, userId primitive.ObjectID) {
+			setupMock: func(m *mock_service.MockService, userId models.ID) {
 				m.EXPECT().UpdatePassword(gomock.Any(), userId, "newpass").Return(nil)
 			},
 			userId:         userId,
@@ -686,7 +1240,7 @@ func TestChangePasswordHandler(t *testing.T) {
 		{
 			name:           "Unauthorized",
 			requestBody:    models.PasswordRequest{Password: "newpass"},
-			setupMock:      func(m *mock_service.MockService, userId primitive.ObjectID) {},
+			setupMock:      func(m *mock_service.MockService, userId models.ID) {},
 			userId:         nil,
 			expectedStatus: http.StatusUnauthorized,
 			expectedBody:   models.ErrorResponse{Error: handler.Unauthorized},
@@ -694,7 +1248,7 @@ func TestChangePasswordHandler(t *testing.T) {
 		{
 			name:           "Empty password",
 			requestBody:    models.PasswordRequest{Password: ""},
-			setupMock:      func(m *mock_service.MockService, userId primitive.ObjectID) {},
+			setupMock:      func(m *mock_service.MockService, userId models.ID) {},
 			userId:         userId,
 			expectedStatus: http.StatusBadRequest,
 			expectedBody:   models.ErrorResponse{Error: handler.InvalidBody},
@@ -702,7 +1256,7 @@ func TestChangePasswordHandler(t *testing.T) {
 		{
 			name:           "Invalid body",
 			requestBody:    "invalid",
-			setupMock:      func(m *mock_service.MockService, userId primitive.ObjectID) {},
+			setupMock:      func(m *mock_service.MockService, userId models.ID) {},
 			userId:         userId,
 			expectedStatus: http.StatusBadRequest,
 			expectedBody:   models.ErrorResponse{Error: handler.InvalidBody},
@@ -712,7 +1266,7 @@ func TestChangePasswordHandler(t *testing.T) {
 			requestBody: models.PasswordRequest{
 				Password: "newpass",
 			},
-			setupMock: func(m *mock_service.MockService, userId primitive.ObjectID) {
+			setupMock: func(m *mock_service.MockService, userId models.ID) {
 				m.EXPECT().UpdatePassword(gomock.Any(), userId, "newpass").Return(errors.New("some error"))
 			},
 			userId:         userId,
@@ -740,7 +1294,7 @@ func TestChangePasswordHandler(t *testing.T) {
 			}
 
 			if tt.setupMock != nil && tt.userId != nil {
-				tt.setupMock(mockService, tt.userId.(primitive.ObjectID))
+				tt.setupMock(mockService, tt.userId.(models.ID))
 			}
 
 			h.ChangePasswordHandler(ctx)
@@ -756,18 +1310,18 @@ func TestChangePasswordHandler(t *testing.T) {
 }
 
 func TestDeleteHandler(t *testing.T) {
-	userId := primitive.NewObjectID()
+	userId := models.NewID()
 
 	tests := []struct {
 		name           string
-		setupMock      func(*mock_service.MockService, primitive.ObjectID)
+		setupMock      func(*mock_service.MockService, models.ID)
 		userId         interface{}
 		expectedStatus int
 		expectedBody   interface{}
 	}{
 		{
 			name: "Success",
-			setupMock: func(m *mock_service.MockService, userId primitive.ObjectID) {
+			setupMock: func(m *mock_service.MockService, userId models.ID) {
 				m.EXPECT().Delete(gomock.Any(), userId).Return(nil)
 			},
 			userId:         userId,
@@ -775,14 +1329,14 @@ func TestDeleteHandler(t *testing.T) {
 		},
 		{
 			name:           "Unauthorized",
-			setupMock:      func(m *mock_service.MockService, userId primitive.ObjectID) {},
+			setupMock:      func(m *mock_service.MockService, userId models.ID) {},
 			userId:         nil,
 			expectedStatus: http.StatusUnauthorized,
 			expectedBody:   models.ErrorResponse{Error: handler.Unauthorized},
 		},
 		{
 			name: "Service error",
-			setupMock: func(m *mock_service.MockService, userId primitive.ObjectID) {
+			setupMock: func(m *mock_service.MockService, userId models.ID) {
 				m.EXPECT().Delete(gomock.Any(), userId).Return(errors.New("some error"))
 			},
 			userId:         userId,
@@ -809,7 +1363,7 @@ func TestDeleteHandler(t *testing.T) {
 			}
 
 			if tt.setupMock != nil && tt.userId != nil {
-				tt.setupMock(mockService, tt.userId.(primitive.ObjectID))
+				tt.setupMock(mockService, tt.userId.(models.ID))
 			}
 
 			h.DeleteHandler(ctx)
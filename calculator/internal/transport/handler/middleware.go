@@ -9,7 +9,10 @@ import (
 	"go.uber.org/zap"
 )
 
-const UserIDKey = "userID"
+const (
+	UserIDKey        = "userID"
+	AdminTokenHeader = "X-Admin-Token"
+)
 
 func ContentType() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
@@ -63,7 +66,7 @@ func CORSMiddleware() gin.HandlerFunc {
 
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-Admin-Token, accept, origin, Cache-Control, X-Requested-With")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET")
 
 		if c.Request.Method == "OPTIONS" {
@@ -91,3 +94,13 @@ func (h *Handler) AuthMiddleware() gin.HandlerFunc {
 		ctx.Next()
 	}
 }
+
+func (h *Handler) AdminMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if err := h.Service.CheckAdmin(ctx.Request.Context(), ctx.GetHeader(AdminTokenHeader)); err != nil {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, models.ErrorResponse{Error: Forbidden})
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -14,7 +14,6 @@ import (
 	"github.com/vandi37/Calculator/internal/service"
 	"github.com/vandi37/Calculator/internal/service/mock_service"
 	"github.com/vandi37/Calculator/internal/transport/handler"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest"
 )
@@ -157,7 +156,7 @@ func TestCORSMiddleware(t *testing.T) {
 func TestAuthMiddleware(t *testing.T) {
 	validToken := "valid_token"
 	invalidToken := "invalid_token"
-	userId := primitive.NewObjectID()
+	userId := models.NewID()
 
 	tests := []struct {
 		name           string
@@ -188,7 +187,7 @@ func TestAuthMiddleware(t *testing.T) {
 			name:  "Invalid token",
 			token: invalidToken,
 			setupMock: func(m *mock_service.MockService) {
-				m.EXPECT().CheckToken(gomock.Any(), invalidToken).Return(primitive.ObjectID{}, service.InvalidToken)
+				m.EXPECT().CheckToken(gomock.Any(), invalidToken).Return(models.ID{}, service.InvalidToken)
 			},
 			expectedStatus: http.StatusUnauthorized,
 			expectedBody:   models.ErrorResponse{Error: handler.Unauthorized},
@@ -198,7 +197,7 @@ func TestAuthMiddleware(t *testing.T) {
 			name:  "Service error",
 			token: validToken,
 			setupMock: func(m *mock_service.MockService) {
-				m.EXPECT().CheckToken(gomock.Any(), validToken).Return(primitive.ObjectID{}, errors.New("some error"))
+				m.EXPECT().CheckToken(gomock.Any(), validToken).Return(models.ID{}, errors.New("some error"))
 			},
 			expectedStatus: http.StatusUnauthorized,
 			expectedBody:   models.ErrorResponse{Error: handler.Unauthorized},
@@ -247,6 +246,47 @@ func TestAuthMiddleware(t *testing.T) {
 	}
 }
 
+func TestAdminMiddleware(t *testing.T) {
+	tests := []struct {
+		name           string
+		token          string
+		err            error
+		expectedStatus int
+	}{
+		{"Valid token", "admin", nil, http.StatusOK},
+		{"Invalid token", "user", service.NotAdmin, http.StatusForbidden},
+		{"Empty token", "", service.NotAdmin, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockService := mock_service.NewMockService(ctrl)
+			h := handler.New(mockService, zap.NewNop())
+			mockService.EXPECT().CheckAdmin(gomock.Any(), tt.token).Return(tt.err)
+
+			req, _ := http.NewRequest(http.MethodPut, "/", nil)
+			req.Header.Set(handler.AdminTokenHeader, tt.token)
+			w := httptest.NewRecorder()
+
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Request = req
+
+			middleware := h.AdminMiddleware()
+			middleware(ctx)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus != http.StatusOK {
+				var response models.ErrorResponse
+				_ = json.Unmarshal(w.Body.Bytes(), &response)
+				assert.Equal(t, models.ErrorResponse{Error: handler.Forbidden}, response)
+			}
+		})
+	}
+}
+
 func TestMiddlewareChain(t *testing.T) {
 	t.Run("Multiple middlewares execute in order", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
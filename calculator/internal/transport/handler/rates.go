@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vandi37/Calculator/internal/models"
+)
+
+func (h *Handler) RatesHandler(ctx *gin.Context) {
+	rates, err := h.Service.GetRates(ctx.Request.Context())
+	if err != nil {
+		SendError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, models.RatesResponse{Rates: rates})
+}
+
+func (h *Handler) SetRateHandler(ctx *gin.Context) {
+	req := new(models.RateRequest)
+	err := json.NewDecoder(ctx.Request.Body).Decode(req)
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, models.ErrorResponse{Error: InvalidBody})
+		return
+	}
+	err = h.Service.SetRate(ctx.Request.Context(), ctx.Param("code"), req.Rate)
+	if err != nil {
+		SendError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+func (h *Handler) DeleteRateHandler(ctx *gin.Context) {
+	err := h.Service.DeleteRate(ctx.Request.Context(), ctx.Param("code"))
+	if err != nil {
+		SendError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusNoContent, nil)
+}
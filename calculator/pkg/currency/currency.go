@@ -0,0 +1,65 @@
+// This package has currency codes and the conversion of amounts in expressions
+//
+// Rates aren't here, the admin saves them and every expression keeps the rates it used
+package currency
+
+import (
+	"math"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/vandi37/vanerrors"
+)
+
+const (
+	UnknownCurrency = "unknown currency"
+)
+
+// Codes are three capital letters like in ISO 4217: USD, EUR
+func IsCode(name string) bool {
+	if len(name) != 3 {
+		return false
+	}
+	for _, r := range name {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// A rate is the price of the base currency in the currency, the base currency itself has 1
+func IsRate(rate float64) bool {
+	return rate > 0 && !math.IsInf(rate, 0)
+}
+
+// Converts the amount with the rates, the codes without a rate are listed in the error
+func Convert(amount *big.Rat, from, to string, rates map[string]float64) (*big.Rat, error) {
+	if from == to {
+		return amount, nil
+	}
+	if missing := Missing(rates, from, to); len(missing) > 0 {
+		return nil, vanerrors.New(UnknownCurrency, strings.Join(missing, ", "))
+	}
+	result := new(big.Rat).Mul(amount, rat(rates[to]))
+	return result.Quo(result, rat(rates[from])), nil
+}
+
+// Codes that don't have a rate, each code once
+func Missing(rates map[string]float64, codes ...string) []string {
+	var missing []string
+	for _, code := range codes {
+		if _, ok := rates[code]; !ok && !slices.Contains(missing, code) {
+			missing = append(missing, code)
+		}
+	}
+	return missing
+}
+
+// The shortest decimal of the rate, so 0.92 is 92/100 and not the binary float
+func rat(rate float64) *big.Rat {
+	r, _ := new(big.Rat).SetString(strconv.FormatFloat(rate, 'g', -1, 64))
+	return r
+}
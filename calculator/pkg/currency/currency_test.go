@@ -0,0 +1,54 @@
+package currency_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vandi37/Calculator/pkg/currency"
+)
+
+func TestIsCode(t *testing.T) {
+	assert.True(t, currency.IsCode("USD"))
+	assert.False(t, currency.IsCode("usd"))
+	assert.False(t, currency.IsCode("US"))
+	assert.False(t, currency.IsCode("USDT"))
+	assert.False(t, currency.IsCode("U5D"))
+}
+
+func TestConvert(t *testing.T) {
+	rates := map[string]float64{"USD": 1, "EUR": 0.92, "GBP": 0.8}
+	tests := []struct {
+		amount   string
+		from, to string
+		expected string
+	}{
+		{"100", "USD", "EUR", "92"},
+		{"92", "EUR", "USD", "100"},
+		{"50", "EUR", "GBP", "1000/23"},
+		{"7", "JPY", "JPY", "7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.amount+" "+tt.from+" in "+tt.to, func(t *testing.T) {
+			amount, _ := new(big.Rat).SetString(tt.amount)
+			result, err := currency.Convert(amount, tt.from, tt.to, rates)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result.RatString())
+		})
+	}
+}
+
+func TestConvert_Unknown(t *testing.T) {
+	_, err := currency.Convert(big.NewRat(1, 1), "USD", "JPY", map[string]float64{"USD": 1})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), currency.UnknownCurrency)
+	assert.Contains(t, err.Error(), "JPY")
+}
+
+func TestMissing(t *testing.T) {
+	rates := map[string]float64{"USD": 1}
+	assert.Equal(t, []string{"EUR"}, currency.Missing(rates, "USD", "EUR", "EUR"))
+	assert.Empty(t, currency.Missing(rates, "USD"))
+}
@@ -9,17 +9,39 @@ type Power int
 
 const (
 	Lowest Power = iota
+	// The ternary a ? b : c takes everything around it
+	Conditional
+	Or
+	And
+	Comparison
 	Additive
 	Multiplicative
+	Prefix // Unary plus, minus and not: -2*3 is (-2)*3, but -2^2 is -(2^2)
+	Exponential
 )
 
 func GetPower(kind tokens.TokenKind) (Power, bool) {
 	switch kind {
+	case tokens.Question:
+		return Conditional, true
+	case tokens.Or:
+		return Or, true
+	case tokens.And:
+		return And, true
+	case tokens.Less, tokens.LessEqual, tokens.Greater, tokens.GreaterEqual, tokens.Equal, tokens.NotEqual:
+		return Comparison, true
 	case tokens.Addition, tokens.Subtraction:
 		return Additive, true
-	case tokens.Multiplication, tokens.Division:
+	case tokens.Multiplication, tokens.Division, tokens.Modulo, tokens.IntDivision:
 		return Multiplicative, true
+	case tokens.Power:
+		return Exponential, true
 	default:
 		return Lowest, false
 	}
 }
+
+// Right associative operations are grouped from the right: 2^3^2 is 2^(3^2), a ? b : c ? d : e is a ? b : (c ? d : e)
+func IsRightAssociative(power Power) bool {
+	return power == Exponential || power == Conditional
+}
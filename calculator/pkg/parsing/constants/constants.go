@@ -0,0 +1,17 @@
+// This package has named constants that can be used in an expression
+//
+// It doesn't need test just a model package
+package constants
+
+import "math"
+
+var constants = map[string]float64{
+	"pi":  math.Pi,
+	"e":   math.E,
+	"tau": 2 * math.Pi,
+}
+
+func Get(name string) (float64, bool) {
+	v, ok := constants[name]
+	return v, ok
+}
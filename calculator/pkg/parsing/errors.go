@@ -0,0 +1,43 @@
+package parsing
+
+import (
+	"strings"
+
+	"github.com/vandi37/vanerrors"
+)
+
+// Error with the place of the broken part in the expression.
+//
+// Start and End are byte offsets in the expression, End is exclusive
+type Error struct {
+	Name  string
+	Start int
+	End   int
+	err   error
+}
+
+func NewError(name string, description string, start int, end int) *Error {
+	return &Error{Name: name, Start: start, End: end, err: vanerrors.New(name, description)}
+}
+
+func SimpleError(name string, start int, end int) *Error {
+	return &Error{Name: name, Start: start, End: end, err: vanerrors.Simple(name)}
+}
+
+func WrapError(name string, err error, start int, end int) *Error {
+	return &Error{Name: name, Start: start, End: end, err: vanerrors.Wrap(name, err)}
+}
+
+func (e *Error) Error() string {
+	return e.err.Error()
+}
+
+// The inner error is a vanerrors error, so errors.Is still works with the name
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// Machine readable name: "unexpected EOF" is "unexpected_eof"
+func (e *Error) Code() string {
+	return strings.ReplaceAll(strings.ToLower(e.Name), " ", "_")
+}
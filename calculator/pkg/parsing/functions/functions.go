@@ -0,0 +1,55 @@
+// This package has built-in functions that can be called in an expression
+//
+// It doesn't need test just a model package
+package functions
+
+import "github.com/vandi37/Calculator/pkg/parsing/tree"
+
+const NoOperation tree.Operation = -1
+
+type Function struct {
+	MinArgs int
+	// Any number of arguments if it is less than zero
+	MaxArgs int
+	// Operation for a call with one argument
+	Unary tree.Operation
+	// Operation for a call with two arguments, it is split into a balanced tree if there are more
+	Binary tree.Operation
+}
+
+var functions = map[string]Function{
+	"sqrt":  {MinArgs: 1, MaxArgs: 1, Unary: tree.Sqrt, Binary: NoOperation},
+	"abs":   {MinArgs: 1, MaxArgs: 1, Unary: tree.Abs, Binary: NoOperation},
+	"round": {MinArgs: 1, MaxArgs: 2, Unary: tree.Round, Binary: tree.Round},
+	"log":   {MinArgs: 1, MaxArgs: 2, Unary: tree.Ln, Binary: tree.Log},
+	"min":   {MinArgs: 2, MaxArgs: -1, Unary: NoOperation, Binary: tree.Min},
+	"max":   {MinArgs: 2, MaxArgs: -1, Unary: NoOperation, Binary: tree.Max},
+	// Only the chosen branch is calculated
+	"if": {MinArgs: 3, MaxArgs: 3, Unary: NoOperation, Binary: tree.Conditional},
+	// Aggregates of any number of items, the population standard deviation is used
+	"sum":    {MinArgs: 1, MaxArgs: -1, Unary: tree.Sum, Binary: tree.Sum},
+	"avg":    {MinArgs: 1, MaxArgs: -1, Unary: tree.Avg, Binary: tree.Avg},
+	"median": {MinArgs: 1, MaxArgs: -1, Unary: tree.Median, Binary: tree.Median},
+	"stddev": {MinArgs: 1, MaxArgs: -1, Unary: tree.Stddev, Binary: tree.Stddev},
+}
+
+func Get(name string) (Function, bool) {
+	f, ok := functions[name]
+	return f, ok
+}
+
+// Lists in the arguments are flattened, so sum([1, 2], 3) has three arguments
+func (f Function) TakesLists() bool {
+	return f.MaxArgs < 0
+}
+
+// Checks the arguments count and gets the operation for it
+func (f Function) Operation(args int) (tree.Operation, bool) {
+	if args < f.MinArgs || (f.MaxArgs >= 0 && args > f.MaxArgs) {
+		return NoOperation, false
+	}
+	if args == 1 {
+		return f.Unary, true
+	}
+	return f.Binary, true
+}
@@ -9,6 +9,7 @@ import (
 const (
 	ItIsNotANumber = "this is not a number"
 	UnexpectedChar = "unexpected char"
+	OutOfRange     = "number is out of range"
 )
 
 func IsNotANumber(r rune) error {
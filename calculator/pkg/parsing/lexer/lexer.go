@@ -4,20 +4,36 @@
 package lexer
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/vandi37/Calculator/pkg/currency"
 	"github.com/vandi37/Calculator/pkg/parsing"
 	"github.com/vandi37/Calculator/pkg/parsing/tokens"
-	"github.com/vandi37/vanerrors"
+	"github.com/vandi37/Calculator/pkg/units"
 )
 
 type Lexer struct {
 	v []rune
+	// Byte offset of the first rune left
+	pos int
+	// For every opened bracket: is it an argument list of a function call or a list
+	calls []bool
+	last  tokens.TokenKind
+	// The numbers are kept as text, so they aren't limited by the float range
+	exact bool
 }
 
 func New(v []rune) Lexer {
-	return Lexer{v}
+	return Lexer{v: v, last: tokens.EOF}
+}
+
+// For the decimal and rational precisions, the parser checks the literals itself
+func NewExact(v []rune) Lexer {
+	return Lexer{v: v, last: tokens.EOF, exact: true}
 }
 
 func (l *Lexer) IsEmpty() bool {
@@ -26,12 +42,43 @@ func (l *Lexer) IsEmpty() bool {
 
 func (l *Lexer) nextRune() (rune, error) {
 	r := l.v[0]
-	l.v = l.v[1:]
+	l.move()
 	return r, nil
 }
 
 func (l *Lexer) backRune(r rune) {
 	l.v = append([]rune{r}, l.v...)
+	l.pos -= utf8.RuneLen(r)
+}
+
+func (l *Lexer) move() {
+	l.pos += utf8.RuneLen(l.v[0])
+	l.v = l.v[1:]
+}
+
+// Moves to the next rune only if it is the expected one
+func (l *Lexer) nextIs(expected rune) bool {
+	if l.IsEmpty() || l.v[0] != expected {
+		return false
+	}
+	l.move()
+	return true
+}
+
+// Error about the rune that is next
+func (l *Lexer) unexpectedNext() error {
+	return parsing.NewError(UnexpectedChar, fmt.Sprintf("%c", l.v[0]), l.pos, l.pos+utf8.RuneLen(l.v[0]))
+}
+
+func (l *Lexer) skipWhitespace() {
+	for !l.IsEmpty() && unicode.IsSpace(l.v[0]) {
+		l.move()
+	}
+}
+
+// Inside of a call the comma separates arguments instead of the decimal part
+func (l *Lexer) inCall() bool {
+	return len(l.calls) > 0 && l.calls[len(l.calls)-1]
 }
 
 func (l *Lexer) GetTokens() ([]tokens.Token, error) {
@@ -49,11 +96,50 @@ func (l *Lexer) GetTokens() ([]tokens.Token, error) {
 	return t, nil
 }
 
+// Doesn't stop at errors: the broken part is skipped and the lexing goes on
+func (l *Lexer) GetAllTokens() ([]tokens.Token, []*parsing.Error) {
+	t := []tokens.Token{}
+	errs := []*parsing.Error{}
+	for len(l.v) > 0 {
+		start := l.pos
+		token, err := l.Next()
+		if err != nil {
+			var parsingErr *parsing.Error
+			if !errors.As(err, &parsingErr) {
+				parsingErr = parsing.WrapError(parsing.UnknownParsingError, err, start, l.pos)
+			}
+			errs = append(errs, parsingErr)
+			// Skipping the rest of the broken number or word
+			for !l.IsEmpty() && (l.pos < parsingErr.End || IsIdentifier(l.v[0]) || l.v[0] == '.') {
+				l.move()
+			}
+			continue
+		}
+		if token == tokens.EOFToken {
+			break
+		}
+		t = append(t, token)
+	}
+	return t, errs
+}
+
 func (l *Lexer) Next() (tokens.Token, error) {
+	l.skipWhitespace()
 	if l.IsEmpty() {
 		return tokens.EOFToken, nil
 	}
+	start := l.pos
+	t, err := l.next()
+	if err == nil {
+		t.Start, t.Length = start, l.pos-start
+		l.last = t.Kind
+	}
+	return t, err
+}
+
+func (l *Lexer) next() (tokens.Token, error) {
 	t := tokens.EmptyToken
+	start := l.pos
 	r, err := l.nextRune()
 	if err != nil {
 		return t, err
@@ -66,45 +152,205 @@ func (l *Lexer) Next() (tokens.Token, error) {
 		t.Kind = tokens.Subtraction
 	case '*':
 		t.Kind = tokens.Multiplication
+		if l.nextIs('*') {
+			t.Kind = tokens.Power
+		}
 	case '/':
 		t.Kind = tokens.Division
+		if l.nextIs('/') {
+			t.Kind = tokens.IntDivision
+		}
+	case '%':
+		t.Kind = tokens.Modulo
+	case '<':
+		t.Kind = tokens.Less
+		if l.nextIs('=') {
+			t.Kind = tokens.LessEqual
+		}
+	case '>':
+		t.Kind = tokens.Greater
+		if l.nextIs('=') {
+			t.Kind = tokens.GreaterEqual
+		}
+	case '=':
+		// A lonely = would be an assignment
+		if !l.nextIs('=') {
+			return t, parsing.NewError(UnexpectedChar, fmt.Sprintf("%c", r), start, l.pos)
+		}
+		t.Kind = tokens.Equal
+	case '!':
+		t.Kind = tokens.Not
+		if l.nextIs('=') {
+			t.Kind = tokens.NotEqual
+		}
+	case '&':
+		if !l.nextIs('&') {
+			return t, parsing.NewError(UnexpectedChar, fmt.Sprintf("%c", r), start, l.pos)
+		}
+		t.Kind = tokens.And
+	case '|':
+		if !l.nextIs('|') {
+			return t, parsing.NewError(UnexpectedChar, fmt.Sprintf("%c", r), start, l.pos)
+		}
+		t.Kind = tokens.Or
+	case '?':
+		t.Kind = tokens.Question
+	case ':':
+		t.Kind = tokens.Colon
+	case '^':
+		t.Kind = tokens.Power
 	case '(':
 		t.Kind = tokens.BracketOpen
+		l.calls = append(l.calls, l.last == tokens.Identifier)
 	case ')':
 		t.Kind = tokens.BracketClose
+		if len(l.calls) > 0 {
+			l.calls = l.calls[:len(l.calls)-1]
+		}
+	case '[':
+		t.Kind = tokens.ListOpen
+		l.calls = append(l.calls, true)
+	case ']':
+		t.Kind = tokens.ListClose
+		if len(l.calls) > 0 {
+			l.calls = l.calls[:len(l.calls)-1]
+		}
+	case ',':
+		if !l.inCall() {
+			return t, parsing.NewError(UnexpectedChar, fmt.Sprintf("%c", r), start, l.pos)
+		}
+		t.Kind = tokens.Comma
 	case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
 		t.Kind = tokens.Number
 		l.backRune(r)
-		start, ok, err := l.buildInt()
+		v, literal, err := l.buildNumber()
 		if err != nil {
 			return t, err
 		}
-		if !ok {
-			v, err := strconv.Atoi(start)
-			if err != nil {
-				return t, vanerrors.Wrap(parsing.UnknownParsingError, err)
-			}
-			t.Value = float64(v)
-			break
+		t.Value, t.Literal = v, literal
+		if l.isImaginaryUnit() {
+			l.move()
+			t.Kind = tokens.Imaginary
+		} else if t.Unit = l.buildUnit(); t.Unit == "" {
+			t.Currency = l.buildCurrency()
 		}
-
-		after, ok, err := l.buildInt()
-		if err != nil {
-			return t, err
+	default:
+		if !IsIdentifierStart(r) {
+			return t, parsing.NewError(UnexpectedChar, fmt.Sprintf("%c", r), start, l.pos)
+		}
+		l.backRune(r)
+		t = tokens.BuildIdentifier(l.buildIdentifier())
+		// The lonely i is the imaginary unit, so it can't be a variable
+		if t.Name == ImaginaryUnit {
+			t = tokens.Token{Kind: tokens.Imaginary, Value: 1, Literal: "1"}
 		}
+	}
+	return t, nil
+}
 
-		if ok {
-			return t, vanerrors.New(UnexpectedChar, fmt.Sprintf("%c", r))
+const ImaginaryUnit = "i"
+
+// Units go right after the number: 5 km, 9.81 m/s^2, 3 N*m.
+// Spaces are allowed only before the unit, so in 10 kg * 2 the * is the multiplication
+func (l *Lexer) buildUnit() string {
+	i := 0
+	for i < len(l.v) && unicode.IsSpace(l.v[i]) {
+		i++
+	}
+	name, end, ok := l.unitAt(i)
+	if !ok {
+		return ""
+	}
+	unit := name
+	for end < len(l.v) {
+		if l.v[end] == '^' {
+			digits := end + 1
+			if digits < len(l.v) && l.v[digits] == '-' {
+				digits++
+			}
+			after := digits
+			for after < len(l.v) && IsNum(l.v[after]) {
+				after++
+			}
+			if after == digits {
+				break
+			}
+			unit += string(l.v[end:after])
+			end = after
+			if end >= len(l.v) {
+				break
+			}
 		}
-		v, err := strconv.ParseFloat(start+"."+after, 64)
-		if err != nil {
-			return t, vanerrors.Wrap(parsing.UnknownParsingError, err)
+		if l.v[end] != '/' && l.v[end] != '*' {
+			break
 		}
-		t.Value = v
-	default:
-		return t, vanerrors.New(UnexpectedChar, fmt.Sprintf("%c", r))
+		name, next, ok := l.unitAt(end + 1)
+		if !ok {
+			break
+		}
+		unit += string(l.v[end]) + name
+		end = next
 	}
-	return t, nil
+	for range end {
+		l.move()
+	}
+	return unit
+}
+
+// Currency codes go after the number like units: 100 USD
+func (l *Lexer) buildCurrency() string {
+	i := 0
+	for i < len(l.v) && unicode.IsSpace(l.v[i]) {
+		i++
+	}
+	end := i
+	for end < len(l.v) && IsIdentifier(l.v[end]) {
+		end++
+	}
+	code := string(l.v[i:end])
+	if !currency.IsCode(code) {
+		return ""
+	}
+	for range end {
+		l.move()
+	}
+	return code
+}
+
+// Reads the unit name at the index, a name followed by a bracket is a function (min).
+// A name followed by a currency code isn't a unit too, in 2 in USD the in converts the result
+func (l *Lexer) unitAt(i int) (string, int, bool) {
+	end := i
+	for end < len(l.v) && IsIdentifier(l.v[end]) {
+		end++
+	}
+	if end == i || !IsIdentifierStart(l.v[i]) {
+		return "", i, false
+	}
+	name := string(l.v[i:end])
+	if _, ok := units.Lookup(name); !ok {
+		return "", i, false
+	}
+	next := end
+	for next < len(l.v) && unicode.IsSpace(l.v[next]) {
+		next++
+	}
+	if next < len(l.v) && l.v[next] == '(' {
+		return "", i, false
+	}
+	after := next
+	for after < len(l.v) && IsIdentifier(l.v[after]) {
+		after++
+	}
+	if after > next && currency.IsCode(string(l.v[next:after])) {
+		return "", i, false
+	}
+	return name, end, true
+}
+
+// Checks that i right after the number isn't a start of a name: 2i is imaginary, 2in is not
+func (l *Lexer) isImaginaryUnit() bool {
+	return !l.IsEmpty() && string(l.v[0]) == ImaginaryUnit && (len(l.v) == 1 || !IsIdentifier(l.v[1]))
 }
 
 func IsNum(r rune) bool {
@@ -116,29 +362,143 @@ func IsNum(r rune) bool {
 	}
 }
 
-func (l *Lexer) buildInt() (string, bool, error) {
+func IsIdentifierStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func IsIdentifier(r rune) bool {
+	return IsIdentifierStart(r) || IsNum(r)
+}
+
+func (l *Lexer) buildIdentifier() string {
 	current := []rune{}
-	ok := false
-	for {
-		if l.IsEmpty() {
-			break
+	for !l.IsEmpty() && IsIdentifier(l.v[0]) {
+		current = append(current, l.v[0])
+		l.move()
+	}
+	return string(current)
+}
+
+func IsHex(r rune) bool {
+	return IsNum(r) || r >= 'a' && r <= 'f' || r >= 'A' && r <= 'F'
+}
+
+func IsBinary(r rune) bool {
+	return r == '0' || r == '1'
+}
+
+func (l *Lexer) isDecimalSeparator() bool {
+	return !l.IsEmpty() && (l.v[0] == '.' || l.v[0] == ',' && !l.inCall())
+}
+
+// Checks that an exponent (e5, E-9, e+3) is next, a lonely 'e' isn't a part of the number
+func (l *Lexer) isExponent() bool {
+	if l.IsEmpty() || l.v[0] != 'e' && l.v[0] != 'E' {
+		return false
+	}
+	i := 1
+	if i < len(l.v) && (l.v[i] == '+' || l.v[i] == '-') {
+		i++
+	}
+	return i < len(l.v) && IsNum(l.v[i])
+}
+
+// Supports decimals (1_000.5, 3,14), exponents (6.02e23) and integers with prefixes (0xFF, 0b101).
+// The literal is the number without underscores and with '.' as the separator
+func (l *Lexer) buildNumber() (float64, string, error) {
+	start := l.pos
+	if len(l.v) > 1 && l.v[0] == '0' {
+		switch l.v[1] {
+		case 'x', 'X':
+			l.move()
+			l.move()
+			return l.buildBased(16, IsHex, start)
+		case 'b', 'B':
+			l.move()
+			l.move()
+			return l.buildBased(2, IsBinary, start)
 		}
-		next, err := l.nextRune()
+	}
+
+	number, err := l.buildDigits(IsNum)
+	if err != nil {
+		return 0, "", err
+	}
+	if l.isDecimalSeparator() {
+		l.move()
+		after, err := l.buildDigits(IsNum)
 		if err != nil {
-			return "", false, err
+			return 0, "", err
 		}
-		if next == '.' || next == ',' {
-			ok = true
-			break
+		if l.isDecimalSeparator() {
+			return 0, "", l.unexpectedNext()
+		}
+		if after != "" {
+			number += "." + after
+		}
+	}
+	if l.isExponent() {
+		number += "e"
+		l.move()
+		if l.v[0] == '+' || l.v[0] == '-' {
+			number += string(l.v[0])
+			l.move()
+		}
+		exponent, err := l.buildDigits(IsNum)
+		if err != nil {
+			return 0, "", err
 		}
+		number += exponent
+	}
 
-		if !IsNum(next) {
-			l.backRune(next)
-			break
+	v, err := strconv.ParseFloat(number, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		if l.exact {
+			// The value is ±Inf or 0, only the literal is used
+			return v, number, nil
 		}
+		return 0, "", parsing.NewError(OutOfRange, number, start, l.pos)
+	} else if err != nil {
+		return 0, "", parsing.WrapError(parsing.UnknownParsingError, err, start, l.pos)
+	}
+	return v, number, nil
+}
 
-		current = append(current, next)
+// Start is the offset of the prefix
+func (l *Lexer) buildBased(base int, isDigit func(rune) bool, start int) (float64, string, error) {
+	digits, err := l.buildDigits(isDigit)
+	if err != nil {
+		return 0, "", err
+	}
+	if digits == "" {
+		return 0, "", parsing.NewError(ItIsNotANumber, fmt.Sprintf("no digits after the base %d prefix", base), start, l.pos)
+	}
+	v, err := strconv.ParseUint(digits, base, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		return 0, "", parsing.NewError(OutOfRange, digits, start, l.pos)
+	} else if err != nil {
+		return 0, "", parsing.WrapError(parsing.UnknownParsingError, err, start, l.pos)
 	}
+	return float64(v), strconv.FormatUint(v, 10), nil
+}
 
-	return string(current), ok, nil
+// Single underscores are allowed between digits: 1_000_000
+func (l *Lexer) buildDigits(isDigit func(rune) bool) (string, error) {
+	current := []rune{}
+	for !l.IsEmpty() {
+		next := l.v[0]
+		if next == '_' {
+			if len(current) == 0 || len(l.v) < 2 || !isDigit(l.v[1]) {
+				return "", l.unexpectedNext()
+			}
+			l.move()
+			continue
+		}
+		if !isDigit(next) {
+			break
+		}
+		current = append(current, next)
+		l.move()
+	}
+	return string(current), nil
 }
@@ -5,6 +5,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vandi37/Calculator/pkg/parsing"
 	"github.com/vandi37/Calculator/pkg/parsing/lexer"
 	"github.com/vandi37/Calculator/pkg/parsing/tokens"
 )
@@ -45,6 +46,34 @@ func TestLexer_Next(t *testing.T) {
 				{Kind: tokens.Division},
 			},
 		},
+		{
+			name:  "Single power",
+			input: "^",
+			expected: []tokens.Token{
+				{Kind: tokens.Power},
+			},
+		},
+		{
+			name:  "Double star power",
+			input: "**",
+			expected: []tokens.Token{
+				{Kind: tokens.Power},
+			},
+		},
+		{
+			name:  "Single modulo",
+			input: "%",
+			expected: []tokens.Token{
+				{Kind: tokens.Modulo},
+			},
+		},
+		{
+			name:  "Integer division",
+			input: "//",
+			expected: []tokens.Token{
+				{Kind: tokens.IntDivision},
+			},
+		},
 		{
 			name:  "Single open bracket",
 			input: "(",
@@ -133,6 +162,39 @@ func TestLexer_Next(t *testing.T) {
 				{Kind: tokens.Number, Value: 1.2},
 			},
 		},
+		{
+			name:  "Function call",
+			input: "max(1,2.5)",
+			expected: []tokens.Token{
+				{Kind: tokens.Identifier, Name: "max"},
+				{Kind: tokens.BracketOpen},
+				{Kind: tokens.Number, Value: 1.0},
+				{Kind: tokens.Comma},
+				{Kind: tokens.Number, Value: 2.5},
+				{Kind: tokens.BracketClose},
+			},
+		},
+		{
+			name:  "Comma is decimal outside of a call",
+			input: "log(2)*(3,5)",
+			expected: []tokens.Token{
+				{Kind: tokens.Identifier, Name: "log"},
+				{Kind: tokens.BracketOpen},
+				{Kind: tokens.Number, Value: 2.0},
+				{Kind: tokens.BracketClose},
+				{Kind: tokens.Multiplication},
+				{Kind: tokens.BracketOpen},
+				{Kind: tokens.Number, Value: 3.5},
+				{Kind: tokens.BracketClose},
+			},
+		},
+		{
+			name:  "Identifier with digits and underscore",
+			input: "_log2",
+			expected: []tokens.Token{
+				{Kind: tokens.Identifier, Name: "_log2"},
+			},
+		},
 
 		{
 			name:    "Invalid character",
@@ -158,6 +220,36 @@ func TestLexer_Next(t *testing.T) {
 			wantErr: true,
 			errMsg:  lexer.UnexpectedChar,
 		},
+		{
+			name:    "Double underscore",
+			input:   "1__000",
+			wantErr: true,
+			errMsg:  lexer.UnexpectedChar,
+		},
+		{
+			name:    "Trailing underscore",
+			input:   "1_",
+			wantErr: true,
+			errMsg:  lexer.UnexpectedChar,
+		},
+		{
+			name:    "Hex prefix without digits",
+			input:   "0x",
+			wantErr: true,
+			errMsg:  lexer.ItIsNotANumber,
+		},
+		{
+			name:    "Too big exponent",
+			input:   "1e400",
+			wantErr: true,
+			errMsg:  lexer.OutOfRange,
+		},
+		{
+			name:    "Too big hex number",
+			input:   "0x1_0000_0000_0000_0000",
+			wantErr: true,
+			errMsg:  lexer.OutOfRange,
+		},
 	}
 
 	for _, tt := range tests {
@@ -179,6 +271,9 @@ func TestLexer_Next(t *testing.T) {
 				if expectedToken.Kind == tokens.Number {
 					assert.Equal(t, expectedToken.Value, ts[i].Value, "token value mismatch at position %d", i)
 				}
+				if expectedToken.Kind == tokens.Identifier {
+					assert.Equal(t, expectedToken.Name, ts[i].Name, "token name mismatch at position %d", i)
+				}
 			}
 		})
 	}
@@ -246,6 +341,41 @@ func TestLexer_NumberParsing(t *testing.T) {
 			input:    "1234567890",
 			expected: 1234567890,
 		},
+		{
+			name:     "Digit groups",
+			input:    "1_234_567.000_1",
+			expected: 1234567.0001,
+		},
+		{
+			name:     "Exponent",
+			input:    "6.02E23",
+			expected: 6.02e23,
+		},
+		{
+			name:     "Negative exponent",
+			input:    "1e-9",
+			expected: 1e-9,
+		},
+		{
+			name:     "Positive exponent with comma",
+			input:    "2,5e+3",
+			expected: 2500,
+		},
+		{
+			name:     "Hex number",
+			input:    "0xFF",
+			expected: 255,
+		},
+		{
+			name:     "Hex number with groups",
+			input:    "0Xdead_beef",
+			expected: 0xdeadbeef,
+		},
+		{
+			name:     "Binary number",
+			input:    "0b101",
+			expected: 5,
+		},
 	}
 
 	for _, tt := range tests {
@@ -299,6 +429,42 @@ func TestLexer_Combinations(t *testing.T) {
 				{Kind: tokens.BracketClose},
 			},
 		},
+		{
+			name:  "Power and multiplication",
+			input: "2**3*4^5",
+			expected: []tokens.Token{
+				{Kind: tokens.Number, Value: 2.0},
+				{Kind: tokens.Power},
+				{Kind: tokens.Number, Value: 3.0},
+				{Kind: tokens.Multiplication},
+				{Kind: tokens.Number, Value: 4.0},
+				{Kind: tokens.Power},
+				{Kind: tokens.Number, Value: 5.0},
+			},
+		},
+		{
+			name:  "Modulo and divisions",
+			input: "7%3//2/1",
+			expected: []tokens.Token{
+				{Kind: tokens.Number, Value: 7.0},
+				{Kind: tokens.Modulo},
+				{Kind: tokens.Number, Value: 3.0},
+				{Kind: tokens.IntDivision},
+				{Kind: tokens.Number, Value: 2.0},
+				{Kind: tokens.Division},
+				{Kind: tokens.Number, Value: 1.0},
+			},
+		},
+		{
+			name:  "Lonely e is not an exponent",
+			input: "2e+e",
+			expected: []tokens.Token{
+				{Kind: tokens.Number, Value: 2.0},
+				{Kind: tokens.Identifier, Name: "e"},
+				{Kind: tokens.Addition},
+				{Kind: tokens.Identifier, Name: "e"},
+			},
+		},
 		{
 			name:  "Decimal numbers in expression",
 			input: "1.5+2.5*3.0",
@@ -328,3 +494,234 @@ func TestLexer_Combinations(t *testing.T) {
 		})
 	}
 }
+
+func TestLexer_Positions(t *testing.T) {
+	l := lexer.New([]rune(" 12 +  sqrt(0x1F)"))
+	ts, err := l.GetTokens()
+	require.NoError(t, err)
+
+	expected := []struct{ start, length int }{{1, 2}, {4, 1}, {7, 4}, {11, 1}, {12, 4}, {16, 1}}
+	require.Equal(t, len(expected), len(ts))
+	for i, e := range expected {
+		assert.Equal(t, e.start, ts[i].Start, "token start mismatch at position %d", i)
+		assert.Equal(t, e.length, ts[i].Length, "token length mismatch at position %d", i)
+	}
+}
+
+func TestLexer_ErrorPositions(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		start int
+		end   int
+	}{
+		{"Unexpected char", "1 + &", 4, 5},
+		{"Unexpected char after multibyte", "π & 1", 3, 4},
+		{"Double underscore", "1__0", 1, 2},
+		{"Second decimal point", "1.2.3", 3, 4},
+		{"Out of range", "2 * 1e400", 4, 9},
+		{"Empty prefix", "0b", 0, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := lexer.New([]rune(tt.input))
+			_, err := l.GetTokens()
+			require.Error(t, err)
+
+			var parsingErr *parsing.Error
+			require.ErrorAs(t, err, &parsingErr)
+			assert.Equal(t, tt.start, parsingErr.Start)
+			assert.Equal(t, tt.end, parsingErr.End)
+		})
+	}
+}
+
+func TestLexer_Literals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"0.1", "0.1"},
+		{"1_000,50", "1000.50"},
+		{"3.", "3"},
+		{"6.02e23", "6.02e23"},
+		{"1E-9", "1e-9"},
+		{"0xFF", "255"},
+		{"0b101", "5"},
+		{"0.12345678901234567890123", "0.12345678901234567890123"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			l := lexer.New([]rune(tt.input))
+			token, err := l.Next()
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, token.Literal)
+		})
+	}
+}
+
+func TestLexer_Imaginary(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []tokens.Token
+	}{
+		{"2i", []tokens.Token{{Kind: tokens.Imaginary, Value: 2, Literal: "2", Start: 0, Length: 2}}},
+		{"i", []tokens.Token{{Kind: tokens.Imaginary, Value: 1, Literal: "1", Start: 0, Length: 1}}},
+		{"1.5e2i", []tokens.Token{{Kind: tokens.Imaginary, Value: 150, Literal: "1.5e2", Start: 0, Length: 6}}},
+		{"3+i", []tokens.Token{
+			{Kind: tokens.Number, Value: 3, Literal: "3", Start: 0, Length: 1},
+			{Kind: tokens.Addition, Value: -1, Start: 1, Length: 1},
+			{Kind: tokens.Imaginary, Value: 1, Literal: "1", Start: 2, Length: 1},
+		}},
+		{"2ix", []tokens.Token{
+			{Kind: tokens.Number, Value: 2, Literal: "2", Start: 0, Length: 1},
+			{Kind: tokens.Identifier, Name: "ix", Start: 1, Length: 2},
+		}},
+		{"im", []tokens.Token{{Kind: tokens.Identifier, Name: "im", Start: 0, Length: 2}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			l := lexer.New([]rune(tt.input))
+			result, err := l.GetTokens()
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestLexer_Currency(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []tokens.Token
+	}{
+		{"100 USD", []tokens.Token{{Kind: tokens.Number, Value: 100, Literal: "100", Currency: "USD", Start: 0, Length: 7}}},
+		{"2.5EUR", []tokens.Token{{Kind: tokens.Number, Value: 2.5, Literal: "2.5", Currency: "EUR", Start: 0, Length: 6}}},
+		{"50 EUR in GBP", []tokens.Token{
+			{Kind: tokens.Number, Value: 50, Literal: "50", Currency: "EUR", Start: 0, Length: 6},
+			{Kind: tokens.Identifier, Name: "in", Start: 7, Length: 2},
+			{Kind: tokens.Identifier, Name: "GBP", Start: 10, Length: 3},
+		}},
+		{"2 in USD", []tokens.Token{
+			{Kind: tokens.Number, Value: 2, Literal: "2", Start: 0, Length: 1},
+			{Kind: tokens.Identifier, Name: "in", Start: 2, Length: 2},
+			{Kind: tokens.Identifier, Name: "USD", Start: 5, Length: 3},
+		}},
+		{"2 usd", []tokens.Token{
+			{Kind: tokens.Number, Value: 2, Literal: "2", Start: 0, Length: 1},
+			{Kind: tokens.Identifier, Name: "usd", Start: 2, Length: 3},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			l := lexer.New([]rune(tt.input))
+			result, err := l.GetTokens()
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestLexer_Units(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []tokens.Token
+	}{
+		{"5 km", []tokens.Token{{Kind: tokens.Number, Value: 5, Literal: "5", Unit: "km", Start: 0, Length: 4}}},
+		{"2in", []tokens.Token{{Kind: tokens.Number, Value: 2, Literal: "2", Unit: "in", Start: 0, Length: 3}}},
+		{"9.81 m/s^2", []tokens.Token{{Kind: tokens.Number, Value: 9.81, Literal: "9.81", Unit: "m/s^2", Start: 0, Length: 10}}},
+		{"3 N*m", []tokens.Token{{Kind: tokens.Number, Value: 3, Literal: "3", Unit: "N*m", Start: 0, Length: 5}}},
+		{"10 kg * 2", []tokens.Token{
+			{Kind: tokens.Number, Value: 10, Literal: "10", Unit: "kg", Start: 0, Length: 5},
+			{Kind: tokens.Multiplication, Value: -1, Start: 6, Length: 1},
+			{Kind: tokens.Number, Value: 2, Literal: "2", Start: 8, Length: 1},
+		}},
+		{"6 m//s", []tokens.Token{
+			{Kind: tokens.Number, Value: 6, Literal: "6", Unit: "m", Start: 0, Length: 3},
+			{Kind: tokens.IntDivision, Value: -1, Start: 3, Length: 2},
+			{Kind: tokens.Identifier, Name: "s", Start: 5, Length: 1},
+		}},
+		{"2 m^x", []tokens.Token{
+			{Kind: tokens.Number, Value: 2, Literal: "2", Unit: "m", Start: 0, Length: 3},
+			{Kind: tokens.Power, Value: -1, Start: 3, Length: 1},
+			{Kind: tokens.Identifier, Name: "x", Start: 4, Length: 1},
+		}},
+		{"2 x", []tokens.Token{
+			{Kind: tokens.Number, Value: 2, Literal: "2", Start: 0, Length: 1},
+			{Kind: tokens.Identifier, Name: "x", Start: 2, Length: 1},
+		}},
+		{"2 min(1, 2)", []tokens.Token{
+			{Kind: tokens.Number, Value: 2, Literal: "2", Start: 0, Length: 1},
+			{Kind: tokens.Identifier, Name: "min", Start: 2, Length: 3},
+			{Kind: tokens.BracketOpen, Value: -1, Start: 5, Length: 1},
+			{Kind: tokens.Number, Value: 1, Literal: "1", Start: 6, Length: 1},
+			{Kind: tokens.Comma, Value: -1, Start: 7, Length: 1},
+			{Kind: tokens.Number, Value: 2, Literal: "2", Start: 9, Length: 1},
+			{Kind: tokens.BracketClose, Value: -1, Start: 10, Length: 1},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			l := lexer.New([]rune(tt.input))
+			result, err := l.GetTokens()
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestLexer_Logic(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []tokens.TokenKind
+	}{
+		{"1 < 2", []tokens.TokenKind{tokens.Number, tokens.Less, tokens.Number}},
+		{"1<=2", []tokens.TokenKind{tokens.Number, tokens.LessEqual, tokens.Number}},
+		{"1 > 2 >= 3", []tokens.TokenKind{tokens.Number, tokens.Greater, tokens.Number, tokens.GreaterEqual, tokens.Number}},
+		{"x == 1 != y", []tokens.TokenKind{tokens.Identifier, tokens.Equal, tokens.Number, tokens.NotEqual, tokens.Identifier}},
+		{"!x && y || z", []tokens.TokenKind{tokens.Not, tokens.Identifier, tokens.And, tokens.Identifier, tokens.Or, tokens.Identifier}},
+		{"x ? 1 : 2", []tokens.TokenKind{tokens.Identifier, tokens.Question, tokens.Number, tokens.Colon, tokens.Number}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			l := lexer.New([]rune(tt.input))
+			result, err := l.GetTokens()
+			require.NoError(t, err)
+			kinds := make([]tokens.TokenKind, len(result))
+			for i, token := range result {
+				kinds[i] = token.Kind
+			}
+			assert.Equal(t, tt.expected, kinds)
+		})
+	}
+
+	for _, input := range []string{"x = 1", "x & y", "x | y"} {
+		t.Run(input, func(t *testing.T) {
+			l := lexer.New([]rune(input))
+			_, err := l.GetTokens()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), lexer.UnexpectedChar)
+		})
+	}
+}
+
+func TestLexer_Lists(t *testing.T) {
+	l := lexer.New([]rune("sum([1,5, 2], 3)"))
+	result, err := l.GetTokens()
+	require.NoError(t, err)
+	kinds := make([]tokens.TokenKind, len(result))
+	for i, token := range result {
+		kinds[i] = token.Kind
+	}
+	// The comma in the list separates the items, it isn't a decimal comma
+	assert.Equal(t, []tokens.TokenKind{
+		tokens.Identifier, tokens.BracketOpen, tokens.ListOpen, tokens.Number, tokens.Comma, tokens.Number, tokens.Comma,
+		tokens.Number, tokens.ListClose, tokens.Comma, tokens.Number, tokens.BracketClose,
+	}, kinds)
+	assert.Equal(t, 5.0, result[5].Value)
+}
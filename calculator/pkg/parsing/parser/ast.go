@@ -2,47 +2,168 @@
 package parser
 
 import (
+	"fmt"
 	"slices"
 	"unicode"
 
-	pb "github.com/vandi37/Calculator-Models"
+	"github.com/vandi37/Calculator/pkg/currency"
+	"github.com/vandi37/Calculator/pkg/parsing"
 	"github.com/vandi37/Calculator/pkg/parsing/binding"
+	"github.com/vandi37/Calculator/pkg/parsing/constants"
+	"github.com/vandi37/Calculator/pkg/parsing/functions"
 	"github.com/vandi37/Calculator/pkg/parsing/lexer"
 	"github.com/vandi37/Calculator/pkg/parsing/tokens"
 	"github.com/vandi37/Calculator/pkg/parsing/tree"
-	"github.com/vandi37/vanerrors"
+	"github.com/vandi37/Calculator/pkg/units"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Placeholder for a broken part of the expression in the recovering mode.
+// The tree isn't used when there are errors, it only lets the parser go on
+var missing = tree.Num(0)
+
+const (
+	// The word before the currency of the result
+	CurrencyKeyword = "in"
+	// The ternary is a call of this function
+	ConditionalFunction = "if"
 )
 
 type Parser struct {
 	t []tokens.Token
+	// Byte offset of the end of the expression, it is the place of EOF errors
+	end int
+	// In the recovering mode errors are collected instead of stopping the parsing
+	recovering bool
+	errors     []*parsing.Error
+	// Saved functions of the user, nil if there are no
+	definitions *Definitions
+	precision   tree.Precision
 }
 
 func RemoveWhitespace(s string) string {
 	return string(slices.DeleteFunc([]rune(s), unicode.IsSpace))
 }
 
+// Whitespace is skipped by the lexer, so the positions in errors match the original expression
 func Build(expression string) (tree.Ast, error) {
-	expression = RemoveWhitespace(expression)
-	lexer := lexer.New([]rune(expression))
+	return BuildWith(expression, nil)
+}
+
+// Calls of the saved functions are parsed as tree.Apply, they should be expanded later
+func BuildWith(expression string, definitions *Definitions) (tree.Ast, error) {
+	return BuildWithPrecision(expression, definitions, tree.FloatPrecision)
+}
+
+// In the decimal precision numbers are parsed as tree.Decimal with their exact text, in the rational one as tree.Rational
+func BuildWithPrecision(expression string, definitions *Definitions, precision tree.Precision) (tree.Ast, error) {
+	newLexer := lexer.New
+	if precision == tree.DecimalPrecision || precision == tree.RationalPrecision {
+		newLexer = lexer.NewExact
+	}
+	lexer := newLexer([]rune(expression))
 	tokens, err := lexer.GetTokens()
 	if err != nil {
 		return tree.Ast{}, err
 	}
 
 	parser := New(tokens)
+	parser.end = len(expression)
+	parser.definitions = definitions
+	parser.precision = precision
 	return parser.Ast()
 }
 
+// Collects all problems of the expression instead of stopping at the first one.
+// The errors are sorted by their place, no errors means the expression is valid
+func Validate(expression string, definitions *Definitions) []*parsing.Error {
+	lexer := lexer.New([]rune(expression))
+	tokens, errs := lexer.GetAllTokens()
+
+	parser := New(tokens)
+	parser.end = len(expression)
+	parser.recovering = true
+	parser.definitions = definitions
+	parser.Ast()
+
+	errs = append(errs, parser.errors...)
+	slices.SortStableFunc(errs, func(a, b *parsing.Error) int {
+		return a.Start - b.Start
+	})
+	return errs
+}
+
 func (p *Parser) Ast() (tree.Ast, error) {
 	expr, err := p.Expression(binding.Lowest)
-	if err == nil && len(p.t) > 0 {
-		err = vanerrors.New(UnexpectedToken, p.t[0].String())
+	if err != nil {
+		return tree.Ast{Expression: expr}, err
 	}
-	return tree.Ast{Expression: expr}, err
+	code := p.targetCurrency()
+	for len(p.t) > 0 {
+		t := p.t[0]
+		if t.Kind == tokens.BracketClose {
+			if err := p.fail(tokenError(UnopenedBracket, t.String(), t)); err != nil {
+				return tree.Ast{Expression: expr}, err
+			}
+			p.Move()
+		} else {
+			if err := p.fail(tokenError(UnexpectedToken, t.String(), t)); err != nil {
+				return tree.Ast{Expression: expr}, err
+			}
+			// The rest is parsed only to find its errors
+			p.Expression(binding.Lowest)
+			if len(p.t) > 0 && p.t[0] == t {
+				p.Move()
+			}
+		}
+		expr, _ = p.Infix(expr, binding.Lowest)
+	}
+	return tree.Ast{Expression: expr, Currency: code}, nil
+}
+
+// The currency of the result is written at the end: 100 USD + 50 EUR in GBP
+func (p *Parser) targetCurrency() string {
+	if len(p.t) < 2 || p.t[0].Kind != tokens.Identifier || p.t[0].Name != CurrencyKeyword {
+		return ""
+	}
+	if p.t[1].Kind != tokens.Identifier || !currency.IsCode(p.t[1].Name) {
+		return ""
+	}
+	code := p.t[1].Name
+	p.Move()
+	p.Move()
+	return code
 }
 
 func New(t []tokens.Token) Parser {
-	return Parser{t}
+	p := Parser{t: t}
+	if len(t) > 0 {
+		p.end = t[len(t)-1].End()
+	}
+	return p
+}
+
+func tokenError(name string, description string, t tokens.Token) *parsing.Error {
+	return parsing.NewError(name, description, t.Start, t.End())
+}
+
+func (p *Parser) eofError() *parsing.Error {
+	return parsing.SimpleError(UnexpectedEOF, p.end, p.end)
+}
+
+// In the recovering mode the error is saved and nil is returned, so the caller goes on
+func (p *Parser) fail(err *parsing.Error) error {
+	if !p.recovering {
+		return err
+	}
+	// The parts after EOF are synchronised on the same place, so the error is saved once
+	if slices.ContainsFunc(p.errors, func(e *parsing.Error) bool {
+		return e.Name == err.Name && e.Start == err.Start && e.End == err.End
+	}) {
+		return nil
+	}
+	p.errors = append(p.errors, err)
+	return nil
 }
 
 func (p *Parser) Next() (tokens.Token, bool) {
@@ -69,46 +190,203 @@ func (p *Parser) Move() {
 }
 
 func (p *Parser) ExpectKindError(kind tokens.TokenKind) error {
-	if len(p.t) > 0 && p.t[0].Kind == kind {
+	if err := p.expectKind(kind); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (p *Parser) expectKind(kind tokens.TokenKind) *parsing.Error {
+	if len(p.t) <= 0 {
+		return parsing.NewError(ExpectedKind, kind.String(), p.end, p.end)
+	}
+	if p.t[0].Kind == kind {
 		p.Move()
 		return nil
 	}
-	return vanerrors.New(ExpectedKind, kind.String())
+	return tokenError(ExpectedKind, kind.String(), p.t[0])
+}
+
+// Skips tokens until the bracket that closes the current one (depth is the number of already opened brackets).
+// Returns the end of the closing bracket, or the end of the expression if there is no such bracket
+func (p *Parser) skipToClose(depth int) int {
+	for t, ok := p.Next(); ok; t, ok = p.Next() {
+		switch t.Kind {
+		case tokens.BracketOpen:
+			depth++
+		case tokens.BracketClose:
+			if depth == 0 {
+				return t.End()
+			}
+			depth--
+		}
+	}
+	return p.end
 }
 
 func (p *Parser) PrimExpression() (tree.ExpressionType, error) {
-	t, ok := p.Next()
+	t, ok := p.Peek()
 	if !ok {
-		return nil, vanerrors.Simple(UnexpectedEOF)
+		return missing, p.fail(p.eofError())
 	}
 
 	switch t.Kind {
 	case tokens.Addition:
-		return p.PrimExpression()
+		p.Move()
+		return p.Expression(binding.Prefix)
 	case tokens.Number:
+		p.Move()
+		if t.Unit != "" {
+			return p.quantity(t)
+		}
+		if t.Currency != "" {
+			return p.money(t)
+		}
+		switch p.precision {
+		case tree.DecimalPrecision:
+			return p.decimal(t)
+		case tree.RationalPrecision:
+			return p.rational(t)
+		}
 		return tree.Num(t.Value), nil
+	case tokens.Imaginary:
+		p.Move()
+		if p.precision == tree.DecimalPrecision || p.precision == tree.RationalPrecision {
+			return missing, p.fail(tokenError(ComplexPrecision, fmt.Sprintf("%s in the %s precision", t, p.precision), t))
+		}
+		return tree.Complex{Im: t.Value}, nil
 	case tokens.Subtraction:
-		right, err := p.PrimExpression()
+		p.Move()
+		value, err := p.Expression(binding.Prefix)
 		if err != nil {
 			return nil, err
 		}
-		return tree.Expression{Left: tree.Num(0), Operation: tree.Operation(pb.Operation_SUBTRACT), Right: right}, nil
+		// Negative literals are folded, so they don't need an agent
+		if num, ok := tree.Negated(value); ok {
+			return num, nil
+		}
+		return tree.Unary{Operation: tree.Negate, Value: value}, nil
+	case tokens.Not:
+		p.Move()
+		value, err := p.Expression(binding.Prefix)
+		if err != nil {
+			return nil, err
+		}
+		return tree.Unary{Operation: tree.Not, Value: value}, nil
 	case tokens.BracketOpen:
+		p.Move()
+		if next, ok := p.Peek(); ok && next.Kind == tokens.BracketClose {
+			p.Move()
+			return missing, p.fail(parsing.SimpleError(EmptyBrackets, t.Start, next.End()))
+		}
 		expr, err := p.Expression(binding.Lowest)
 		if err != nil {
 			return nil, err
 		}
-		return expr, p.ExpectKindError(tokens.BracketClose)
+		if err := p.expectKind(tokens.BracketClose); err != nil {
+			if err := p.fail(err); err != nil {
+				return nil, err
+			}
+			p.skipToClose(0)
+		}
+		return expr, nil
+	case tokens.ListOpen:
+		// The list is still parsed, so the recovering mode goes on after it
+		if err := p.fail(tokenError(ListOutsideCall, "lists are only the arguments of min, max, sum, avg, median and stddev", t)); err != nil {
+			return nil, err
+		}
+		if _, err := p.List(); err != nil {
+			return nil, err
+		}
+		return missing, nil
+	case tokens.Identifier:
+		p.Move()
+		if next, ok := p.Peek(); ok && next.Kind == tokens.BracketOpen {
+			return p.Call(t)
+		}
+		if value, ok := constants.Get(t.Name); ok {
+			switch p.precision {
+			case tree.DecimalPrecision:
+				return tree.DecimalFrom(value), nil
+			case tree.RationalPrecision:
+				return tree.RationalFrom(value), nil
+			}
+			return tree.Num(value), nil
+		}
+		_, builtIn := functions.Get(t.Name)
+		if _, saved := p.definitions.arity(t.Name); builtIn || saved {
+			return missing, p.fail(tokenError(ExpectedKind, tokens.BracketOpen.String(), t))
+		}
+		// Other names are variables, they get values before the calculation
+		return tree.Var{Name: t.Name}, nil
 	default:
-		return nil, vanerrors.New(UnexpectedTokenKind, t.Kind.String())
+		// The operand is missing, the token is left for the caller
+		return missing, p.fail(tokenError(UnexpectedTokenKind, t.Kind.String(), t))
 	}
 }
 
+// Decimals are stored as Decimal128, so they can't have more significant digits or a bigger exponent
+func (p *Parser) decimal(t tokens.Token) (tree.ExpressionType, error) {
+	if digits := SignificantDigits(t.Literal); digits > MaxDecimalDigits {
+		return missing, p.fail(tokenError(TooManyDigits, fmt.Sprintf("%s has %d significant digits, the limit is %d", t.Literal, digits, MaxDecimalDigits), t))
+	}
+	if _, err := primitive.ParseDecimal128(t.Literal); err != nil {
+		return missing, p.fail(tokenError(lexer.OutOfRange, t.Literal, t))
+	}
+	return tree.Decimal(t.Literal), nil
+}
+
+// Units are only for floats, the agent converts nothing in the exact precisions
+func (p *Parser) quantity(t tokens.Token) (tree.ExpressionType, error) {
+	if p.precision == tree.DecimalPrecision || p.precision == tree.RationalPrecision {
+		return missing, p.fail(tokenError(UnitPrecision, fmt.Sprintf("%s in the %s precision", t, p.precision), t))
+	}
+	// The lexer reads only known names, so it can be just a wrong exponent (m^0)
+	u, err := units.Parse(t.Unit)
+	if err != nil {
+		return missing, p.fail(tokenError(units.UnknownUnit, fmt.Sprintf("invalid unit %s", t.Unit), t))
+	}
+	// m/m is just a number
+	if u.Dimension.IsZero() {
+		return tree.Num(t.Value * u.Factor), nil
+	}
+	return tree.Quantity{Value: t.Value * u.Factor, Dimension: u.Dimension}, nil
+}
+
+// The exact text is kept, the amount is converted later with the rates of the time of the calculation
+func (p *Parser) money(t tokens.Token) (tree.ExpressionType, error) {
+	var err error
+	switch p.precision {
+	case tree.DecimalPrecision:
+		_, err = p.decimal(t)
+	case tree.RationalPrecision:
+		_, err = p.rational(t)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tree.Money{Amount: t.Literal, Currency: t.Currency}, nil
+}
+
+// The exponent is limited, because the fraction keeps every digit of the number
+func (p *Parser) rational(t tokens.Token) (tree.ExpressionType, error) {
+	r, ok := Rational(t.Literal)
+	if !ok {
+		return missing, p.fail(tokenError(TooManyDigits, fmt.Sprintf("%s has a too big exponent, the limit is %d", t.Literal, MaxRationalExponent), t))
+	}
+	return r, nil
+}
+
 func (p *Parser) Expression(bp binding.Power) (tree.ExpressionType, error) {
 	left, err := p.PrimExpression()
 	if err != nil {
 		return nil, err
 	}
+	return p.Infix(left, bp)
+}
+
+// Takes the operations after the left side while they are stronger than bp
+func (p *Parser) Infix(left tree.ExpressionType, bp binding.Power) (tree.ExpressionType, error) {
 	for t, ok := p.Peek(); ok; t, ok = p.Peek() {
 		if t == tokens.EOFToken {
 			break
@@ -117,11 +395,20 @@ func (p *Parser) Expression(bp binding.Power) (tree.ExpressionType, error) {
 		if !ok || power <= bp {
 			break
 		}
+		if t.Kind == tokens.Question {
+			p.Move()
+			var err error
+			if left, err = p.Ternary(left); err != nil {
+				return nil, err
+			}
+			continue
+		}
 		sep, ok := tree.SepFrom(t.Kind)
 		if !ok {
 			break
 		}
 		p.Move()
+		var err error
 		left, err = p.BinExpr(left, sep, power)
 		if err != nil {
 			return nil, err
@@ -131,6 +418,10 @@ func (p *Parser) Expression(bp binding.Power) (tree.ExpressionType, error) {
 }
 
 func (p *Parser) BinExpr(left tree.ExpressionType, operation tree.Operation, bp binding.Power) (tree.ExpressionType, error) {
+	if binding.IsRightAssociative(bp) {
+		// Lowering the power lets the right side take an operation with the same power
+		bp--
+	}
 	right, err := p.Expression(bp)
 	if err != nil {
 		return nil, err
@@ -141,3 +432,130 @@ func (p *Parser) BinExpr(left tree.ExpressionType, operation tree.Operation, bp
 		Right:     right,
 	}, nil
 }
+
+// Parses c ? a : b after the question mark, it is the same as if(c, a, b)
+func (p *Parser) Ternary(condition tree.ExpressionType) (tree.ExpressionType, error) {
+	then, err := p.Expression(binding.Lowest)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectKind(tokens.Colon); err != nil {
+		if err := p.fail(err); err != nil {
+			return nil, err
+		}
+	}
+	// The right side can be one more ternary
+	otherwise, err := p.Expression(binding.Conditional - 1)
+	if err != nil {
+		return nil, err
+	}
+	return tree.Call{Name: ConditionalFunction, Operation: tree.Conditional, Args: []tree.ExpressionType{condition, then, otherwise}}, nil
+}
+
+// Parses [a, b, c], the opening bracket is the next token
+func (p *Parser) List() ([]tree.ExpressionType, error) {
+	p.Move()
+	items := []tree.ExpressionType{}
+	if t, ok := p.Peek(); ok && t.Kind == tokens.ListClose {
+		p.Move()
+		return items, nil
+	}
+	for {
+		item, err := p.Expression(binding.Lowest)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+
+		t, ok := p.Next()
+		if !ok {
+			return items, p.fail(p.eofError())
+		}
+		switch t.Kind {
+		case tokens.ListClose:
+			return items, nil
+		case tokens.Comma:
+		default:
+			return items, p.fail(tokenError(UnexpectedToken, t.String(), t))
+		}
+	}
+}
+
+// Parses the argument list of a function call, the name token is already taken
+func (p *Parser) Call(nameToken tokens.Token) (tree.ExpressionType, error) {
+	name := nameToken.Name
+	function, builtIn := functions.Get(name)
+	params, saved := p.definitions.arity(name)
+	known := builtIn || saved
+	if !known {
+		// In the recovering mode the arguments are still checked
+		if err := p.fail(tokenError(UnknownFunction, name, nameToken)); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.ExpectKindError(tokens.BracketOpen); err != nil {
+		return nil, err
+	}
+
+	args := []tree.ExpressionType{}
+	end := p.end
+	if t, ok := p.Peek(); ok && t.Kind == tokens.BracketClose {
+		p.Move()
+		end = t.End()
+	} else {
+		for {
+			if t, ok := p.Peek(); ok && t.Kind == tokens.ListOpen && builtIn && function.TakesLists() {
+				items, err := p.List()
+				if err != nil {
+					return nil, err
+				}
+				args = append(args, items...)
+			} else {
+				arg, err := p.Expression(binding.Lowest)
+				if err != nil {
+					return nil, err
+				}
+				args = append(args, arg)
+			}
+
+			t, ok := p.Next()
+			if !ok {
+				if err := p.fail(p.eofError()); err != nil {
+					return nil, err
+				}
+				break
+			}
+			if t.Kind == tokens.BracketClose {
+				end = t.End()
+				break
+			}
+			if t.Kind != tokens.Comma {
+				if err := p.fail(tokenError(UnexpectedToken, t.String(), t)); err != nil {
+					return nil, err
+				}
+				depth := 0
+				if t.Kind == tokens.BracketOpen {
+					depth++
+				}
+				end = p.skipToClose(depth)
+				break
+			}
+		}
+	}
+	if !known {
+		return missing, nil
+	}
+	if saved {
+		if len(args) != params {
+			return missing, p.fail(parsing.NewError(WrongArgumentsCount, fmt.Sprintf("%s takes %d arguments, not %d", name, params, len(args)), nameToken.Start, end))
+		}
+		return tree.Apply{Name: name, Args: args}, nil
+	}
+
+	operation, ok := function.Operation(len(args))
+	if !ok {
+		// The whole call is wrong
+		return missing, p.fail(parsing.NewError(WrongArgumentsCount, fmt.Sprintf("%s can't take %d arguments", name, len(args)), nameToken.Start, end))
+	}
+	return tree.Call{Name: name, Operation: operation, Args: args}, nil
+}
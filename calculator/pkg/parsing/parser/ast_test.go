@@ -1,16 +1,20 @@
 package parser_test
 
 import (
+	"math"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	pb "github.com/vandi37/Calculator-Models"
+	"github.com/vandi37/Calculator/pkg/parsing"
 	"github.com/vandi37/Calculator/pkg/parsing/binding"
 	"github.com/vandi37/Calculator/pkg/parsing/lexer"
 	"github.com/vandi37/Calculator/pkg/parsing/parser"
 	"github.com/vandi37/Calculator/pkg/parsing/tokens"
 	"github.com/vandi37/Calculator/pkg/parsing/tree"
+	"github.com/vandi37/Calculator/pkg/units"
 )
 
 func TestParser_PrimExpression(t *testing.T) {
@@ -43,27 +47,35 @@ func TestParser_PrimExpression(t *testing.T) {
 			expected: tree.Num(5),
 		},
 		{
-			name:  "Unary minus",
-			input: "-5",
-			expected: tree.Expression{
-				Left:  tree.Num(0),
-				Operation:   tree.Operation(pb.Operation_SUBTRACT),
-				Right: tree.Num(5),
-			},
+			name:     "Unary minus is folded",
+			input:    "-5",
+			expected: tree.Num(-5),
 		},
 		{
-			name:  "Multiple unary operators",
-			input: "--5",
-			expected: tree.Expression{
-				Left: tree.Num(0),
-				Operation:  tree.Operation(pb.Operation_SUBTRACT),
-				Right: tree.Expression{
-					Left:  tree.Num(0),
-					Operation:   tree.Operation(pb.Operation_SUBTRACT),
-					Right: tree.Num(5),
+			name:     "Multiple unary operators",
+			input:    "--5",
+			expected: tree.Num(5),
+		},
+		{
+			name:  "Unary minus of brackets",
+			input: "-(5+3)",
+			expected: tree.Unary{
+				Operation: tree.Negate,
+				Value: tree.Expression{
+					Left:      tree.Num(5),
+					Operation: tree.Operation(pb.Operation_ADD),
+					Right:     tree.Num(3),
 				},
 			},
 		},
+		{
+			name:  "Unary minus of function",
+			input: "-abs(2)",
+			expected: tree.Unary{
+				Operation: tree.Negate,
+				Value:     tree.Call{Name: "abs", Operation: tree.Abs, Args: []tree.ExpressionType{tree.Num(2)}},
+			},
+		},
 		{
 			name:  "Expression in brackets",
 			input: "(5+3)",
@@ -90,6 +102,100 @@ func TestParser_PrimExpression(t *testing.T) {
 			wantErr: true,
 			errMsg:  parser.ExpectedKind,
 		},
+		{
+			name:  "Unary function call",
+			input: "sqrt(4)",
+			expected: tree.Call{
+				Name:      "sqrt",
+				Operation: tree.Sqrt,
+				Args:      []tree.ExpressionType{tree.Num(4)},
+			},
+		},
+		{
+			name:  "Function with optional argument",
+			input: "log(8,2)",
+			expected: tree.Call{
+				Name:      "log",
+				Operation: tree.Log,
+				Args:      []tree.ExpressionType{tree.Num(8), tree.Num(2)},
+			},
+		},
+		{
+			name:  "Variadic function with expressions",
+			input: "max(1,2+3,abs(4))",
+			expected: tree.Call{
+				Name:      "max",
+				Operation: tree.Max,
+				Args: []tree.ExpressionType{
+					tree.Num(1),
+					tree.Expression{
+						Left:      tree.Num(2),
+						Operation: tree.Operation(pb.Operation_ADD),
+						Right:     tree.Num(3),
+					},
+					tree.Call{Name: "abs", Operation: tree.Abs, Args: []tree.ExpressionType{tree.Num(4)}},
+				},
+			},
+		},
+		{
+			name:    "Unknown function",
+			input:   "foo(1)",
+			wantErr: true,
+			errMsg:  parser.UnknownFunction,
+		},
+		{
+			name:    "Too many arguments",
+			input:   "sqrt(1,2)",
+			wantErr: true,
+			errMsg:  parser.WrongArgumentsCount,
+		},
+		{
+			name:    "Too few arguments",
+			input:   "min(1)",
+			wantErr: true,
+			errMsg:  parser.WrongArgumentsCount,
+		},
+		{
+			name:    "No arguments",
+			input:   "abs()",
+			wantErr: true,
+			errMsg:  parser.WrongArgumentsCount,
+		},
+		{
+			name:    "Function without brackets",
+			input:   "abs",
+			wantErr: true,
+			errMsg:  parser.ExpectedKind,
+		},
+		{
+			name:     "Named constant",
+			input:    "pi",
+			expected: tree.Num(math.Pi),
+		},
+		{
+			name:     "Scientific notation",
+			input:    "6.02e23",
+			expected: tree.Num(6.02e23),
+		},
+		{
+			name:     "Variable",
+			input:    "foo",
+			expected: tree.Var{Name: "foo"},
+		},
+		{
+			name:  "Negated variable",
+			input: "-foo",
+			expected: tree.Unary{
+				Operation: tree.Negate,
+				Value:     tree.Var{Name: "foo"},
+			},
+		},
+		{
+			name:    "Unclosed call",
+			input:   "abs(1",
+			wantErr: true,
+			errMsg:  parser.UnexpectedEOF,
+		},
 		// { // Wouldn't work in primary expression
 		// 	name:    "Extra closing bracket",
 		// 	input:   "5)",
@@ -209,26 +315,132 @@ func TestParser_Expression(t *testing.T) {
 			},
 		},
 		{
-			name:  "Complex expression with unary",
-			input: "-1+2*-3",
+			name:  "Simple power",
+			input: "2^3",
+			expected: tree.Expression{
+				Left:      tree.Num(2),
+				Operation: tree.Power,
+				Right:     tree.Num(3),
+			},
+		},
+		{
+			name:  "Power is right associative",
+			input: "2^3**2",
+			expected: tree.Expression{
+				Left:      tree.Num(2),
+				Operation: tree.Power,
+				Right: tree.Expression{
+					Left:      tree.Num(3),
+					Operation: tree.Power,
+					Right:     tree.Num(2),
+				},
+			},
+		},
+		{
+			name:  "Power before multiplication",
+			input: "2*3^2*4",
 			expected: tree.Expression{
 				Left: tree.Expression{
-					Left:  tree.Num(0),
-					Operation:   tree.Operation(pb.Operation_SUBTRACT),
-					Right: tree.Num(1),
+					Left:      tree.Num(2),
+					Operation: tree.Operation(pb.Operation_MULTIPLY),
+					Right: tree.Expression{
+						Left:      tree.Num(3),
+						Operation: tree.Power,
+						Right:     tree.Num(2),
+					},
+				},
+				Operation: tree.Operation(pb.Operation_MULTIPLY),
+				Right:     tree.Num(4),
+			},
+		},
+		{
+			name:  "Subtraction stays left associative",
+			input: "5-3-1",
+			expected: tree.Expression{
+				Left: tree.Expression{
+					Left:      tree.Num(5),
+					Operation: tree.Operation(pb.Operation_SUBTRACT),
+					Right:     tree.Num(3),
 				},
+				Operation: tree.Operation(pb.Operation_SUBTRACT),
+				Right:     tree.Num(1),
+			},
+		},
+		{
+			name:  "Modulo and integer division are multiplicative",
+			input: "1+7%4//2",
+			expected: tree.Expression{
+				Left:      tree.Num(1),
 				Operation: tree.Operation(pb.Operation_ADD),
 				Right: tree.Expression{
-					Left: tree.Num(2),
-					Operation:  tree.Operation(pb.Operation_MULTIPLY),
-					Right: tree.Expression{
-						Left:  tree.Num(0),
-						Operation:   tree.Operation(pb.Operation_SUBTRACT),
-						Right: tree.Num(3),
+					Left: tree.Expression{
+						Left:      tree.Num(7),
+						Operation: tree.Modulo,
+						Right:     tree.Num(4),
 					},
+					Operation: tree.IntDivide,
+					Right:     tree.Num(2),
+				},
+			},
+		},
+		{
+			name:  "Complex expression with unary",
+			input: "-1+2*-3",
+			expected: tree.Expression{
+				Left:      tree.Num(-1),
+				Operation: tree.Operation(pb.Operation_ADD),
+				Right: tree.Expression{
+					Left:      tree.Num(2),
+					Operation: tree.Operation(pb.Operation_MULTIPLY),
+					Right:     tree.Num(-3),
+				},
+			},
+		},
+		{
+			name:  "Unary minus is weaker than power",
+			input: "-2^2",
+			expected: tree.Unary{
+				Operation: tree.Negate,
+				Value: tree.Expression{
+					Left:      tree.Num(2),
+					Operation: tree.Power,
+					Right:     tree.Num(2),
 				},
 			},
 		},
+		{
+			name:  "Unary minus is stronger than multiplication",
+			input: "-abs(2)*3",
+			expected: tree.Expression{
+				Left: tree.Unary{
+					Operation: tree.Negate,
+					Value:     tree.Call{Name: "abs", Operation: tree.Abs, Args: []tree.ExpressionType{tree.Num(2)}},
+				},
+				Operation: tree.Operation(pb.Operation_MULTIPLY),
+				Right:     tree.Num(3),
+			},
+		},
+		{
+			name:  "Unary minus in exponent",
+			input: "2^-3^2",
+			expected: tree.Expression{
+				Left:      tree.Num(2),
+				Operation: tree.Power,
+				Right: tree.Unary{
+					Operation: tree.Negate,
+					Value: tree.Expression{
+						Left:      tree.Num(3),
+						Operation: tree.Power,
+						Right:     tree.Num(2),
+					},
+				},
+			},
+		},
+		{
+			name:     "Unary minus of power in brackets",
+			input:    "(-2)^2",
+			expected: tree.Expression{Left: tree.Num(-2), Operation: tree.Power, Right: tree.Num(2)},
+		},
 	}
 
 	for _, tt := range tests {
@@ -280,6 +492,32 @@ func TestParser_Build(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:  "Function call with whitespace",
+			input: " round( 2.5 ) * 2 ",
+			expected: tree.Ast{
+				Expression: tree.Expression{
+					Left:      tree.Call{Name: "round", Operation: tree.Round, Args: []tree.ExpressionType{tree.Num(2.5)}},
+					Operation: tree.Operation(pb.Operation_MULTIPLY),
+					Right:     tree.Num(2),
+				},
+			},
+		},
+		{
+			name:  "Constants and literals",
+			input: "tau / 0x2 * e",
+			expected: tree.Ast{
+				Expression: tree.Expression{
+					Left: tree.Expression{
+						Left:      tree.Num(2 * math.Pi),
+						Operation: tree.Operation(pb.Operation_DIVIDE),
+						Right:     tree.Num(2),
+					},
+					Operation: tree.Operation(pb.Operation_MULTIPLY),
+					Right:     tree.Num(math.E),
+				},
+			},
+		},
 		{
 			name:    "Invalid expression",
 			input:   "1+",
@@ -358,3 +596,564 @@ func TestParser_Methods(t *testing.T) {
 		assert.Contains(t, err.Error(), parser.ExpectedKind)
 	})
 }
+
+func TestParser_ErrorPositions(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		errMsg string
+		start  int
+		end    int
+	}{
+		{"Unexpected token", "1 + 2 3", parser.UnexpectedToken, 6, 7},
+		{"Unexpected EOF", "1 +  ", parser.UnexpectedEOF, 5, 5},
+		{"Unclosed bracket", "(1 + 2", parser.ExpectedKind, 6, 6},
+		{"Unexpected kind", "1 * )", parser.UnexpectedTokenKind, 4, 5},
+		{"Unknown function", "foo(1)", parser.UnknownFunction, 0, 3},
+		{"Wrong arguments count", "1 + sqrt(1, 2)", parser.WrongArgumentsCount, 4, 14},
+		{"Lexer error", "1 + 2 $", lexer.UnexpectedChar, 6, 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parser.Build(tt.input)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+
+			var parsingErr *parsing.Error
+			require.ErrorAs(t, err, &parsingErr)
+			assert.Equal(t, tt.start, parsingErr.Start)
+			assert.Equal(t, tt.end, parsingErr.End)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	type place struct {
+		errMsg string
+		start  int
+		end    int
+	}
+	tests := []struct {
+		name     string
+		input    string
+		expected []place
+	}{
+		{
+			name:  "Valid expression",
+			input: "max(1, 2) * -(3 + pi)",
+		},
+		{
+			name:     "Empty brackets",
+			input:    "2 * ( )",
+			expected: []place{{parser.EmptyBrackets, 4, 7}},
+		},
+		{
+			name:  "Dangling operators",
+			input: "1 + * 2 -",
+			expected: []place{
+				{parser.UnexpectedTokenKind, 4, 5},
+				{parser.UnexpectedEOF, 9, 9},
+			},
+		},
+		{
+			name:  "Unbalanced brackets",
+			input: "(1 + 2)) * (3",
+			expected: []place{
+				{parser.UnopenedBracket, 7, 8},
+				{parser.ExpectedKind, 13, 13},
+			},
+		},
+		{
+			name:  "Synchronises on the closing bracket",
+			input: "(1 2 3) + foo()",
+			expected: []place{
+				{parser.ExpectedKind, 3, 4},
+				{parser.UnknownFunction, 10, 13},
+			},
+		},
+		{
+			name:  "Errors in a call",
+			input: "max(1) + sqrt(, 2) + bar(())",
+			expected: []place{
+				{parser.WrongArgumentsCount, 0, 6},
+				{parser.WrongArgumentsCount, 9, 18},
+				{parser.UnexpectedTokenKind, 14, 15},
+				{parser.UnknownFunction, 21, 24},
+				{parser.EmptyBrackets, 25, 27},
+			},
+		},
+		{
+			name:  "Lexer and parser errors",
+			input: "1 $ 2 + 1__0 + ",
+			expected: []place{
+				{lexer.UnexpectedChar, 2, 3},
+				{parser.UnexpectedToken, 4, 5},
+				{lexer.UnexpectedChar, 9, 10},
+				{parser.UnexpectedEOF, 15, 15},
+			},
+		},
+		{
+			name:  "EOF in a call is reported once",
+			input: "f(",
+			expected: []place{
+				{parser.UnknownFunction, 0, 1},
+				{parser.UnexpectedEOF, 2, 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := parser.Validate(tt.input, nil)
+			require.Equal(t, len(tt.expected), len(errs), "unexpected errors %v", errs)
+			for i, e := range tt.expected {
+				assert.Contains(t, errs[i].Error(), e.errMsg, "error mismatch at position %d", i)
+				assert.Equal(t, e.start, errs[i].Start, "error start mismatch at position %d", i)
+				assert.Equal(t, e.end, errs[i].End, "error end mismatch at position %d", i)
+			}
+		})
+	}
+}
+
+func TestBind(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		variables map[string]float64
+		expected  tree.ExpressionType
+		errMsg    string
+	}{
+		{
+			name:      "Variables",
+			input:     "price * qty",
+			variables: map[string]float64{"price": 2.5, "qty": 4, "unused": 1},
+			expected: tree.Expression{
+				Left:      tree.Num(2.5),
+				Operation: tree.Operation(pb.Operation_MULTIPLY),
+				Right:     tree.Num(4),
+			},
+		},
+		{
+			name:      "Negated variable is folded",
+			input:     "max(-x, 1)",
+			variables: map[string]float64{"x": 3},
+			expected:  tree.Call{Name: "max", Operation: tree.Max, Args: []tree.ExpressionType{tree.Num(-3), tree.Num(1)}},
+		},
+		{
+			name:      "Constants are not variables",
+			input:     "pi",
+			variables: map[string]float64{"pi": 3},
+			expected:  tree.Num(math.Pi),
+		},
+		{
+			name:      "Every missing name once",
+			input:     "price * qty * (1 + tax) + qty",
+			variables: map[string]float64{"price": 1},
+			errMsg:    "unbound variables - qty, tax",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, err := parser.Build(tt.input)
+			require.NoError(t, err)
+
+			result, err := parser.Bind(ast, tt.variables)
+			if tt.errMsg != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.errMsg, err.Error())
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result.Expression)
+		})
+	}
+}
+
+func TestBuildWithPrecision(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected tree.ExpressionType
+		errMsg   string
+	}{
+		{
+			name:  "Literals are kept",
+			input: "0.1 + 0.20",
+			expected: tree.Expression{
+				Left:      tree.Decimal("0.1"),
+				Operation: tree.Operation(pb.Operation_ADD),
+				Right:     tree.Decimal("0.20"),
+			},
+		},
+		{
+			name:     "Negation is folded",
+			input:    "-1_000.5",
+			expected: tree.Decimal("-1000.5"),
+		},
+		{
+			name:     "Based numbers",
+			input:    "0xff",
+			expected: tree.Decimal("255"),
+		},
+		{
+			name:   "Too many digits",
+			input:  "1.00000000000000000000000000000000001",
+			errMsg: "too many digits",
+		},
+		{
+			name:  "Out of the float range",
+			input: "1e400 + 1",
+			expected: tree.Expression{
+				Left:      tree.Decimal("1e400"),
+				Operation: tree.Operation(pb.Operation_ADD),
+				Right:     tree.Decimal("1"),
+			},
+		},
+		{
+			name:   "Out of the decimal range",
+			input:  "1e7000",
+			errMsg: "number is out of range",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parser.BuildWithPrecision(tt.input, nil, tree.DecimalPrecision)
+			if tt.errMsg != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result.Expression)
+		})
+	}
+}
+
+func TestToDecimal(t *testing.T) {
+	ast, err := parser.Build("max(2, 0.5) * 3")
+	require.NoError(t, err)
+
+	result := parser.ToDecimal(ast)
+	assert.Equal(t, tree.Expression{
+		Left:      tree.Call{Name: "max", Operation: tree.Max, Args: []tree.ExpressionType{tree.Decimal("2"), tree.Decimal("0.5")}},
+		Operation: tree.Operation(pb.Operation_MULTIPLY),
+		Right:     tree.Decimal("3"),
+	}, result.Expression)
+	assert.Equal(t, 34, parser.SignificantDigits("0.0001000000000000000000000000000000001e5"))
+}
+
+func TestBuildWithPrecision_Rational(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected tree.ExpressionType
+		errMsg   string
+	}{
+		{
+			name:  "Literals are fractions",
+			input: "0.25 / 3",
+			expected: tree.Expression{
+				Left:      tree.Rational("1/4"),
+				Operation: tree.Operation(pb.Operation_DIVIDE),
+				Right:     tree.Rational("3"),
+			},
+		},
+		{
+			name:     "Negation is folded",
+			input:    "-1.5e-1",
+			expected: tree.Rational("-3/20"),
+		},
+		{
+			name:     "Constants",
+			input:    "pi",
+			expected: tree.Rational("3141592653589793/1000000000000000"),
+		},
+		{
+			name:     "Out of the float range",
+			input:    "1e-400",
+			expected: tree.Rational("1/1" + strings.Repeat("0", 400)),
+		},
+		{
+			name:   "Too big exponent",
+			input:  "1e-1001",
+			errMsg: "too many digits",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parser.BuildWithPrecision(tt.input, nil, tree.RationalPrecision)
+			if tt.errMsg != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result.Expression)
+		})
+	}
+}
+
+func TestParser_Complex(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected tree.ExpressionType
+	}{
+		{
+			name:  "Impedance",
+			input: "10 + 2i",
+			expected: tree.Expression{
+				Left:      tree.Num(10),
+				Operation: tree.Operation(pb.Operation_ADD),
+				Right:     tree.Complex{Im: 2},
+			},
+		},
+		{
+			name:     "Negated unit",
+			input:    "-i",
+			expected: tree.Complex{Im: -1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parser.Build(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result.Expression)
+		})
+	}
+
+	_, err := parser.BuildWithPrecision("1 + 2i", nil, tree.DecimalPrecision)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), parser.ComplexPrecision)
+}
+
+func TestParser_Quantity(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected tree.ExpressionType
+	}{
+		{
+			name:     "Kilometers",
+			input:    "5 km",
+			expected: tree.Quantity{Value: 5000, Dimension: units.Dimension{1}},
+		},
+		{
+			name:     "Negated",
+			input:    "-2 s",
+			expected: tree.Quantity{Value: -2, Dimension: units.Dimension{0, 0, 1}},
+		},
+		{
+			name:     "Same units",
+			input:    "3 m/m",
+			expected: tree.Num(3),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parser.Build(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result.Expression)
+		})
+	}
+
+	_, err := parser.BuildWithPrecision("5 km", nil, tree.RationalPrecision)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), parser.UnitPrecision)
+}
+
+func TestParser_Logic(t *testing.T) {
+	x, y := tree.Var{Name: "x"}, tree.Var{Name: "y"}
+	conditional := func(args ...tree.ExpressionType) tree.Call {
+		return tree.Call{Name: parser.ConditionalFunction, Operation: tree.Conditional, Args: args}
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected tree.ExpressionType
+	}{
+		{
+			name:  "Comparison is lower than addition",
+			input: "x + 1 < y",
+			expected: tree.Expression{
+				Left:      tree.Expression{Left: x, Operation: tree.Operation(pb.Operation_ADD), Right: tree.Num(1)},
+				Operation: tree.Less,
+				Right:     y,
+			},
+		},
+		{
+			name:  "And is higher than or",
+			input: "x || y && !x",
+			expected: tree.Expression{
+				Left:      x,
+				Operation: tree.Or,
+				Right:     tree.Expression{Left: y, Operation: tree.And, Right: tree.Unary{Operation: tree.Not, Value: x}},
+			},
+		},
+		{
+			name:     "Ternary",
+			input:    "x >= 0 ? x : -x",
+			expected: conditional(tree.Expression{Left: x, Operation: tree.GreaterEqual, Right: tree.Num(0)}, x, tree.Unary{Operation: tree.Negate, Value: x}),
+		},
+		{
+			name:     "Nested ternary is right associative",
+			input:    "x ? 1 : y ? 2 : 3",
+			expected: conditional(x, tree.Num(1), conditional(y, tree.Num(2), tree.Num(3))),
+		},
+		{
+			name:     "If function",
+			input:    "if(x == y, 1, 2)",
+			expected: conditional(tree.Expression{Left: x, Operation: tree.Equal, Right: y}, tree.Num(1), tree.Num(2)),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parser.Build(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result.Expression)
+		})
+	}
+
+	_, err := parser.Build("x ? 1")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), parser.ExpectedKind)
+}
+
+func TestParser_Lists(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected tree.ExpressionType
+	}{
+		{
+			name:     "List",
+			input:    "sum([1, 2, 3])",
+			expected: tree.Call{Name: "sum", Operation: tree.Sum, Args: []tree.ExpressionType{tree.Num(1), tree.Num(2), tree.Num(3)}},
+		},
+		{
+			name:     "Lists and items are flattened",
+			input:    "median([1, 2], x, [3])",
+			expected: tree.Call{Name: "median", Operation: tree.Median, Args: []tree.ExpressionType{tree.Num(1), tree.Num(2), tree.Var{Name: "x"}, tree.Num(3)}},
+		},
+		{
+			name:     "Min of a list",
+			input:    "min([3, 1])",
+			expected: tree.Call{Name: "min", Operation: tree.Min, Args: []tree.ExpressionType{tree.Num(3), tree.Num(1)}},
+		},
+		{
+			name:     "One item",
+			input:    "avg(x)",
+			expected: tree.Call{Name: "avg", Operation: tree.Avg, Args: []tree.ExpressionType{tree.Var{Name: "x"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parser.Build(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result.Expression)
+		})
+	}
+
+	errorTests := []struct {
+		input  string
+		errMsg string
+	}{
+		{"[1, 2]", parser.ListOutsideCall},
+		{"sqrt([4])", parser.ListOutsideCall},
+		{"sum([1, 2] + 3)", parser.UnexpectedToken},
+		{"sum([])", parser.WrongArgumentsCount},
+		{"sum([1, 2)", parser.UnexpectedToken},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := parser.Build(tt.input)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
+
+func TestDimension(t *testing.T) {
+	tests := []struct {
+		input     string
+		dimension string
+		known     bool
+	}{
+		{"5 km + 300 m", "m", true},
+		{"100 km / 2 h", "m/s", true},
+		{"2 kg * 9.81 m/s^2", "m*kg/s^2", true},
+		{"(3 m)^2", "m^2", true},
+		{"sqrt(16 m^2)", "m", true},
+		{"max(1 s, 2 min, 3 h)", "s", true},
+		{"7 m // 2 m", "", true},
+		{"(2 m)^(1 + 1)", "", false},
+		{"2^(1 + 1)", "", true},
+		{"1 + 2", "", true},
+		{"1 m < 2 km", "", true},
+		{"1 < 2 ? 1 m : 2 km", "m", true},
+		{"!(1 s) || 0", "", true},
+		{"sum([1 m, 2 km])", "m", true},
+		{"stddev(1 s, 2 min)", "s", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			ast, err := parser.Build(tt.input)
+			require.NoError(t, err)
+			dimension, known, err := parser.Dimension(ast)
+			require.NoError(t, err)
+			assert.Equal(t, tt.known, known)
+			if tt.known {
+				assert.Equal(t, tt.dimension, dimension.String())
+			}
+		})
+	}
+}
+
+func TestDimension_Errors(t *testing.T) {
+	tests := []string{
+		"5 m + 2 s",
+		"5 m + 1",
+		"2^(1 s)",
+		"(2 m)^0.5",
+		"sqrt(2 m)",
+		"log(10 kg)",
+		"round(1.5 m, 1 s)",
+		"min(1 A, 1 K)",
+		"1 m // 1 s",
+		"1 m == 1 s",
+		"1 ? 1 m : 1 s",
+		"avg([1 m, 1 s])",
+		"(2 m)^100 * (2 m)^100",
+		"(1 m/s/s)^100",
+		"1 / (1 m)^100 / (1 m)^100",
+	}
+
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			ast, err := parser.Build(input)
+			require.NoError(t, err)
+			_, _, err = parser.Dimension(ast)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), units.IncompatibleUnits)
+		})
+	}
+
+	ast, err := parser.Build("median([1, 2i])")
+	require.NoError(t, err)
+	_, _, err = parser.Dimension(ast)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), parser.ComplexArguments)
+}
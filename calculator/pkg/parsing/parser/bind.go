@@ -0,0 +1,56 @@
+package parser
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/vandi37/Calculator/pkg/parsing/tree"
+	"github.com/vandi37/vanerrors"
+)
+
+// Replaces variables with their values.
+//
+// All unbound variables are listed in the error, each name once
+func Bind(ast tree.Ast, variables map[string]float64) (tree.Ast, error) {
+	missing := []string{}
+	expr := bind(ast.Expression, variables, &missing)
+	if len(missing) > 0 {
+		return tree.Ast{}, vanerrors.New(UnboundVariables, strings.Join(missing, ", "))
+	}
+	return tree.Ast{Expression: expr, Currency: ast.Currency}, nil
+}
+
+func bind(expr tree.ExpressionType, variables map[string]float64, missing *[]string) tree.ExpressionType {
+	switch v := expr.(type) {
+	case tree.Var:
+		if value, ok := variables[v.Name]; ok {
+			return tree.Num(value)
+		}
+		if !slices.Contains(*missing, v.Name) {
+			*missing = append(*missing, v.Name)
+		}
+		return v
+	case tree.Expression:
+		v.Left = bind(v.Left, variables, missing)
+		v.Right = bind(v.Right, variables, missing)
+		return v
+	case tree.Unary:
+		v.Value = bind(v.Value, variables, missing)
+		// The same folding as while parsing
+		if v.Operation == tree.Negate {
+			if num, ok := tree.Negated(v.Value); ok {
+				return num
+			}
+		}
+		return v
+	case tree.Call:
+		args := make([]tree.ExpressionType, len(v.Args))
+		for i, arg := range v.Args {
+			args[i] = bind(arg, variables, missing)
+		}
+		v.Args = args
+		return v
+	default:
+		return expr
+	}
+}
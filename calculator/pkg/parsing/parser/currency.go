@@ -0,0 +1,103 @@
+package parser
+
+import (
+	"math/big"
+	"slices"
+
+	"github.com/vandi37/Calculator/pkg/currency"
+	"github.com/vandi37/Calculator/pkg/parsing/tree"
+	"github.com/vandi37/Calculator/pkg/units"
+	"github.com/vandi37/vanerrors"
+)
+
+// Codes of the amounts in the order they are written, each code once
+func Currencies(ast tree.Ast) []string {
+	codes := []string{}
+	currencies(ast.Expression, &codes)
+	return codes
+}
+
+func currencies(expr tree.ExpressionType, codes *[]string) {
+	switch v := expr.(type) {
+	case tree.Money:
+		if !slices.Contains(*codes, v.Currency) {
+			*codes = append(*codes, v.Currency)
+		}
+	case tree.Expression:
+		currencies(v.Left, codes)
+		currencies(v.Right, codes)
+	case tree.Unary:
+		currencies(v.Value, codes)
+	case tree.Call:
+		for _, arg := range v.Args {
+			currencies(arg, codes)
+		}
+	}
+}
+
+// Replaces the amounts with numbers in the currency of the result.
+//
+// In the float precision they become quantities, so the agents check the money like units.
+// In the exact precisions they are plain numbers, Dimension must check them before
+func ApplyRates(ast tree.Ast, rates map[string]float64, to string, precision tree.Precision) (tree.Ast, error) {
+	expr, err := applyRates(ast.Expression, rates, to, precision)
+	if err != nil {
+		return tree.Ast{}, err
+	}
+	return tree.Ast{Expression: expr, Currency: ast.Currency}, nil
+}
+
+func applyRates(expr tree.ExpressionType, rates map[string]float64, to string, precision tree.Precision) (tree.ExpressionType, error) {
+	var err error
+	switch v := expr.(type) {
+	case tree.Money:
+		return money(v, rates, to, precision)
+	case tree.Expression:
+		if v.Left, err = applyRates(v.Left, rates, to, precision); err != nil {
+			return nil, err
+		}
+		if v.Right, err = applyRates(v.Right, rates, to, precision); err != nil {
+			return nil, err
+		}
+		return v, nil
+	case tree.Unary:
+		if v.Value, err = applyRates(v.Value, rates, to, precision); err != nil {
+			return nil, err
+		}
+		return v, nil
+	case tree.Call:
+		args := make([]tree.ExpressionType, len(v.Args))
+		for i, arg := range v.Args {
+			if args[i], err = applyRates(arg, rates, to, precision); err != nil {
+				return nil, err
+			}
+		}
+		v.Args = args
+		return v, nil
+	default:
+		return expr, nil
+	}
+}
+
+func money(m tree.Money, rates map[string]float64, to string, precision tree.Precision) (tree.ExpressionType, error) {
+	amount, ok := new(big.Rat).SetString(m.Amount)
+	if !ok {
+		return nil, vanerrors.New(InvalidAmount, m.Amount)
+	}
+	converted, err := currency.Convert(amount, m.Currency, to, rates)
+	if err != nil {
+		return nil, err
+	}
+	switch precision {
+	case tree.DecimalPrecision:
+		if m.Currency == to {
+			return tree.Decimal(m.Amount), nil
+		}
+		return tree.Decimal(new(big.Float).SetPrec(256).SetRat(converted).Text('g', MaxDecimalDigits)), nil
+	case tree.RationalPrecision:
+		return tree.Rational(converted.RatString()), nil
+	default:
+		f, _ := converted.Float64()
+		return tree.Quantity{Value: f, Dimension: units.Money}, nil
+	}
+}
@@ -0,0 +1,114 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	pb "github.com/vandi37/Calculator-Models"
+	"github.com/vandi37/Calculator/pkg/currency"
+	"github.com/vandi37/Calculator/pkg/parsing/parser"
+	"github.com/vandi37/Calculator/pkg/parsing/tree"
+	"github.com/vandi37/Calculator/pkg/units"
+)
+
+func TestParser_Money(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected tree.Ast
+	}{
+		{
+			name:     "Amount",
+			input:    "100 USD",
+			expected: tree.Ast{Expression: tree.Money{Amount: "100", Currency: "USD"}},
+		},
+		{
+			name:     "Negated",
+			input:    "-2.5 EUR",
+			expected: tree.Ast{Expression: tree.Money{Amount: "-2.5", Currency: "EUR"}},
+		},
+		{
+			name:  "Target currency",
+			input: "100 USD + 50 EUR in GBP",
+			expected: tree.Ast{Expression: tree.Expression{
+				Left:      tree.Money{Amount: "100", Currency: "USD"},
+				Operation: tree.Operation(pb.Operation_ADD),
+				Right:     tree.Money{Amount: "50", Currency: "EUR"},
+			}, Currency: "GBP"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parser.Build(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+
+	for _, input := range []string{"100 USD in", "100 USD in usd", "100 USD in GBP in EUR"} {
+		t.Run(input, func(t *testing.T) {
+			_, err := parser.Build(input)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestCurrencies(t *testing.T) {
+	ast, err := parser.Build("100 USD + max(50 EUR, 20 USD) - 3 JPY * 2")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"USD", "EUR", "JPY"}, parser.Currencies(ast))
+
+	dimension, known, err := parser.Dimension(ast)
+	require.NoError(t, err)
+	assert.True(t, known)
+	assert.Equal(t, units.Money, dimension)
+
+	ast, err = parser.Build("100 USD + 5 m")
+	require.NoError(t, err)
+	_, _, err = parser.Dimension(ast)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), units.IncompatibleUnits)
+}
+
+func TestApplyRates(t *testing.T) {
+	rates := map[string]float64{"USD": 1, "EUR": 0.5, "GBP": 0.8}
+	tests := []struct {
+		name      string
+		precision tree.Precision
+		expected  tree.ExpressionType
+	}{
+		{
+			name:     "Float",
+			expected: tree.Quantity{Value: 16, Dimension: units.Money},
+		},
+		{
+			name:      "Decimal",
+			precision: tree.DecimalPrecision,
+			expected:  tree.Decimal("16"),
+		},
+		{
+			name:      "Rational",
+			precision: tree.RationalPrecision,
+			expected:  tree.Rational("16"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, err := parser.BuildWithPrecision("10 EUR in GBP", nil, tt.precision)
+			require.NoError(t, err)
+			result, err := parser.ApplyRates(ast, rates, "GBP", tt.precision)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result.Expression)
+			assert.Equal(t, "GBP", result.Currency)
+		})
+	}
+
+	ast, err := parser.Build("10 CHF")
+	require.NoError(t, err)
+	_, err = parser.ApplyRates(ast, rates, "USD", tree.FloatPrecision)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), currency.UnknownCurrency)
+}
@@ -0,0 +1,222 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/vandi37/Calculator/pkg/parsing/constants"
+	"github.com/vandi37/Calculator/pkg/parsing/functions"
+	"github.com/vandi37/Calculator/pkg/parsing/lexer"
+	"github.com/vandi37/Calculator/pkg/parsing/tree"
+	"github.com/vandi37/vanerrors"
+)
+
+// Value or function saved by the user.
+//
+// Definitions without parameters are values: rate = 0.2, with parameters they are functions: f(x) = x^2 + 1
+type Definition struct {
+	Name   string
+	Params []string
+	Body   string
+}
+
+type savedFunction struct {
+	params []string
+	body   tree.ExpressionType
+}
+
+// Parsed definitions of a user
+type Definitions struct {
+	values    map[string]tree.ExpressionType
+	functions map[string]savedFunction
+}
+
+// Checks that the name can be used for a definition or a parameter
+func CheckName(name string) error {
+	runes := []rune(name)
+	if len(runes) == 0 || !lexer.IsIdentifierStart(runes[0]) || slices.ContainsFunc(runes, func(r rune) bool { return !lexer.IsIdentifier(r) }) {
+		return vanerrors.New(InvalidName, fmt.Sprintf("%q is not an identifier", name))
+	}
+	if _, ok := constants.Get(name); ok {
+		return vanerrors.New(InvalidName, fmt.Sprintf("%s is a constant", name))
+	}
+	if _, ok := functions.Get(name); ok {
+		return vanerrors.New(InvalidName, fmt.Sprintf("%s is a built-in function", name))
+	}
+	return nil
+}
+
+// Parses the bodies of the definitions. Bodies can use each other, cycles are found by Check and Expand
+func NewDefinitions(definitions []Definition) (*Definitions, error) {
+	d := &Definitions{
+		values:    map[string]tree.ExpressionType{},
+		functions: map[string]savedFunction{},
+	}
+	// All names are known before parsing, so a body can call a function that is defined later
+	for _, definition := range definitions {
+		if err := CheckName(definition.Name); err != nil {
+			return nil, err
+		}
+		for i, param := range definition.Params {
+			if err := CheckName(param); err != nil {
+				return nil, err
+			}
+			if slices.Contains(definition.Params[:i], param) {
+				return nil, vanerrors.New(InvalidName, fmt.Sprintf("%s has two parameters %s", definition.Name, param))
+			}
+		}
+		if _, ok := d.functions[definition.Name]; ok {
+			return nil, vanerrors.New(InvalidName, fmt.Sprintf("%s is defined twice", definition.Name))
+		} else if _, ok := d.values[definition.Name]; ok {
+			return nil, vanerrors.New(InvalidName, fmt.Sprintf("%s is defined twice", definition.Name))
+		}
+		if len(definition.Params) > 0 {
+			d.functions[definition.Name] = savedFunction{params: definition.Params}
+		} else {
+			d.values[definition.Name] = nil
+		}
+	}
+
+	for _, definition := range definitions {
+		ast, err := BuildWith(definition.Body, d)
+		if err != nil {
+			return nil, vanerrors.New(InvalidDefinition, fmt.Sprintf("%s: %s", definition.Name, err.Error()))
+		}
+		if len(definition.Params) > 0 {
+			d.functions[definition.Name] = savedFunction{params: definition.Params, body: ast.Expression}
+		} else {
+			d.values[definition.Name] = ast.Expression
+		}
+	}
+	return d, nil
+}
+
+func (d *Definitions) arity(name string) (int, bool) {
+	if d == nil {
+		return 0, false
+	}
+	function, ok := d.functions[name]
+	return len(function.params), ok
+}
+
+// Expands every definition once, so cycles are found even if the definitions aren't used yet
+func (d *Definitions) Check() error {
+	if d == nil {
+		return nil
+	}
+	for name := range d.values {
+		if _, err := d.expand(tree.Var{Name: name}, nil, nil, nil); err != nil {
+			return err
+		}
+	}
+	for name, function := range d.functions {
+		// Parameters stay variables, so they don't take saved values with the same names
+		params := map[string]tree.ExpressionType{}
+		for _, param := range function.params {
+			params[param] = tree.Var{Name: param}
+		}
+		if _, err := d.expand(function.body, nil, params, []string{name}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Replaces saved values and calls of saved functions with their bodies.
+//
+// The variables of the request are stronger than the saved values, variables without any value are left for Bind
+func (d *Definitions) Expand(ast tree.Ast, variables map[string]float64) (tree.Ast, error) {
+	expr, err := d.expand(ast.Expression, variables, nil, nil)
+	if err != nil {
+		return tree.Ast{}, err
+	}
+	return tree.Ast{Expression: expr, Currency: ast.Currency}, nil
+}
+
+// Args are the parameters of the function which body is expanded, stack has the names that are being expanded
+func (d *Definitions) expand(expr tree.ExpressionType, variables map[string]float64, args map[string]tree.ExpressionType, stack []string) (tree.ExpressionType, error) {
+	switch v := expr.(type) {
+	case tree.Var:
+		if arg, ok := args[v.Name]; ok {
+			// The argument is already expanded
+			return arg, nil
+		}
+		if value, ok := variables[v.Name]; ok {
+			return tree.Num(value), nil
+		}
+		if d == nil {
+			return v, nil
+		}
+		body, ok := d.values[v.Name]
+		if !ok {
+			return v, nil
+		}
+		if slices.Contains(stack, v.Name) {
+			return nil, cycleError(stack, v.Name)
+		}
+		return d.expand(body, variables, nil, append(stack, v.Name))
+	case tree.Apply:
+		if d == nil {
+			return nil, vanerrors.New(UnknownFunction, v.Name)
+		}
+		function, ok := d.functions[v.Name]
+		if !ok {
+			return nil, vanerrors.New(UnknownFunction, v.Name)
+		}
+		if slices.Contains(stack, v.Name) {
+			return nil, cycleError(stack, v.Name)
+		}
+		params := map[string]tree.ExpressionType{}
+		for i, arg := range v.Args {
+			arg, err := d.expand(arg, variables, args, stack)
+			if err != nil {
+				return nil, err
+			}
+			params[function.params[i]] = arg
+		}
+		return d.expand(function.body, variables, params, append(stack, v.Name))
+	case tree.Expression:
+		left, err := d.expand(v.Left, variables, args, stack)
+		if err != nil {
+			return nil, err
+		}
+		right, err := d.expand(v.Right, variables, args, stack)
+		if err != nil {
+			return nil, err
+		}
+		v.Left, v.Right = left, right
+		return v, nil
+	case tree.Unary:
+		value, err := d.expand(v.Value, variables, args, stack)
+		if err != nil {
+			return nil, err
+		}
+		// The same folding as while parsing
+		if v.Operation == tree.Negate {
+			if num, ok := tree.Negated(value); ok {
+				return num, nil
+			}
+		}
+		v.Value = value
+		return v, nil
+	case tree.Call:
+		expanded := make([]tree.ExpressionType, len(v.Args))
+		for i, arg := range v.Args {
+			arg, err := d.expand(arg, variables, args, stack)
+			if err != nil {
+				return nil, err
+			}
+			expanded[i] = arg
+		}
+		v.Args = expanded
+		return v, nil
+	default:
+		return expr, nil
+	}
+}
+
+func cycleError(stack []string, name string) error {
+	from := slices.Index(stack, name)
+	return vanerrors.New(CyclicDefinition, strings.Join(append(slices.Clone(stack[from:]), name), " -> "))
+}
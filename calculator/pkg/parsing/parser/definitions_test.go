@@ -0,0 +1,205 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	pb "github.com/vandi37/Calculator-Models"
+	"github.com/vandi37/Calculator/pkg/parsing/parser"
+	"github.com/vandi37/Calculator/pkg/parsing/tree"
+)
+
+func TestNewDefinitions(t *testing.T) {
+	tests := []struct {
+		name        string
+		definitions []parser.Definition
+		errMsg      string
+	}{
+		{
+			name: "Values and functions",
+			definitions: []parser.Definition{
+				{Name: "f", Params: []string{"x"}, Body: "g(x) + rate"},
+				{Name: "g", Params: []string{"x", "y_2"}, Body: "x * y_2"},
+				{Name: "rate", Body: "0.2"},
+			},
+			errMsg: parser.WrongArgumentsCount,
+		},
+		{
+			name:        "Built-in function name",
+			definitions: []parser.Definition{{Name: "sqrt", Params: []string{"x"}, Body: "x"}},
+			errMsg:      parser.InvalidName,
+		},
+		{
+			name:        "Constant name",
+			definitions: []parser.Definition{{Name: "pi", Body: "3"}},
+			errMsg:      parser.InvalidName,
+		},
+		{
+			name:        "Not an identifier",
+			definitions: []parser.Definition{{Name: "1x", Body: "3"}},
+			errMsg:      parser.InvalidName,
+		},
+		{
+			name:        "Same parameters",
+			definitions: []parser.Definition{{Name: "f", Params: []string{"x", "x"}, Body: "x"}},
+			errMsg:      parser.InvalidName,
+		},
+		{
+			name: "Defined twice",
+			definitions: []parser.Definition{
+				{Name: "f", Params: []string{"x"}, Body: "x"},
+				{Name: "f", Body: "1"},
+			},
+			errMsg: parser.InvalidName,
+		},
+		{
+			name:        "Broken body",
+			definitions: []parser.Definition{{Name: "f", Params: []string{"x"}, Body: "x +"}},
+			errMsg:      parser.InvalidDefinition,
+		},
+		{
+			name: "Valid",
+			definitions: []parser.Definition{
+				{Name: "f", Params: []string{"x"}, Body: "g(x, 2) + rate"},
+				{Name: "g", Params: []string{"x", "y_2"}, Body: "x * y_2"},
+				{Name: "rate", Body: "0.2"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := parser.NewDefinitions(tt.definitions)
+			if tt.errMsg != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.NoError(t, d.Check())
+		})
+	}
+}
+
+func TestDefinitions_Expand(t *testing.T) {
+	d, err := parser.NewDefinitions([]parser.Definition{
+		{Name: "f", Params: []string{"x"}, Body: "x^2 + 1"},
+		{Name: "g", Params: []string{"x"}, Body: "f(-x) * rate"},
+		{Name: "rate", Body: "tax / 2"},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		input     string
+		variables map[string]float64
+		expected  tree.ExpressionType
+		errMsg    string
+	}{
+		{
+			name:      "Function call",
+			input:     "g(3)",
+			variables: map[string]float64{"tax": 0.4},
+			expected: tree.Expression{
+				Left: tree.Expression{
+					Left:      tree.Expression{Left: tree.Num(-3), Operation: tree.Power, Right: tree.Num(2)},
+					Operation: tree.Operation(pb.Operation_ADD),
+					Right:     tree.Num(1),
+				},
+				Operation: tree.Operation(pb.Operation_MULTIPLY),
+				Right: tree.Expression{
+					Left:      tree.Num(0.4),
+					Operation: tree.Operation(pb.Operation_DIVIDE),
+					Right:     tree.Num(2),
+				},
+			},
+		},
+		{
+			name:      "Request variables are stronger",
+			input:     "rate + x",
+			variables: map[string]float64{"rate": 1},
+			expected: tree.Expression{
+				Left:      tree.Num(1),
+				Operation: tree.Operation(pb.Operation_ADD),
+				Right:     tree.Var{Name: "x"},
+			},
+		},
+		{
+			name:   "Wrong arguments count",
+			input:  "f(1, 2)",
+			errMsg: parser.WrongArgumentsCount,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, err := parser.BuildWith(tt.input, d)
+			if err == nil {
+				ast, err = d.Expand(ast, tt.variables)
+			}
+			if tt.errMsg != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, ast.Expression)
+		})
+	}
+}
+
+func TestDefinitions_Cycles(t *testing.T) {
+	tests := []struct {
+		name        string
+		definitions []parser.Definition
+		errMsg      string
+	}{
+		{
+			name: "Value cycle",
+			definitions: []parser.Definition{
+				{Name: "a", Body: "b + 1"},
+				{Name: "b", Body: "a * 2"},
+			},
+			errMsg: "cyclic definition - ",
+		},
+		{
+			name: "Recursive function",
+			definitions: []parser.Definition{
+				{Name: "f", Params: []string{"x"}, Body: "f(x - 1)"},
+			},
+			errMsg: "cyclic definition - f -> f",
+		},
+		{
+			name: "Function and value",
+			definitions: []parser.Definition{
+				{Name: "f", Params: []string{"x"}, Body: "x + a"},
+				{Name: "a", Body: "f(1)"},
+			},
+			errMsg: "cyclic definition - ",
+		},
+		{
+			name: "Parameter with the name of a value",
+			definitions: []parser.Definition{
+				{Name: "f", Params: []string{"x"}, Body: "x + 1"},
+				{Name: "x", Body: "f(1)"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := parser.NewDefinitions(tt.definitions)
+			require.NoError(t, err)
+
+			err = d.Check()
+			if tt.errMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
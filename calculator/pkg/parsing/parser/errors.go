@@ -5,4 +5,18 @@ const (
 	UnexpectedToken     = "unexpected kind"
 	UnexpectedEOF       = "unexpected EOF"
 	ExpectedKind        = "expected kind"
+	UnknownFunction     = "unknown function"
+	WrongArgumentsCount = "wrong arguments count"
+	EmptyBrackets       = "empty brackets"
+	UnopenedBracket     = "closing bracket without opening"
+	UnboundVariables    = "unbound variables"
+	InvalidName         = "invalid name"
+	InvalidDefinition   = "invalid definition"
+	CyclicDefinition    = "cyclic definition"
+	TooManyDigits       = "too many digits"
+	ComplexPrecision    = "complex in exact precision"
+	UnitPrecision       = "unit in exact precision"
+	InvalidAmount       = "invalid amount"
+	ListOutsideCall     = "list outside of a call"
+	ComplexArguments    = "complex arguments"
 )
@@ -0,0 +1,73 @@
+package parser
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/vandi37/Calculator/pkg/parsing/tree"
+)
+
+// Decimal128 keeps 34 significant digits
+const MaxDecimalDigits = 34
+
+// The largest exponent of a literal in the rational precision
+const MaxRationalExponent = 1000
+
+// Counts the digits of a decimal literal without the leading zeros.
+// The trailing zeros are counted, because Decimal128 keeps them: 1.50 isn't 1.5
+func SignificantDigits(literal string) int {
+	mantissa, _, _ := strings.Cut(strings.ToLower(literal), "e")
+	return len(strings.TrimLeft(strings.ReplaceAll(strings.TrimPrefix(mantissa, "-"), ".", ""), "0"))
+}
+
+// Converts a decimal literal to the fraction, false is returned if its exponent is too big
+func Rational(literal string) (tree.Rational, bool) {
+	if _, exponent, ok := strings.Cut(strings.ToLower(literal), "e"); ok {
+		if e, err := strconv.Atoi(exponent); err != nil || e > MaxRationalExponent || e < -MaxRationalExponent {
+			return "", false
+		}
+	}
+	r, ok := new(big.Rat).SetString(literal)
+	if !ok {
+		return "", false
+	}
+	return tree.Rational(r.RatString()), true
+}
+
+// Replaces the float numbers that are left in the tree (variables, saved definitions) with decimals
+func ToDecimal(ast tree.Ast) tree.Ast {
+	return tree.Ast{Expression: convert(ast.Expression, func(n tree.Num) tree.ExpressionType {
+		return tree.DecimalFrom(float64(n))
+	}), Currency: ast.Currency}
+}
+
+// Replaces the float numbers that are left in the tree with fractions
+func ToRational(ast tree.Ast) tree.Ast {
+	return tree.Ast{Expression: convert(ast.Expression, func(n tree.Num) tree.ExpressionType {
+		return tree.RationalFrom(float64(n))
+	}), Currency: ast.Currency}
+}
+
+func convert(expr tree.ExpressionType, to func(tree.Num) tree.ExpressionType) tree.ExpressionType {
+	switch v := expr.(type) {
+	case tree.Num:
+		return to(v)
+	case tree.Expression:
+		v.Left = convert(v.Left, to)
+		v.Right = convert(v.Right, to)
+		return v
+	case tree.Unary:
+		v.Value = convert(v.Value, to)
+		return v
+	case tree.Call:
+		args := make([]tree.ExpressionType, len(v.Args))
+		for i, arg := range v.Args {
+			args[i] = convert(arg, to)
+		}
+		v.Args = args
+		return v
+	default:
+		return expr
+	}
+}
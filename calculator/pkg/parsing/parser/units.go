@@ -0,0 +1,218 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	pb "github.com/vandi37/Calculator-Models"
+	"github.com/vandi37/Calculator/pkg/parsing/tree"
+	"github.com/vandi37/Calculator/pkg/units"
+	"github.com/vandi37/vanerrors"
+)
+
+// Finds the dimension of the result and checks the units on the way, so 5 m + 2 s fails before the calculation.
+//
+// False is returned if the dimension is known only after the calculation: (2 m)^(1 + 1).
+// Variables must be bound before, they are plain numbers
+func Dimension(ast tree.Ast) (units.Dimension, bool, error) {
+	return dimension(ast.Expression)
+}
+
+func dimension(expr tree.ExpressionType) (units.Dimension, bool, error) {
+	switch v := expr.(type) {
+	case tree.Quantity:
+		return v.Dimension, true, nil
+	case tree.Money:
+		return units.Money, true, nil
+	case tree.Expression:
+		left, leftKnown, err := dimension(v.Left)
+		if err != nil {
+			return units.Dimension{}, false, err
+		}
+		right, rightKnown, err := dimension(v.Right)
+		if err != nil {
+			return units.Dimension{}, false, err
+		}
+		known := leftKnown && rightKnown
+		switch pb.Operation(v.Operation) {
+		case pb.Operation_MULTIPLY:
+			d, ok := left.Mul(right)
+			if !ok {
+				return units.Dimension{}, false, outOfRange(v)
+			}
+			return d, known, nil
+		case pb.Operation_DIVIDE:
+			d, ok := left.Div(right)
+			if !ok {
+				return units.Dimension{}, false, outOfRange(v)
+			}
+			return d, known, nil
+		case pb.Operation(tree.Power):
+			return power(v, left, leftKnown, right, rightKnown)
+		case pb.Operation(tree.IntDivide):
+			if known && left != right {
+				return units.Dimension{}, false, incompatible(v, left, right)
+			}
+			return units.Dimension{}, known, nil
+		case pb.Operation(tree.Less), pb.Operation(tree.LessEqual), pb.Operation(tree.Greater),
+			pb.Operation(tree.GreaterEqual), pb.Operation(tree.Equal), pb.Operation(tree.NotEqual):
+			if known && left != right {
+				return units.Dimension{}, false, incompatible(v, left, right)
+			}
+			// True or false is a number even if the units are known only later
+			return units.Dimension{}, true, nil
+		case pb.Operation(tree.And), pb.Operation(tree.Or):
+			return units.Dimension{}, true, nil
+		default:
+			if known && left != right {
+				return units.Dimension{}, false, incompatible(v, left, right)
+			}
+			if !leftKnown {
+				return right, false, nil
+			}
+			return left, rightKnown, nil
+		}
+	case tree.Unary:
+		if v.Operation == tree.Not {
+			_, _, err := dimension(v.Value)
+			return units.Dimension{}, true, err
+		}
+		return dimension(v.Value)
+	case tree.Call:
+		if v.Operation == tree.Conditional {
+			return conditional(v)
+		}
+		return call(v)
+	default:
+		return units.Dimension{}, true, nil
+	}
+}
+
+// The exponent is a number, the base with a unit needs a literal integer exponent
+func power(expr tree.Expression, base units.Dimension, baseKnown bool, exponent units.Dimension, exponentKnown bool) (units.Dimension, bool, error) {
+	if exponentKnown && !exponent.IsZero() {
+		return units.Dimension{}, false, vanerrors.New(units.IncompatibleUnits, fmt.Sprintf("the exponent of %s has the unit %s", expr, exponent))
+	}
+	if baseKnown && base.IsZero() {
+		return base, true, nil
+	}
+	n, ok := expr.Right.(tree.Num)
+	if !ok {
+		return units.Dimension{}, false, nil
+	}
+	if float64(n) != math.Trunc(float64(n)) || n > math.MaxInt8 || n < math.MinInt8 {
+		return units.Dimension{}, false, vanerrors.New(units.IncompatibleUnits, fmt.Sprintf("the exponent of %s must be an integer", expr))
+	}
+	d, ok := base.Pow(int8(n))
+	if !ok {
+		return units.Dimension{}, false, outOfRange(expr)
+	}
+	return d, baseKnown, nil
+}
+
+func call(c tree.Call) (units.Dimension, bool, error) {
+	// Complex numbers have no order, and the deviation squares the items
+	if (c.Operation == tree.Median || c.Operation == tree.Stddev) && slices.ContainsFunc(c.Args, isComplex) {
+		return units.Dimension{}, false, vanerrors.New(ComplexArguments, fmt.Sprintf("%s needs real numbers", c))
+	}
+	args := make([]units.Dimension, len(c.Args))
+	known := true
+	for i, arg := range c.Args {
+		dim, ok, err := dimension(arg)
+		if err != nil {
+			return units.Dimension{}, false, err
+		}
+		args[i], known = dim, known && ok
+	}
+	if !known {
+		return units.Dimension{}, false, nil
+	}
+	switch c.Operation {
+	case tree.Sqrt:
+		half := args[0]
+		for i, e := range half {
+			if e%2 != 0 {
+				return units.Dimension{}, false, vanerrors.New(units.IncompatibleUnits, fmt.Sprintf("the square root of %s", args[0]))
+			}
+			half[i] = e / 2
+		}
+		return half, true, nil
+	case tree.Round:
+		if len(args) > 1 && !args[1].IsZero() {
+			return units.Dimension{}, false, vanerrors.New(units.IncompatibleUnits, fmt.Sprintf("the digits of %s have the unit %s", c, args[1]))
+		}
+		return args[0], true, nil
+	case tree.Ln, tree.Log:
+		for _, arg := range args {
+			if !arg.IsZero() {
+				return units.Dimension{}, false, vanerrors.New(units.IncompatibleUnits, fmt.Sprintf("the logarithm of %s", arg))
+			}
+		}
+		return units.Dimension{}, true, nil
+	case tree.Min, tree.Max, tree.Sum, tree.Avg, tree.Median, tree.Stddev:
+		for _, arg := range args[1:] {
+			if arg != args[0] {
+				return units.Dimension{}, false, vanerrors.New(units.IncompatibleUnits, fmt.Sprintf("%s has %s and %s", c, unitName(args[0]), unitName(arg)))
+			}
+		}
+		return args[0], true, nil
+	default:
+		return args[0], true, nil
+	}
+}
+
+// The condition can have any unit, both branches must have the same one
+func conditional(c tree.Call) (units.Dimension, bool, error) {
+	if _, _, err := dimension(c.Args[0]); err != nil {
+		return units.Dimension{}, false, err
+	}
+	then, thenKnown, err := dimension(c.Args[1])
+	if err != nil {
+		return units.Dimension{}, false, err
+	}
+	otherwise, otherwiseKnown, err := dimension(c.Args[2])
+	if err != nil {
+		return units.Dimension{}, false, err
+	}
+	if thenKnown && otherwiseKnown && then != otherwise {
+		return units.Dimension{}, false, vanerrors.New(units.IncompatibleUnits, fmt.Sprintf("%s has %s and %s", c, unitName(then), unitName(otherwise)))
+	}
+	if !thenKnown {
+		return otherwise, false, nil
+	}
+	return then, otherwiseKnown, nil
+}
+
+// Complex numbers come only from the imaginary literals
+func isComplex(expr tree.ExpressionType) bool {
+	switch v := expr.(type) {
+	case tree.Complex:
+		return true
+	case tree.Expression:
+		return isComplex(v.Left) || isComplex(v.Right)
+	case tree.Unary:
+		return isComplex(v.Value)
+	case tree.Call:
+		return slices.ContainsFunc(v.Args, isComplex)
+	default:
+		return false
+	}
+}
+
+func incompatible(expr tree.Expression, left, right units.Dimension) error {
+	return vanerrors.New(units.IncompatibleUnits, fmt.Sprintf("%s has %s and %s", expr, unitName(left), unitName(right)))
+}
+
+// The exponents of the units are int8
+func outOfRange(expr tree.Expression) error {
+	return vanerrors.New(units.IncompatibleUnits, fmt.Sprintf("the unit exponent of %s is out of range", expr))
+}
+
+// The zero dimension has no text
+func unitName(d units.Dimension) string {
+	if d.IsZero() {
+		return "a number"
+	}
+	return d.String()
+}
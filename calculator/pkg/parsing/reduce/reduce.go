@@ -0,0 +1,178 @@
+// This package builds balanced trees of binary operations for the agents
+//
+// Calls with many arguments and long chains of + and * are balanced,
+// so the agents calculate a long sum in parallel instead of a chain
+package reduce
+
+import (
+	pb "github.com/vandi37/Calculator-Models"
+	"github.com/vandi37/Calculator/pkg/parsing/tree"
+)
+
+var (
+	add      = tree.Operation(pb.Operation_ADD)
+	subtract = tree.Operation(pb.Operation_SUBTRACT)
+	multiply = tree.Operation(pb.Operation_MULTIPLY)
+	divide   = tree.Operation(pb.Operation_DIVIDE)
+)
+
+// Builds a balanced tree of the operation, the operation must be associative.
+// The items must not be empty
+func Balanced(operation tree.Operation, items []tree.ExpressionType) tree.ExpressionType {
+	if len(items) == 1 {
+		return items[0]
+	}
+	half := len(items) / 2
+	return tree.Expression{
+		Left:      Balanced(operation, items[:half]),
+		Operation: operation,
+		Right:     Balanced(operation, items[half:]),
+	}
+}
+
+// Replaces the call with binary operations.
+// False is returned if the call stays: functions with one or two arguments, the conditional and the median
+func Expand(c tree.Call) (tree.ExpressionType, bool) {
+	if len(c.Args) == 0 {
+		return c, false
+	}
+	switch c.Operation {
+	case tree.Sum:
+		return Balanced(add, c.Args), true
+	case tree.Avg:
+		if len(c.Args) == 1 {
+			return c.Args[0], true
+		}
+		return tree.Expression{Left: Balanced(add, c.Args), Operation: divide, Right: tree.Num(len(c.Args))}, true
+	case tree.Stddev:
+		return stddev(c.Args), true
+	case tree.Min, tree.Max:
+		if len(c.Args) <= 2 {
+			return c, false
+		}
+		return Balanced(c.Operation, c.Args), true
+	default:
+		return c, false
+	}
+}
+
+// sqrt(|(sum(d^2) - sum(d)^2/n) / n|) with d = x - k.
+// The shift k is the first item if it is a number, so the big items don't lose the precision.
+// The absolute value keeps the rounding of equal items from a negative square root
+func stddev(items []tree.ExpressionType) tree.ExpressionType {
+	n := tree.Num(len(items))
+	shift, shifted := items[0], isNumber(items[0])
+	deviations := make([]tree.ExpressionType, len(items))
+	squares := make([]tree.ExpressionType, len(items))
+	for i, item := range items {
+		deviation := item
+		if shifted {
+			deviation = tree.Expression{Left: item, Operation: subtract, Right: shift}
+		}
+		deviations[i] = deviation
+		squares[i] = tree.Expression{Left: deviation, Operation: tree.Power, Right: tree.Num(2)}
+	}
+	sum := tree.Expression{Left: Balanced(add, deviations), Operation: tree.Power, Right: tree.Num(2)}
+	variance := tree.Expression{
+		Left: tree.Expression{
+			Left:      Balanced(add, squares),
+			Operation: subtract,
+			Right:     tree.Expression{Left: sum, Operation: divide, Right: n},
+		},
+		Operation: divide,
+		Right:     n,
+	}
+	return tree.Call{
+		Name:      "sqrt",
+		Operation: tree.Sqrt,
+		Args:      []tree.ExpressionType{tree.Call{Name: "abs", Operation: tree.Abs, Args: []tree.ExpressionType{variance}}},
+	}
+}
+
+// Numbers can be repeated in the tree for free, other items would be calculated twice
+func isNumber(expr tree.ExpressionType) bool {
+	switch expr.(type) {
+	case tree.Num, tree.Decimal, tree.Rational, tree.Quantity:
+		return true
+	default:
+		return false
+	}
+}
+
+// Rebalances the chains of + and *, so 1+2+...+1000 has the depth 10 instead of 999.
+// The floats are added in another order, so the last digits of the result can change
+func Rebalance(ast tree.Ast) tree.Ast {
+	return tree.Ast{Expression: rebalance(ast.Expression), Currency: ast.Currency}
+}
+
+func rebalance(expr tree.ExpressionType) tree.ExpressionType {
+	switch v := expr.(type) {
+	case tree.Expression:
+		if v.Operation != add && v.Operation != multiply {
+			v.Left = rebalance(v.Left)
+			v.Right = rebalance(v.Right)
+			return v
+		}
+		operands := chain(v, v.Operation, nil)
+		for i, operand := range operands {
+			operands[i] = rebalance(operand)
+		}
+		return Balanced(v.Operation, operands)
+	case tree.Unary:
+		v.Value = rebalance(v.Value)
+		return v
+	case tree.Call:
+		args := make([]tree.ExpressionType, len(v.Args))
+		for i, arg := range v.Args {
+			args[i] = rebalance(arg)
+		}
+		v.Args = args
+		return v
+	default:
+		return expr
+	}
+}
+
+// Collects the operands of the chain in their order
+func chain(expr tree.ExpressionType, operation tree.Operation, operands []tree.ExpressionType) []tree.ExpressionType {
+	v, ok := expr.(tree.Expression)
+	if !ok || v.Operation != operation {
+		return append(operands, expr)
+	}
+	operands = chain(v.Left, operation, operands)
+	return chain(v.Right, operation, operands)
+}
+
+// The number of agent rounds on the longest path of the saved tree, the numbers have zero.
+// Choosing a branch and the middle of a median take no round
+func Depth(expr tree.ExpressionType) int {
+	switch v := expr.(type) {
+	case tree.Expression:
+		return 1 + max(Depth(v.Left), Depth(v.Right))
+	case tree.Unary:
+		return 1 + Depth(v.Value)
+	case tree.Call:
+		if expanded, ok := Expand(v); ok {
+			return Depth(expanded)
+		}
+		deepest := 0
+		for _, arg := range v.Args {
+			deepest = max(deepest, Depth(arg))
+		}
+		switch v.Operation {
+		case tree.Conditional:
+			// The branches wait for the condition
+			return Depth(v.Args[0]) + max(Depth(v.Args[1]), Depth(v.Args[2]))
+		case tree.Median:
+			// (a + b) / 2 of the two middle items
+			if len(v.Args)%2 == 0 {
+				return deepest + 2
+			}
+			return deepest
+		default:
+			return 1 + deepest
+		}
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,148 @@
+package reduce_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	pb "github.com/vandi37/Calculator-Models"
+	"github.com/vandi37/Calculator/pkg/parsing/parser"
+	"github.com/vandi37/Calculator/pkg/parsing/reduce"
+	"github.com/vandi37/Calculator/pkg/parsing/simplify"
+	"github.com/vandi37/Calculator/pkg/parsing/tree"
+)
+
+func depth(expr tree.ExpressionType) int {
+	switch v := expr.(type) {
+	case tree.Expression:
+		return 1 + max(depth(v.Left), depth(v.Right))
+	case tree.Unary:
+		return 1 + depth(v.Value)
+	case tree.Call:
+		d := 0
+		for _, arg := range v.Args {
+			d = max(d, depth(arg))
+		}
+		return 1 + d
+	default:
+		return 0
+	}
+}
+
+func TestBalanced(t *testing.T) {
+	add := tree.Operation(pb.Operation_ADD)
+	items := make([]tree.ExpressionType, 1000)
+	for i := range items {
+		items[i] = tree.Num(i)
+	}
+
+	result := reduce.Balanced(add, items)
+	assert.Equal(t, 10, depth(result))
+	assert.Equal(t, 1999, simplify.Count(result))
+
+	assert.Equal(t, tree.Num(7), reduce.Balanced(add, items[7:8]))
+	assert.Equal(t, tree.Expression{
+		Left:      tree.Num(1),
+		Operation: add,
+		Right:     tree.Expression{Left: tree.Num(2), Operation: add, Right: tree.Num(3)},
+	}, reduce.Balanced(add, items[1:4]))
+}
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   float64
+	}{
+		{"sum([1, 2, 3, 4, 5])", 15},
+		{"avg([1, 2, 3, 4])", 2.5},
+		{"avg(7)", 7},
+		{"stddev([2, 4, 4, 4, 5, 5, 7, 9])", 2},
+		{"stddev([1000000001, 1000000002, 1000000003])", 0.816496580927726},
+		{"stddev([5, 5, 5])", 0},
+		{"max([3, 9, 1, 4])", 9},
+	}
+
+	all := func(tree.Operation) bool { return true }
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			ast, err := parser.Build(tt.expression)
+			require.NoError(t, err)
+			call, ok := ast.Expression.(tree.Call)
+			require.True(t, ok)
+
+			expanded, ok := reduce.Expand(call)
+			require.True(t, ok)
+			// The expanded tree is calculated like the agents do it
+			result, _ := simplify.Simplify(tree.Ast{Expression: expanded}, all)
+			num, ok := result.Expression.(tree.Num)
+			require.True(t, ok)
+			assert.InDelta(t, tt.expected, float64(num), 1e-9)
+		})
+	}
+}
+
+func TestExpand_Stays(t *testing.T) {
+	for _, expression := range []string{"median([1, 2, 3])", "min(1, 2)", "sqrt(4)", "if(1, 2, 3)"} {
+		t.Run(expression, func(t *testing.T) {
+			ast, err := parser.Build(expression)
+			require.NoError(t, err)
+			call, ok := ast.Expression.(tree.Call)
+			require.True(t, ok)
+			_, ok = reduce.Expand(call)
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestRebalance(t *testing.T) {
+	tests := []struct {
+		expression string
+		depth      int
+	}{
+		{"1+2+3+4+5+6+7+8", 3},
+		{"2*3*4*5", 2},
+		{"1-2-3-4", 3},
+		{"(1+2+3+4) * 5 - 6", 4},
+		{"sqrt(1+2+3+4)", 3},
+		{"x", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			ast, err := parser.Build(tt.expression)
+			require.NoError(t, err)
+			rebalanced := reduce.Rebalance(ast)
+			assert.Equal(t, tt.depth, reduce.Depth(rebalanced.Expression))
+			assert.Equal(t, simplify.Count(ast.Expression), simplify.Count(rebalanced.Expression))
+		})
+	}
+
+	// The chain is long
+	ast, err := parser.Build(strings.Repeat("1+", 999) + "1")
+	require.NoError(t, err)
+	assert.Equal(t, 999, reduce.Depth(ast.Expression))
+	assert.Equal(t, 10, reduce.Depth(reduce.Rebalance(ast).Expression))
+}
+
+func TestDepth(t *testing.T) {
+	tests := []struct {
+		expression string
+		depth      int
+	}{
+		{"1", 0},
+		{"-(1 + 2)", 2},
+		{"sum([1, 2, 3, 4])", 2},
+		{"x > 0 ? 1 + 2 : 3", 2},
+		{"median([1 + 2, 3, 4])", 1},
+		{"median([1 + 2, 3])", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			ast, err := parser.Build(tt.expression)
+			require.NoError(t, err)
+			assert.Equal(t, tt.depth, reduce.Depth(ast.Expression))
+		})
+	}
+}
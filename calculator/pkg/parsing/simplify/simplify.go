@@ -0,0 +1,315 @@
+// This package makes the tree smaller before it is saved, so agents get less tasks
+package simplify
+
+import (
+	"math"
+	"math/big"
+	"slices"
+
+	pb "github.com/vandi37/Calculator-Models"
+	"github.com/vandi37/Calculator/pkg/parsing/reduce"
+	"github.com/vandi37/Calculator/pkg/parsing/tree"
+)
+
+// Says if the operation can be calculated by the orchestrator.
+// Operations that aren't enabled are always sent to agents, even with number operands
+type Enabled func(tree.Operation) bool
+
+// Folds operations with number operands and drops operations that don't change the value: x*1, x+0 etc.
+//
+// Returns the new tree and the number of eliminated nodes
+func Simplify(ast tree.Ast, enabled Enabled) (tree.Ast, int) {
+	before := Count(ast.Expression)
+	expr := simplify(ast.Expression, enabled)
+	return tree.Ast{Expression: expr, Currency: ast.Currency}, before - Count(expr)
+}
+
+func simplify(expr tree.ExpressionType, enabled Enabled) tree.ExpressionType {
+	switch v := expr.(type) {
+	case tree.Expression:
+		v.Left = simplify(v.Left, enabled)
+		v.Right = simplify(v.Right, enabled)
+		if !enabled(v.Operation) {
+			return v
+		}
+		left, leftOk := v.Left.(tree.Num)
+		right, rightOk := v.Right.(tree.Num)
+		if leftOk && rightOk {
+			if result, ok := Calc(v.Operation, float64(left), float64(right)); ok {
+				return tree.Num(result)
+			}
+			return v
+		}
+		if identity(v.Operation, right, rightOk, false) {
+			return v.Left
+		}
+		if identity(v.Operation, left, leftOk, true) {
+			return v.Right
+		}
+		return v
+	case tree.Unary:
+		v.Value = simplify(v.Value, enabled)
+		if num, ok := v.Value.(tree.Num); ok && enabled(v.Operation) {
+			if result, ok := Calc(v.Operation, float64(num), 0); ok {
+				return tree.Num(result)
+			}
+		}
+		return v
+	case tree.Call:
+		args := make([]tree.ExpressionType, len(v.Args))
+		for i, arg := range v.Args {
+			args[i] = simplify(arg, enabled)
+		}
+		v.Args = args
+		// Choosing the branch isn't a calculation, so it doesn't wait for the agents
+		if v.Operation == tree.Conditional {
+			if condition, ok := Truth(args[0]); ok {
+				if condition {
+					return args[1]
+				}
+				return args[2]
+			}
+			return v
+		}
+		if !enabled(v.Operation) {
+			return v
+		}
+		if aggregate(v.Operation) {
+			nums := make([]float64, len(args))
+			for i, arg := range args {
+				num, ok := arg.(tree.Num)
+				if !ok {
+					return v
+				}
+				nums[i] = float64(num)
+			}
+			if result, ok := Aggregate(v.Operation, nums); ok {
+				return tree.Num(result)
+			}
+			return v
+		}
+		if len(args) == 1 {
+			if num, ok := args[0].(tree.Num); ok {
+				if result, ok := Calc(v.Operation, float64(num), 0); ok {
+					return tree.Num(result)
+				}
+			}
+			return v
+		}
+		// Longer calls are saved as balanced binary operations, so the pairs of numbers in them are folded: max(x, 1, 2) is max(x, 2)
+		if expanded, ok := reduce.Expand(v); ok {
+			return simplify(expanded, enabled)
+		}
+		if len(args) == 2 {
+			first, firstOk := args[0].(tree.Num)
+			second, secondOk := args[1].(tree.Num)
+			if firstOk && secondOk {
+				if result, ok := Calc(v.Operation, float64(first), float64(second)); ok {
+					return tree.Num(result)
+				}
+			}
+		}
+		return v
+	default:
+		return expr
+	}
+}
+
+func aggregate(operation tree.Operation) bool {
+	return operation == tree.Sum || operation == tree.Avg || operation == tree.Median || operation == tree.Stddev
+}
+
+// Calculates the aggregate of the numbers, the order of the additions differs from the agents
+func Aggregate(operation tree.Operation, nums []float64) (float64, bool) {
+	if len(nums) == 0 {
+		return 0, false
+	}
+	var f float64
+	switch operation {
+	case tree.Sum:
+		for _, num := range nums {
+			f += num
+		}
+	case tree.Avg:
+		for _, num := range nums {
+			f += num
+		}
+		f /= float64(len(nums))
+	case tree.Median:
+		sorted := slices.Clone(nums)
+		slices.Sort(sorted)
+		half := len(sorted) / 2
+		f = sorted[half]
+		if len(sorted)%2 == 0 {
+			f = (sorted[half-1] + sorted[half]) / 2
+		}
+	case tree.Stddev:
+		avg, _ := Aggregate(tree.Avg, nums)
+		for _, num := range nums {
+			f += (num - avg) * (num - avg)
+		}
+		f = math.Sqrt(f / float64(len(nums)))
+	default:
+		return 0, false
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, false
+	}
+	return f, true
+}
+
+// Checks that the operand doesn't change the other side. First is true if the operand is on the left
+func identity(operation tree.Operation, operand tree.Num, ok bool, first bool) bool {
+	if !ok {
+		return false
+	}
+	switch pb.Operation(operation) {
+	case pb.Operation_ADD:
+		return operand == 0
+	case pb.Operation_MULTIPLY:
+		return operand == 1
+	case pb.Operation_SUBTRACT:
+		return operand == 0 && !first
+	case pb.Operation_DIVIDE, pb.Operation(tree.Power):
+		return operand == 1 && !first
+	default:
+		return false
+	}
+}
+
+// Calculates the operation like the agent does.
+// False is returned if the operation fails, the agent should report the error then
+func Calc(operation tree.Operation, a, b float64) (float64, bool) {
+	var f float64
+	switch pb.Operation(operation) {
+	case pb.Operation_ADD:
+		f = a + b
+	case pb.Operation_SUBTRACT:
+		f = a - b
+	case pb.Operation_MULTIPLY:
+		f = a * b
+	case pb.Operation_DIVIDE:
+		if b == 0 {
+			return 0, false
+		}
+		f = a / b
+	case pb.Operation(tree.Power):
+		f = math.Pow(a, b)
+	case pb.Operation(tree.Modulo):
+		if b == 0 {
+			return 0, false
+		}
+		f = math.Mod(a, b)
+		if f != 0 && (f < 0) != (b < 0) {
+			f += b
+		}
+	case pb.Operation(tree.IntDivide):
+		if b == 0 {
+			return 0, false
+		}
+		f = math.Floor(a / b)
+	case pb.Operation(tree.Sqrt):
+		if a < 0 {
+			return 0, false
+		}
+		f = math.Sqrt(a)
+	case pb.Operation(tree.Abs):
+		f = math.Abs(a)
+	case pb.Operation(tree.Round):
+		p := math.Pow(10, math.Trunc(b))
+		f = math.Round(a*p) / p
+	case pb.Operation(tree.Ln):
+		if a <= 0 {
+			return 0, false
+		}
+		f = math.Log(a)
+	case pb.Operation(tree.Log):
+		if a <= 0 || b <= 0 || b == 1 {
+			return 0, false
+		}
+		f = math.Log(a) / math.Log(b)
+	case pb.Operation(tree.Min):
+		f = math.Min(a, b)
+	case pb.Operation(tree.Max):
+		f = math.Max(a, b)
+	case pb.Operation(tree.Negate):
+		f = -a
+	case pb.Operation(tree.Less):
+		f = boolean(a < b)
+	case pb.Operation(tree.LessEqual):
+		f = boolean(a <= b)
+	case pb.Operation(tree.Greater):
+		f = boolean(a > b)
+	case pb.Operation(tree.GreaterEqual):
+		f = boolean(a >= b)
+	case pb.Operation(tree.Equal):
+		f = boolean(a == b)
+	case pb.Operation(tree.NotEqual):
+		f = boolean(a != b)
+	case pb.Operation(tree.And):
+		f = boolean(a != 0 && b != 0)
+	case pb.Operation(tree.Or):
+		f = boolean(a != 0 || b != 0)
+	case pb.Operation(tree.Not):
+		f = boolean(a == 0)
+	default:
+		return 0, false
+	}
+	// NaN and infinity are left for the agent too
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, false
+	}
+	return f, true
+}
+
+func boolean(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Any number except zero is true, false is returned as the second value if the expression isn't a number
+func Truth(expr tree.ExpressionType) (bool, bool) {
+	switch v := expr.(type) {
+	case tree.Num:
+		return v != 0, true
+	case tree.Decimal:
+		r, ok := new(big.Rat).SetString(string(v))
+		return ok && r.Sign() != 0, ok
+	case tree.Rational:
+		r, ok := new(big.Rat).SetString(string(v))
+		return ok && r.Sign() != 0, ok
+	case tree.Complex:
+		return v.Re != 0 || v.Im != 0, true
+	case tree.Quantity:
+		return v.Value != 0, true
+	default:
+		return false, false
+	}
+}
+
+// Counts the nodes that are saved for the tree
+func Count(expr tree.ExpressionType) int {
+	switch v := expr.(type) {
+	case tree.Expression:
+		return 1 + Count(v.Left) + Count(v.Right)
+	case tree.Unary:
+		return 1 + Count(v.Value)
+	case tree.Call:
+		if expanded, ok := reduce.Expand(v); ok {
+			return Count(expanded)
+		}
+		count := 0
+		for _, arg := range v.Args {
+			count += Count(arg)
+		}
+		// The conditional and the median are one node, other calls with more arguments are binary operations
+		if v.Operation == tree.Conditional || v.Operation == tree.Median {
+			return count + 1
+		}
+		return count + max(len(v.Args)-1, 1)
+	default:
+		return 1
+	}
+}
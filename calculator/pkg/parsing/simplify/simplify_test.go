@@ -0,0 +1,225 @@
+package simplify_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	pb "github.com/vandi37/Calculator-Models"
+	"github.com/vandi37/Calculator/pkg/parsing/parser"
+	"github.com/vandi37/Calculator/pkg/parsing/simplify"
+	"github.com/vandi37/Calculator/pkg/parsing/tree"
+)
+
+func all(tree.Operation) bool { return true }
+
+func only(operations ...tree.Operation) simplify.Enabled {
+	return func(operation tree.Operation) bool {
+		for _, o := range operations {
+			if o == operation {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func TestSimplify(t *testing.T) {
+	add := tree.Operation(pb.Operation_ADD)
+	mul := tree.Operation(pb.Operation_MULTIPLY)
+
+	tests := []struct {
+		name       string
+		expression string
+		variables  map[string]float64
+		enabled    simplify.Enabled
+		expected   tree.ExpressionType
+		eliminated int
+	}{
+		{
+			name:       "All constants",
+			expression: "2*3+4*5",
+			enabled:    all,
+			expected:   tree.Num(26),
+			eliminated: 6,
+		},
+		{
+			name:       "Only multiplication",
+			expression: "2*3+4*5",
+			enabled:    only(mul),
+			expected:   tree.Expression{Left: tree.Num(6), Operation: add, Right: tree.Num(20)},
+			eliminated: 4,
+		},
+		{
+			name:       "Nothing enabled",
+			expression: "2*3+4*5",
+			enabled:    only(),
+			expected: tree.Expression{
+				Left:      tree.Expression{Left: tree.Num(2), Operation: mul, Right: tree.Num(3)},
+				Operation: add,
+				Right:     tree.Expression{Left: tree.Num(4), Operation: mul, Right: tree.Num(5)},
+			},
+			eliminated: 0,
+		},
+		{
+			name:       "Identities",
+			expression: "(x*1 + 0) - 0",
+			enabled:    all,
+			expected:   tree.Var{Name: "x"},
+			eliminated: 6,
+		},
+		{
+			name:       "Right identities only",
+			expression: "1/x - (0 - x)",
+			enabled:    all,
+			expected: tree.Expression{
+				Left:      tree.Expression{Left: tree.Num(1), Operation: tree.Operation(pb.Operation_DIVIDE), Right: tree.Var{Name: "x"}},
+				Operation: tree.Operation(pb.Operation_SUBTRACT),
+				Right:     tree.Expression{Left: tree.Num(0), Operation: tree.Operation(pb.Operation_SUBTRACT), Right: tree.Var{Name: "x"}},
+			},
+			eliminated: 0,
+		},
+		{
+			name:       "Division by zero is left for the agent",
+			expression: "1/0",
+			enabled:    all,
+			expected:   tree.Expression{Left: tree.Num(1), Operation: tree.Operation(pb.Operation_DIVIDE), Right: tree.Num(0)},
+			eliminated: 0,
+		},
+		{
+			name:       "Functions",
+			expression: "sqrt(16) + max(1, 5, x, 2)",
+			enabled:    all,
+			expected: tree.Expression{
+				Left:      tree.Num(4),
+				Operation: add,
+				Right: tree.Expression{
+					Left:      tree.Num(5),
+					Operation: tree.Max,
+					Right:     tree.Expression{Left: tree.Var{Name: "x"}, Operation: tree.Max, Right: tree.Num(2)},
+				},
+			},
+			eliminated: 3,
+		},
+		{
+			name:       "Numbers after a variable",
+			expression: "max(x, 1, 2)",
+			enabled:    all,
+			expected:   tree.Expression{Left: tree.Var{Name: "x"}, Operation: tree.Max, Right: tree.Num(2)},
+			eliminated: 2,
+		},
+		{
+			name:       "Negation",
+			expression: "-(2+3)",
+			enabled:    all,
+			expected:   tree.Num(-5),
+			eliminated: 3,
+		},
+		{
+			name:       "Chosen branch",
+			expression: "2 > 1 ? x : y * 2",
+			enabled:    all,
+			expected:   tree.Var{Name: "x"},
+			eliminated: 7,
+		},
+		{
+			name:       "Branch is chosen even if nothing is enabled",
+			expression: "0 ? x : y",
+			enabled:    only(),
+			expected:   tree.Var{Name: "y"},
+			eliminated: 3,
+		},
+		{
+			name:       "Aggregates",
+			expression: "avg([1, 2, 3]) + median([x, 1])",
+			enabled:    all,
+			expected: tree.Expression{
+				Left:      tree.Num(2),
+				Operation: add,
+				Right:     tree.Call{Name: "median", Operation: tree.Median, Args: []tree.ExpressionType{tree.Var{Name: "x"}, tree.Num(1)}},
+			},
+			eliminated: 6,
+		},
+		{
+			name:       "Even median",
+			expression: "median([4, 1, 3, 2])",
+			enabled:    all,
+			expected:   tree.Num(2.5),
+			eliminated: 4,
+		},
+		{
+			name:       "Unknown condition",
+			expression: "x ? 1 + 1 : 3",
+			enabled:    all,
+			expected: tree.Call{Name: "if", Operation: tree.Conditional, Args: []tree.ExpressionType{
+				tree.Var{Name: "x"}, tree.Num(2), tree.Num(3),
+			}},
+			eliminated: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, err := parser.Build(tt.expression)
+			require.NoError(t, err)
+
+			simplified, eliminated := simplify.Simplify(ast, tt.enabled)
+			assert.Equal(t, tt.expected, simplified.Expression)
+			assert.Equal(t, tt.eliminated, eliminated)
+		})
+	}
+}
+
+func TestCalc(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation tree.Operation
+		a, b      float64
+		expected  float64
+		ok        bool
+	}{
+		{"Modulo has the sign of the divisor", tree.Modulo, -7, 2, 1, true},
+		{"Integer division rounds down", tree.IntDivide, -7, 2, -4, true},
+		{"Round", tree.Round, 3.14159, 2, 3.14, true},
+		{"Log base", tree.Log, 8, 2, 3, true},
+		{"Negative sqrt", tree.Sqrt, -1, 0, 0, false},
+		{"Invalid log base", tree.Log, 8, 1, 0, false},
+		{"Infinity", tree.Power, 10, 1000, 0, false},
+		{"Less", tree.Less, 1, 2, 1, true},
+		{"Not equal", tree.NotEqual, 2, 2, 0, true},
+		{"And", tree.And, 3, 0, 0, true},
+		{"Or", tree.Or, 0, -1, 1, true},
+		{"Not", tree.Not, 0, 0, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, ok := simplify.Calc(tt.operation, tt.a, tt.b)
+			assert.Equal(t, tt.ok, ok)
+			assert.InDelta(t, tt.expected, result, 1e-9)
+		})
+	}
+}
+
+func TestTruth(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression tree.ExpressionType
+		truth, ok  bool
+	}{
+		{"Zero", tree.Num(0), false, true},
+		{"Negative", tree.Num(-1), true, true},
+		{"Decimal zero", tree.Decimal("0.000"), false, true},
+		{"Rational", tree.Rational("1/3"), true, true},
+		{"Imaginary", tree.Complex{Im: 1}, true, true},
+		{"Variable", tree.Var{Name: "x"}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			truth, ok := simplify.Truth(tt.expression)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.truth, truth)
+		})
+	}
+}
@@ -15,8 +15,27 @@ const (
 	Subtraction
 	Multiplication
 	Division
+	Modulo
+	IntDivision
+	Power
 	BracketOpen
 	BracketClose
+	Identifier
+	Comma
+	Imaginary
+	Less
+	LessEqual
+	Greater
+	GreaterEqual
+	Equal
+	NotEqual
+	And
+	Or
+	Not
+	Question
+	Colon
+	ListOpen
+	ListClose
 	EOF = -2
 )
 
@@ -32,10 +51,48 @@ func (k TokenKind) String() string {
 		return "[multiplication]"
 	case Division:
 		return "[division]"
+	case Modulo:
+		return "[modulo]"
+	case IntDivision:
+		return "[integer division]"
+	case Power:
+		return "[power]"
 	case BracketOpen:
 		return "[opening bracket]"
 	case BracketClose:
 		return "[closing bracket]"
+	case Identifier:
+		return "[identifier]"
+	case Comma:
+		return "[comma]"
+	case Imaginary:
+		return "[imaginary]"
+	case Less:
+		return "[less]"
+	case LessEqual:
+		return "[less or equal]"
+	case Greater:
+		return "[greater]"
+	case GreaterEqual:
+		return "[greater or equal]"
+	case Equal:
+		return "[equal]"
+	case NotEqual:
+		return "[not equal]"
+	case And:
+		return "[and]"
+	case Or:
+		return "[or]"
+	case Not:
+		return "[not]"
+	case Question:
+		return "[question mark]"
+	case Colon:
+		return "[colon]"
+	case ListOpen:
+		return "[opening square bracket]"
+	case ListClose:
+		return "[closing square bracket]"
 	case EOF:
 		return "[eof]"
 	default:
@@ -45,12 +102,38 @@ func (k TokenKind) String() string {
 
 type Token struct {
 	Kind  TokenKind `json:"kind"`
-	Value float64   `json:"value"`
+	Value float64   `json:"value"`          // For imaginary numbers it is the coefficient: 2i is 2
+	Name  string    `json:"name,omitempty"` // Only for identifiers
+	// Only for numbers, the exact decimal text of the number: "1_000,50" is "1000.50", "0xFF" is "255"
+	Literal string `json:"literal,omitempty"`
+	// Only for numbers, the unit text right after the number: "9.81 m/s^2" is "m/s^2"
+	Unit string `json:"unit,omitempty"`
+	// Only for numbers, the currency code right after the number: "100 USD" is "USD"
+	Currency string `json:"currency,omitempty"`
+	// Place of the token in the expression, both are in bytes
+	Start  int `json:"start"`
+	Length int `json:"length"`
+}
+
+// Byte offset right after the token
+func (t Token) End() int {
+	return t.Start + t.Length
 }
 
 func (t Token) String() string {
-	if t.Kind == Number {
+	switch t.Kind {
+	case Number:
+		if t.Unit != "" {
+			return fmt.Sprint(t.Value) + " " + t.Unit
+		}
+		if t.Currency != "" {
+			return fmt.Sprint(t.Value) + " " + t.Currency
+		}
 		return fmt.Sprint(t.Value)
+	case Imaginary:
+		return fmt.Sprint(t.Value) + "i"
+	case Identifier:
+		return t.Name
 	}
 	return t.Kind.String()
 }
@@ -59,5 +142,9 @@ var EmptyToken = Token{Kind: -1, Value: -1}
 var EOFToken = Token{Kind: EOF, Value: -2}
 
 func BuildToken(kind TokenKind, val float64) Token {
-	return Token{kind, val}
+	return Token{Kind: kind, Value: val}
+}
+
+func BuildIdentifier(name string) Token {
+	return Token{Kind: Identifier, Name: name}
 }
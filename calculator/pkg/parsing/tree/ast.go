@@ -5,13 +5,42 @@ package tree
 
 import (
 	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
 
 	pb "github.com/vandi37/Calculator-Models"
 	"github.com/vandi37/Calculator/pkg/parsing/tokens"
+	"github.com/vandi37/Calculator/pkg/units"
 )
 
 type Ast struct {
 	Expression ExpressionType
+	// Currency of the result written after the expression: 100 USD + 50 EUR in GBP
+	Currency string
+}
+
+// The way numbers are stored and calculated
+type Precision string
+
+const (
+	// Numbers are float64, it is the default
+	FloatPrecision Precision = "float"
+	// Numbers are exact decimals, the division is rounded by the agent
+	DecimalPrecision Precision = "decimal"
+	// Numbers are exact fractions
+	RationalPrecision Precision = "rational"
+)
+
+// Empty precision is the float one
+func (p Precision) IsValid() bool {
+	switch p {
+	case "", FloatPrecision, DecimalPrecision, RationalPrecision:
+		return true
+	default:
+		return false
+	}
 }
 
 type ExpressionType interface {
@@ -21,6 +50,40 @@ type ExpressionType interface {
 
 type Operation pb.Operation
 
+// Operations that the models don't declare.
+// They are sent to agents as pb.Operation values, so the agent must use the same numbers
+const (
+	Power Operation = iota + Operation(pb.Operation_DIVIDE) + 1
+	Modulo
+	IntDivide
+	Sqrt
+	Abs
+	Round
+	Ln
+	Log
+	Min
+	Max
+	Negate
+	// Comparisons and logic give 1 for true and 0 for false, any number except 0 is true
+	Less
+	LessEqual
+	Greater
+	GreaterEqual
+	Equal
+	NotEqual
+	And
+	Or
+	Not
+	// It is never sent to agents, the orchestrator chooses the branch when the condition is calculated
+	Conditional
+	// Aggregates are never sent to agents too, they are split into balanced trees of binary operations.
+	// The median waits for its items, then the orchestrator takes the middle one
+	Sum
+	Avg
+	Median
+	Stddev
+)
+
 func SepFrom(kind tokens.TokenKind) (Operation, bool) {
 	switch kind {
 	case tokens.Addition:
@@ -31,6 +94,28 @@ func SepFrom(kind tokens.TokenKind) (Operation, bool) {
 		return Operation(pb.Operation_MULTIPLY), true
 	case tokens.Division:
 		return Operation(pb.Operation_DIVIDE), true
+	case tokens.Power:
+		return Power, true
+	case tokens.Modulo:
+		return Modulo, true
+	case tokens.IntDivision:
+		return IntDivide, true
+	case tokens.Less:
+		return Less, true
+	case tokens.LessEqual:
+		return LessEqual, true
+	case tokens.Greater:
+		return Greater, true
+	case tokens.GreaterEqual:
+		return GreaterEqual, true
+	case tokens.Equal:
+		return Equal, true
+	case tokens.NotEqual:
+		return NotEqual, true
+	case tokens.And:
+		return And, true
+	case tokens.Or:
+		return Or, true
 	default:
 		return Operation(-1), false
 	}
@@ -46,6 +131,54 @@ func (s Operation) String() string {
 		return "*"
 	case pb.Operation_DIVIDE:
 		return "/"
+	case pb.Operation(Power):
+		return "^"
+	case pb.Operation(Modulo):
+		return "%"
+	case pb.Operation(IntDivide):
+		return "//"
+	case pb.Operation(Sqrt):
+		return "sqrt"
+	case pb.Operation(Abs):
+		return "abs"
+	case pb.Operation(Round):
+		return "round"
+	case pb.Operation(Ln), pb.Operation(Log):
+		return "log"
+	case pb.Operation(Min):
+		return "min"
+	case pb.Operation(Max):
+		return "max"
+	case pb.Operation(Negate):
+		return "-"
+	case pb.Operation(Less):
+		return "<"
+	case pb.Operation(LessEqual):
+		return "<="
+	case pb.Operation(Greater):
+		return ">"
+	case pb.Operation(GreaterEqual):
+		return ">="
+	case pb.Operation(Equal):
+		return "=="
+	case pb.Operation(NotEqual):
+		return "!="
+	case pb.Operation(And):
+		return "&&"
+	case pb.Operation(Or):
+		return "||"
+	case pb.Operation(Not):
+		return "!"
+	case pb.Operation(Conditional):
+		return "if"
+	case pb.Operation(Sum):
+		return "sum"
+	case pb.Operation(Avg):
+		return "avg"
+	case pb.Operation(Median):
+		return "median"
+	case pb.Operation(Stddev):
+		return "stddev"
 	default:
 		return "[unknown separator]"
 	}
@@ -63,6 +196,80 @@ func (e Expression) String() string {
 	return fmt.Sprintf("(%s) %s (%s)", e.Left.String(), e.Operation.String(), e.Right.String())
 }
 
+// Operation with one operand, like negation
+type Unary struct {
+	Operation Operation
+	Value     ExpressionType
+}
+
+func (u Unary) expression() {}
+
+func (u Unary) String() string {
+	return fmt.Sprintf("%s(%s)", u.Operation.String(), u.Value.String())
+}
+
+// Call of a function saved by the user, it must be replaced by the body of the function before creating nodes
+type Apply struct {
+	Name string
+	Args []ExpressionType
+}
+
+func (a Apply) expression() {}
+
+func (a Apply) String() string {
+	return Call{Name: a.Name, Args: a.Args}.String()
+}
+
+// Variable, it must be replaced by a number before creating nodes
+type Var struct {
+	Name string
+}
+
+func (v Var) expression() {}
+
+func (v Var) String() string {
+	return v.Name
+}
+
+// Complex number, it is used for imaginary literals (2i), the other numbers stay Num
+type Complex struct {
+	Re float64
+	Im float64
+}
+
+func (c Complex) expression() {}
+
+func (c Complex) String() string {
+	if c.Re == 0 {
+		return fmt.Sprintf("%vi", c.Im)
+	}
+	return fmt.Sprintf("(%v%+vi)", c.Re, c.Im)
+}
+
+// Number with a unit, the value is in the SI base units: 5 km is 5000 with the dimension of the length
+type Quantity struct {
+	Value     float64
+	Dimension units.Dimension
+}
+
+func (q Quantity) expression() {}
+
+func (q Quantity) String() string {
+	return fmt.Sprintf("%v %s", q.Value, q.Dimension)
+}
+
+// Amount of money with the exact text of the number, it is converted to the currency of the result before creating nodes
+type Money struct {
+	Amount   string
+	Currency string
+}
+
+func (m Money) expression() {}
+
+func (m Money) String() string {
+	return fmt.Sprintf("%s %s", m.Amount, m.Currency)
+}
+
 type Num float64
 
 func (n Num) expression() {}
@@ -70,3 +277,95 @@ func (n Num) expression() {}
 func (n Num) String() string {
 	return fmt.Sprint(float64(n))
 }
+
+// Exact decimal number, it is used instead of Num in the decimal precision
+type Decimal string
+
+func (d Decimal) expression() {}
+
+func (d Decimal) String() string {
+	return string(d)
+}
+
+func DecimalFrom(f float64) Decimal {
+	return Decimal(strconv.FormatFloat(f, 'g', -1, 64))
+}
+
+// Exact fraction written as p/q (or just p for integers), it is used instead of Num in the rational precision
+type Rational string
+
+func (r Rational) expression() {}
+
+func (r Rational) String() string {
+	return string(r)
+}
+
+// The fraction of the shortest decimal of the float, so 0.1 is 1/10
+func RationalFrom(f float64) Rational {
+	r, _ := new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, 64))
+	return Rational(r.RatString())
+}
+
+// The float value of an exact decimal or fraction, it is kept beside the exact one.
+// Values out of the float range are clamped to ±math.MaxFloat64, false is returned for invalid text
+func Approximate(exact string) (float64, bool) {
+	r, ok := new(big.Rat).SetString(exact)
+	if !ok {
+		return 0, false
+	}
+	f, _ := r.Float64()
+	if math.IsInf(f, 0) {
+		f = math.Copysign(math.MaxFloat64, f)
+	}
+	return f, true
+}
+
+// Folds the negation of a number, false is returned if the value isn't a number
+func Negated(value ExpressionType) (ExpressionType, bool) {
+	switch v := value.(type) {
+	case Num:
+		return -v, true
+	case Decimal:
+		if after, ok := strings.CutPrefix(string(v), "-"); ok {
+			return Decimal(after), true
+		}
+		return "-" + v, true
+	case Rational:
+		if after, ok := strings.CutPrefix(string(v), "-"); ok {
+			return Rational(after), true
+		}
+		return "-" + v, true
+	case Complex:
+		return Complex{Re: -v.Re, Im: -v.Im}, true
+	case Quantity:
+		return Quantity{Value: -v.Value, Dimension: v.Dimension}, true
+	case Money:
+		if after, ok := strings.CutPrefix(v.Amount, "-"); ok {
+			return Money{Amount: after, Currency: v.Currency}, true
+		}
+		return Money{Amount: "-" + v.Amount, Currency: v.Currency}, true
+	default:
+		return value, false
+	}
+}
+
+// Call of a built-in function.
+//
+// With one argument the operation is unary, with two it is binary.
+// Longer calls are expanded to balanced binary operations: max(a, b, c, d) is max(max(a, b), max(c, d)).
+// The conditional if(c, a, b) isn't folded, c ? a : b is the same call
+type Call struct {
+	Name      string
+	Operation Operation
+	Args      []ExpressionType
+}
+
+func (c Call) expression() {}
+
+func (c Call) String() string {
+	args := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		args[i] = arg.String()
+	}
+	return fmt.Sprintf("%s(%s)", c.Name, strings.Join(args, ", "))
+}
@@ -0,0 +1,236 @@
+// This package has units of measurement for quantities in expressions
+//
+// A quantity is stored in SI base units, so 5 km is 5000 with the dimension of the length.
+// The agent has the same base symbols, the dimension is sent to it as the normalized text ("m*kg/s^2")
+package units
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/vandi37/vanerrors"
+)
+
+const (
+	UnknownUnit       = "unknown unit"
+	IncompatibleUnits = "incompatible units"
+)
+
+// Exponents of the SI base units and the money, zero dimension is a plain number
+type Dimension [8]int8
+
+// The order of the base units in the dimension and in the normalized text.
+// Money isn't SI, amounts are converted to the currency of the result before the calculation
+var base = [len(Dimension{})]string{"m", "kg", "s", "A", "K", "mol", "cd", "money"}
+
+// Dimension of currency amounts (100 USD)
+var Money = Dimension{0, 0, 0, 0, 0, 0, 0, 1}
+
+func (d Dimension) IsZero() bool {
+	return d == Dimension{}
+}
+
+// False is returned if an exponent leaves the int8 range: m^100 * m^100
+func (d Dimension) Mul(o Dimension) (Dimension, bool) {
+	ok := true
+	for i := range d {
+		d[i], ok = exponent(int(d[i])+int(o[i]), ok)
+	}
+	return d, ok
+}
+
+func (d Dimension) Div(o Dimension) (Dimension, bool) {
+	ok := true
+	for i := range d {
+		d[i], ok = exponent(int(d[i])-int(o[i]), ok)
+	}
+	return d, ok
+}
+
+func (d Dimension) Pow(n int8) (Dimension, bool) {
+	ok := true
+	for i := range d {
+		d[i], ok = exponent(int(d[i])*int(n), ok)
+	}
+	return d, ok
+}
+
+// Keeps ok false once one of the exponents didn't fit
+func exponent(e int, ok bool) (int8, bool) {
+	return int8(e), ok && e >= math.MinInt8 && e <= math.MaxInt8
+}
+
+// The normalized text: "m*kg/s^2", the zero dimension is ""
+func (d Dimension) String() string {
+	var num, den []string
+	for i, e := range d {
+		switch {
+		case e > 0:
+			num = append(num, withExponent(base[i], e))
+		case e < 0:
+			den = append(den, withExponent(base[i], -e))
+		}
+	}
+	if len(num) == 0 && len(den) == 0 {
+		return ""
+	}
+	text := strings.Join(num, "*")
+	if text == "" {
+		text = "1"
+	}
+	for _, part := range den {
+		text += "/" + part
+	}
+	return text
+}
+
+func withExponent(symbol string, e int8) string {
+	if e == 1 {
+		return symbol
+	}
+	return symbol + "^" + strconv.Itoa(int(e))
+}
+
+// A value in the unit is Factor times the value in the SI base units
+type Unit struct {
+	Factor    float64
+	Dimension Dimension
+}
+
+func (u Unit) Mul(o Unit) (Unit, bool) {
+	d, ok := u.Dimension.Mul(o.Dimension)
+	return Unit{Factor: u.Factor * o.Factor, Dimension: d}, ok
+}
+
+func (u Unit) Div(o Unit) (Unit, bool) {
+	d, ok := u.Dimension.Div(o.Dimension)
+	return Unit{Factor: u.Factor / o.Factor, Dimension: d}, ok
+}
+
+func (u Unit) Pow(n int8) (Unit, bool) {
+	factor := 1.0
+	for range abs(n) {
+		factor *= u.Factor
+	}
+	if n < 0 {
+		factor = 1 / factor
+	}
+	d, ok := u.Dimension.Pow(n)
+	return Unit{Factor: factor, Dimension: d}, ok
+}
+
+func abs(n int8) int8 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+var (
+	length      = Dimension{1, 0, 0, 0, 0, 0, 0, 0}
+	mass        = Dimension{0, 1, 0, 0, 0, 0, 0, 0}
+	duration    = Dimension{0, 0, 1, 0, 0, 0, 0, 0}
+	current     = Dimension{0, 0, 0, 1, 0, 0, 0, 0}
+	temperature = Dimension{0, 0, 0, 0, 1, 0, 0, 0}
+	amount      = Dimension{0, 0, 0, 0, 0, 1, 0, 0}
+	luminosity  = Dimension{0, 0, 0, 0, 0, 0, 1, 0}
+
+	force       = Dimension{1, 1, -2, 0, 0, 0, 0, 0}
+	energy      = Dimension{2, 1, -2, 0, 0, 0, 0, 0}
+	power       = Dimension{2, 1, -3, 0, 0, 0, 0, 0}
+	pressure    = Dimension{-1, 1, -2, 0, 0, 0, 0, 0}
+	frequency   = Dimension{0, 0, -1, 0, 0, 0, 0, 0}
+	charge      = Dimension{0, 0, 1, 1, 0, 0, 0, 0}
+	voltage     = Dimension{2, 1, -3, -1, 0, 0, 0, 0}
+	resistance  = Dimension{2, 1, -3, -2, 0, 0, 0, 0}
+	capacitance = Dimension{-2, -1, 4, 2, 0, 0, 0, 0}
+	inductance  = Dimension{2, 1, -2, -2, 0, 0, 0, 0}
+	volume      = Dimension{3, 0, 0, 0, 0, 0, 0, 0}
+)
+
+// Temperatures with an offset (°C, °F) aren't here, they can't be just multiplied
+var units = map[string]Unit{
+	"m": {1, length}, "km": {1e3, length}, "cm": {1e-2, length}, "mm": {1e-3, length}, "um": {1e-6, length}, "nm": {1e-9, length},
+	"mi": {1609.344, length}, "yd": {0.9144, length}, "ft": {0.3048, length}, "in": {0.0254, length},
+	"kg": {1, mass}, "g": {1e-3, mass}, "mg": {1e-6, mass}, "t": {1e3, mass}, "lb": {0.45359237, mass}, "oz": {0.028349523125, mass},
+	"s": {1, duration}, "ms": {1e-3, duration}, "us": {1e-6, duration}, "ns": {1e-9, duration},
+	"min": {60, duration}, "h": {3600, duration}, "day": {86400, duration},
+	"A": {1, current}, "mA": {1e-3, current}, "kA": {1e3, current},
+	"K":   {1, temperature},
+	"mol": {1, amount},
+	"cd":  {1, luminosity},
+	"N":   {1, force}, "kN": {1e3, force},
+	"J": {1, energy}, "kJ": {1e3, energy}, "Wh": {3600, energy}, "kWh": {3.6e6, energy},
+	"W": {1, power}, "mW": {1e-3, power}, "kW": {1e3, power}, "MW": {1e6, power},
+	"Pa": {1, pressure}, "kPa": {1e3, pressure}, "bar": {1e5, pressure},
+	"Hz": {1, frequency}, "kHz": {1e3, frequency}, "MHz": {1e6, frequency}, "GHz": {1e9, frequency},
+	"C": {1, charge},
+	"V": {1, voltage}, "mV": {1e-3, voltage}, "kV": {1e3, voltage},
+	"ohm": {1, resistance}, "kohm": {1e3, resistance}, "Mohm": {1e6, resistance}, "Ω": {1, resistance},
+	"F": {1, capacitance}, "uF": {1e-6, capacitance}, "nF": {1e-9, capacitance}, "pF": {1e-12, capacitance},
+	"H": {1, inductance}, "mH": {1e-3, inductance},
+	"L": {1e-3, volume}, "mL": {1e-6, volume},
+}
+
+func Lookup(name string) (Unit, bool) {
+	u, ok := units[name]
+	return u, ok
+}
+
+// Parses the unit text like "km/h", "m/s^2" or "kg*m^2/s^2".
+// Spaces aren't allowed, "1/s" is the only number
+func Parse(text string) (Unit, error) {
+	if text == "" {
+		return Unit{}, vanerrors.New(UnknownUnit, "empty unit")
+	}
+	result := Unit{Factor: 1}
+	div, ok := false, true
+	for i, part := range split(text) {
+		if part == "*" || part == "/" {
+			div = part == "/"
+			continue
+		}
+		name, exponent, hasExponent := strings.Cut(part, "^")
+		var u Unit
+		if name == "1" && i == 0 && !hasExponent {
+			u = Unit{Factor: 1}
+		} else if known, ok := Lookup(name); ok {
+			u = known
+		} else {
+			return Unit{}, vanerrors.New(UnknownUnit, fmt.Sprintf("%s in %s", name, text))
+		}
+		if hasExponent {
+			n, err := strconv.ParseInt(exponent, 10, 8)
+			if err != nil || n == 0 {
+				return Unit{}, vanerrors.New(UnknownUnit, fmt.Sprintf("invalid exponent %s in %s", exponent, text))
+			}
+			if u, ok = u.Pow(int8(n)); !ok {
+				return Unit{}, vanerrors.New(UnknownUnit, fmt.Sprintf("exponent out of range in %s", text))
+			}
+		}
+		if div {
+			result, ok = result.Div(u)
+		} else {
+			result, ok = result.Mul(u)
+		}
+		if !ok {
+			return Unit{}, vanerrors.New(UnknownUnit, fmt.Sprintf("exponent out of range in %s", text))
+		}
+	}
+	return result, nil
+}
+
+// Splits the text into names with exponents and the signs between them, a sign without a name is an error later
+func split(text string) []string {
+	var parts []string
+	start := 0
+	for i, r := range text {
+		if r == '*' || r == '/' {
+			parts = append(parts, text[start:i], string(r))
+			start = i + 1
+		}
+	}
+	return append(parts, text[start:])
+}
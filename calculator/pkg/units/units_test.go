@@ -0,0 +1,79 @@
+package units_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vandi37/Calculator/pkg/units"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text      string
+		factor    float64
+		dimension string
+	}{
+		{"m", 1, "m"},
+		{"km/h", 1000.0 / 3600, "m/s"},
+		{"m/s^2", 1, "m/s^2"},
+		{"kg*m^2/s^2", 1, "m^2*kg/s^2"},
+		{"kWh", 3.6e6, "m^2*kg/s^2"},
+		{"1/s", 1, "1/s"},
+		{"Hz", 1, "1/s"},
+		{"cm^3", 1e-6, "m^3"},
+		{"m/m", 1, ""},
+		{"L", 1e-3, "m^3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			u, err := units.Parse(tt.text)
+			require.NoError(t, err)
+			assert.InDelta(t, tt.factor, u.Factor, tt.factor*1e-12)
+			assert.Equal(t, tt.dimension, u.Dimension.String())
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []string{"", "parsec", "m/", "m^0", "m^x", "2/s", "m*1", "m^100*m^100"}
+	for _, text := range tests {
+		t.Run(text, func(t *testing.T) {
+			_, err := units.Parse(text)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), units.UnknownUnit)
+		})
+	}
+}
+
+func TestDimension(t *testing.T) {
+	speed, err := units.Parse("m/s")
+	require.NoError(t, err)
+	duration, err := units.Parse("s")
+	require.NoError(t, err)
+
+	d, ok := speed.Dimension.Mul(duration.Dimension)
+	assert.True(t, ok)
+	assert.Equal(t, "m", d.String())
+	d, ok = speed.Dimension.Div(duration.Dimension)
+	assert.True(t, ok)
+	assert.Equal(t, "m/s^2", d.String())
+	d, ok = speed.Dimension.Pow(2)
+	assert.True(t, ok)
+	assert.Equal(t, "m^2/s^2", d.String())
+	d, ok = speed.Dimension.Div(speed.Dimension)
+	assert.True(t, ok)
+	assert.True(t, d.IsZero())
+
+	big, ok := speed.Dimension.Pow(100)
+	require.True(t, ok)
+	_, ok = big.Mul(big)
+	assert.False(t, ok)
+	inverse, ok := big.Pow(-1)
+	require.True(t, ok)
+	_, ok = big.Div(inverse)
+	assert.False(t, ok)
+	_, ok = speed.Dimension.Pow(-128)
+	assert.False(t, ok)
+}
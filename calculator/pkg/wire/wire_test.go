@@ -0,0 +1,65 @@
+package wire_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	pb "github.com/vandi37/Calculator-Models"
+	"github.com/vandi37/Calculator/pkg/wire"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestString(t *testing.T) {
+	task := &pb.Task{Id: "1", Arg1: 0.1, Arg2: 0.2, Operation: pb.Operation_ADD}
+	wire.SetString(task, wire.Arg1Decimal, "0.1")
+	wire.SetString(task, wire.Arg2Decimal, "0.2")
+
+	data, err := proto.Marshal(task)
+	require.NoError(t, err)
+
+	var got pb.Task
+	require.NoError(t, proto.Unmarshal(data, &got))
+	assert.Equal(t, "1", got.Id)
+	assert.Equal(t, pb.Operation_ADD, got.Operation)
+
+	value, ok := wire.GetString(&got, wire.Arg1Decimal)
+	assert.True(t, ok)
+	assert.Equal(t, "0.1", value)
+
+	value, ok = wire.GetString(&got, wire.Arg2Decimal)
+	assert.True(t, ok)
+	assert.Equal(t, "0.2", value)
+
+	_, ok = wire.GetString(&pb.Result{Id: "1"}, wire.ResultDecimal)
+	assert.False(t, ok)
+}
+
+func TestString_LastWins(t *testing.T) {
+	result := &pb.Result{Id: "1"}
+	wire.SetString(result, wire.ResultDecimal, "1")
+	wire.SetString(result, wire.ResultDecimal, "2")
+
+	value, ok := wire.GetString(result, wire.ResultDecimal)
+	assert.True(t, ok)
+	assert.Equal(t, "2", value)
+}
+
+func TestDouble(t *testing.T) {
+	result := &pb.Result{Id: "1", Result: 3}
+	wire.SetString(result, wire.ResultRational, "3")
+	wire.SetDouble(result, wire.ResultImag, -0.5)
+
+	data, err := proto.Marshal(result)
+	require.NoError(t, err)
+
+	var got pb.Result
+	require.NoError(t, proto.Unmarshal(data, &got))
+
+	value, ok := wire.GetDouble(&got, wire.ResultImag)
+	assert.True(t, ok)
+	assert.Equal(t, -0.5, value)
+
+	_, ok = wire.GetDouble(&got, wire.ResultDecimal)
+	assert.False(t, ok)
+}